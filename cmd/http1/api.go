@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"http1.dev/internal/http1"
+)
+
+// maxAPIBody bounds JSON request bodies on the API endpoints.
+const maxAPIBody = 64 << 10
+
+// handleGradeAPI serves POST /api/v1/grade: it grades a hypothetical
+// configuration (http1.Configuration as JSON) without scanning anything,
+// e.g. {"h2": true, "h3": true, "tls_versions": ["TLS 1.3"], "hsts_max_age": 31536000}.
+func handleGradeAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, "use POST with a JSON configuration")
+		return
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody))
+	dec.DisallowUnknownFields()
+	var cfg http1.Configuration
+	if err := dec.Decode(&cfg); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid configuration: "+err.Error())
+		return
+	}
+	report, err := http1.GradeConfiguration(cfg)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, report)
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeAPIJSON(w, status, map[string]string{"error": msg})
+}
+
+// apiTokenEnv names the environment variable --api-token defaults to, which
+// keeps the token out of the process list.
+const apiTokenEnv = "HTTP1_API_TOKEN"
+
+// requireToken wraps h so it only runs for requests that present token as
+// an "Authorization: Bearer" header.
+func requireToken(token string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !tokenMatches(got, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http1"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing or wrong API token")
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// campaignSpec is a campaign file: the goal of a deprecation campaign, its
+// deadline and where the fleet's scan history is kept, e.g.
+//
+//	{"name": "HTTP/1.x deprecation", "forbid": "h1.0", "require": "h2",
+//	 "deadline": "2025-12", "history": ["scans/*.json"]}
+type campaignSpec struct {
+	Name string `json:"name"`
+	// MinGrade, Require and Forbid are the goal, as --fail-under and
+	// --require take them; Forbid lists versions that must be gone.
+	MinGrade string `json:"min_grade"`
+	Require  string `json:"require"`
+	Forbid   string `json:"forbid"`
+	// Deadline takes any form http1.ParseExpiry does.
+	Deadline string `json:"deadline"`
+	// History lists the scans' result files (--json or ndjson) as glob
+	// patterns relative to the campaign file.
+	History []string `json:"history"`
+}
+
+// campaign is a loaded campaign file.
+type campaign struct {
+	name     string
+	goal     http1.Goal
+	deadline time.Time
+	history  []string
+}
+
+func loadCampaign(path string) (*campaign, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec campaignSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid campaign file %s: %w", path, err)
+	}
+	c := &campaign{name: spec.Name}
+	if c.goal, err = http1.ParseGoal(spec.MinGrade, spec.Require, spec.Forbid); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if spec.Deadline != "" {
+		if c.deadline, err = http1.ParseExpiry(spec.Deadline); err != nil {
+			return nil, fmt.Errorf("%s: deadline: %w", path, err)
+		}
+	}
+	if len(spec.History) == 0 {
+		return nil, fmt.Errorf("%s: no history: list the scans' result files", path)
+	}
+	for _, pattern := range spec.History {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: history %q: %w", path, pattern, err)
+		}
+		c.history = append(c.history, pattern)
+	}
+	if c.name == "" {
+		c.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return c, nil
+}
+
+// progress reads the history as it is now and measures the campaign. Each
+// file is one scan, dated by its latest result or, failing that, its
+// modification time.
+func (c *campaign) progress() (http1.CampaignProgress, error) {
+	var files []string
+	for _, pattern := range c.history {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+	var snapshots []http1.Snapshot
+	for _, f := range files {
+		byTarget, err := loadBaseline(f)
+		if err != nil {
+			return http1.CampaignProgress{}, err
+		}
+		s := http1.Snapshot{Results: make([]http1.CheckResult, 0, len(byTarget))}
+		for _, res := range byTarget {
+			s.Results = append(s.Results, res)
+		}
+		if s.Time = http1.ScanTime(s.Results); s.Time.IsZero() {
+			if fi, err := os.Stat(f); err == nil {
+				s.Time = fi.ModTime()
+			}
+		}
+		snapshots = append(snapshots, s)
+	}
+	if len(snapshots) == 0 {
+		return http1.CampaignProgress{}, fmt.Errorf("no result files match the history %s", strings.Join(c.history, ", "))
+	}
+	return http1.TrackCampaign(c.goal, snapshots, c.deadline), nil
+}
+
+// campaignReport is the campaign subcommand's --json output.
+type campaignReport struct {
+	Name string `json:"name"`
+	http1.CampaignProgress
+}
+
+// runCampaign implements the "campaign" subcommand: it measures a fleet's
+// progress toward a campaign's goal over its scan history and prints the
+// burn-down. It returns exitPolicyFailed when the campaign is behind, so
+// CI can flag it.
+func runCampaign(args []string) int {
+	fs := flag.NewFlagSet("campaign", flag.ExitOnError)
+	jsonFlag := fs.Bool("json", false, "print the progress as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: http1 campaign [--json] campaign.json")
+		fmt.Fprintln(fs.Output(), "Shows a fleet's progress toward a campaign's goal over the scans in its history.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	c, err := loadCampaign(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "campaign: %v\n", err)
+		return 1
+	}
+	p, err := c.progress()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "campaign: %v\n", err)
+		return 1
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(campaignReport{Name: c.name, CampaignProgress: p}); err != nil {
+			fmt.Fprintf(os.Stderr, "campaign: %v\n", err)
+			return 1
+		}
+	} else {
+		printCampaign(c.name, p)
+	}
+	if !p.OnTrack {
+		return exitPolicyFailed
+	}
+	return 0
+}
+
+// campaignBarWidth is the width of the text burn-down's bars.
+const campaignBarWidth = 30
+
+// printCampaign writes the text burn-down of a campaign.
+func printCampaign(name string, p http1.CampaignProgress) {
+	last := p.Points[len(p.Points)-1]
+	fmt.Printf("%s: %.0f%% done (%d of %d targets)", name, last.Percent, last.Done, last.Targets)
+	if !p.Deadline.IsZero() {
+		fmt.Printf(", deadline %s", lastDay(p.Deadline))
+	}
+	fmt.Println()
+	fmt.Println()
+	for _, pt := range p.Points {
+		filled := int(math.Round(pt.Percent / 100 * campaignBarWidth))
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", campaignBarWidth-filled)
+		fmt.Printf("  %s  %s %3.0f%%  %d of %d\n", pt.Time.Format(time.DateOnly), bar, pt.Percent, pt.Done, pt.Targets)
+	}
+	fmt.Println()
+	fmt.Println(campaignOutlook(p))
+	if len(p.Remaining) > 0 {
+		fmt.Println()
+		fmt.Println("Still to do:")
+		targets := make([]string, 0, len(p.Remaining))
+		for t := range p.Remaining {
+			targets = append(targets, t)
+		}
+		slices.Sort(targets)
+		for _, t := range targets {
+			fmt.Printf("  %s: %s\n", t, strings.Join(p.Remaining[t], ", "))
+		}
+	}
+}
+
+// campaignOutlook says whether a campaign is done, and if not when it is
+// projected to be.
+func campaignOutlook(p http1.CampaignProgress) string {
+	last := p.Points[len(p.Points)-1]
+	switch {
+	case last.Targets > 0 && last.Done == last.Targets:
+		return "Goal reached."
+	case len(p.Points) == 1:
+		return "Only one scan so far, so there is no trend to project yet."
+	case p.Projected.IsZero():
+		return "No progress since the first scan, so there is no projected finish."
+	case p.Deadline.IsZero():
+		return fmt.Sprintf("Projected to finish %s.", p.Projected.Format(time.DateOnly))
+	case p.OnTrack:
+		return fmt.Sprintf("Projected to finish %s, on track for the deadline.", p.Projected.Format(time.DateOnly))
+	}
+	return fmt.Sprintf("Projected to finish %s, after the deadline.", p.Projected.Format(time.DateOnly))
+}
+
+// lastDay formats the last day before deadline, which http1.ParseExpiry
+// puts at the end of the day or month given, e.g. "2025-05-31" for
+// "2025-05".
+func lastDay(deadline time.Time) string {
+	return deadline.Add(-time.Nanosecond).Format(time.DateOnly)
+}
+
+// campaignView is the web dashboard's campaign page.
+type campaignView struct {
+	Name     string
+	Progress http1.CampaignProgress
+	Outlook  string
+	Deadline string
+	// Remaining lists the targets still to do, as "target: reasons".
+	Remaining []string
+	Error     string
+}
+
+// view measures the campaign for the web dashboard.
+func (c *campaign) view() *campaignView {
+	v := &campaignView{Name: c.name}
+	p, err := c.progress()
+	if err != nil {
+		v.Error = err.Error()
+		return v
+	}
+	v.Progress, v.Outlook = p, campaignOutlook(p)
+	if !p.Deadline.IsZero() {
+		v.Deadline = lastDay(p.Deadline)
+	}
+	for t, misses := range p.Remaining {
+		v.Remaining = append(v.Remaining, t+": "+strings.Join(misses, ", "))
+	}
+	slices.Sort(v.Remaining)
+	return v
+}
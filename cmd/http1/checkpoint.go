@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// checkpointVersion is the version of the --checkpoint journal layout.
+const checkpointVersion = 2
+
+// checkpointHeader is the first line of a --checkpoint journal. Every line
+// after it is a result the scan finished, as scanned, so --resume can
+// output them again without rescanning.
+type checkpointHeader struct {
+	Checkpoint int       `json:"httpver_checkpoint"`
+	Started    time.Time `json:"started"`
+}
+
+// checkpointJournal is what loadCheckpoint read from a --checkpoint journal.
+type checkpointJournal struct {
+	results map[string]http1.CheckResult // keyed by target
+	end     int64                        // offset just past the last complete line
+}
+
+// checkpointer appends each finished result to the --checkpoint journal as
+// one line of NDJSON, so a scan that is interrupted loses only the targets
+// it was still checking, however large the list.
+type checkpointer struct {
+	f         *os.File
+	journaled map[string]bool
+}
+
+// newCheckpointer starts a journal at path, replacing any file there, or,
+// with a journal --resume read, appends to it past its last complete line.
+func newCheckpointer(path string, resumed *checkpointJournal) (*checkpointer, error) {
+	c := &checkpointer{journaled: make(map[string]bool)}
+	if resumed != nil && resumed.end > 0 {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		// A line cut short when the last run was killed would otherwise
+		// run into the first one appended now.
+		if err := f.Truncate(resumed.end); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if _, err := f.Seek(resumed.end, io.SeekStart); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		for target := range resumed.results {
+			c.journaled[target] = true
+		}
+		c.f = f
+		return c, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c.f = f
+	if err := c.append(checkpointHeader{Checkpoint: checkpointVersion, Started: time.Now().UTC()}); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// add appends a finished result to the journal. Targets --max-duration cut
+// off are left for --resume to scan, and results the journal already has,
+// such as those --resume reuses, are not written again.
+func (c *checkpointer) add(res http1.CheckResult) error {
+	if res.Unscanned || c.journaled[res.Target] {
+		return nil
+	}
+	c.journaled[res.Target] = true
+	return c.append(res)
+}
+
+// append writes v as one line, in a single write so that an interrupted
+// scan leaves at most the last line incomplete.
+func (c *checkpointer) append(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = c.f.Write(append(line, '\n'))
+	return err
+}
+
+// close flushes the journal to disk and closes it.
+func (c *checkpointer) close() error {
+	err := c.f.Sync()
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// loadCheckpoint replays a --checkpoint journal. A last line without its
+// newline was cut short when the scan was interrupted and is ignored, so
+// its target is scanned again.
+func loadCheckpoint(path string) (*checkpointJournal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cp := &checkpointJournal{results: make(map[string]http1.CheckResult)}
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return cp, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 1 {
+			var h checkpointHeader
+			if err := json.Unmarshal(line, &h); err != nil {
+				return nil, fmt.Errorf("%s is not a checkpoint file: %v", path, err)
+			}
+			switch {
+			case h.Checkpoint == 0:
+				return nil, errors.New(path + " is not a checkpoint file")
+			case h.Checkpoint > checkpointVersion:
+				return nil, fmt.Errorf("%s was written by a newer http1 (checkpoint version %d)", path, h.Checkpoint)
+			case h.Checkpoint < checkpointVersion:
+				return nil, fmt.Errorf("%s was written by an older http1 (checkpoint version %d); scan again without --resume", path, h.Checkpoint)
+			}
+		} else if len(bytes.TrimSpace(line)) > 0 {
+			var res http1.CheckResult
+			if err := json.Unmarshal(line, &res); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+			cp.results[res.Target] = res
+		}
+		cp.end += int64(len(line))
+	}
+}
+
+// splitResumed implements --resume: it scans the targets the checkpoint has
+// no result for.
+func splitResumed(targets []string, done map[string]http1.CheckResult) (rest []string, resumed []http1.CheckResult) {
+	return splitReusable(targets, func(raw string) (http1.CheckResult, bool) {
+		res, ok := done[raw]
+		return res, ok
+	})
+}
+
+// resumeStream is splitResumed for streamed targets: it passes on the
+// entries the checkpoint has no result for and collects the checkpoint's
+// results for the rest, which the caller outputs once the stream ends.
+func resumeStream(feed <-chan string, done map[string]http1.CheckResult) (<-chan string, func() []http1.CheckResult) {
+	out := make(chan string)
+	finished := make(chan []http1.CheckResult, 1)
+	go func() {
+		defer close(out)
+		var resumed []http1.CheckResult
+		for entry := range feed {
+			rest, res := splitResumed([]string{entry}, done)
+			if len(rest) > 0 {
+				out <- entry
+				continue
+			}
+			resumed = append(resumed, res...)
+		}
+		finished <- resumed
+	}()
+	return out, func() []http1.CheckResult { return <-finished }
+}
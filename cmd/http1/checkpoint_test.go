@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"http1.dev/internal/http1"
+)
+
+func TestCheckpointJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.ndjson")
+	cp, err := newCheckpointer(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range []http1.CheckResult{
+		{Target: "a.example", Score: 90},
+		{Target: "b.example", Unscanned: true},
+		{Target: "c.example", Score: 70},
+	} {
+		if err := cp.add(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cp.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted run leaves its last line incomplete.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"target":"d.exam`); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	journal, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal.results) != 2 || journal.results["a.example"].Score != 90 || journal.results["c.example"].Score != 70 {
+		t.Fatalf("replayed %+v, want a.example and c.example", journal.results)
+	}
+
+	rest, resumed := splitResumed([]string{"a.example", "b.example", "c.example", "d.example"}, journal.results)
+	if strings.Join(rest, ",") != "b.example,d.example" || len(resumed) != 2 {
+		t.Fatalf("rest %v, resumed %d, want b.example,d.example and 2", rest, len(resumed))
+	}
+
+	// Resuming appends after the last complete line and does not journal
+	// the reused results again.
+	cp, err = newCheckpointer(path, journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range append(resumed, http1.CheckResult{Target: "d.example", Score: 50}) {
+		if err := cp.add(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cp.close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("journal has %d lines, want a header and 3 results:\n%s", lines, data)
+	}
+	journal, err = loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal.results) != 3 || journal.results["d.example"].Score != 50 {
+		t.Errorf("replayed %+v after resuming, want a, c and d.example", journal.results)
+	}
+}
+
+func TestCheckpointResumeStream(t *testing.T) {
+	done := map[string]http1.CheckResult{"a.example": {Target: "a.example"}}
+	feed := make(chan string)
+	go func() {
+		for _, entry := range []string{"a.example", "b.example"} {
+			feed <- entry
+		}
+		close(feed)
+	}()
+	rest, resumed := resumeStream(feed, done)
+	var scanned []string
+	for entry := range rest {
+		scanned = append(scanned, entry)
+	}
+	if strings.Join(scanned, ",") != "b.example" {
+		t.Errorf("streamed %v, want b.example", scanned)
+	}
+	if got := resumed(); len(got) != 1 || got[0].Target != "a.example" {
+		t.Errorf("resumed %+v, want a.example", got)
+	}
+}
+
+func TestCheckpointLoadErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, data, want string
+	}{
+		{"not JSON", "hello\n", "is not a checkpoint file"},
+		{"no header", `{"target":"a.example"}` + "\n", "is not a checkpoint file"},
+		{"newer", `{"httpver_checkpoint":99}` + "\n", "newer http1"},
+		{"older", `{"httpver_checkpoint":1,"results":[]}` + "\n", "older http1"},
+		{"bad line", `{"httpver_checkpoint":2}` + "\nnot json\n", ":2:"},
+	} {
+		path := filepath.Join(t.TempDir(), "state.ndjson")
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadCheckpoint(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
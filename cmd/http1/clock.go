@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// clock abstracts the current time so cache expiry and "scanned N minutes
+// ago" labels can be driven deterministically in tests, or shifted by
+// operators who want to see how time-based behaviour plays out.
+type clock interface {
+	Now() time.Time
+}
+
+// systemClock is the real wall clock, optionally shifted by a fixed offset.
+type systemClock struct {
+	offset time.Duration
+}
+
+func (c systemClock) Now() time.Time { return time.Now().Add(c.offset) }
+
+// fakeClock only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the fake time forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
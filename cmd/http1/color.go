@@ -0,0 +1,23 @@
+package main
+
+import "os"
+
+// textStyle is how the text format renders results. On a terminal it uses
+// aligned columns of status words instead of emoji, colored unless turned
+// off; piped output keeps the emoji lines that scripts and logs expect.
+type textStyle struct {
+	columns bool
+	color   bool
+}
+
+// detectTextStyle picks the text style for output written to f. Color is off
+// with noColor (--no-color), a non-empty NO_COLOR (https://no-color.org) or
+// TERM=dumb.
+func detectTextStyle(f *os.File, noColor bool) textStyle {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return textStyle{}
+	}
+	noColor = noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+	return textStyle{columns: true, color: !noColor}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"http1.dev/internal/http1"
+)
+
+// diffReport is the diff subcommand's --json output.
+type diffReport struct {
+	Changes     []http1.Difference `json:"changes"`
+	Added       []string           `json:"added,omitempty"`
+	Removed     []string           `json:"removed,omitempty"`
+	Regressions int                `json:"regressions"`
+}
+
+// runDiff implements the "diff" subcommand: it compares two result files,
+// written with --json or --format ndjson, target by target. It returns
+// exitPolicyFailed when anything regressed, so CI can gate on it.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonFlag := fs.Bool("json", false, "print the differences as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: http1 diff [--json] old.json new.json")
+		fmt.Fprintln(fs.Output(), "Compares two result files and lists per-target regressions and improvements.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 1
+	}
+	old, err := loadBaseline(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+		return 1
+	}
+	cur, err := loadBaseline(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+		return 1
+	}
+
+	report := diffReport{Changes: []http1.Difference{}}
+	targets := make([]string, 0, len(old)+len(cur))
+	for t := range old {
+		targets = append(targets, t)
+	}
+	for t := range cur {
+		if _, ok := old[t]; !ok {
+			targets = append(targets, t)
+		}
+	}
+	slices.Sort(targets)
+	for _, t := range targets {
+		was, inOld := old[t]
+		now, inNew := cur[t]
+		switch {
+		case !inOld:
+			report.Added = append(report.Added, t)
+		case !inNew:
+			report.Removed = append(report.Removed, t)
+		default:
+			for _, d := range http1.DiffResults(was, now) {
+				if d.Regression {
+					report.Regressions++
+				}
+				report.Changes = append(report.Changes, d)
+			}
+		}
+	}
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+			return 1
+		}
+	} else {
+		printDiff(report)
+	}
+	if report.Regressions > 0 {
+		return exitPolicyFailed
+	}
+	return 0
+}
+
+// printDiff writes the text form of a diff report, grouped by target.
+func printDiff(r diffReport) {
+	last := ""
+	for _, d := range r.Changes {
+		if d.Target != last {
+			fmt.Println(d.Target)
+			last = d.Target
+		}
+		change := "improved"
+		if d.Regression {
+			change = "regressed"
+		}
+		fmt.Printf("  %-9s  %-11s  %s → %s\n", change, d.Aspect, d.Old, d.New)
+	}
+	for _, t := range r.Added {
+		fmt.Printf("%s: only in the new results\n", t)
+	}
+	for _, t := range r.Removed {
+		fmt.Printf("%s: only in the old results\n", t)
+	}
+	fmt.Printf("%d change(s), %d regression(s)\n", len(r.Changes), r.Regressions)
+}
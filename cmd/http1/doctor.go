@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// Well-known anycast endpoints the doctor checks talk to. They answer TCP
+// and QUIC on 443 over both address families and expose the caller's
+// public address at /cdn-cgi/trace.
+const (
+	doctorIPv4    = "1.1.1.1"
+	doctorIPv6    = "2606:4700:4700::1111"
+	doctorName    = "one.one.one.one"
+	doctorTimeout = 3 * time.Second
+
+	// maxClockSkew is how far the local clock may drift from a server's
+	// Date header before timestamps in results become misleading.
+	maxClockSkew = 5 * time.Second
+)
+
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+func (s doctorStatus) String() string {
+	switch s {
+	case doctorOK:
+		return "✅"
+	case doctorWarn:
+		return "⚠️"
+	}
+	return "❌"
+}
+
+// doctorCheck is one environment check. impact describes the false
+// negatives to expect from this vantage point when the check does not pass.
+type doctorCheck struct {
+	name   string
+	impact string
+	run    func(ctx context.Context) (doctorStatus, string)
+}
+
+var doctorChecks = []doctorCheck{
+	{"TCP egress on 443", "every probe will fail; results from this host are meaningless", checkTCPEgress},
+	{"UDP egress on 443 (QUIC)", "HTTP/3 will be reported as unsupported everywhere", checkUDPEgress},
+	{"UDP buffer sizes", "HTTP/3 probes may time out under load and be reported as unsupported", checkUDPBufferSizes},
+	{"IPv6 connectivity", "IPv6-only targets will fail; dual-stack targets are probed over IPv4 only", checkIPv6},
+	{"DNS resolution", "targets will fail to resolve, and ECH (HTTPS record) checks will report errors", checkDNS},
+	{"Clock skew", "scan timestamps and cache ages will be off", checkClockSkew},
+	{"File descriptor limit", "large scans may fail with \"too many open files\"", checkFileLimit},
+	{"Egress IP", "", checkEgressIP},
+}
+
+// runDoctor implements the "doctor" subcommand: it checks the local
+// prerequisites for accurate scans and explains what a failing check means
+// for the results. It returns 1 if any check failed outright.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if runDoctorChecks(os.Stdout, doctorChecks) {
+		return 1
+	}
+	return 0
+}
+
+// runDoctorChecks runs checks concurrently and writes their outcomes to w
+// in order, each failing or warning one with its impact. It reports whether
+// any check failed outright.
+func runDoctorChecks(w io.Writer, checks []doctorCheck) (failed bool) {
+	type outcome struct {
+		status doctorStatus
+		detail string
+	}
+	outcomes := make([]outcome, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+			defer cancel()
+			outcomes[i].status, outcomes[i].detail = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		o := outcomes[i]
+		fmt.Fprintf(w, "%s %s: %s\n", o.status, c.name, o.detail)
+		if o.status != doctorOK && c.impact != "" {
+			fmt.Fprintf(w, "   Expect: %s\n", c.impact)
+		}
+		if o.status == doctorFail {
+			failed = true
+		}
+	}
+	return failed
+}
+
+func checkTCPEgress(ctx context.Context) (doctorStatus, string) {
+	start := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(doctorIPv4, "443"))
+	if err != nil {
+		return doctorFail, err.Error()
+	}
+	_ = conn.Close()
+	return doctorOK, fmt.Sprintf("connected to %s in %s", doctorIPv4, time.Since(start).Round(time.Millisecond))
+}
+
+func checkUDPEgress(ctx context.Context) (doctorStatus, string) {
+	if !http1.HTTP3Available() {
+		return doctorWarn, "not tested (HTTP/3 is disabled in this build)"
+	}
+	start := time.Now()
+	if err := http1.QUICHandshake(ctx, net.JoinHostPort(doctorIPv4, "443"), doctorName); err != nil {
+		return doctorFail, fmt.Sprintf("QUIC handshake with %s failed: %v", doctorIPv4, err)
+	}
+	return doctorOK, fmt.Sprintf("QUIC handshake with %s in %s", doctorIPv4, time.Since(start).Round(time.Millisecond))
+}
+
+func checkUDPBufferSizes(context.Context) (doctorStatus, string) {
+	udp := http1.CheckUDPBuffers()
+	if !udp.Sufficient {
+		return doctorWarn, udp.Guidance
+	}
+	if udp.Receive == 0 {
+		return doctorOK, "cannot be inspected on this platform"
+	}
+	return doctorOK, fmt.Sprintf("%d KiB receive / %d KiB send", udp.Receive/1024, udp.Send/1024)
+}
+
+func checkIPv6(ctx context.Context) (doctorStatus, string) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(doctorIPv6, "443"))
+	if err != nil {
+		return doctorWarn, err.Error()
+	}
+	_ = conn.Close()
+	return doctorOK, "connected to " + doctorIPv6
+}
+
+func checkDNS(ctx context.Context) (doctorStatus, string) {
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, doctorName)
+	if err != nil {
+		return doctorFail, err.Error()
+	}
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if elapsed > time.Second {
+		return doctorWarn, fmt.Sprintf("resolved %s slowly (%s); probes may time out", doctorName, elapsed)
+	}
+	return doctorOK, fmt.Sprintf("resolved %s to %d address(es) in %s", doctorName, len(addrs), elapsed)
+}
+
+func checkClockSkew(ctx context.Context) (doctorStatus, string) {
+	resp, err := doctorGet(ctx, "/")
+	if err != nil {
+		return doctorWarn, "could not fetch a reference time: " + err.Error()
+	}
+	resp.Body.Close()
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return doctorWarn, "server sent no usable Date header"
+	}
+	skew := time.Since(remote).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	// The Date header has one-second resolution.
+	if skew > maxClockSkew {
+		return doctorWarn, fmt.Sprintf("local clock is %s off", skew)
+	}
+	return doctorOK, fmt.Sprintf("within %s of %s", maxClockSkew, doctorIPv4)
+}
+
+func checkFileLimit(context.Context) (doctorStatus, string) {
+	limit, err := openFileLimit()
+	if err != nil {
+		return doctorOK, "cannot be inspected on this platform"
+	}
+	// Each target can hold a handful of sockets at once across the parallel
+	// probes; leave room for the worker pool at full size.
+	const needed = 64 * 8
+	if limit < needed {
+		return doctorWarn, fmt.Sprintf("limit is %d, want at least %d (raise with ulimit -n)", limit, needed)
+	}
+	return doctorOK, fmt.Sprintf("limit is %d", limit)
+}
+
+func checkEgressIP(ctx context.Context) (doctorStatus, string) {
+	resp, err := doctorGet(ctx, "/cdn-cgi/trace")
+	if err != nil {
+		return doctorWarn, "could not detect: " + err.Error()
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if ip, ok := strings.CutPrefix(sc.Text(), "ip="); ok {
+			return doctorOK, ip + " (targets see scans coming from this address)"
+		}
+	}
+	return doctorWarn, "could not detect: no ip in trace response"
+}
+
+func doctorGet(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://"+doctorIPv4+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", http1.DefaultUserAgent())
+	return doctorClient.Do(req)
+}
+
+// doctorClient skips certificate checks like the probes do: the doctor cares
+// about reachability, not about who terminates TLS on the path.
+var doctorClient = &http.Client{
+	Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// openFileLimit is not available outside Unix.
+func openFileLimit() (uint64, error) {
+	return 0, errors.New("not supported on this platform")
+}
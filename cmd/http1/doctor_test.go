@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	stub := func(status doctorStatus, detail string) func(context.Context) (doctorStatus, string) {
+		return func(ctx context.Context) (doctorStatus, string) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("check run without a deadline")
+			}
+			return status, detail
+		}
+	}
+	checks := []doctorCheck{
+		{"TCP egress on 443", "every probe will fail", stub(doctorOK, "connected")},
+		{"UDP buffer sizes", "HTTP/3 probes may time out", stub(doctorWarn, "208 KiB receive")},
+		{"DNS resolution", "targets will fail to resolve", stub(doctorFail, "no such host")},
+		{"Egress IP", "", stub(doctorWarn, "unknown")},
+	}
+
+	var out strings.Builder
+	if !runDoctorChecks(&out, checks) {
+		t.Error("a failed check did not fail the doctor")
+	}
+	want := `✅ TCP egress on 443: connected
+⚠️ UDP buffer sizes: 208 KiB receive
+   Expect: HTTP/3 probes may time out
+❌ DNS resolution: no such host
+   Expect: targets will fail to resolve
+⚠️ Egress IP: unknown
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if runDoctorChecks(&out, checks[:2]) {
+		t.Errorf("warnings failed the doctor:\n%s", out.String())
+	}
+}
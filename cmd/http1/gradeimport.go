@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"http1.dev/internal/http1"
+)
+
+// runGradeImport implements the "grade-import" subcommand: it reads zgrab2
+// or tls-scan JSON records (one per line, or any stream of JSON objects)
+// from the named files or stdin, grades each with the same engine as a live
+// scan and writes http1 results. It returns the process exit code.
+func runGradeImport(args []string) int {
+	fs := flag.NewFlagSet("grade-import", flag.ExitOnError)
+	format := fs.String("format", "ndjson", "output format: text, plain, json, ndjson, csv or zgrab")
+	fields := fs.String("fields", "", "comma-separated fields to project JSON/CSV output to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: http1 grade-import [--format F] [--fields LIST] [file ...]")
+		fmt.Fprintln(fs.Output(), "Grades zgrab2 (tls or http module) or tls-scan JSON output; reads stdin when no file is given.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	out, err := newResultWriter(*format, os.Stdout, nil, http1.ParseFields(*fields), nil, detectTextStyle(os.Stdout, false))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "grade-import: %v\n", err)
+		return 1
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	imported, failed := 0, 0
+	for _, name := range inputs {
+		in := io.Reader(os.Stdin)
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "grade-import: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			in = f
+		}
+
+		dec := json.NewDecoder(in)
+		for n := 1; ; n++ {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				fmt.Fprintf(os.Stderr, "grade-import: %s: record %d: %v\n", name, n, err)
+				failed++
+				break
+			}
+			obs, err := http1.ParseScanRecord(raw)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "grade-import: %s: record %d: %v\n", name, n, err)
+				failed++
+				continue
+			}
+			if err := out.Write(http1.GradeObservation(obs)); err != nil {
+				fmt.Fprintf(os.Stderr, "grade-import: failed to write results: %v\n", err)
+				return 1
+			}
+			imported++
+		}
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "grade-import: failed to write results: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d record(s), %d failed\n", imported, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
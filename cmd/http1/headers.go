@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// headerFlag collects repeatable -H "Name: value" flags.
+type headerFlag struct {
+	h http.Header
+}
+
+func (f *headerFlag) String() string {
+	if f == nil || len(f.h) == 0 {
+		return ""
+	}
+	var parts []string
+	for name, values := range f.h {
+		for _, v := range values {
+			parts = append(parts, name+": "+v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *headerFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("want \"Name: value\", got %q", s)
+	}
+	if f.h == nil {
+		f.h = make(http.Header)
+	}
+	f.h.Add(name, strings.TrimSpace(value))
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"http1.dev/internal/http1"
+)
+
+// howtoAdvice is one change in the howto report, with the grade it would
+// bring when it improves the score.
+type howtoAdvice struct {
+	http1.HowtoStep
+	Projection *http1.Projection `json:"projection,omitempty"`
+}
+
+// howtoReport is the howto subcommand's --json output.
+type howtoReport struct {
+	Target string `json:"target"`
+	Grade  string `json:"grade"`
+	Score  int    `json:"score"`
+	Server string `json:"server,omitempty"`
+	// Stack is the stack the advice is for: --stack, or the detected one.
+	Stack   string        `json:"stack,omitempty"`
+	Changes []string      `json:"changes,omitempty"`
+	Advice  []howtoAdvice `json:"advice,omitempty"`
+}
+
+// runHowto implements the "howto" subcommand: it scans a target, names the
+// server or CDN answering it and prints the settings that stack needs for
+// the HTTP/2, HTTP/3 and TLS 1.3 support the target lacks. It returns the
+// process exit code.
+func runHowto(args []string) int {
+	fs := flag.NewFlagSet("howto", flag.ExitOnError)
+	stackFlag := fs.String("stack", "", "server stack to give instructions for, instead of the detected one: "+strings.Join(http1.Stacks(), ", "))
+	jsonFlag := fs.Bool("json", false, "print the report as JSON")
+	portFlag := fs.Int("port", 0, "port to test (default 443 for https, 80 for http)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: http1 howto [--stack NAME] [--json] [-port N] <domain-or-url>")
+		fmt.Fprintln(fs.Output(), "Scans a target and explains how to enable what it lacks on the server or CDN serving it.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	var stack string
+	if *stackFlag != "" {
+		var err error
+		if stack, err = http1.ParseStack(*stackFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "howto: --stack: %v\n", err)
+			return 1
+		}
+	}
+
+	opts := http1.Options{Proxy: http.ProxyFromEnvironment}
+	if *portFlag > 0 {
+		opts.Port = strconv.Itoa(*portFlag)
+	}
+	res := http1.CheckHTTPVersionsJSON(fs.Arg(0), opts)
+	if res.Grade == "" {
+		detail := "the target could not be checked"
+		if len(res.Results) > 0 {
+			detail = res.Results[0].Detail
+		}
+		fmt.Fprintf(os.Stderr, "howto: %s: %s\n", fs.Arg(0), detail)
+		return 1
+	}
+	if !slices.ContainsFunc(res.Results, func(vr http1.VersionResult) bool { return vr.Supported }) {
+		fmt.Fprintf(os.Stderr, "howto: %s: no HTTP version answered, so there is nothing to build on\n", fs.Arg(0))
+		return 1
+	}
+	if stack == "" {
+		stack = res.Stack
+	}
+
+	report := howtoReport{Target: res.Target, Grade: res.Grade, Score: res.Score, Server: res.Server, Stack: stack, Changes: http1.HowtoChanges(res)}
+	projections := make(map[string]http1.Projection)
+	for _, p := range http1.Projections(res) {
+		projections[p.Change] = p
+	}
+	for _, step := range http1.Howto(res, stack) {
+		a := howtoAdvice{HowtoStep: step}
+		if p, ok := projections[step.Change]; ok {
+			a.Projection = &p
+		}
+		report.Advice = append(report.Advice, a)
+	}
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "howto: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printHowto(report)
+	return 0
+}
+
+// printHowto writes the text form of a howto report.
+func printHowto(r howtoReport) {
+	servedBy := "an unrecognized server"
+	switch {
+	case r.Stack != "" && r.Server != "" && !strings.EqualFold(r.Stack, r.Server):
+		servedBy = fmt.Sprintf("%s (Server: %s)", r.Stack, r.Server)
+	case r.Stack != "":
+		servedBy = r.Stack
+	case r.Server != "":
+		servedBy = fmt.Sprintf("an unrecognized server (Server: %s)", r.Server)
+	}
+	fmt.Printf("%s: grade %s (%d), served by %s\n", r.Target, r.Grade, r.Score, servedBy)
+
+	if len(r.Changes) == 0 {
+		fmt.Println("\nNothing to do: HTTP/2, HTTP/3 and TLS 1.3 all work.")
+		return
+	}
+	if r.Stack == "" {
+		fmt.Printf("\nStill to do: %s.\n", strings.Join(r.Changes, ", "))
+		fmt.Printf("Rerun with --stack to get instructions for one of: %s.\n", strings.Join(http1.Stacks(), ", "))
+		return
+	}
+	for _, a := range r.Advice {
+		fmt.Println()
+		if a.Projection != nil {
+			fmt.Println(a.Projection.String())
+		} else {
+			fmt.Println(a.Change)
+		}
+		fmt.Printf("  %s\n", a.Steps)
+		if a.Config != "" {
+			fmt.Println()
+			for _, line := range strings.Split(a.Config, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+			fmt.Println()
+		}
+		if a.Docs != "" {
+			fmt.Printf("  See %s\n", a.Docs)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// Batch job limits: the targets of one job, the jobs kept at once, and how
+// long a finished job's results stay available.
+const (
+	maxJobTargets = 1000
+	maxJobs       = 100
+	jobTTL        = 24 * time.Hour
+)
+
+// jobSaveInterval is how often a running job's results so far are saved,
+// so a restart loses at most this much work.
+const jobSaveInterval = 5 * time.Second
+
+// Batch job states.
+const (
+	jobRunning  = "running"
+	jobPaused   = "paused"
+	jobCanceled = "canceled"
+	jobDone     = "done"
+)
+
+// scanJob is a batch scan started through the API. Its targets are fed to
+// the worker pool one at a time, so pausing or canceling stops new targets
+// from starting while those already in flight finish. Results are kept as
+// they arrive, including after a cancel.
+type scanJob struct {
+	id       string
+	total    int
+	priority scanPriority
+
+	mu      sync.Mutex
+	resumed *sync.Cond
+	state   string
+	results []http1.CheckResult
+	// finished is when the last result came in, after which the job
+	// expires in jobTTL.
+	finished time.Time
+	// saved is when the job was last saved.
+	saved time.Time
+}
+
+// jobStatus is the API representation of a scanJob, and what a job is
+// saved as.
+type jobStatus struct {
+	ID        string              `json:"id"`
+	State     string              `json:"state"`
+	Priority  string              `json:"priority"`
+	Total     int                 `json:"total"`
+	Completed int                 `json:"completed"`
+	Finished  time.Time           `json:"finished,omitzero"`
+	Expires   time.Time           `json:"expires,omitzero"`
+	Results   []http1.CheckResult `json:"results,omitempty"`
+}
+
+func (j *scanJob) status(withResults bool) jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.statusLocked(withResults)
+}
+
+func (j *scanJob) statusLocked(withResults bool) jobStatus {
+	s := jobStatus{ID: j.id, State: j.state, Priority: j.priority.String(), Total: j.total, Completed: len(j.results), Finished: j.finished}
+	if !j.finished.IsZero() {
+		s.Expires = j.finished.Add(jobTTL)
+	}
+	if withResults {
+		s.Results = append([]http1.CheckResult(nil), j.results...)
+	}
+	return s
+}
+
+// expired reports whether the job finished more than jobTTL before now.
+func (j *scanJob) expired(now time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finished.IsZero() && now.Sub(j.finished) >= jobTTL
+}
+
+// waitRunnable blocks while the job is paused and reports whether it may
+// start another target.
+func (j *scanJob) waitRunnable() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for j.state == jobPaused {
+		j.resumed.Wait()
+	}
+	return j.state == jobRunning
+}
+
+// transition moves the job from one of the states in from to to, and
+// reports whether it did.
+func (j *scanJob) transition(to string, from ...string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, f := range from {
+		if j.state == f {
+			j.state = to
+			j.resumed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// jobStore runs and tracks batch jobs. Finished jobs are dropped jobTTL
+// after their last result; with a directory, jobs are also saved there as
+// <id>.json, every jobSaveInterval while they run and once they finish, so
+// their results outlive a restart until they expire. A job the restart
+// interrupted comes back canceled with the results saved so far.
+type jobStore struct {
+	opts  http1.Options
+	sched *scheduler
+	clock clock
+	dir   string
+	// stream scans targets until the channel closes; it is
+	// http1.CheckHTTPVersionsStream outside tests.
+	stream func(targets <-chan string, opts http1.Options, fn func(http1.CheckResult))
+
+	mu   sync.Mutex
+	jobs map[string]*scanJob
+}
+
+// newJobStore returns a job store, loading the jobs saved in dir, if set.
+func newJobStore(opts http1.Options, sched *scheduler, clk clock, dir string) (*jobStore, error) {
+	s := &jobStore{opts: opts, sched: sched, clock: clk, dir: dir, stream: http1.CheckHTTPVersionsStream, jobs: make(map[string]*scanJob)}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var st jobStatus
+		if err := json.Unmarshal(data, &st); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		priority, err := parseJobPriority(st.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		j := &scanJob{id: st.ID, total: st.Total, priority: priority, state: st.State, results: st.Results, finished: st.Finished}
+		j.resumed = sync.NewCond(&j.mu)
+		if j.state == jobRunning || j.state == jobPaused {
+			j.state = jobCanceled
+			j.finished = clk.Now().UTC()
+		}
+		s.jobs[j.id] = j
+	}
+	s.mu.Lock()
+	s.evict()
+	s.mu.Unlock()
+	return s, nil
+}
+
+// errTooManyJobs is returned by start when maxJobs jobs are kept.
+var errTooManyJobs = errors.New("too many jobs; wait for some to expire")
+
+func (s *jobStore) start(targets []string, priority scanPriority) (*scanJob, error) {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	j := &scanJob{id: hex.EncodeToString(id[:]), total: len(targets), priority: priority, state: jobRunning}
+	j.resumed = sync.NewCond(&j.mu)
+	s.mu.Lock()
+	s.evict()
+	if len(s.jobs) >= maxJobs {
+		s.mu.Unlock()
+		return nil, errTooManyJobs
+	}
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	// Each target holds a scan slot from before it is fed until its result
+	// comes back. The job is checked again after the possibly long wait for
+	// a slot, so a pause or cancel in the meantime starts nothing.
+	feed := make(chan string)
+	go func() {
+		defer close(feed)
+		for i := 0; i < len(targets); {
+			if !j.waitRunnable() {
+				return
+			}
+			s.sched.acquire(priority, 1)
+			if j.status(false).State != jobRunning {
+				s.sched.release(priority, 1)
+				continue
+			}
+			feed <- targets[i]
+			i++
+		}
+	}()
+	go func() {
+		s.stream(feed, s.opts, func(res http1.CheckResult) {
+			s.sched.release(priority, 1)
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			j.results = append(j.results, res)
+			if now := s.clock.Now(); now.Sub(j.saved) >= jobSaveInterval {
+				j.saved = now
+				if err := s.save(j.statusLocked(true)); err != nil {
+					fmt.Fprintf(os.Stderr, "web: saving job %s: %v\n", j.id, err)
+				}
+			}
+		})
+		// The job is saved before anyone can see it finished.
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if j.state != jobCanceled {
+			j.state = jobDone
+		}
+		j.finished = s.clock.Now().UTC()
+		if err := s.save(j.statusLocked(true)); err != nil {
+			fmt.Fprintf(os.Stderr, "web: saving job %s: %v\n", j.id, err)
+		}
+	}()
+	return j, nil
+}
+
+func (s *jobStore) get(id string) *scanJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict()
+	return s.jobs[id]
+}
+
+// evict drops expired jobs and their files; s.mu must be held.
+func (s *jobStore) evict() {
+	now := s.clock.Now()
+	for id, j := range s.jobs {
+		if !j.expired(now) {
+			continue
+		}
+		delete(s.jobs, id)
+		if s.dir != "" {
+			_ = os.Remove(s.jobFile(id))
+		}
+	}
+}
+
+// save writes a job to the store's directory, if it has one.
+func (s *jobStore) save(st jobStatus) error {
+	if s.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	f := &atomicFile{path: s.jobFile(st.ID)}
+	if _, err := f.Write(data); err != nil {
+		f.Abort()
+		return err
+	}
+	return f.Commit()
+}
+
+func (s *jobStore) jobFile(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// register adds the batch job endpoints to mux, each requiring token:
+//
+//	POST /api/v1/jobs               {"targets": [...], "priority": "batch"} starts a job
+//	GET  /api/v1/jobs/{id}          state, progress and results so far
+//	POST /api/v1/jobs/{id}/pause    stop starting new targets
+//	POST /api/v1/jobs/{id}/resume   continue a paused job
+//	POST /api/v1/jobs/{id}/cancel   stop for good, keeping partial results
+func (s *jobStore) register(mux *http.ServeMux, token string) {
+	mux.HandleFunc("POST /api/v1/jobs", requireToken(token, s.handleCreate))
+	mux.HandleFunc("GET /api/v1/jobs/{id}", requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+		j := s.get(r.PathValue("id"))
+		if j == nil {
+			writeAPIError(w, http.StatusNotFound, "no such job")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, j.status(true))
+	}))
+	for action, t := range map[string]struct {
+		to   string
+		from []string
+	}{
+		"pause":  {jobPaused, []string{jobRunning}},
+		"resume": {jobRunning, []string{jobPaused}},
+		"cancel": {jobCanceled, []string{jobRunning, jobPaused}},
+	} {
+		mux.HandleFunc("POST /api/v1/jobs/{id}/"+action, requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+			j := s.get(r.PathValue("id"))
+			if j == nil {
+				writeAPIError(w, http.StatusNotFound, "no such job")
+				return
+			}
+			if !j.transition(t.to, t.from...) {
+				writeAPIError(w, http.StatusConflict, "cannot "+action+" a job that is "+j.status(false).State)
+				return
+			}
+			writeAPIJSON(w, http.StatusOK, j.status(false))
+		}))
+	}
+}
+
+func (s *jobStore) handleCreate(w http.ResponseWriter, r *http.Request) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody*16))
+	dec.DisallowUnknownFields()
+	var req struct {
+		Targets  []string `json:"targets"`
+		Priority string   `json:"priority"`
+	}
+	if err := dec.Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid job: "+err.Error())
+		return
+	}
+	targets, err := parseTargetsParam(strings.Join(req.Targets, "\n"), "api")
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if len(targets) == 0 || len(targets) > maxJobTargets {
+		writeAPIError(w, http.StatusUnprocessableEntity, fmt.Sprintf("a job needs between 1 and %d targets", maxJobTargets))
+		return
+	}
+	priority, err := parseJobPriority(req.Priority)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	j, err := s.start(targets, priority)
+	if err != nil {
+		writeAPIError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+j.id)
+	writeAPIJSON(w, http.StatusCreated, j.status(false))
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,28 +23,124 @@ func printUsage() {
 	fmt.Println("http1 - HTTP version and minimal ALPN-based grading tool")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  http1 [-port N] [--json] [--targets a.com,b.com] [--targets-file file] <domain-or-url> ...")
+	fmt.Println("  http1 [-port N] [--json | --format F] [--fields LIST] [--where EXPR] [--targets a.com,b.com] [--targets-file file] <domain-or-url> ...")
 	fmt.Println("  http1 --web 8080")
+	fmt.Println("  http1 doctor                                  Check this machine's network setup for scanning")
+	fmt.Println("  http1 grade-import [--format F] [file ...]   Grade existing zgrab2/tls-scan JSON without probing")
+	fmt.Println("  http1 replay [--format F] session.httpver     Render a scan recorded with --record again, offline")
+	fmt.Println("  http1 diff [--json] old.json new.json         Compare two result files; exits 3 on regressions")
+	fmt.Println("  http1 campaign [--json] campaign.json         Show a fleet's burn-down toward a campaign goal; exits 3 when behind")
+	fmt.Println("  http1 validate [--schema FILE] results.json   Check result files against the JSON Schema of the output; exits 3 when invalid")
+	fmt.Println("  http1 howto [--stack NAME] <domain-or-url>    Explain how to enable HTTP/2, HTTP/3 and TLS 1.3 on the target's server")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -port N            Port to test (default 443 for https, 80 for http)")
-	fmt.Println("  --json             Output results as JSON")
+	fmt.Println("  --sni NAME         TLS server name to present instead of the target host")
+	fmt.Println("  --proxy URL        HTTP or SOCKS5 proxy for the TCP probes (default: $HTTPS_PROXY / $HTTP_PROXY); HTTP/3 goes direct")
+	fmt.Println("  --pac FILE|URL     Route each target's TCP probes as this proxy auto-config (PAC) script decides")
+	fmt.Println("  --source-ip IP     Local address for the probes' TCP and UDP sockets (multi-homed scanners)")
+	fmt.Println("  --interface NAME   Bind the probes' sockets to this network interface (Linux)")
+	fmt.Println("  --doh URL          Resolve names over DoH (https://1.1.1.1/dns-query) or DoT (tls://9.9.9.9)")
+	fmt.Println("  --host-header H    Host / :authority to send instead of the target host")
+	fmt.Println("  -H \"Name: value\"    Add a request header to every probe (repeatable)")
+	fmt.Println("  --json             Output results as JSON (same as --format json)")
+	fmt.Println("  --ndjson           Stream one JSON object per line as each target completes (same as --format ndjson)")
+	fmt.Println("  --format F         Output format: text (default), plain (prose for screen readers), table (aligned columns with a header), json, ndjson, csv, zgrab (zgrab2 http module schema)")
+	fmt.Println("  -o FILE            Write results in the chosen format to FILE, replacing it only once the scan succeeds;")
+	fmt.Println("                     stdout keeps the summary lines")
+	fmt.Println("  --record FILE      Record inputs, options and results (DNS answers, probe outcomes) for \"http1 replay FILE\"")
+	fmt.Println("  --discover-subdomains D  Also scan every name under these comma-separated domains found in Certificate Transparency logs")
+	fmt.Println("  --max-hosts N      Most addresses to scan from CIDR ranges (203.0.113.0/28) in all (default 256, at most 65536)")
+	fmt.Println("  --sort KEY         Print results sorted by grade, score (worst first) or target once the scan ends")
+	fmt.Println("  --preserve-order   Print results in input order once the scan ends, instead of as they complete")
+	fmt.Println("  --summary          Show the grade distribution, HTTP/2, HTTP/3 and HTTP/1.0 shares and slowest targets after the results")
+	fmt.Println("  --group-by-label   Show the grade distribution per target label (e.g. team or env) after the results")
+	fmt.Println("  --fields LIST      Project JSON/CSV output to these fields (e.g. target,grade,results.HTTP/3.0.supported)")
 	fmt.Println("  --targets LIST     Comma-separated list of targets (e.g. \"a.com,b.com\")")
 	fmt.Println("  --targets-file F   File with one target per line")
+	fmt.Println("  --where EXPR       Only output results matching EXPR (e.g. 'grade==\"F\" && results[\"HTTP/1.0\"].supported')")
+	fmt.Println("  --versions LIST    Only probe these protocols (e.g. 2,3 or h2,h3); the others show as not tested")
+	fmt.Println("  --fail-under G     Exit with status 3 if any target grades below G (A, B, C or F)")
+	fmt.Println("  --require LIST     Exit with status 3 if any target lacks one of these protocols (e.g. h2,h3)")
+	fmt.Println("  --waivers FILE     Approved, expiring exemptions from --fail-under/--require rules and --webhook events (JSON)")
+	fmt.Println("  --lang L           Language for summary lines and details: en (default), " + strings.Join(http1.Languages(), ", "))
+	fmt.Println("  --vantage LABEL    Record where the scan ran from (e.g. office, aws-eu) on every result")
+	fmt.Println("  --vantage-profile NAME  Use a named proxy/source IP/interface/resolver profile from the config file")
+	fmt.Println("  --config FILE      Config file (default: $HTTP1_CONFIG, else http1/config.json in the user config dir)")
+	fmt.Println("  --redact           Replace hostnames and IPs in the output with keyed pseudonyms")
+	fmt.Println("  --redact-key K     Secret for --redact tokens (default: $HTTP1_REDACT_KEY, else random per run)")
 	fmt.Println("  --web PORT         Run the web UI on the given port (e.g. 8080)")
+	fmt.Println("  --self-scan HOST   With --web, scan HOST hourly and serve the result at " + http1.DefaultSelfScanPath + " (metrics under /metrics)")
+	fmt.Println("  --campaign FILE    With --web, show the burn-down of this campaign file at /campaign")
+	fmt.Println("  --calibrate        Handshake with HTTP/3 reference hosts first; if none answers, mark h3 negatives unreliable")
+	fmt.Println("  --reference-hosts L  Comma-separated reference hosts for --calibrate (implies it)")
+	fmt.Println("  --dual-stack       Also probe over IPv4 and IPv6 separately and flag differences")
+	fmt.Println("  --cross-check      Have the hosted http1.dev instance check each target too and flag differences")
+	fmt.Println("  --cross-check-endpoint URL  http1 web instance for --cross-check (implies it)")
+	fmt.Println("  --sample-bodies    Scan the start of each response for meta refreshes, browser interstitials and challenges")
+	fmt.Println("  --dnssec           Report whether each target's name is DNSSEC-signed and validates")
+	fmt.Println("  --detect-parked    Tag likely parked domains (wildcard DNS, parking nameservers, landing pages)")
+	fmt.Println("  --ech              Look up the HTTPS DNS record and attempt an Encrypted ClientHello handshake")
+	fmt.Println("  --sni-mismatch     Send disagreeing SNI and Host values and report how the server reacts")
+	fmt.Println("  --extended-connect Report whether HTTP/2 and HTTP/3 accept a websocket Extended CONNECT")
+	fmt.Println("  --key-exchange     Handshake once more offering only the post-quantum X25519MLKEM768 group")
+	fmt.Println("  --quic-versions    When HTTP/3 works, handshake once more to list the QUIC versions it accepts")
+	fmt.Println("  --early-data       Reconnect to report TLS session resumption and QUIC 0-RTT (resends the request)")
+	fmt.Println("  --h2-settings      After HTTP/2 works, connect once more to record its SETTINGS and ORIGIN frames")
+	fmt.Println("  --coalescing       Request another certificate name over the target's HTTP/2 connection")
+	fmt.Println("  --follow-redirects Chase redirects (up to 10) and grade the final destination")
+	fmt.Println("  --method M         Probe with GET (default), HEAD or OPTIONS; any response proves support")
+	fmt.Println("  --user-agent UA    User-Agent sent by every probe (default \"" + http1.DefaultUserAgent() + "\")")
+	fmt.Println("  --no-color         Do not color the aligned columns shown on a terminal (or set NO_COLOR)")
+	fmt.Println("  -q                 Print results only, without the scanning banner and closing summary")
+	fmt.Println("  -v, -vv            Print each probe's timing and evidence to stderr (-vv: also DNS, TLS, warnings)")
+	fmt.Println("  --diagnostics      Report scanning environment checks (UDP buffer sizes) before scanning")
+	fmt.Println("  --retries N        Resend a probe that got no response up to N more times (jittered backoff)")
+	fmt.Println("  --max-per-origin N Check at most N targets on the same IP address at once (default 4, 0 = no limit)")
+	fmt.Println("  --rate N           Start at most N probes per second across all workers (e.g. 20, or 0.5)")
+	fmt.Println("  --baseline FILE    Earlier --json or ndjson results to compare against; flags changed certificates")
+	fmt.Println("  --stale-only       Rescan only targets whose --baseline result is older than --max-age (24h) or inconclusive")
+	fmt.Println("  --max-duration D   Stop the scan after D (e.g. 10m); targets not done by then are output as unscanned")
+	fmt.Println("  --progress         Show completed/total, rate, ETA and the worst grade so far on stderr while scanning")
+	fmt.Println("  --retry-errors FILE Rescan only the targets whose probes errored in FILE (--json or ndjson results) and")
+	fmt.Println("                     output them with the rest of FILE's results; FILE's targets are scanned when none are given")
+	fmt.Println("  --checkpoint FILE  Append each finished result to FILE (an NDJSON journal), so --resume can pick up")
+	fmt.Println("  --resume           Reuse the results in --checkpoint and scan only the targets it lacks")
+	fmt.Println("  --notes FILE       Notes on targets (JSON); shown with results, and acknowledgements excuse policy failures")
+	fmt.Println("                     (with --web and --api-token: also served at /api/v1/notes, where notes can be added)")
+	fmt.Println("  --webhook URL      POST an event for each anomaly against --baseline (e.g. h3_unreachable)")
+	fmt.Println("  --webhook-events L Events to send: " + strings.Join(http1.AnomalyTypes, ", ") + " (default all)")
+	fmt.Println("  --yes              Start scans of more than 10000 targets, which otherwise stop after a cost estimate")
+	fmt.Println("  --low-resource     Use few workers, shared transports and small buffers (e.g. on a Raspberry Pi)")
+	fmt.Println("  --pprof PREFIX     Write CPU/heap profiles to PREFIX.cpu.pprof and PREFIX.heap.pprof")
+	fmt.Println("                     (with --web: serve net/http/pprof under /debug/pprof/ instead)")
+	fmt.Println("  --clock-offset D   With --web: shift the server clock by D (e.g. 3h59m) to preview cache expiry")
+	fmt.Println("  --api-token T      With --web: enable the batch job API at /api/v1/jobs, for requests bearing T (default $" + apiTokenEnv + ")")
+	fmt.Println("  --jobs-dir DIR     With --api-token: keep jobs and their results in DIR across restarts")
 	fmt.Println("  --help             Show this help message and exit")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  http1 cloudflare.com")
 	fmt.Println("  http1 --json example.org")
+	fmt.Println("  http1 -H \"Authorization: Bearer $TOKEN\" internal.example.com")
 	fmt.Println("  http1 --targets cloudflare.com,example.com --json")
 	fmt.Println("  http1 --targets-file targets.txt --json")
+	fmt.Println("  http1 --targets-file targets.txt --ndjson | jq -r .target")
+	fmt.Println("  http1 --targets-file targets.txt --where 'grade==\"F\"'")
+	fmt.Println("  http1 --targets-file targets.txt --format csv --fields target,grade,tls_version")
+	fmt.Println("  http1 --targets-file targets.txt --json -o results.json")
+	fmt.Println("  http1 --targets-file prod.txt --fail-under B --require h2")
+	fmt.Println("  http1 --versions 2,3 example.com")
 	fmt.Println("  http1 cloudflare.com google.com floqast.app neverssl.com")
 	fmt.Println("  http1 --web 8080")
 }
 
-func gatherTargets(targetsFlag, targetsFile string, positional []string) ([]string, error) {
-	var targets []string
+// gatherTargets collects the targets from the targets file, the --targets
+// flag and positional args, in that order, dropping duplicates. Any invalid
+// target fails the whole run before anything is scanned.
+func gatherTargets(targetsFlag, targetsFile string, positional []string) ([]http1.Target, error) {
+	var targets []http1.Target
+	var errs []error
 
 	// From file (one per line, ignore blanks and lines starting with '#')
 	if targetsFile != "" {
@@ -47,50 +148,148 @@ func gatherTargets(targetsFlag, targetsFile string, positional []string) ([]stri
 		if err != nil {
 			return nil, fmt.Errorf("failed to read targets file: %w", err)
 		}
-		for _, line := range strings.Split(string(data), "\n") {
-			line = strings.TrimSpace(line)
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
-			targets = append(targets, line)
+		fromFile, err := http1.ParseTargetList(string(data), targetsFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", targetsFile, err))
 		}
+		targets = append(targets, fromFile...)
 	}
 
 	// From --targets comma-separated flag
-	if targetsFlag != "" {
-		for _, part := range strings.Split(targetsFlag, ",") {
-			part = strings.TrimSpace(part)
-			if part == "" {
-				continue
-			}
-			targets = append(targets, part)
-		}
+	fromFlag, err := http1.ParseTargetList(targetsFlag, "--targets")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("--targets: %w", err))
 	}
+	targets = append(targets, fromFlag...)
 
 	// From positional args
-	targets = append(targets, positional...)
-
-	// Optional: dedupe while preserving order
-	seen := make(map[string]struct{}, len(targets))
-	deduped := make([]string, 0, len(targets))
-	for _, t := range targets {
-		if _, ok := seen[t]; ok {
+	for _, arg := range positional {
+		t, err := http1.ParseTarget(arg)
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
-		seen[t] = struct{}{}
-		deduped = append(deduped, t)
+		t.Source = "args"
+		targets = append(targets, t)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
-	return deduped, nil
+	return http1.DedupeTargets(targets), nil
 }
 
 func main() {
+	// quic-go's UDP buffer warning is a bare log line; we check the buffers
+	// ourselves and report them with the results instead.
+	_ = os.Setenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING", "true")
+
+	// Subcommands; selftest is hidden from the usage text.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "selftest":
+			os.Exit(runSelftest(os.Args[2:]))
+		case "grade-import":
+			os.Exit(runGradeImport(os.Args[2:]))
+		case "howto":
+			os.Exit(runHowto(os.Args[2:]))
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "campaign":
+			os.Exit(runCampaign(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		}
+	}
+
 	portFlag := flag.Int("port", 0, "port to test (default 443 for https, 80 for http)")
 	jsonFlag := flag.Bool("json", false, "output results as JSON")
+	ndjsonFlag := flag.Bool("ndjson", false, "stream one JSON object per line as each target completes")
 	targetsFlag := flag.String("targets", "", "comma-separated list of targets (e.g. \"a.com,b.com\")")
 	targetsFile := flag.String("targets-file", "", "path to file containing targets (one per line)")
 	helpFlag := flag.Bool("help", false, "show help and usage information")
 	webPort := flag.Int("web", 0, "run in web server mode on the given port (e.g. 8080)")
+	selfScan := flag.String("self-scan", "", "with --web, scan this public hostname of the server hourly and report it at "+http1.DefaultSelfScanPath+" and as Prometheus metrics; --fail-under and --require set the policy")
+	campaignFlag := flag.String("campaign", "", "with --web, show the burn-down of this campaign file (see \"http1 campaign\") at /campaign")
+	failUnder := flag.String("fail-under", "", "exit with status 3 if any target grades below this grade (A, B, C or F)")
+	versionsFlag := flag.String("versions", "", "only probe these protocols (comma-separated: h1.0, h1.1, h2, h3); the rest are reported as not tested")
+	requireFlag := flag.String("require", "", "exit with status 3 if any target does not support all of these protocols (comma-separated: h1.0, h1.1, h2, h3)")
+	waiversFlag := flag.String("waivers", "", "JSON file of waivers (target, rule, expires, approver) exempting targets from --fail-under/--require rules and --webhook events until they expire")
+	whereFlag := flag.String("where", "", "only output results matching this expression")
+	formatFlag := flag.String("format", "", "output format: text, plain, table, json, ndjson, csv or zgrab")
+	recordFlag := flag.String("record", "", "record the inputs, options and every result (DNS answers and probe outcomes included) to this file for \"http1 replay\"")
+	outputFlag := flag.String("o", "", "write results in the selected format to this file, atomically, and the summary lines to stdout")
+	discoverFlag := flag.String("discover-subdomains", "", "comma-separated domains whose names in Certificate Transparency logs (via "+http1.DefaultCTEndpoint+") are added to the targets")
+	ctEndpoint := flag.String("ct-endpoint", "", "crt.sh-compatible search for --discover-subdomains (default "+http1.DefaultCTEndpoint+")")
+	maxHostsFlag := flag.Int("max-hosts", http1.DefaultMaxHosts, fmt.Sprintf("most addresses to scan from address ranges such as 203.0.113.0/28, in all (at most %d)", http1.MaxHosts))
+	sortFlag := flag.String("sort", "", "print results sorted by grade or score (worst first) or by target, once all are in")
+	preserveOrder := flag.Bool("preserve-order", false, "print results in input order, once all are in, rather than as targets complete")
+	summaryFlag := flag.Bool("summary", false, "after the results, show their grade distribution, the share supporting HTTP/2 and HTTP/3 or still serving HTTP/1.0, and the slowest targets (text and plain), or add it as \"summary\" (json)")
+	groupByLabel := flag.Bool("group-by-label", false, "after the results, show each label's grade distribution (text and plain) or add it as \"groups\" (json)")
+	fieldsFlag := flag.String("fields", "", "comma-separated fields to project JSON/CSV output to")
+	pprofFlag := flag.String("pprof", "", "write CPU/heap profiles with this file prefix (web mode: serve /debug/pprof/)")
+	redactFlag := flag.Bool("redact", false, "replace hostnames and IPs in the output with keyed pseudonyms")
+	redactKey := flag.String("redact-key", "", "secret for --redact tokens (default $HTTP1_REDACT_KEY, else random per run)")
+	methodFlag := flag.String("method", "GET", "request method for the probes: GET, HEAD or OPTIONS")
+	userAgent := flag.String("user-agent", "", "User-Agent for every probe (default "+http1.DefaultUserAgent()+")")
+	noColor := flag.Bool("no-color", false, "never color the terminal output (also set by a non-empty NO_COLOR)")
+	quiet := flag.Bool("q", false, "print results only: no scanning banner or closing summary (warnings and errors still go to stderr)")
+	verbose := flag.Bool("v", false, "print each probe's timing, detail and evidence to stderr")
+	veryVerbose := flag.Bool("vv", false, "like -v, plus DNS timing, TLS details, warnings and probe errors")
+	diagnostics := flag.Bool("diagnostics", false, "report scanning environment checks (UDP buffer sizes) before scanning")
+	langFlag := flag.String("lang", "", "language for human-readable output: en (default), "+strings.Join(http1.Languages(), ", "))
+	vantage := flag.String("vantage", "", "label recorded with every result for where the scan ran from (e.g. office, aws-eu)")
+	vantageProfileFlag := flag.String("vantage-profile", "", "apply the named vantage profile (proxy, source IP, interface, resolver) from the config file")
+	configFlag := flag.String("config", "", "config file (default $"+configEnv+", else http1/config.json in the user config directory)")
+	pacFlag := flag.String("pac", "", "proxy auto-config file or URL (e.g. http://wpad/wpad.dat) choosing each target's proxy for the TCP probes, instead of --proxy")
+	proxyFlag := flag.String("proxy", "", "HTTP or SOCKS5 proxy URL for the TCP probes (default $HTTPS_PROXY / $HTTP_PROXY); HTTP/3 always goes direct")
+	sourceIP := flag.String("source-ip", "", "local address for the probes' TCP and UDP sockets, to choose the egress path")
+	ifaceFlag := flag.String("interface", "", "bind the probes' sockets to this network interface (Linux only)")
+	dohFlag := flag.String("doh", "", "resolve names over DNS-over-HTTPS (https://host/path) or DNS-over-TLS (tls://host[:port]) instead of the system resolver")
+	hostHeader := flag.String("host-header", "", "Host / :authority to send instead of the target host (e.g. to probe an origin behind a CDN)")
+	sniFlag := flag.String("sni", "", "TLS server name to present instead of the target host (e.g. when scanning a load balancer by IP)")
+	calibrate := flag.Bool("calibrate", false, "handshake with HTTP/3 reference hosts first and flag h3 negatives as unreliable if none answers")
+	referenceHosts := flag.String("reference-hosts", "", "comma-separated HTTP/3 reference hosts for --calibrate (default "+strings.Join(http1.DefaultReferenceHosts, ",")+")")
+	dualStack := flag.Bool("dual-stack", false, "also probe hosts with IPv4 and IPv6 addresses over each family and report discrepancies")
+	crossCheck := flag.Bool("cross-check", false, "also have the hosted http1 instance check each target and report where its findings differ from the local ones, e.g. because of local network interference")
+	crossCheckEndpoint := flag.String("cross-check-endpoint", "", "http1 web instance to ask for --cross-check (default "+http1.DefaultCrossCheckEndpoint+"; implies --cross-check)")
+	sampleBodies := flag.Bool("sample-bodies", false, "read the start of each probe response and annotate meta refreshes to HTTPS, browser upgrade interstitials and challenge pages")
+	dnssecFlag := flag.Bool("dnssec", false, "report whether each target's name is DNSSEC-signed and validated by the resolver")
+	detectParked := flag.Bool("detect-parked", false, "tag likely parked domains (wildcard DNS, parking nameservers and landing pages)")
+	echFlag := flag.Bool("ech", false, "look up each target's HTTPS DNS record and, if it advertises an Encrypted ClientHello config, attempt an ECH handshake")
+	sniMismatch := flag.Bool("sni-mismatch", false, "send requests whose TLS server name and Host disagree and report whether the server refuses the handshake, answers 421 or serves a default virtual host")
+	extendedConnect := flag.Bool("extended-connect", false, "report whether the HTTP/2 and HTTP/3 SETTINGS enable Extended CONNECT and whether a websocket CONNECT is accepted")
+	keyExchange := flag.Bool("key-exchange", false, "attempt one more TLS 1.3 handshake per HTTPS target offering only the hybrid post-quantum X25519MLKEM768 group and report whether the server accepts it")
+	quicVersionsFlag := flag.Bool("quic-versions", false, "when HTTP/3 works, make one more QUIC handshake offering only v2 to enumerate the QUIC versions the server accepts")
+	earlyData := flag.Bool("early-data", false, "reconnect after HTTP/2 and HTTP/3 work to report TLS session resumption and whether a QUIC 0-RTT request is accepted (sends the probe request again as early data)")
+	h2SettingsFlag := flag.Bool("h2-settings", false, "after HTTP/2 works, open one more connection to record the server's SETTINGS and ORIGIN frames")
+	coalescingFlag := flag.Bool("coalescing", false, "request a second host named in the certificate over the target's HTTP/2 connection and report whether the server coalesces it or answers 421")
+	followRedirects := flag.Bool("follow-redirects", false, "chase redirects (up to 10) and grade the final destination")
+	retries := flag.Int("retries", 0, "resend a protocol probe that got no response up to N more times, with jittered backoff")
+	rate := flag.Float64("rate", 0, "start at most this many probes per second across all workers (0 = no limit)")
+	maxPerOrigin := flag.Int("max-per-origin", 4, "check at most this many targets resolving to the same IP address at once (0 = no limit)")
+	baselineFlag := flag.String("baseline", "", "results of an earlier run (--json or --ndjson) to flag certificate changes and --webhook anomalies against")
+	staleOnly := flag.Bool("stale-only", false, "rescan only the targets whose --baseline result is older than --max-age or was inconclusive, and reuse the rest")
+	maxAgeFlag := flag.Duration("max-age", http1.DefaultMaxAge, "with --stale-only, how old a --baseline result may be and still be reused")
+	maxDuration := flag.Duration("max-duration", 0, "end the scan after this long (e.g. 10m): probes still running are abandoned and targets not yet scanned are output marked \"unscanned\" (0 = no limit)")
+	progressFlag := flag.Bool("progress", false, "show a progress line on stderr (completed/total, rate, ETA, worst grade so far), redrawn in place on a terminal and logged every 10 seconds otherwise")
+	retryErrors := flag.String("retry-errors", "", "results of an earlier run (--json or --ndjson): rescan only the targets whose probes errored and output the new results with the rest (the run's targets when none are given)")
+	checkpointFlag := flag.String("checkpoint", "", "append each finished result to this file (an NDJSON journal), so an interrupted scan can be continued with --resume")
+	resumeFlag := flag.Bool("resume", false, "reuse the results saved in --checkpoint and scan only the targets it has none for")
+	notesFlag := flag.String("notes", "", "JSON file of notes on targets to show with the results; notes that acknowledge a target's findings excuse its policy failures until they expire (with --web: notes added through the API and UI are saved to it)")
+	webhookFlag := flag.String("webhook", "", "POST an event to this URL for each anomaly found against --baseline")
+	webhookEvents := flag.String("webhook-events", "", "comma-separated events for --webhook (default all): "+strings.Join(http1.AnomalyTypes, ", "))
+	yesFlag := flag.Bool("yes", false, fmt.Sprintf("start scans of more than %d targets without stopping at the cost estimate", largeScanTargets))
+	lowResource := flag.Bool("low-resource", false, "few workers, shared transports and small buffers for constrained machines")
+	var headers headerFlag
+	flag.Var(&headers, "H", "add a request header to every probe, as \"Name: value\" (repeatable)")
+	clockOffset := flag.Duration("clock-offset", 0, "shift the web server clock by this duration (e.g. 3h59m) to preview cache expiry")
+	apiToken := flag.String("api-token", os.Getenv(apiTokenEnv), "with --web, enable the batch job API and require this bearer token on it (default $"+apiTokenEnv+")")
+	jobsDir := flag.String("jobs-dir", "", "with --web and --api-token, save batch jobs in this directory so their results survive a restart")
 	flag.Parse()
 
 	if *helpFlag {
@@ -98,10 +297,58 @@ func main() {
 		return
 	}
 
+	if *vantageProfileFlag != "" {
+		err := func() error {
+			path, err := configPath(*configFlag)
+			if err != nil {
+				return err
+			}
+			profile, err := loadVantageProfile(path, *vantageProfileFlag)
+			if err != nil {
+				return err
+			}
+			return profile.apply(flag.CommandLine)
+		}()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --vantage-profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Web mode: http1 --web 8080
 	if *webPort > 0 {
 		addr := ":" + strconv.Itoa(*webPort)
-		if err := runWebServer(addr); err != nil {
+		var self *http1.SelfScan
+		if *selfScan != "" {
+			policy, err := loadPolicy(*failUnder, *requireFlag, *waiversFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			self = &http1.SelfScan{Target: *selfScan, Policy: policy, Options: http1.Options{Proxy: http.ProxyFromEnvironment}}
+		}
+		clk := systemClock{offset: *clockOffset}
+		var notes *noteStore
+		if *notesFlag != "" {
+			var err error
+			if notes, err = newNoteStore(*notesFlag, clk, *apiToken); err != nil {
+				fmt.Fprintf(os.Stderr, "error: --notes: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		var camp *campaign
+		if *campaignFlag != "" {
+			var err error
+			if camp, err = loadCampaign(*campaignFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "error: --campaign: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *jobsDir != "" && *apiToken == "" {
+			fmt.Fprintf(os.Stderr, "error: --jobs-dir needs --api-token (or $%s) to enable the job API\n", apiTokenEnv)
+			os.Exit(1)
+		}
+		if err := runWebServer(addr, *pprofFlag != "", clk, strings.TrimSpace(*vantage), self, notes, camp, *apiToken, *jobsDir); err != nil {
 			fmt.Fprintf(os.Stderr, "web server error: %v\n", err)
 			os.Exit(1)
 		}
@@ -110,67 +357,743 @@ func main() {
 
 	positional := flag.Args()
 
-	targets, err := gatherTargets(*targetsFlag, *targetsFile, positional)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n\n", err)
-		printUsage()
+	format := *formatFlag
+	for _, short := range []struct {
+		name string
+		set  bool
+	}{{"json", *jsonFlag}, {"ndjson", *ndjsonFlag}} {
+		if !short.set {
+			continue
+		}
+		if format != "" && format != short.name {
+			fmt.Fprintf(os.Stderr, "error: --%s conflicts with --format %s\n", short.name, format)
+			os.Exit(1)
+		}
+		format = short.name
+	}
+	// Line-oriented formats stream targets through the worker pool as they
+	// are read, so very large target files never have to fit in memory.
+	streaming := format == "ndjson" || format == "zgrab"
+
+	// Discovered names join the positional targets, deduplicated with the
+	// rest.
+	for _, domain := range strings.Split(*discoverFlag, ",") {
+		if domain = strings.TrimSpace(domain); domain == "" {
+			continue
+		}
+		names, err := http1.DiscoverSubdomains(context.Background(), domain, *ctEndpoint, http1.Options{Proxy: http.ProxyFromEnvironment, UserAgent: *userAgent})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --discover-subdomains %s: %v\n", domain, err)
+			os.Exit(1)
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Found %d name(s) under %s in Certificate Transparency logs.\n", len(names), domain)
+		}
+		positional = append(positional, names...)
+	}
+
+	// --retry-errors rescans the targets of an earlier run whose probes
+	// errored; without targets of its own, it takes them all from that run.
+	var previous []http1.CheckResult
+	if *retryErrors != "" {
+		switch {
+		case streaming:
+			fmt.Fprintf(os.Stderr, "error: --retry-errors cannot be combined with --format %s, which streams results\n", format)
+			os.Exit(1)
+		case *staleOnly:
+			fmt.Fprintf(os.Stderr, "error: --retry-errors cannot be combined with --stale-only\n")
+			os.Exit(1)
+		}
+		var err error
+		if previous, err = readResults(*retryErrors); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --retry-errors: %v\n", err)
+			os.Exit(1)
+		}
+		if *targetsFlag == "" && *targetsFile == "" && len(positional) == 0 {
+			positional = previousTargets(previous)
+		}
+	}
+
+	if *maxHostsFlag < 1 || *maxHostsFlag > http1.MaxHosts {
+		fmt.Fprintf(os.Stderr, "error: --max-hosts must be between 1 and %d\n", http1.MaxHosts)
 		os.Exit(1)
 	}
 
-	if len(targets) == 0 {
+	// targets are scanned as entries (target and labels) but results are
+	// ordered by their bare target names.
+	var targets, order []string
+	if !streaming {
+		parsed, err := gatherTargets(*targetsFlag, *targetsFile, positional)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n\n", err)
+			printUsage()
+			os.Exit(1)
+		}
+		if len(parsed) == 0 {
+			printUsage()
+			os.Exit(1)
+		}
+		// Ranges expand after deduplication so an address given both on
+		// its own and in a range is scanned once.
+		if parsed, err = http1.ExpandRanges(parsed, *maxHostsFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v; raise --max-hosts to scan them\n", err)
+			os.Exit(1)
+		}
+		parsed = http1.DedupeTargets(parsed)
+		for _, t := range parsed {
+			targets = append(targets, t.String())
+			order = append(order, t.Raw)
+		}
+	} else if *targetsFile == "" && *targetsFlag == "" && len(positional) == 0 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	// Suppress noisy logs from dependencies (e.g. quic-go UDP buffer warnings).
-	log.SetOutput(io.Discard)
+	verbosity := 0
+	switch {
+	case *veryVerbose:
+		verbosity = 2
+	case *verbose:
+		verbosity = 1
+	}
+	if *quiet && verbosity > 0 {
+		fmt.Fprintf(os.Stderr, "error: -q conflicts with -v and -vv\n")
+		os.Exit(1)
+	}
 
-	overridePort := ""
-	if *portFlag > 0 {
-		overridePort = strconv.Itoa(*portFlag)
+	var err error
+	var where *http1.Where
+	if *whereFlag != "" {
+		where, err = http1.ParseWhere(*whereFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --where expression: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	matches := func(res http1.CheckResult) bool {
+		return where == nil || where.Match(res)
+	}
+	policy, err := loadPolicy(*failUnder, *requireFlag, *waiversFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if expired := policy.Waivers.Expired(time.Now()); len(expired) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d waiver(s) in --waivers have expired and no longer apply:\n", len(expired))
+		for _, w := range expired {
+			fmt.Fprintf(os.Stderr, "  %s: %s, approved by %s, expired %s\n", w.Target, w.Rule, w.Approver, w.Expires.Format(time.DateOnly))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	versions, err := http1.ParseVersions(*versionsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: --versions: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Quick summary so it is obvious something is happening.
-	fmt.Fprintf(os.Stderr,
-		"Scanning %d host(s)... (✅ supported, ❌ not supported, 🟧 error/probe failed)\n\n",
-		len(targets),
-	)
+	// Results print as targets complete unless --sort or --preserve-order
+	// asks for them all to be held back and written in order.
+	var compare func(a, b http1.CheckResult) int
+	switch {
+	case *sortFlag != "" && *preserveOrder:
+		fmt.Fprintf(os.Stderr, "error: --sort conflicts with --preserve-order\n")
+		os.Exit(1)
+	case (*sortFlag != "" || *preserveOrder) && streaming:
+		fmt.Fprintf(os.Stderr, "error: --sort and --preserve-order cannot be combined with --format %s, which streams results\n", format)
+		os.Exit(1)
+	case *sortFlag != "":
+		if compare, err = http1.ResultOrder(*sortFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --sort: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	start := time.Now()
+	// Redaction happens after --where, so filters still see real hostnames;
+	// the writer orders results by the redacted targets it will receive.
+	var redactor *http1.Redactor
+	if *redactFlag {
+		redactor, err = newRedactor(*redactKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		for i, t := range order {
+			order[i] = redactor.Target(t)
+		}
+	}
+	if *preserveOrder {
+		compare = inputOrder(order)
+	}
+
+	translator, err := http1.NewTranslator(*langFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// With -o the results go to the file and stdout gets the summary lines
+	// (plain ones for --format plain) as targets complete.
+	var outFile *atomicFile
+	resultsOut := io.Writer(os.Stdout)
+	if *outputFlag != "" {
+		if info, err := os.Stat(filepath.Dir(*outputFlag)); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "error: -o: directory %s does not exist\n", filepath.Dir(*outputFlag))
+			os.Exit(1)
+		}
+		outFile = &atomicFile{path: *outputFlag}
+		resultsOut = outFile
+	}
+	// Only stdout gets columns and color; an -o file keeps the emoji lines.
+	style := detectTextStyle(os.Stdout, *noColor)
+	fileStyle := style
+	if outFile != nil {
+		fileStyle = textStyle{}
+	}
+	out, err := newResultWriter(format, resultsOut, order, http1.ParseFields(*fieldsFlag), translator, fileStyle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if compare != nil {
+		out = withOrder(out, compare)
+	}
+	if *groupByLabel {
+		if out, err = withGroups(out, format, resultsOut, translator); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *summaryFlag {
+		if out, err = withSummary(out, format, resultsOut, translator); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if outFile != nil {
+		summaryFormat := "text"
+		if format == "plain" || format == "table" {
+			summaryFormat = format
+		}
+		summary, _ := newResultWriter(summaryFormat, os.Stdout, order, nil, translator, style)
+		if compare != nil {
+			summary = withOrder(summary, compare)
+		}
+		if *groupByLabel {
+			summary, _ = withGroups(summary, summaryFormat, os.Stdout, translator)
+		}
+		if *summaryFlag {
+			summary, _ = withSummary(summary, summaryFormat, os.Stdout, translator)
+		}
+		out = teeWriter{out, summary}
+	}
+	// Only the default text output shares stdout with the closing summary.
+	summaryOut := os.Stderr
+	if format == "" || format == "text" || format == "plain" || format == "table" || outFile != nil {
+		summaryOut = os.Stdout
+	}
+
+	// Undersized UDP buffers make HTTP/3 probes unreliable; say so up front.
+	if udp := http1.CheckUDPBuffers(); !udp.Sufficient {
+		fmt.Fprintf(os.Stderr, "warning: %s\n\n", udp.Guidance)
+	} else if *diagnostics {
+		fmt.Fprintf(os.Stderr, "UDP buffers: %d KiB receive / %d KiB send (want %d KiB)\n\n", udp.Receive/1024, udp.Send/1024, udp.Wanted/1024)
+	}
+
+	method := strings.ToUpper(*methodFlag)
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+	default:
+		fmt.Fprintf(os.Stderr, "error: --method must be GET, HEAD or OPTIONS\n")
+		os.Exit(1)
+	}
+	opts := http1.Options{
+		Headers:              headers.h,
+		LowResource:          *lowResource,
+		UserAgent:            *userAgent,
+		Method:               method,
+		Versions:             versions,
+		FollowRedirects:      *followRedirects,
+		SNI:                  *sniFlag,
+		HostHeader:           *hostHeader,
+		DetectParking:        *detectParked,
+		CheckDNSSEC:          *dnssecFlag,
+		CheckSNIMismatch:     *sniMismatch,
+		CheckECH:             *echFlag,
+		CheckCoalescing:      *coalescingFlag,
+		CheckExtendedConnect: *extendedConnect,
+		CheckKeyExchange:     *keyExchange,
+		CheckQUICVersions:    *quicVersionsFlag,
+		CheckEarlyData:       *earlyData,
+		CheckH2Settings:      *h2SettingsFlag,
+		SampleBodies:         *sampleBodies,
+		DualStack:            *dualStack,
+		MaxPerOrigin:         *maxPerOrigin,
+		Retries:              *retries,
+		Rate:                 *rate,
+		Vantage:              strings.TrimSpace(*vantage),
+	}
+	if *crossCheckEndpoint != "" {
+		u, err := url.Parse(*crossCheckEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "error: --cross-check-endpoint must be a URL such as %s\n", http1.DefaultCrossCheckEndpoint)
+			os.Exit(1)
+		}
+		opts.CrossCheck = *crossCheckEndpoint
+	} else if *crossCheck {
+		opts.CrossCheck = http1.DefaultCrossCheckEndpoint
+	}
+	if *portFlag > 0 {
+		opts.Port = strconv.Itoa(*portFlag)
+	}
+	opts.Proxy = http.ProxyFromEnvironment
+	if *proxyFlag != "" {
+		proxyURL, err := url.Parse(*proxyFlag)
+		if err != nil || proxyURL.Host == "" {
+			fmt.Fprintf(os.Stderr, "error: --proxy must be a URL such as http://proxy.example:3128 or socks5://127.0.0.1:1080\n")
+			os.Exit(1)
+		}
+		opts.Proxy = http.ProxyURL(proxyURL)
+	}
+	if *pacFlag != "" {
+		if *proxyFlag != "" {
+			fmt.Fprintf(os.Stderr, "error: --pac and --proxy cannot be combined\n")
+			os.Exit(1)
+		}
+		pac, err := http1.LoadPAC(context.Background(), *pacFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --pac: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Proxy = pac.Proxy
+	}
+	if *sourceIP != "" {
+		opts.SourceIP = net.ParseIP(*sourceIP)
+		if opts.SourceIP == nil {
+			fmt.Fprintf(os.Stderr, "error: --source-ip must be an IP address\n")
+			os.Exit(1)
+		}
+	}
+	if *ifaceFlag != "" {
+		if _, err := net.InterfaceByName(*ifaceFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --interface: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Interface = *ifaceFlag
+	}
+	if *dohFlag != "" {
+		resolver, err := http1.NewResolver(*dohFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --doh: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Resolver = resolver
+	}
 
-	if *jsonFlag {
-		if len(targets) == 1 {
-			res := http1.CheckHTTPVersionsJSON(targets[0], overridePort)
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(res); err != nil {
-				fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
+	var baseline map[string]http1.CheckResult
+	var certs *http1.CertTracker
+	if *baselineFlag != "" {
+		baseline, err = loadBaseline(*baselineFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		certs = http1.NewCertTracker()
+		for _, res := range baseline {
+			certs.Remember(res)
+		}
+	}
+	// Unlike the web server, which starts a missing notes file, a scan
+	// needs the file to exist.
+	var notebook *http1.Notebook
+	if *notesFlag != "" {
+		if _, err := os.Stat(*notesFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --notes: %v\n", err)
+			os.Exit(1)
+		}
+		if notebook, err = http1.LoadNotes(*notesFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --notes: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// --stale-only reuses the recent, conclusive --baseline results and
+	// scans only the rest.
+	var reused []http1.CheckResult
+	if *staleOnly {
+		switch {
+		case baseline == nil:
+			fmt.Fprintf(os.Stderr, "error: --stale-only needs --baseline to reuse results from\n")
+			os.Exit(1)
+		case streaming:
+			fmt.Fprintf(os.Stderr, "error: --stale-only cannot be combined with --format %s, which streams results\n", format)
+			os.Exit(1)
+		}
+		targets, reused = splitStale(targets, baseline, *maxAgeFlag, time.Now())
+		fmt.Fprintf(os.Stderr, "Rescanning %d of %d target(s); %d --baseline result(s) are recent enough to reuse.\n\n", len(targets), len(targets)+len(reused), len(reused))
+	}
+	if *retryErrors != "" {
+		targets, reused = splitErrored(targets, previous)
+		fmt.Fprintf(os.Stderr, "Rescanning %d of %d target(s) whose probes errored in --retry-errors; reusing the other %d result(s).\n\n", len(targets), len(targets)+len(reused), len(reused))
+	}
+	// --checkpoint journals the results as they finish; --resume replays
+	// the journal and scans only the targets the interrupted run did not
+	// get to.
+	var checkpoint *checkpointer
+	var journal *checkpointJournal
+	if *checkpointFlag != "" || *resumeFlag {
+		if *checkpointFlag == "" {
+			fmt.Fprintf(os.Stderr, "error: --resume needs --checkpoint to resume from\n")
+			os.Exit(1)
+		}
+		if *resumeFlag {
+			journal, err = loadCheckpoint(*checkpointFlag)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				fmt.Fprintf(os.Stderr, "No --checkpoint at %s yet; scanning every target.\n\n", *checkpointFlag)
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "error: --checkpoint: %v\n", err)
 				os.Exit(1)
+			case streaming:
+				// Streamed targets are matched against the journal as
+				// they are read.
+				fmt.Fprintf(os.Stderr, "Resuming: %d result(s) in --checkpoint are reused; scanning the other targets.\n\n", len(journal.results))
+			default:
+				var resumed []http1.CheckResult
+				targets, resumed = splitResumed(targets, journal.results)
+				reused = append(reused, resumed...)
+				fmt.Fprintf(os.Stderr, "Resuming: %d of %d target(s) are done in --checkpoint; scanning the other %d.\n\n", len(resumed), len(targets)+len(resumed), len(targets))
+			}
+		}
+		if checkpoint, err = newCheckpointer(*checkpointFlag, journal); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// A mistyped targets file should not start a mass scan unnoticed.
+	count := len(targets)
+	if streaming {
+		count, err = countTargets(*targetsFlag, *targetsFile, positional, *maxHostsFlag)
+		if errors.Is(err, http1.ErrTooManyHosts) {
+			fmt.Fprintf(os.Stderr, "error: %v; raise --max-hosts to scan them\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if count > largeScanTargets {
+		est := http1.EstimateScan(count, opts)
+		fmt.Fprintf(os.Stderr, "This scan covers %d targets: about %d probes, %s of traffic and %s at the current settings.\n",
+			est.Targets, est.Probes, formatBytes(est.Bytes), est.Duration.Round(time.Second))
+		if !*yesFlag {
+			fmt.Fprintf(os.Stderr, "Re-run with --yes to start it.\n")
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	var notifier *webhookNotifier
+	if *webhookFlag != "" {
+		if baseline == nil {
+			fmt.Fprintf(os.Stderr, "error: --webhook needs --baseline to compare against\n")
+			os.Exit(1)
+		}
+		events, err := parseWebhookEvents(*webhookEvents)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --webhook-events: %v\n", err)
+			os.Exit(1)
+		}
+		notifier = newWebhookNotifier(*webhookFlag, events, baseline)
+		notifier.waivers = policy.Waivers
+	}
+
+	// A network that blocks UDP/443 makes every target look HTTP/3-less.
+	if (*calibrate || *referenceHosts != "") && http1.HTTP3Available() {
+		hosts := http1.DefaultReferenceHosts
+		if *referenceHosts != "" {
+			hosts = nil
+			for _, h := range strings.Split(*referenceHosts, ",") {
+				if h = strings.TrimSpace(h); h != "" {
+					hosts = append(hosts, h)
+				}
+			}
+		}
+		cal := http1.CalibrateQUIC(hosts)
+		if cal.OK {
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "QUIC calibration: %s\n\n", cal.Detail)
 			}
 		} else {
-			res := http1.CheckHTTPVersionsJSONMulti(targets, overridePort)
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(res); err != nil {
-				fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
-				os.Exit(1)
+			fmt.Fprintf(os.Stderr, "warning: QUIC calibration failed: %s; HTTP/3 \"not supported\" results will be marked unreliable\n\n", cal.Detail)
+		}
+		opts.QUICCalibration = &cal
+	}
+
+	// Quick summary so it is obvious something is happening. Plain output
+	// skips the emoji legend, which screen readers read out symbol by symbol.
+	switch {
+	case *quiet:
+	case format == "plain":
+		fmt.Fprintf(os.Stderr, "Scanning %d host(s).\n\n", len(targets))
+	case format == "table":
+		fmt.Fprintf(os.Stderr, "%s\n\n", translator.Sprintf("Scanning %d host(s)...", len(targets)))
+	case style.columns && streaming:
+		fmt.Fprintf(os.Stderr, "%s\n\n", translator.Message("Scanning hosts as they are read..."))
+	case style.columns:
+		fmt.Fprintf(os.Stderr, "%s\n\n", translator.Sprintf("Scanning %d host(s)...", len(targets)))
+	case streaming:
+		fmt.Fprintf(os.Stderr, "%s\n\n", translator.Message("Scanning hosts as they are read... (✅ supported, ❌ not supported, 🟧 error/probe failed)"))
+	default:
+		fmt.Fprintf(os.Stderr, "%s\n\n",
+			translator.Sprintf("Scanning %d host(s)... (✅ supported, ❌ not supported, 🟧 error/probe failed)", len(targets)),
+		)
+	}
+
+	var stopProfiling func() error
+	if *pprofFlag != "" {
+		stopProfiling, err = startProfiling(*pprofFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The recording holds the results as scanned (redacted with --redact),
+	// before --where and translation, so replay can apply its own.
+	var recorder *sessionRecorder
+	if *recordFlag != "" {
+		recorder, err = newSessionRecorder(*recordFlag, sessionHeader{
+			Recorded: time.Now().UTC(),
+			Args:     os.Args[1:],
+			Targets:  order,
+			Format:   format,
+			Fields:   *fieldsFlag,
+			Where:    *whereFlag,
+			Lang:     *langFlag,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --record: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var recordErr error
+
+	var progress *progressLine
+	if *progressFlag {
+		progress = newProgressLine(os.Stderr, count+len(reused), len(reused))
+	}
+
+	start := time.Now()
+
+	if *maxDuration > 0 {
+		opts.Deadline = start.Add(*maxDuration)
+	}
+
+	scanned, matched, waived, unscanned := 0, 0, 0, 0
+	var failed, acknowledged []string
+	var writeErr, checkpointErr error
+	handle := func(res http1.CheckResult) {
+		scanned++
+		if res.Unscanned {
+			unscanned++
+		}
+		if progress != nil {
+			progress.clear()
+			defer progress.add(res)
+		}
+		if checkpoint != nil && checkpointErr == nil {
+			if checkpointErr = checkpoint.add(res); checkpointErr != nil {
+				fmt.Fprintf(os.Stderr, "failed to write to the --checkpoint, scanning on without it: %v\n", checkpointErr)
+			}
+		}
+		if notebook != nil {
+			res = notebook.Annotate(res)
+		}
+		var v []string
+		for _, f := range policy.Findings(res, start) {
+			if f.Waived {
+				waived++
+			} else {
+				v = append(v, f.String())
+			}
+		}
+		if len(v) > 0 {
+			target := res.Target
+			if redactor != nil {
+				target = redactor.Target(target)
+			}
+			if n := http1.Acknowledged(res, start); n != nil {
+				acknowledged = append(acknowledged, fmt.Sprintf("%s: %s (%s)", target, strings.Join(v, ", "), noteSummary(*n)))
+			} else {
+				failed = append(failed, fmt.Sprintf("%s: %s", target, strings.Join(v, ", ")))
+			}
+		}
+		if certs != nil {
+			res = certs.Result(res)
+		}
+		if verbosity > 0 || recorder != nil {
+			shown := res
+			if redactor != nil {
+				shown = redactor.Result(shown)
+			}
+			printProbeLog(os.Stderr, shown, verbosity)
+			if recorder != nil && recordErr == nil {
+				recordErr = recorder.Record(shown)
 			}
 		}
+		if notifier != nil {
+			target := res.Target
+			if redactor != nil {
+				target = redactor.Target(target)
+			}
+			notifier.notify(res, target)
+		}
+		if writeErr != nil || !matches(res) {
+			return
+		}
+		matched++
+		if redactor != nil {
+			res = redactor.Result(res)
+		}
+		res = translator.Result(res)
+		writeErr = out.Write(res)
+	}
 
-		// Print timing summary to stderr so JSON on stdout remains clean.
-		elapsed := time.Since(start)
-		fmt.Fprintln(os.Stderr)
-		fmt.Fprintf(os.Stderr, "Scanned %d host(s) in %s\n", len(targets), elapsed.Truncate(time.Millisecond))
+	for _, res := range reused {
+		handle(res)
+	}
+	if streaming {
+		feed, wait, err := streamTargets(*targetsFlag, *targetsFile, positional)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		var resumed func() []http1.CheckResult
+		if journal != nil {
+			feed, resumed = resumeStream(feed, journal.results)
+		}
+		http1.CheckHTTPVersionsStream(feed, opts, handle)
+		if resumed != nil {
+			for _, res := range resumed() {
+				handle(res)
+			}
+		}
+		if err := wait(); err != nil && writeErr == nil {
+			writeErr = err
+		}
 	} else {
-		if len(targets) == 1 {
-			http1.CheckHTTPVersions(targets[0], overridePort)
+		http1.CheckHTTPVersionsEach(targets, opts, handle)
+	}
+	if progress != nil {
+		progress.clear()
+	}
+	if notifier != nil {
+		notifier.close()
+	}
+	if checkpoint != nil {
+		if err := checkpoint.close(); err != nil && checkpointErr == nil {
+			fmt.Fprintf(os.Stderr, "failed to save the --checkpoint: %v\n", err)
+		}
+	}
+	if recorder != nil {
+		if err := recorder.Close(); recordErr == nil {
+			recordErr = err
+		}
+		if recordErr != nil {
+			fmt.Fprintf(os.Stderr, "failed to write the --record session: %v\n", recordErr)
+		}
+	}
+	if writeErr == nil {
+		writeErr = out.Close()
+	}
+	if outFile != nil {
+		if writeErr == nil {
+			writeErr = outFile.Commit()
 		} else {
-			http1.CheckHTTPVersionsMulti(targets, overridePort)
+			outFile.Abort()
+		}
+	}
+	if writeErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to write results: %v\n", writeErr)
+		os.Exit(1)
+	}
+
+	elapsed := time.Since(start)
+	if stopProfiling != nil {
+		if err := stopProfiling(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write profiles: %v\n", err)
+		}
+	}
+	if !*quiet {
+		fmt.Fprintln(summaryOut)
+		fmt.Fprintln(summaryOut, scanSummary(translator, scanned, matched, where, elapsed))
+	}
+	if unscanned > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d target(s) were not scanned before --max-duration %s passed; they are marked \"unscanned\".\n", unscanned, *maxDuration)
+	}
+	if waived > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d policy finding(s) waived by --waivers.\n", waived)
+	}
+	if len(acknowledged) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d host(s) miss the policy but are acknowledged in --notes:\n", len(acknowledged))
+		sort.Strings(acknowledged)
+		for _, a := range acknowledged {
+			fmt.Fprintf(os.Stderr, "  %s\n", a)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d of %d host(s) miss the policy:\n", len(failed), scanned)
+		sort.Strings(failed)
+		for _, f := range failed {
+			fmt.Fprintf(os.Stderr, "  %s\n", f)
+		}
+		os.Exit(exitPolicyFailed)
+	}
+}
+
+// loadPolicy builds the policy of --fail-under and --require, with the
+// waivers in the --waivers file, if any.
+func loadPolicy(minGrade, require, waiversPath string) (http1.Policy, error) {
+	policy, err := http1.ParsePolicy(minGrade, require)
+	if err != nil {
+		return policy, fmt.Errorf("--fail-under/--require: %w", err)
+	}
+	if waiversPath != "" {
+		if policy.Waivers, err = http1.LoadWaivers(waiversPath); err != nil {
+			return policy, fmt.Errorf("--waivers: %w", err)
 		}
+	}
+	return policy, nil
+}
+
+// exitPolicyFailed is the exit status when a target misses --fail-under or
+// --require, distinct from 1 for errors so CI can tell them apart.
+const exitPolicyFailed = 3
+
+// largeScanTargets is the target count above which a scan needs --yes.
+const largeScanTargets = 10000
+
+// formatBytes renders n in binary units, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
 
-		// Human-readable summary on stdout.
-		elapsed := time.Since(start)
-		fmt.Println()
-		fmt.Printf("Scanned %d host(s) in %s\n", len(targets), elapsed.Truncate(time.Millisecond))
+// scanSummary formats the closing "Scanned N host(s)" line, noting how many
+// results survived the --where filter when one is set.
+func scanSummary(tr *http1.Translator, total, matched int, where *http1.Where, elapsed time.Duration) string {
+	line := tr.Sprintf("Scanned %d host(s) in %s", total, elapsed.Truncate(time.Millisecond))
+	if where != nil {
+		line += tr.Sprintf(" (%d matched --where)", matched)
 	}
+	return line
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// csrfCookie holds the token the note form must echo back.
+const csrfCookie = "http1_csrf"
+
+// noteStore is the web server's notebook. Notes added through the API or
+// the UI are saved to path straight away. Adding notes takes the API token;
+// without one the notes are read-only.
+type noteStore struct {
+	book  *http1.Notebook
+	path  string
+	clock clock
+	token string
+
+	// mu serializes additions with the saves that follow them.
+	mu sync.Mutex
+}
+
+func newNoteStore(path string, clk clock, token string) (*noteStore, error) {
+	book, err := http1.LoadNotes(path)
+	if err != nil {
+		return nil, err
+	}
+	return &noteStore{book: book, path: path, clock: clk, token: token}, nil
+}
+
+// writable reports whether notes can be added.
+func (s *noteStore) writable() bool {
+	return s != nil && s.token != ""
+}
+
+// annotate returns copies of results with their targets' notes attached;
+// results may be shared with the cache, so they are not changed in place.
+func (s *noteStore) annotate(results []http1.CheckResult) []http1.CheckResult {
+	out := make([]http1.CheckResult, len(results))
+	for i, res := range results {
+		out[i] = s.book.Annotate(res)
+	}
+	return out
+}
+
+// add stores a note, stamped with the time, and saves the notebook.
+// expires is parsed with http1.ParseExpiry; empty means never.
+func (s *noteStore) add(n http1.Note, expires string) (http1.Note, error) {
+	if strings.TrimSpace(expires) != "" {
+		t, err := http1.ParseExpiry(expires)
+		if err != nil {
+			return http1.Note{}, err
+		}
+		n.Expires = t
+	}
+	n.Added = s.clock.Now().UTC().Truncate(time.Second)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err := s.book.Add(n)
+	if err != nil {
+		return http1.Note{}, err
+	}
+	return n, s.book.Save(s.path)
+}
+
+// register adds the notes endpoints to mux; the POST ones only when the
+// store is writable. The API ones require the API token, so without one
+// the notes are only shown on the result cards:
+//
+//	GET  /api/v1/notes?target=T  the notes on T, or every note
+//	POST /api/v1/notes           {"target": "legacy.example", "text": "...", "acknowledged": true, "expires": "2025-06"}
+//	POST /notes                  the same from the result card form, back to the scan page
+//
+// The form carries the API token in its token field, and the CSRF token
+// the page was rendered with, which must match the csrfCookie.
+func (s *noteStore) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/notes", requireToken(s.token, func(w http.ResponseWriter, r *http.Request) {
+		notes := s.book.Notes()
+		if target := r.URL.Query().Get("target"); target != "" {
+			notes = s.book.For(target)
+		}
+		writeAPIJSON(w, http.StatusOK, map[string][]http1.Note{"notes": notes})
+	}))
+	if !s.writable() {
+		return
+	}
+	mux.HandleFunc("POST /api/v1/notes", requireToken(s.token, func(w http.ResponseWriter, r *http.Request) {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody))
+		dec.DisallowUnknownFields()
+		var req struct {
+			Target       string `json:"target"`
+			Text         string `json:"text"`
+			Author       string `json:"author"`
+			Acknowledged bool   `json:"acknowledged"`
+			Expires      string `json:"expires"`
+		}
+		if err := dec.Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid note: "+err.Error())
+			return
+		}
+		n, err := s.add(http1.Note{Target: req.Target, Text: req.Text, Author: req.Author, Acknowledged: req.Acknowledged}, req.Expires)
+		if err != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		writeAPIJSON(w, http.StatusCreated, n)
+	}))
+	mux.HandleFunc("POST /notes", func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxAPIBody)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "failed to parse request", http.StatusBadRequest)
+			return
+		}
+		cookie, err := r.Cookie(csrfCookie)
+		if err != nil || !tokenMatches(r.PostForm.Get("csrf"), cookie.Value) {
+			http.Error(w, "invalid or missing CSRF token; reload the page and try again", http.StatusForbidden)
+			return
+		}
+		if !tokenMatches(r.PostForm.Get("token"), s.token) {
+			http.Error(w, "wrong API token", http.StatusUnauthorized)
+			return
+		}
+		n := http1.Note{Target: r.PostForm.Get("target"), Text: r.PostForm.Get("text"), Acknowledged: r.PostForm.Get("acknowledge") != ""}
+		if _, err := s.add(n, r.PostForm.Get("expires")); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Redirect(w, r, "/scan?"+url.Values{"t": {r.PostForm.Get("t")}}.Encode(), http.StatusSeeOther)
+	})
+}
+
+// csrfToken returns the CSRF token for the note forms on a page, from the
+// request's csrfCookie or, the first time, a new one set on w.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookie); err == nil && len(c.Value) == 32 {
+		return c.Value
+	}
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	token := hex.EncodeToString(b[:])
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	return token
+}
+
+// tokenMatches compares a presented token with the expected one in
+// constant time; an empty token never matches.
+func tokenMatches(got, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// noteSummary describes an acknowledging note in the list of excused policy
+// failures, e.g. "known legacy appliance, until 2025-07-01".
+func noteSummary(n http1.Note) string {
+	if n.Expires.IsZero() {
+		return n.Text
+	}
+	return n.Text + ", until " + n.Expires.Format(time.DateOnly)
+}
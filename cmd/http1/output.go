@@ -0,0 +1,503 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"http1.dev/internal/http1"
+)
+
+// resultWriter renders scan results in one output format. Write is called
+// once per result as targets complete; Close flushes anything buffered.
+type resultWriter interface {
+	Write(res http1.CheckResult) error
+	Close() error
+}
+
+// newResultWriter returns the writer for the given --format value. targets is
+// the input order, used by formats that buffer and emit results in order. tr
+// localizes the text format's labels; nil means English. style is the text
+// format's layout.
+func newResultWriter(format string, w io.Writer, targets []string, fields []string, tr *http1.Translator, style textStyle) (resultWriter, error) {
+	switch format {
+	case "", "text":
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("--fields requires --format json, ndjson or csv")
+		}
+		return &textWriter{w: w, tr: tr, style: style}, nil
+	case "plain":
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("--fields requires --format json, ndjson or csv")
+		}
+		return &plainWriter{w: w}, nil
+	case "table":
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("--fields requires --format json, ndjson or csv")
+		}
+		width := 0
+		for _, t := range targets {
+			width = max(width, utf8.RuneCountInString(t))
+		}
+		return &tableWriter{w: w, tr: tr, width: width, color: style.color}, nil
+	case "json":
+		return &jsonWriter{w: w, single: len(targets) == 1, compare: inputOrder(targets), fields: fields}, nil
+	case "ndjson":
+		return &ndjsonWriter{enc: json.NewEncoder(w), fields: fields}, nil
+	case "zgrab":
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("--fields cannot be combined with --format zgrab")
+		}
+		return &zgrabWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		if len(fields) == 0 {
+			fields = http1.DefaultFields
+		}
+		return &csvWriter{w: csv.NewWriter(w), fields: fields}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want text, plain, table, json, ndjson, csv or zgrab)", format)
+	}
+}
+
+// inputOrder compares results by the position of their targets in targets.
+func inputOrder(targets []string) func(a, b http1.CheckResult) int {
+	order := make(map[string]int, len(targets))
+	for i, t := range targets {
+		order[t] = i
+	}
+	return func(a, b http1.CheckResult) int {
+		return cmp.Compare(order[a.Target], order[b.Target])
+	}
+}
+
+// withOrder makes w write its results in compare's order rather than as
+// they complete. The JSON writer buffers and sorts anyway, so it just takes
+// the new order.
+func withOrder(w resultWriter, compare func(a, b http1.CheckResult) int) resultWriter {
+	if j, ok := w.(*jsonWriter); ok {
+		j.compare = compare
+		return j
+	}
+	return &sortedWriter{w: w, compare: compare}
+}
+
+// withGroups adds the grade distribution per label to w's output: next to
+// the results for JSON, and as a table after them for text, plain and
+// table. The other formats have nowhere to put it.
+func withGroups(w resultWriter, format string, out io.Writer, tr *http1.Translator) (resultWriter, error) {
+	switch format {
+	case "json":
+		if j, ok := w.(*jsonWriter); ok {
+			j.groups = true
+			return j, nil
+		}
+	case "", "text", "plain", "table":
+		return &groupWriter{w: w, out: out, tr: tr}, nil
+	}
+	return nil, fmt.Errorf("--group-by-label works with text, plain, table and json output, not %s", format)
+}
+
+// withSummary adds the executive summary of the results to w's output: as
+// "summary" next to the results for JSON, and after them for text, plain
+// and table.
+func withSummary(w resultWriter, format string, out io.Writer, tr *http1.Translator) (resultWriter, error) {
+	switch format {
+	case "json":
+		if j, ok := w.(*jsonWriter); ok {
+			j.summary = true
+			return j, nil
+		}
+	case "", "text", "plain", "table":
+		return &summaryWriter{w: w, out: out, tr: tr}, nil
+	}
+	return nil, fmt.Errorf("--summary works with text, plain, table and json output, not %s", format)
+}
+
+// summaryWriter passes results to w and, on Close, prints their grade
+// distribution, HTTP/2, HTTP/3 and HTTP/1.0 support and slowest targets.
+type summaryWriter struct {
+	w     resultWriter
+	out   io.Writer
+	tr    *http1.Translator
+	tally http1.StatsTally
+}
+
+func (s *summaryWriter) Write(res http1.CheckResult) error {
+	s.tally.Add(res)
+	return s.w.Write(res)
+}
+
+func (s *summaryWriter) Close() error {
+	if err := s.w.Close(); err != nil {
+		return err
+	}
+	stats := s.tally.Stats()
+	if stats.Targets == 0 {
+		return nil
+	}
+	grades, slowest := s.tr.Message("Grades"), s.tr.Message("Slowest")
+	width := max(utf8.RuneCountInString(grades), utf8.RuneCountInString(slowest), len("HTTP/1.0"))
+	row := func(b *strings.Builder, label, value string) {
+		fmt.Fprintf(b, "  %s%s  %s\n", label, strings.Repeat(" ", width-utf8.RuneCountInString(label)), value)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s\n", s.tr.Sprintf("Summary of %d host(s):", stats.Targets))
+	var counts []string
+	for _, c := range stats.GradeCounts() {
+		counts = append(counts, fmt.Sprintf("%s %d", c.Grade, c.Count))
+	}
+	if stats.Errors > 0 {
+		counts = append(counts, fmt.Sprintf("%s %d", s.tr.Message("error"), stats.Errors))
+	}
+	row(&b, grades, strings.Join(counts, "  "))
+	row(&b, "HTTP/2", fmt.Sprintf("%d (%.1f%%)", stats.H2, stats.H2Percent))
+	row(&b, "HTTP/3", fmt.Sprintf("%d (%.1f%%)", stats.H3, stats.H3Percent))
+	row(&b, "HTTP/1.0", fmt.Sprintf("%d (%.1f%%)", stats.H10, stats.H10Percent))
+	if len(stats.Slowest) > 0 {
+		var slow []string
+		for _, t := range stats.Slowest {
+			slow = append(slow, fmt.Sprintf("%s %s", t.Target, time.Duration(t.DurationMS)*time.Millisecond))
+		}
+		row(&b, slowest, strings.Join(slow, ", "))
+	}
+	_, err := io.WriteString(s.out, b.String())
+	return err
+}
+
+// groupWriter passes results to w and, on Close, prints how they grade per
+// label, unless none had a label.
+type groupWriter struct {
+	w     resultWriter
+	out   io.Writer
+	tr    *http1.Translator
+	tally http1.LabelTally
+}
+
+func (g *groupWriter) Write(res http1.CheckResult) error {
+	g.tally.Add(res)
+	return g.w.Write(res)
+}
+
+func (g *groupWriter) Close() error {
+	if err := g.w.Close(); err != nil {
+		return err
+	}
+	groups := g.tally.Groups()
+	if groups == nil {
+		return nil
+	}
+	labels := make([]string, len(groups))
+	width := 0
+	for i, grp := range groups {
+		labels[i] = grp.Label
+		if grp.Label == http1.Unlabeled {
+			labels[i] = g.tr.Message(http1.Unlabeled)
+		}
+		width = max(width, utf8.RuneCountInString(labels[i]))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s\n", g.tr.Message("Grades by label:"))
+	for i, grp := range groups {
+		fmt.Fprintf(&b, "  %s%s  %s", labels[i], strings.Repeat(" ", width-utf8.RuneCountInString(labels[i])), g.tr.Sprintf("%d host(s)", grp.Targets))
+		for _, c := range grp.GradeCounts() {
+			fmt.Fprintf(&b, "  %s %d", c.Grade, c.Count)
+		}
+		if grp.Errors > 0 {
+			fmt.Fprintf(&b, "  %s %d", g.tr.Message("error"), grp.Errors)
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(g.out, b.String())
+	return err
+}
+
+// sortedWriter buffers results and hands them to w in compare's order on
+// Close.
+type sortedWriter struct {
+	w       resultWriter
+	compare func(a, b http1.CheckResult) int
+	results []http1.CheckResult
+}
+
+func (s *sortedWriter) Write(res http1.CheckResult) error {
+	s.results = append(s.results, res)
+	return nil
+}
+
+func (s *sortedWriter) Close() error {
+	slices.SortStableFunc(s.results, s.compare)
+	for _, res := range s.results {
+		if err := s.w.Write(res); err != nil {
+			return err
+		}
+	}
+	return s.w.Close()
+}
+
+// textWriter prints the one-line summary per host as results arrive: emoji
+// statuses, or aligned columns when style says so.
+type textWriter struct {
+	w     io.Writer
+	tr    *http1.Translator
+	style textStyle
+}
+
+func (t *textWriter) Write(res http1.CheckResult) error {
+	line := t.tr.SummaryLine(res)
+	if t.style.columns {
+		line = t.tr.ColumnLine(res, t.style.color)
+	}
+	_, err := fmt.Fprintln(t.w, line)
+	return err
+}
+
+func (t *textWriter) Close() error { return nil }
+
+// plainWriter prints one prose sentence per host, without emoji or columns,
+// for screen readers and pagers.
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p *plainWriter) Write(res http1.CheckResult) error {
+	_, err := fmt.Fprintln(p.w, http1.PlainSummary(res))
+	return err
+}
+
+func (p *plainWriter) Close() error { return nil }
+
+// tableWriter prints a header row and then one row of fixed-width columns
+// per host as results arrive. The target column is as wide as the longest
+// target given.
+type tableWriter struct {
+	w       io.Writer
+	tr      *http1.Translator
+	width   int
+	color   bool
+	started bool
+}
+
+func (t *tableWriter) header() error {
+	if t.started {
+		return nil
+	}
+	t.started = true
+	_, err := fmt.Fprintln(t.w, t.tr.TableHeader(t.width))
+	return err
+}
+
+func (t *tableWriter) Write(res http1.CheckResult) error {
+	if err := t.header(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(t.w, t.tr.TableRow(res, t.width, t.color))
+	return err
+}
+
+func (t *tableWriter) Close() error { return t.header() }
+
+// jsonWriter buffers results and encodes them in input order, or compare's,
+// on Close: a single object for one target, an array otherwise. With fields
+// set, each result is projected into a flat object first.
+type jsonWriter struct {
+	w       io.Writer
+	single  bool
+	compare func(a, b http1.CheckResult) int
+	fields  []string
+	// groups and summary wrap the results in an object with their grades
+	// by label and their executive summary.
+	groups  bool
+	summary bool
+	results []http1.CheckResult
+}
+
+func (j *jsonWriter) Write(res http1.CheckResult) error {
+	j.results = append(j.results, res)
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	slices.SortStableFunc(j.results, j.compare)
+
+	items := make([]any, len(j.results))
+	for i, res := range j.results {
+		if len(j.fields) > 0 {
+			items[i] = http1.ProjectFields(res, j.fields)
+		} else {
+			items[i] = res
+		}
+	}
+
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	if j.groups || j.summary {
+		var wrapped struct {
+			Results []any             `json:"results"`
+			Groups  any               `json:"groups,omitempty"`
+			Summary *http1.BatchStats `json:"summary,omitempty"`
+		}
+		wrapped.Results = items
+		if j.groups {
+			groups := http1.GroupByLabel(j.results)
+			if groups == nil {
+				groups = []http1.LabelGroup{}
+			}
+			wrapped.Groups = groups
+		}
+		if j.summary {
+			stats := http1.Summarize(j.results)
+			wrapped.Summary = &stats
+		}
+		return enc.Encode(wrapped)
+	}
+	if j.single {
+		// Single target returns a single object (or nothing if filtered out).
+		if len(items) == 0 {
+			return nil
+		}
+		return enc.Encode(items[0])
+	}
+	return enc.Encode(items)
+}
+
+// ndjsonWriter emits one compact JSON object per line as results complete.
+type ndjsonWriter struct {
+	enc    *json.Encoder
+	fields []string
+}
+
+func (n *ndjsonWriter) Write(res http1.CheckResult) error {
+	if len(n.fields) > 0 {
+		return n.enc.Encode(http1.ProjectFields(res, n.fields))
+	}
+	return n.enc.Encode(res)
+}
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+// zgrabWriter emits one zgrab2-style http module record per line.
+type zgrabWriter struct {
+	enc *json.Encoder
+}
+
+func (z *zgrabWriter) Write(res http1.CheckResult) error {
+	return z.enc.Encode(http1.ZgrabRecord(res))
+}
+
+func (z *zgrabWriter) Close() error { return nil }
+
+// csvWriter streams one flat row per result after a header row.
+type csvWriter struct {
+	w       *csv.Writer
+	fields  []string
+	started bool
+}
+
+func (c *csvWriter) Write(res http1.CheckResult) error {
+	if !c.started {
+		c.started = true
+		if err := c.w.Write(c.fields); err != nil {
+			return err
+		}
+	}
+	if err := c.w.Write(http1.ProjectFields(res, c.fields).Strings()); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if !c.started {
+		c.started = true
+		if err := c.w.Write(c.fields); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// teeWriter hands every result to each of its writers, e.g. the -o file and
+// the summary lines on stdout.
+type teeWriter []resultWriter
+
+func (t teeWriter) Write(res http1.CheckResult) error {
+	for _, w := range t {
+		if err := w.Write(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t teeWriter) Close() error {
+	for _, w := range t {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// atomicFile is the -o output file. Output goes to a temporary file next to
+// path, created on the first write, which Commit renames over path; until
+// then an earlier file at path stays intact, and a failed run leaves it so.
+type atomicFile struct {
+	path string
+	tmp  *os.File
+}
+
+func (f *atomicFile) Write(p []byte) (int, error) {
+	if f.tmp == nil {
+		tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".tmp*")
+		if err != nil {
+			return 0, err
+		}
+		f.tmp = tmp
+	}
+	return f.tmp.Write(p)
+}
+
+// Commit moves the output into place, creating an empty file when nothing
+// was written. A file it replaces keeps its permissions; a new one gets
+// 0644.
+func (f *atomicFile) Commit() error {
+	if _, err := f.Write(nil); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(f.path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	err := f.tmp.Chmod(mode)
+	if err == nil {
+		err = f.tmp.Sync()
+	}
+	if cerr := f.tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.tmp.Name(), f.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.tmp.Name())
+	}
+	return err
+}
+
+// Abort discards the output.
+func (f *atomicFile) Abort() {
+	if f.tmp != nil {
+		_ = f.tmp.Close()
+		_ = os.Remove(f.tmp.Name())
+	}
+}
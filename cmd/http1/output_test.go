@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"http1.dev/internal/http1"
+)
+
+// tempFiles lists the files in dir other than name.
+func tempFiles(t *testing.T, dir, name string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, e := range entries {
+		if e.Name() != name {
+			out = append(out, e.Name())
+		}
+	}
+	return out
+}
+
+func TestAtomicFile(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "results.json")
+		f := &atomicFile{path: path}
+		if _, err := f.Write([]byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("output in place before Commit: %v", err)
+		}
+		if err := f.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != "new" {
+			t.Errorf("committed %q, %v; want %q", data, err, "new")
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o644 {
+			t.Errorf("new file mode = %v, %v; want 0644", fi.Mode().Perm(), err)
+		}
+		if left := tempFiles(t, dir, "results.json"); len(left) != 0 {
+			t.Errorf("temporary files left behind: %v", left)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		if err := (&atomicFile{path: path}).Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+			t.Errorf("empty commit: %v, %v", fi, err)
+		}
+	})
+
+	t.Run("keeps mode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0o640); err != nil {
+			t.Fatal(err)
+		}
+		f := &atomicFile{path: path}
+		if _, err := f.Write([]byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o640 {
+			t.Errorf("replaced file mode = %v, %v; want 0640", fi.Mode().Perm(), err)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "results.json")
+		if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f := &atomicFile{path: path}
+		if _, err := f.Write([]byte("partial")); err != nil {
+			t.Fatal(err)
+		}
+		f.Abort()
+		if data, _ := os.ReadFile(path); string(data) != "old" {
+			t.Errorf("aborted output replaced the file with %q", data)
+		}
+		if left := tempFiles(t, dir, "results.json"); len(left) != 0 {
+			t.Errorf("temporary files left behind: %v", left)
+		}
+	})
+
+	t.Run("commit fails", func(t *testing.T) {
+		// Renaming over a directory fails after the temporary file is
+		// written.
+		dir := t.TempDir()
+		path := filepath.Join(dir, "results.json")
+		if err := os.Mkdir(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "keep"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f := &atomicFile{path: path}
+		if _, err := f.Write([]byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Commit(); err == nil {
+			t.Fatal("Commit over a directory succeeded")
+		}
+		if left := tempFiles(t, dir, "results.json"); len(left) != 0 {
+			t.Errorf("temporary files left behind: %v", left)
+		}
+	})
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	results := []http1.CheckResult{
+		{Target: "a.example", Grade: "A", Results: []http1.VersionResult{{Version: "HTTP/2.0", Supported: true, Detail: "line\nbreak"}}},
+		{Target: "b.example", Grade: "F"},
+		{Target: "c.example", Grade: "B"},
+	}
+	for _, fields := range [][]string{nil, {"target", "grade"}} {
+		var buf bytes.Buffer
+		w, err := newResultWriter("ndjson", &buf, []string{"a.example", "b.example", "c.example"}, fields, nil, textStyle{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, res := range results {
+			if err := w.Write(res); err != nil {
+				t.Fatal(err)
+			}
+			// Each result reaches the output as soon as it is written,
+			// as exactly one more line.
+			lines := strings.SplitAfter(buf.String(), "\n")
+			if len(lines) != i+2 || lines[i+1] != "" {
+				t.Fatalf("fields %v: after %d writes, output %q, want %d complete lines", fields, i+1, buf.String(), i+1)
+			}
+			var got map[string]any
+			if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+				t.Fatalf("fields %v: line %d: %v", fields, i+1, err)
+			}
+			if got["target"] != res.Target || got["grade"] != res.Grade {
+				t.Errorf("fields %v: line %d = %v, want %s graded %s", fields, i+1, got, res.Target, res.Grade)
+			}
+			if len(fields) > 0 && len(got) != len(fields) {
+				t.Errorf("fields %v: line %d has keys %v", fields, i+1, got)
+			}
+		}
+		before := buf.Len()
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != before {
+			t.Errorf("fields %v: Close wrote %q, want nothing more", fields, buf.String()[before:])
+		}
+		if out := buf.String(); strings.HasPrefix(out, "[") || strings.HasSuffix(strings.TrimSpace(out), "]") {
+			t.Errorf("fields %v: output is wrapped in an array: %q", fields, out)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+// startProfiling starts a CPU profile written to prefix.cpu.pprof and returns
+// a stop function that finishes it and writes a heap profile to
+// prefix.heap.pprof.
+func startProfiling(prefix string) (func() error, error) {
+	cpuFile, err := os.Create(prefix + ".cpu.pprof")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := runtimepprof.StartCPUProfile(cpuFile); err != nil {
+		_ = cpuFile.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	return func() error {
+		runtimepprof.StopCPUProfile()
+		if err := cpuFile.Close(); err != nil {
+			return err
+		}
+
+		heapFile, err := os.Create(prefix + ".heap.pprof")
+		if err != nil {
+			return fmt.Errorf("failed to create heap profile: %w", err)
+		}
+		defer heapFile.Close()
+		// Collect garbage first so the profile reflects live memory.
+		runtime.GC()
+		return runtimepprof.WriteHeapProfile(heapFile)
+	}, nil
+}
+
+// registerPprof mounts the net/http/pprof handlers under /debug/pprof/.
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+const (
+	// progressRedraw is how often the progress line is redrawn on a
+	// terminal.
+	progressRedraw = 200 * time.Millisecond
+	// progressLogInterval is how often a progress line is logged when
+	// stderr is not a terminal.
+	progressLogInterval = 10 * time.Second
+)
+
+// progressGrades lists the grades from best to worst, for the worst grade
+// seen so far.
+var progressGrades = []string{"A", "B", "C", "F"}
+
+// progressLine is --progress: a line on stderr with how far a scan is,
+// its rate and ETA, and how many targets have the worst grade seen so far.
+// On a terminal it is redrawn in place, and cleared around anything else
+// printed; otherwise it is logged as a line of its own every
+// progressLogInterval.
+type progressLine struct {
+	w     io.Writer
+	tty   bool
+	clk   clock
+	total int
+	// reused counts the results passed through without a scan, which do
+	// not count toward the rate.
+	reused int
+	start  time.Time
+	last   time.Time
+	drawn  bool
+
+	done, errors int
+	worst        string
+	worstCount   int
+}
+
+func newProgressLine(f *os.File, total, reused int) *progressLine {
+	info, err := f.Stat()
+	tty := err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+	return newProgress(f, tty, systemClock{}, total, reused)
+}
+
+// newProgress starts a progress line on w, drawn in place when tty is set.
+func newProgress(w io.Writer, tty bool, clk clock, total, reused int) *progressLine {
+	now := clk.Now()
+	return &progressLine{w: w, tty: tty, clk: clk, total: total, reused: reused, start: now, last: now}
+}
+
+// add counts a finished result and shows the progress when it is due.
+func (p *progressLine) add(res http1.CheckResult) {
+	p.done++
+	if i := slices.Index(progressGrades, res.Grade); i < 0 {
+		p.errors++
+	} else if j := slices.Index(progressGrades, p.worst); i > j {
+		p.worst, p.worstCount = res.Grade, 1
+	} else if i == j {
+		p.worstCount++
+	}
+	interval := progressLogInterval
+	if p.tty {
+		interval = progressRedraw
+	}
+	now := p.clk.Now()
+	if now.Sub(p.last) < interval && p.done != p.total {
+		return
+	}
+	p.last = now
+	if p.tty {
+		fmt.Fprintf(p.w, "\r\033[K%s", p.String())
+		p.drawn = true
+	} else {
+		fmt.Fprintln(p.w, p.String())
+	}
+}
+
+// clear erases the line on a terminal, before something else is printed;
+// the next add draws it again.
+func (p *progressLine) clear() {
+	if p.drawn {
+		fmt.Fprint(p.w, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// String formats the progress, e.g. "Progress: 120/5000 (2%), 8.3/s, ETA
+// 9m48s, worst grade F: 12".
+func (p *progressLine) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Progress: %d", p.done)
+	if p.total > 0 {
+		fmt.Fprintf(&b, "/%d (%d%%)", p.total, 100*p.done/p.total)
+	}
+	var rate float64
+	if elapsed := p.clk.Now().Sub(p.start).Seconds(); elapsed > 0 {
+		rate = max(float64(p.done-p.reused)/elapsed, 0)
+	}
+	fmt.Fprintf(&b, ", %.1f/s", rate)
+	if remaining := p.total - p.done; remaining > 0 {
+		if rate > 0 {
+			fmt.Fprintf(&b, ", ETA %s", time.Duration(float64(remaining)/rate*float64(time.Second)).Round(time.Second))
+		} else {
+			b.WriteString(", ETA unknown")
+		}
+	}
+	if p.worst != "" {
+		fmt.Fprintf(&b, ", worst grade %s: %d", p.worst, p.worstCount)
+	}
+	if p.errors > 0 {
+		fmt.Fprintf(&b, ", %d error(s)", p.errors)
+	}
+	return b.String()
+}
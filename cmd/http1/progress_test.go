@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+func TestProgressString(t *testing.T) {
+	tests := []struct {
+		name                string
+		total, reused, done int
+		elapsed             time.Duration
+		want                string
+	}{
+		{"rate and ETA", 5000, 0, 120, 10 * time.Second, "Progress: 120/5000 (2%), 12.0/s, ETA 6m47s"},
+		{"reused results do not count toward the rate", 10, 4, 6, time.Second, "Progress: 6/10 (60%), 2.0/s, ETA 2s"},
+		{"only reused results", 10, 4, 4, time.Second, "Progress: 4/10 (40%), 0.0/s, ETA unknown"},
+		{"just started", 10, 0, 0, 0, "Progress: 0/10 (0%), 0.0/s, ETA unknown"},
+		{"done", 10, 0, 10, 4 * time.Second, "Progress: 10/10 (100%), 2.5/s"},
+		{"unknown total", 0, 0, 3, 2 * time.Second, "Progress: 3, 1.5/s"},
+	}
+	for _, tt := range tests {
+		clk := newFakeClock(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC))
+		p := newProgress(&strings.Builder{}, false, clk, tt.total, tt.reused)
+		p.done = tt.done
+		clk.Advance(tt.elapsed)
+		if got := p.String(); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProgressWorstGrade(t *testing.T) {
+	p := newProgress(&strings.Builder{}, false, newFakeClock(time.Now()), 0, 0)
+	for _, grade := range []string{"A", "B", "A", "B", "", "C", "C", "B"} {
+		p.add(http1.CheckResult{Grade: grade})
+	}
+	if p.worst != "C" || p.worstCount != 2 || p.errors != 1 {
+		t.Errorf("worst %q: %d, errors %d; want C: 2, 1 error", p.worst, p.worstCount, p.errors)
+	}
+	if s := p.String(); !strings.HasSuffix(s, ", worst grade C: 2, 1 error(s)") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestProgressOutput(t *testing.T) {
+	start := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	res := http1.CheckResult{Grade: "A"}
+
+	t.Run("terminal", func(t *testing.T) {
+		var out strings.Builder
+		clk := newFakeClock(start)
+		p := newProgress(&out, true, clk, 3, 0)
+		p.add(res)
+		if out.Len() != 0 {
+			t.Errorf("redrawn before %v: %q", progressRedraw, out.String())
+		}
+		clk.Advance(progressRedraw)
+		p.add(res)
+		want := "\r\033[KProgress: 2/3 (66%), 10.0/s, ETA 0s, worst grade A: 2"
+		if out.String() != want {
+			t.Errorf("drew %q, want %q", out.String(), want)
+		}
+		out.Reset()
+		p.clear()
+		p.clear()
+		if out.String() != "\r\033[K" {
+			t.Errorf("clear wrote %q, want one erase", out.String())
+		}
+		// The last result is always shown.
+		out.Reset()
+		p.add(res)
+		if !strings.HasPrefix(out.String(), "\r\033[KProgress: 3/3 (100%)") {
+			t.Errorf("final draw %q", out.String())
+		}
+	})
+
+	t.Run("log", func(t *testing.T) {
+		var out strings.Builder
+		clk := newFakeClock(start)
+		p := newProgress(&out, false, clk, 100, 0)
+		clk.Advance(progressRedraw)
+		p.add(res)
+		if out.Len() != 0 {
+			t.Errorf("logged before %v: %q", progressLogInterval, out.String())
+		}
+		clk.Advance(progressLogInterval)
+		p.add(res)
+		p.clear()
+		lines := strings.Split(out.String(), "\n")
+		if len(lines) != 2 || lines[1] != "" || !strings.HasPrefix(lines[0], "Progress: 2/100 (2%), 0.2/s, ETA ") {
+			t.Errorf("logged %q, want one plain line", out.String())
+		}
+	})
+}
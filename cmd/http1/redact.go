@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+
+	"http1.dev/internal/http1"
+)
+
+// redactKeyEnv names the environment variable holding the --redact secret,
+// so it does not have to appear in shell history or process listings.
+const redactKeyEnv = "HTTP1_REDACT_KEY"
+
+// newRedactor builds the --redact Redactor. Without a configured key a
+// random one is used: tokens are then consistent within the run but cannot
+// be matched against other exports.
+func newRedactor(key string) (*http1.Redactor, error) {
+	if key == "" {
+		key = os.Getenv(redactKeyEnv)
+	}
+	if key != "" {
+		return http1.NewRedactor([]byte(key)), nil
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating redaction key: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "note: no --redact-key or $%s set; using a random key, tokens will differ between runs\n", redactKeyEnv)
+	return http1.NewRedactor(secret), nil
+}
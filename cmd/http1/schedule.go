@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// scanSlots is how many targets the web server checks at once across the UI
+// and all batch jobs.
+const scanSlots = 64
+
+// scanPriority orders the work competing for the server's scan slots.
+type scanPriority int
+
+const (
+	// priorityInteractive is a scan someone is waiting on in the UI.
+	priorityInteractive scanPriority = iota
+	// priorityBatch is an API batch job.
+	priorityBatch
+	// priorityBackground is a batch job submitted as a background rescan.
+	priorityBackground
+	numPriorities
+)
+
+func (p scanPriority) String() string {
+	switch p {
+	case priorityInteractive:
+		return "interactive"
+	case priorityBatch:
+		return "batch"
+	}
+	return "background"
+}
+
+// parseJobPriority maps a job's "priority" field to a scanPriority. Jobs
+// cannot claim the interactive class.
+func parseJobPriority(s string) (scanPriority, error) {
+	switch s {
+	case "", "batch":
+		return priorityBatch, nil
+	case "background":
+		return priorityBackground, nil
+	}
+	return 0, fmt.Errorf("unknown priority %q (want batch or background)", s)
+}
+
+// scheduler hands out scan slots by priority, one per target. Waiting work
+// of a higher class always goes first, and batch and background work leave
+// room for one full interactive scan so the UI never queues behind a large
+// batch. Background work is preempted by anything else: its checks already
+// in flight do not count against interactive scans, and it takes no new
+// slot while higher-priority work is waiting.
+type scheduler struct {
+	slots int
+
+	mu      sync.Mutex
+	freed   *sync.Cond
+	running [numPriorities]int
+	waiting [numPriorities]int
+}
+
+func newScheduler(slots int) *scheduler {
+	s := &scheduler{slots: slots}
+	s.freed = sync.NewCond(&s.mu)
+	return s
+}
+
+// acquire blocks until n slots are granted to work of priority p.
+func (s *scheduler) acquire(p scanPriority, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiting[p]++
+	for !s.admits(p, n) {
+		s.freed.Wait()
+	}
+	s.waiting[p]--
+	s.running[p] += n
+	// Lower classes waiting on this class may now be admissible.
+	s.freed.Broadcast()
+}
+
+// release returns n slots held by work of priority p.
+func (s *scheduler) release(p scanPriority, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[p] -= n
+	s.freed.Broadcast()
+}
+
+func (s *scheduler) admits(p scanPriority, n int) bool {
+	for q := priorityInteractive; q < p; q++ {
+		if s.waiting[q] > 0 {
+			return false
+		}
+	}
+	busy := 0
+	for _, r := range s.running {
+		busy += r
+	}
+	if p == priorityInteractive {
+		busy -= s.running[priorityBackground]
+		return busy+n <= s.slots
+	}
+	return busy+n <= s.slots-maxWebTargets
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// selftestCheck is one expectation about a scan of the loopback test servers.
+type selftestCheck struct {
+	name string
+	ok   func(res http1.CheckResult) bool
+	// h3 marks checks that need HTTP/3, skipped in builds without it.
+	h3 bool
+}
+
+var selftestChecks = []selftestCheck{
+	{"HTTP/1.1 supported", func(res http1.CheckResult) bool { return versionSupported(res, "HTTP/1.1") }, false},
+	{"HTTP/2.0 supported", func(res http1.CheckResult) bool { return versionSupported(res, "HTTP/2.0") }, false},
+	{"HTTP/3.0 supported", func(res http1.CheckResult) bool { return versionSupported(res, "HTTP/3.0") }, true},
+	{"TLS 1.3 negotiated", func(res http1.CheckResult) bool { return res.TLSVersion == "TLS 1.3" }, false},
+	{"ALPN h2 negotiated", func(res http1.CheckResult) bool { return res.ALPN == "h2" }, false},
+	{"grade A", func(res http1.CheckResult) bool { return res.Grade == "A" }, true},
+	{"HTTP/2 SETTINGS captured", func(res http1.CheckResult) bool { return res.H2Settings != nil }, false},
+	{"QUIC versions enumerated", func(res http1.CheckResult) bool { return len(res.QUICVersions) > 0 }, true},
+	{"TLS session resumed", func(res http1.CheckResult) bool { return res.EarlyData != nil && res.EarlyData.TLSResumption }, false},
+}
+
+func versionSupported(res http1.CheckResult, version string) bool {
+	for _, vr := range res.Results {
+		if vr.Version == version {
+			return vr.Supported
+		}
+	}
+	return false
+}
+
+// runSelftest implements the hidden "selftest" subcommand: it starts the
+// in-package HTTP/1.1, HTTP/2 and HTTP/3 test servers on loopback and runs the
+// full multi-target scan pipeline against them. With -n it repeats the scan
+// as a soak test. It returns the process exit code.
+func runSelftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	rounds := fs.Int("n", 1, "number of scan rounds to run (soak testing)")
+	lowResource := fs.Bool("low-resource", false, "scan with the low-resource profile")
+	_ = fs.Parse(args)
+
+	log.SetOutput(io.Discard)
+
+	srv, err := http1.StartTestServers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "selftest: %v\n", err)
+		return 1
+	}
+	defer srv.Close()
+
+	// Scan the same servers by IP and by name so both the IP-literal and the
+	// resolver paths are exercised.
+	targets := []string{
+		"https://127.0.0.1:" + srv.Port,
+		"https://localhost:" + srv.Port,
+	}
+
+	start := time.Now()
+	failures := 0
+	for round := 1; round <= *rounds; round++ {
+		http1.CheckHTTPVersionsEach(targets, http1.Options{Port: srv.Port, LowResource: *lowResource, CheckQUICVersions: true, CheckEarlyData: true, CheckH2Settings: true}, func(res http1.CheckResult) {
+			for _, c := range selftestChecks {
+				if c.h3 && !http1.HTTP3Available() {
+					continue
+				}
+				if c.ok(res) {
+					if *rounds == 1 {
+						fmt.Printf("✅ %s: %s\n", res.Target, c.name)
+					}
+					continue
+				}
+				failures++
+				fmt.Printf("❌ %s: %s (round %d)\n", res.Target, c.name, round)
+			}
+		})
+	}
+
+	fmt.Println()
+	fmt.Printf("Selftest ran %d round(s) against %d target(s) in %s: %d failure(s)\n",
+		*rounds, len(targets), time.Since(start).Truncate(time.Millisecond), failures)
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// sessionVersion is the version of the --record file layout.
+const sessionVersion = 1
+
+// sessionHeader is the first line of a --record file. Each following line
+// is one CheckResult as the scan produced it, before --where, translation
+// or output formatting, so replay can render them again.
+type sessionHeader struct {
+	Session  int       `json:"httpver_session"`
+	Recorded time.Time `json:"recorded"`
+	// Tool and Platform identify the build that made the recording.
+	Tool     string `json:"tool"`
+	Platform string `json:"platform"`
+	// Args is the command line, which holds every option given.
+	Args []string `json:"args"`
+	// Targets are the targets as read from arguments and files, in input
+	// order; streaming scans do not collect them.
+	Targets []string `json:"targets,omitempty"`
+	// Format, Fields, Where and Lang are the rendering options replay uses
+	// unless told otherwise.
+	Format string `json:"format,omitempty"`
+	Fields string `json:"fields,omitempty"`
+	Where  string `json:"where,omitempty"`
+	Lang   string `json:"lang,omitempty"`
+}
+
+// sessionRecorder writes a --record file as results arrive, so a scan that
+// is interrupted still leaves the results it got.
+type sessionRecorder struct {
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newSessionRecorder(path string, h sessionHeader) (*sessionRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	r := &sessionRecorder{f: f, w: w, enc: json.NewEncoder(w)}
+	h.Session = sessionVersion
+	h.Tool = http1.DefaultUserAgent()
+	h.Platform = runtime.GOOS + "/" + runtime.GOARCH + " " + runtime.Version()
+	if err := r.enc.Encode(h); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *sessionRecorder) Record(res http1.CheckResult) error {
+	return r.enc.Encode(res)
+}
+
+func (r *sessionRecorder) Close() error {
+	err := r.w.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readSession reads a --record file.
+func readSession(in io.Reader) (sessionHeader, []http1.CheckResult, error) {
+	dec := json.NewDecoder(in)
+	var h sessionHeader
+	if err := dec.Decode(&h); err != nil {
+		return h, nil, fmt.Errorf("not a session recording: %v", err)
+	}
+	if h.Session == 0 {
+		return h, nil, errors.New("not a session recording")
+	}
+	if h.Session > sessionVersion {
+		return h, nil, fmt.Errorf("recorded by a newer http1 (session version %d)", h.Session)
+	}
+	var results []http1.CheckResult
+	for n := 1; ; n++ {
+		var res http1.CheckResult
+		if err := dec.Decode(&res); err != nil {
+			if errors.Is(err, io.EOF) {
+				return h, results, nil
+			}
+			return h, results, fmt.Errorf("result %d: %v", n, err)
+		}
+		results = append(results, res)
+	}
+}
+
+// runReplay implements the "replay" subcommand: it renders the results of a
+// --record file again, regraded with this build's grading, without touching
+// the network. It returns the process exit code.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	format := fs.String("format", "", "output format: text, plain, json, ndjson, csv or zgrab (default: the recorded one)")
+	fields := fs.String("fields", "", "comma-separated fields to project JSON/CSV output to (default: the recorded ones)")
+	whereFlag := fs.String("where", "", "only output results matching this expression (default: the recorded one)")
+	lang := fs.String("lang", "", "language for human-readable output (default: the recorded one)")
+	noColor := fs.Bool("no-color", false, "never color the terminal output (also set by a non-empty NO_COLOR)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: http1 replay [--format F] [--fields LIST] [--where EXPR] [--lang L] session.httpver")
+		fmt.Fprintln(fs.Output(), "Renders a scan recorded with --record again, without network access.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	h, results, err := readSession(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, o := range []struct {
+		flag     string
+		value    *string
+		recorded string
+	}{
+		{"format", format, h.Format},
+		{"fields", fields, h.Fields},
+		{"where", whereFlag, h.Where},
+		{"lang", lang, h.Lang},
+	} {
+		if !set[o.flag] {
+			*o.value = o.recorded
+		}
+	}
+
+	var where *http1.Where
+	if *whereFlag != "" {
+		if where, err = http1.ParseWhere(*whereFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "replay: invalid --where expression: %v\n", err)
+			return 1
+		}
+	}
+	translator, err := http1.NewTranslator(*lang)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+	out, err := newResultWriter(*format, os.Stdout, h.Targets, http1.ParseFields(*fields), translator, detectTextStyle(os.Stdout, *noColor))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Replaying %d result(s) recorded %s by %s on %s\n", len(results), h.Recorded.Format(time.RFC3339), h.Tool, h.Platform)
+	for _, res := range results {
+		regraded := http1.Regrade(res)
+		if regraded.Grade != res.Grade || regraded.Score != res.Score {
+			fmt.Fprintf(os.Stderr, "  %s: recorded grade %s (%d), now %s (%d)\n", res.Target, res.Grade, res.Score, regraded.Grade, regraded.Score)
+		}
+		if where != nil && !where.Match(regraded) {
+			continue
+		}
+		if err := out.Write(translator.Result(regraded)); err != nil {
+			fmt.Fprintf(os.Stderr, "replay: failed to write results: %v\n", err)
+			return 1
+		}
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "replay: failed to write results: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// splitReusable splits target entries into those that need scanning and
+// the earlier results reused for the rest, labelled as the entries are now.
+// lookup returns the result to reuse for a target, and false when there is
+// none or it must be scanned again; entries that do not parse are scanned.
+func splitReusable(targets []string, lookup func(raw string) (http1.CheckResult, bool)) (rescan []string, reused []http1.CheckResult) {
+	for _, entry := range targets {
+		t, err := http1.ParseTarget(entry)
+		if err != nil {
+			rescan = append(rescan, entry)
+			continue
+		}
+		res, ok := lookup(t.Raw)
+		if !ok {
+			rescan = append(rescan, entry)
+			continue
+		}
+		res.Labels = t.Labels
+		reused = append(reused, res)
+	}
+	return rescan, reused
+}
+
+// splitStale implements --stale-only: it rescans targets without a
+// baseline result and those whose result http1.NeedsRescan rejects.
+func splitStale(targets []string, baseline map[string]http1.CheckResult, maxAge time.Duration, now time.Time) (rescan []string, reused []http1.CheckResult) {
+	return splitReusable(targets, func(raw string) (http1.CheckResult, bool) {
+		res, ok := baseline[raw]
+		return res, ok && !http1.NeedsRescan(res, maxAge, now)
+	})
+}
+
+// splitErrored implements --retry-errors: it rescans targets without a
+// previous result and those whose result http1.Errored.
+func splitErrored(targets []string, previous []http1.CheckResult) (rescan []string, reused []http1.CheckResult) {
+	byTarget := make(map[string]http1.CheckResult, len(previous))
+	for _, res := range previous {
+		byTarget[res.Target] = res
+	}
+	return splitReusable(targets, func(raw string) (http1.CheckResult, bool) {
+		res, ok := byTarget[raw]
+		return res, ok && !http1.Errored(res)
+	})
+}
+
+// previousTargets lists the target entries of previous results, labels
+// included, for --retry-errors without targets of its own.
+func previousTargets(previous []http1.CheckResult) []string {
+	entries := make([]string, 0, len(previous))
+	for _, res := range previous {
+		entries = append(entries, http1.Target{Raw: res.Target, Labels: res.Labels}.String())
+	}
+	return entries
+}
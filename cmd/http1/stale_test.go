@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+func TestSplitReusable(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	fresh := http1.CheckResult{Target: "fresh.example", Grade: "A", ScannedAt: now.Add(-time.Hour), Labels: []string{"old"}}
+	old := http1.CheckResult{Target: "old.example", Grade: "A", ScannedAt: now.Add(-48 * time.Hour)}
+	errored := http1.CheckResult{Target: "errored.example", Grade: "F", Results: []http1.VersionResult{{Version: "HTTP/2.0", Error: true}}}
+	targets := []string{"fresh.example prod", "old.example", "errored.example", "new.example", "::bad::"}
+
+	for _, tt := range []struct {
+		name       string
+		split      func() ([]string, []http1.CheckResult)
+		wantRescan string
+		wantReused string
+	}{
+		{"stale", func() ([]string, []http1.CheckResult) {
+			baseline := map[string]http1.CheckResult{fresh.Target: fresh, old.Target: old}
+			return splitStale(targets, baseline, 24*time.Hour, now)
+		}, "old.example,errored.example,new.example,::bad::", "fresh.example"},
+		{"errored", func() ([]string, []http1.CheckResult) {
+			return splitErrored(targets, []http1.CheckResult{fresh, old, errored})
+		}, "errored.example,new.example,::bad::", "fresh.example,old.example"},
+		{"resumed", func() ([]string, []http1.CheckResult) {
+			return splitResumed(targets, map[string]http1.CheckResult{errored.Target: errored})
+		}, "fresh.example prod,old.example,new.example,::bad::", "errored.example"},
+	} {
+		rescan, reused := tt.split()
+		var names []string
+		for _, res := range reused {
+			names = append(names, res.Target)
+		}
+		if got := strings.Join(rescan, ","); got != tt.wantRescan {
+			t.Errorf("%s: rescan %s, want %s", tt.name, got, tt.wantRescan)
+		}
+		if got := strings.Join(names, ","); got != tt.wantReused {
+			t.Errorf("%s: reused %s, want %s", tt.name, got, tt.wantReused)
+		}
+		for _, res := range reused {
+			if res.Target == fresh.Target && strings.Join(res.Labels, ",") != "prod" {
+				t.Errorf("%s: labels %v, want the entry's labels", tt.name, res.Labels)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+
+	"http1.dev/internal/http1"
+)
+
+// streamTargets feeds targets from the targets file, the --targets flag and
+// positional args into a channel as they are read, in the same order and with
+// the same parsing as gatherTargets, but without ever holding the whole list.
+// Duplicates are dropped using 64-bit hashes of their keys rather than the
+// keys themselves, so a duplicate's labels are lost. The returned wait
+// function blocks until the channel is closed and reports any read error.
+func streamTargets(targetsFlag, targetsFile string, positional []string) (<-chan string, func() error, error) {
+	var f *os.File
+	if targetsFile != "" {
+		var err error
+		f, err = os.Open(targetsFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read targets file: %w", err)
+		}
+	}
+
+	out := make(chan string)
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		seen := make(map[uint64]struct{})
+		var emit func(entry string)
+		emit = func(entry string) {
+			// Invalid entries go through as typed, so each shows up as an
+			// error result instead of ending the stream.
+			key, send := entry, entry
+			if t, err := http1.ParseTarget(entry); err == nil {
+				if t.Range.IsValid() {
+					// countTargets has held the ranges to --max-hosts.
+					expanded, _ := http1.ExpandRanges([]http1.Target{t}, http1.MaxHosts)
+					for _, a := range expanded {
+						emit(a.String())
+					}
+					return
+				}
+				key, send = t.Key(), t.String()
+			}
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(key))
+			sum := h.Sum64()
+			if _, ok := seen[sum]; ok {
+				return
+			}
+			seen[sum] = struct{}{}
+			out <- send
+		}
+
+		var readErr error
+		if f != nil {
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				line := strings.TrimSpace(sc.Text())
+				if strings.HasPrefix(line, "#") {
+					continue
+				}
+				for _, entry := range strings.Split(line, ",") {
+					if entry = strings.TrimSpace(entry); entry != "" {
+						emit(entry)
+					}
+				}
+			}
+			if err := sc.Err(); err != nil {
+				readErr = fmt.Errorf("failed to read targets file: %w", err)
+			}
+			_ = f.Close()
+		}
+
+		for _, part := range strings.Split(targetsFlag, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				emit(part)
+			}
+		}
+		for _, t := range positional {
+			emit(t)
+		}
+		done <- readErr
+	}()
+
+	return out, func() error { return <-done }, nil
+}
+
+// countTargets counts the targets streamTargets would emit, duplicates
+// included, reading the targets file once without keeping it. Address ranges
+// count as their addresses and fail the count, with http1.ErrTooManyHosts,
+// when they hold more than maxHosts together.
+func countTargets(targetsFlag, targetsFile string, positional []string, maxHosts int) (int, error) {
+	n, inRanges := 0, 0
+	limit := min(maxHosts, http1.MaxHosts)
+	count := func(entry string) error {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			return nil
+		}
+		t, err := http1.ParseTarget(entry)
+		if err != nil || !t.Range.IsValid() {
+			n++
+			return nil
+		}
+		n += t.HostCount()
+		inRanges += t.HostCount()
+		if inRanges > limit {
+			return fmt.Errorf("%s: %w: the address ranges hold more than %d", t.Raw, http1.ErrTooManyHosts, limit)
+		}
+		return nil
+	}
+	for _, arg := range positional {
+		if err := count(arg); err != nil {
+			return 0, err
+		}
+	}
+	for _, part := range strings.Split(targetsFlag, ",") {
+		if err := count(part); err != nil {
+			return 0, err
+		}
+	}
+	if targetsFile == "" {
+		return n, nil
+	}
+	f, err := os.Open(targetsFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read targets file: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, entry := range strings.Split(line, ",") {
+			if err := count(entry); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read targets file: %w", err)
+	}
+	return n, nil
+}
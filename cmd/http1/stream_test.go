@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"http1.dev/internal/http1"
+)
+
+// writeTargets writes lines to a targets file in a temporary directory.
+func writeTargets(t *testing.T, lines []string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStreamTargets(t *testing.T) {
+	// A large file mixing blank lines, comments, labels, comma-separated
+	// entries and duplicates, which differ only in case or labels.
+	const hosts = 20000
+	var lines, want []string
+	for i := range hosts {
+		host := fmt.Sprintf("host%d.example", i)
+		switch i % 4 {
+		case 0:
+			lines = append(lines, "", "# "+host)
+			lines = append(lines, "  "+host+" prod eu  ")
+			want = append(want, host+" prod eu")
+		case 1:
+			lines = append(lines, host+", "+strings.ToUpper(host)+" dup")
+			want = append(want, host)
+		case 2:
+			lines = append(lines, "\t"+host, "#"+host+" comment")
+			want = append(want, host)
+		case 3:
+			lines = append(lines, host+" a", host+" b")
+			want = append(want, host+" a")
+		}
+	}
+	lines = append(lines, "::bad::", "192.0.2.0/30 lab")
+	want = append(want, "::bad::")
+	for _, a := range []string{"192.0.2.1", "192.0.2.2"} {
+		want = append(want, a+" 192.0.2.0/30 lab")
+	}
+	want = append(want, "flag.example", "arg.example")
+	file := writeTargets(t, lines)
+
+	feed, wait, err := streamTargets(" flag.example, host0.example,", file, []string{"arg.example", "host1.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for entry := range feed {
+		got = append(got, entry)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("streamed %d targets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("target %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// countTargets counts every entry, duplicates and invalid ones
+	// included, and ranges as their host addresses: six entries per four
+	// hosts in the file, then the invalid one, the range and the four from
+	// the flag and args.
+	n, err := countTargets(" flag.example, host0.example,", file, []string{"arg.example", "host1.example"}, http1.MaxHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantN := hosts/4*6 + 1 + 2 + 4; n != wantN {
+		t.Errorf("countTargets = %d, want %d", n, wantN)
+	}
+}
+
+func TestCountTargets(t *testing.T) {
+	file := writeTargets(t, []string{"# ranges", "", "198.51.100.0/30", "a.example, 203.0.113.0/30"})
+	for _, tt := range []struct {
+		name       string
+		flag, file string
+		args       []string
+		maxHosts   int
+		want       int
+		wantErr    error
+	}{
+		{"none", "", "", nil, 10, 0, nil},
+		{"flag and args", "a.example,,b.example", "", []string{"c.example", " "}, 10, 3, nil},
+		{"invalid entries count once", "::bad::", "", []string{"http://"}, 10, 2, nil},
+		{"ranges", "", file, nil, 4, 5, nil},
+		{"ranges over max-hosts", "", file, nil, 3, 0, http1.ErrTooManyHosts},
+		{"max-hosts capped", "10.0.0.0/8", "", nil, 1 << 30, 0, http1.ErrTooManyHosts},
+		{"missing file", "", filepath.Join(t.TempDir(), "missing"), nil, 10, 0, os.ErrNotExist},
+	} {
+		n, err := countTargets(tt.flag, tt.file, tt.args, tt.maxHosts)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || n != tt.want {
+			t.Errorf("%s: countTargets = %d, %v, want %d", tt.name, n, err, tt.want)
+		}
+	}
+}
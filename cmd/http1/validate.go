@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"http1.dev/internal/http1"
+)
+
+// runValidate implements the "validate" subcommand: it checks result files,
+// written with --json or --format ndjson, against the results schema of this
+// build or, with --schema, of another version, so consumers can tell whether
+// output is compatible. It returns exitPolicyFailed when a file does not
+// conform.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	schemaFile := fs.String("schema", "", "validate against this schema file instead of the built-in one")
+	printSchema := fs.Bool("print-schema", false, "print the built-in schema and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: http1 validate [--schema FILE] results.json ...")
+		fmt.Fprintln(fs.Output(), "       http1 validate --print-schema")
+		fmt.Fprintln(fs.Output(), "Checks result files against the JSON Schema of the output format; reads stdin when no file is given.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *printSchema {
+		_, _ = os.Stdout.Write(http1.ResultsSchema())
+		return 0
+	}
+	schema := http1.ResultsSchema()
+	if *schemaFile != "" {
+		var err error
+		if schema, err = os.ReadFile(*schemaFile); err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+			return 1
+		}
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	invalid := 0
+	for _, name := range inputs {
+		var data []byte
+		var err error
+		if name == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+			return 1
+		}
+		problems, err := http1.ValidateResults(schema, data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %s: %v\n", name, err)
+			return 1
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%s: valid\n", name)
+			continue
+		}
+		invalid++
+		fmt.Printf("%s: %d problem(s)\n", name, len(problems))
+		for _, p := range problems {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	if invalid > 0 {
+		return exitPolicyFailed
+	}
+	return 0
+}
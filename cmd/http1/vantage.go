@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// configEnv names the environment variable that overrides the config file
+// location.
+const configEnv = "HTTP1_CONFIG"
+
+// fileConfig is the http1 config file, JSON such as:
+//
+//	{"vantage_profiles": {
+//	  "office":   {"proxy": "socks5://10.0.0.5:1080"},
+//	  "prod-vpc": {"interface": "wg0", "doh": "https://10.1.0.2/dns-query"}
+//	}}
+type fileConfig struct {
+	VantageProfiles map[string]vantageProfile `json:"vantage_profiles"`
+}
+
+// vantageProfile is a named set of egress settings. A tunnel such as
+// WireGuard is selected by its interface or source address; the tunnel
+// itself is set up outside http1.
+type vantageProfile struct {
+	// Proxy is an http://, https:// or socks5:// proxy URL.
+	Proxy string `json:"proxy,omitempty"`
+	// PAC is a proxy auto-config file or URL, used instead of Proxy.
+	PAC       string `json:"pac,omitempty"`
+	SourceIP  string `json:"source_ip,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	DoH       string `json:"doh,omitempty"`
+	// Vantage is the label recorded on results; it defaults to the
+	// profile's name.
+	Vantage string `json:"vantage,omitempty"`
+}
+
+// configPath returns the config file to read: path if set, else
+// $HTTP1_CONFIG, else http1/config.json in the user config directory.
+func configPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv(configEnv); env != "" {
+		return env, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "http1", "config.json"), nil
+}
+
+func loadVantageProfile(path, name string) (vantageProfile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return vantageProfile{}, fmt.Errorf("no config file at %s for --vantage-profile (set --config or $%s)", path, configEnv)
+	} else if err != nil {
+		return vantageProfile{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg fileConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return vantageProfile{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	p, ok := cfg.VantageProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.VantageProfiles))
+		for n := range cfg.VantageProfiles {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return vantageProfile{}, fmt.Errorf("no vantage profile %q in %s (have: %s)", name, path, strings.Join(names, ", "))
+	}
+	if p.Vantage == "" {
+		p.Vantage = name
+	}
+	return p, nil
+}
+
+// apply sets the flags the profile covers, except those given explicitly
+// on the command line, so "--vantage-profile office --proxy ..." still
+// overrides the office proxy. --proxy and --pac override each other, since
+// they cannot be combined. The flags' usual validation then applies.
+func (p vantageProfile) apply(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if explicit["proxy"] || explicit["pac"] {
+		explicit["proxy"], explicit["pac"] = true, true
+	}
+	for _, kv := range []struct{ flag, value string }{
+		{"proxy", p.Proxy},
+		{"pac", p.PAC},
+		{"source-ip", p.SourceIP},
+		{"interface", p.Interface},
+		{"doh", p.DoH},
+		{"vantage", p.Vantage},
+	} {
+		if kv.value == "" || explicit[kv.flag] {
+			continue
+		}
+		if err := fs.Set(kv.flag, kv.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"http1.dev/internal/http1"
+)
+
+// printProbeLog writes res's per-probe timing, detail and evidence, and the
+// reasons for its grade, for -v.
+// At level 2 (-vv) it adds each probe's phase timings, the DNS lookup, the
+// negotiated TLS version and ALPN, warnings and probe errors. At level 0
+// (the default, and -q) it writes nothing.
+func printProbeLog(w io.Writer, res http1.CheckResult, level int) {
+	if level < 1 {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", res.Target)
+	if d := res.DNS; level > 1 && d != nil {
+		line := fmt.Sprintf("%dms via %s", d.DurationMS, d.Resolver)
+		if d.Cached {
+			line += " (cached)"
+		}
+		if d.Error != "" {
+			line += ": " + d.Error
+		} else {
+			line += ": " + strings.Join(d.Addresses, ", ")
+		}
+		fmt.Fprintf(&b, "  %-9s %s\n", "DNS", line)
+	}
+	for _, vr := range res.Results {
+		took := "-"
+		if !vr.NotTested {
+			took = fmt.Sprintf("%dms", vr.DurationMS)
+		}
+		line := fmt.Sprintf("  %-9s %7s  %s", vr.Version, took, vr.Detail)
+		if vr.Evidence != "" {
+			line += " [" + vr.Evidence + "]"
+		}
+		if tm := vr.Timings; level > 1 && tm != nil {
+			line += fmt.Sprintf(" (dns %dms, connect %dms, tls %dms, ttfb %dms)", tm.DNSMS, tm.ConnectMS, tm.TLSMS, tm.TTFBMS)
+		}
+		fmt.Fprintln(&b, line)
+	}
+	if len(res.GradeReasons) > 0 {
+		reasons := make([]string, len(res.GradeReasons))
+		for i, r := range res.GradeReasons {
+			reasons[i] = r.String()
+		}
+		fmt.Fprintf(&b, "  %-9s %s (%d): %s\n", "grade", res.Grade, res.Score, strings.Join(reasons, "; "))
+	}
+	if level > 1 {
+		if res.TLSVersion != "" {
+			fmt.Fprintf(&b, "  %-9s %s, ALPN %q\n", "TLS", res.TLSVersion, res.ALPN)
+		}
+		for _, warn := range res.Warnings {
+			fmt.Fprintf(&b, "  %-9s %s: %s\n", "warning", warn.Code, warn.Message)
+		}
+		for _, pe := range res.ProbeErrors {
+			fmt.Fprintf(&b, "  %-9s %s: %s\n", "error", pe.Probe, pe.Detail)
+		}
+	}
+	_, _ = io.WriteString(w, b.String())
+}
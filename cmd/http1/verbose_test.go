@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"http1.dev/internal/http1"
+)
+
+func TestPrintProbeLog(t *testing.T) {
+	res := http1.CheckResult{
+		Target: "example.com",
+		DNS:    &http1.DNSTiming{DurationMS: 3, Resolver: "192.0.2.53:53", Addresses: []string{"192.0.2.1"}},
+		Results: []http1.VersionResult{
+			{Version: "HTTP/1.1", Supported: true, DurationMS: 12, Detail: "200 OK", Timings: &http1.ProbeTimings{DNSMS: 3, ConnectMS: 4, TLSMS: 5, TTFBMS: 12}},
+			{Version: "HTTP/3.0", NotTested: true, Detail: "not tested", Evidence: "no alt-svc"},
+		},
+		Grade:        "B",
+		Score:        80,
+		GradeReasons: []http1.GradeReason{{Detail: "no HTTP/3"}},
+		TLSVersion:   "TLS 1.3",
+		ALPN:         "h2",
+		Warnings:     []http1.Warning{{Code: "cert_expiring", Message: "expires in 3 days"}},
+		ProbeErrors:  []http1.ProbeError{{Probe: "ech", Detail: "panic: boom"}},
+	}
+	// Lines only -vv prints.
+	extra := []string{
+		"DNS       3ms via 192.0.2.53:53: 192.0.2.1",
+		"(dns 3ms, connect 4ms, tls 5ms, ttfb 12ms)",
+		`TLS       TLS 1.3, ALPN "h2"`,
+		"warning   cert_expiring: expires in 3 days",
+		"error     ech: panic: boom",
+	}
+	base := []string{
+		"example.com\n",
+		"  HTTP/1.1     12ms  200 OK",
+		"  HTTP/3.0        -  not tested [no alt-svc]\n",
+		"  grade     B (80): no HTTP/3\n",
+	}
+
+	for _, tt := range []struct {
+		name        string
+		level       int
+		want, avoid []string
+	}{
+		{"-q", 0, nil, nil},
+		{"-v", 1, base, extra},
+		{"-vv", 2, append(base, extra...), nil},
+	} {
+		var buf bytes.Buffer
+		printProbeLog(&buf, res, tt.level)
+		out := buf.String()
+		if tt.level == 0 && out != "" {
+			t.Errorf("%s: wrote %q, want nothing", tt.name, out)
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("%s: output lacks %q:\n%s", tt.name, s, out)
+			}
+		}
+		for _, s := range tt.avoid {
+			if strings.Contains(out, s) {
+				t.Errorf("%s: output has %q:\n%s", tt.name, s, out)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"embed"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -31,16 +33,22 @@ type resultCache struct {
 	mu         sync.RWMutex
 	data       map[string]cacheEntry
 	recentKeys []string
+	clock      clock
+	// certs outlives the cached results, so a rescan after expiry can flag
+	// a changed certificate.
+	certs *http1.CertTracker
 }
 
-func newResultCache() *resultCache {
+func newResultCache(clk clock) *resultCache {
 	return &resultCache{
-		data: make(map[string]cacheEntry),
+		data:  make(map[string]cacheEntry),
+		clock: clk,
+		certs: http1.NewCertTracker(),
 	}
 }
 
 func (c *resultCache) get(key string) (results []http1.CheckResult, scannedAt time.Time, ok bool) {
-	now := time.Now()
+	now := c.clock.Now()
 
 	c.mu.RLock()
 	entry, found := c.data[key]
@@ -52,7 +60,7 @@ func (c *resultCache) get(key string) (results []http1.CheckResult, scannedAt ti
 }
 
 func (c *resultCache) set(key string, results []http1.CheckResult, includeInRecent bool) {
-	now := time.Now()
+	now := c.clock.Now()
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -103,7 +111,7 @@ func (c *resultCache) recentSnapshots(limit int) []recentSnapshot {
 		return nil
 	}
 
-	now := time.Now()
+	now := c.clock.Now()
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -136,10 +144,15 @@ func (c *resultCache) recentSnapshots(limit int) []recentSnapshot {
 
 var (
 	webTemplates = template.Must(template.New("index.html").Funcs(template.FuncMap{
+		// projections are the what-if upgrades shown under each result card.
+		"projections": http1.Projections,
 		"statusEmoji": func(v http1.VersionResult) string {
 			if v.Supported {
 				return "✅"
 			}
+			if v.NotTested {
+				return "➖"
+			}
 			if v.Error {
 				return "🟧"
 			}
@@ -241,6 +254,9 @@ var (
 			if v.Supported {
 				return "supported"
 			}
+			if v.NotTested {
+				return "not tested"
+			}
 			if v.Error {
 				return "error / probe failed"
 			}
@@ -267,6 +283,21 @@ var (
 			}
 			return false
 		},
+		// warningTitle labels a result warning's row.
+		"warningTitle": func(code string) string {
+			switch code {
+			case http1.WarningUDPBuffer:
+				return "Scanner UDP buffers"
+			case http1.WarningQUICCalibration:
+				return "QUIC calibration"
+			case http1.WarningH3Unproxied:
+				return "Proxy"
+			case http1.WarningRateLimited:
+				return "Rate limiting"
+			default:
+				return "Certificate"
+			}
+		},
 		"capFirst": func(s string) string {
 			if s == "" {
 				return s
@@ -278,31 +309,67 @@ var (
 			r = unicode.ToUpper(r)
 			return string(r) + s[size:]
 		},
-		"formatAge": func(t time.Time) string {
+		// deref unwraps optional numeric settings for comparisons.
+		"deref": func(p *uint32) uint32 {
+			if p == nil {
+				return 0
+			}
+			return *p
+		},
+		"formatAge": func(t, now time.Time) string {
 			if t.IsZero() {
 				return ""
 			}
-			return formatAge(time.Since(t))
+			return formatAge(now.Sub(t))
 		},
-	}).ParseFiles("cmd/http1/templates/index.html"))
+	}).ParseFS(templateFS, "templates/index.html"))
 )
 
+//go:embed templates/index.html
+var templateFS embed.FS
+
 type pageData struct {
 	TargetsRaw     string
 	HideFromRecent bool
-	Error          string
-	Results        []http1.CheckResult
-	HasResults     bool
-	UsedCache      bool
-	CacheAge       string
-	Recent         []recentSnapshot
-	Best           []recentSnapshot
-	Worst          []recentSnapshot
-	Page           string
+	// DualStack is set when the scan also probed each address family.
+	DualStack bool
+	Error     string
+	Results   []http1.CheckResult
+	// Groups is the grade distribution per label when targets have labels.
+	Groups     []http1.LabelGroup
+	HasResults bool
+	UsedCache  bool
+	CacheAge   string
+	Recent     []recentSnapshot
+	Best       []recentSnapshot
+	Worst      []recentSnapshot
+	Page       string
+	// Notes is set when notes can be added, to offer the note form, which
+	// carries CSRF.
+	Notes bool
+	CSRF  string
+	// Campaign is the campaign page's burn-down.
+	Campaign *campaignView
+	// Now is the page render time, used for relative ages.
+	Now time.Time
 }
 
-func runWebServer(listenAddr string) error {
-	cache := newResultCache()
+// runWebServer serves the web UI on listenAddr. With enablePprof set, the
+// net/http/pprof handlers are also served under /debug/pprof/. clk drives
+// cache expiry and the relative ages shown in the UI. vantage labels every
+// result this server produces. A non-nil self scan runs in the background
+// and is served through its middleware. A non-nil notes store annotates
+// results and serves the notes API, and a non-nil campaign is shown at
+// /campaign. The batch job API is served only with an apiToken, which its
+// callers must present; jobsDir, if set, keeps jobs and their results
+// across restarts.
+func runWebServer(listenAddr string, enablePprof bool, clk clock, vantage string, self *http1.SelfScan, notes *noteStore, camp *campaign, apiToken, jobsDir string) error {
+	cache := newResultCache(clk)
+	// For web mode we always use the default port behavior (no override).
+	// Dual-stack checks triple the probes, so a scan only runs them when it
+	// asks for them; see handleScan. The result cards show ECH and Extended
+	// CONNECT support, so those are always checked.
+	opts := http1.Options{Vantage: vantage, Proxy: http.ProxyFromEnvironment, CheckECH: true, CheckExtendedConnect: true, CheckKeyExchange: true, CheckQUICVersions: true, CheckEarlyData: true, CheckH2Settings: true}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -310,36 +377,81 @@ func runWebServer(listenAddr string) error {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok\n"))
 	})
+	// UI scans and batch jobs share the scan slots, with the UI first.
+	sched := newScheduler(scanSlots)
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		handleScan(w, r, cache)
+		handleScan(w, r, cache, sched, opts, notes)
 	})
 	mux.HandleFunc("/scan", func(w http.ResponseWriter, r *http.Request) {
-		handleScan(w, r, cache)
+		handleScan(w, r, cache, sched, opts, notes)
 	})
+	mux.HandleFunc("/api/v1/grade", handleGradeAPI)
+	mux.HandleFunc("/schema.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		_, _ = w.Write(http1.ResultsSchema())
+	})
+	if apiToken != "" {
+		jobs, err := newJobStore(opts, sched, clk, jobsDir)
+		if err != nil {
+			return fmt.Errorf("--jobs-dir: %w", err)
+		}
+		jobs.register(mux, apiToken)
+	}
+	if notes != nil {
+		notes.register(mux)
+	}
 	mux.HandleFunc("/problem", func(w http.ResponseWriter, r *http.Request) {
 		renderHTML(w, pageData{Page: "problem"})
 	})
 	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
 		renderHTML(w, pageData{Page: "about"})
 	})
+	if camp != nil {
+		mux.HandleFunc("/campaign", func(w http.ResponseWriter, r *http.Request) {
+			renderHTML(w, pageData{Page: "campaign", Campaign: camp.view()})
+		})
+	}
+	if enablePprof {
+		registerPprof(mux)
+	}
+
+	var handler http.Handler = mux
+	if self != nil {
+		go self.Run(context.Background())
+		handler = self.Middleware(mux)
+	}
 
 	server := &http.Server{
 		Addr:    listenAddr,
-		Handler: mux,
+		Handler: handler,
 	}
 
 	fmt.Printf("http1 web UI listening on %s\n", listenAddr)
 	return server.ListenAndServe()
 }
 
-func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache) {
+func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache, sched *scheduler, opts http1.Options, notes *noteStore) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, "failed to parse request", http.StatusBadRequest)
 		return
 	}
 
 	raw := r.Form.Get("t")
-	targets := parseTargetsParam(raw)
+	targets, err := parseTargetsParam(raw, "web")
+	if err != nil {
+		const recentLimit = 12
+		recent := cache.recentSnapshots(recentLimit)
+		renderHTML(w, pageData{
+			TargetsRaw: raw,
+			Error:      err.Error(),
+			Page:       "scanner",
+			Recent:     recent,
+			Best:       filterByGrade(recent, "A", 6),
+			Worst:      filterByGrade(recent, "F", 6),
+			Now:        cache.clock.Now(),
+		})
+		return
+	}
 
 	if len(targets) == 0 {
 		// No targets – just render the empty form and always show recent scans.
@@ -355,6 +467,7 @@ func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache) {
 			Recent:     recent,
 			Best:       best,
 			Worst:      worst,
+			Now:        cache.clock.Now(),
 		})
 		return
 	}
@@ -373,14 +486,21 @@ func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache) {
 			Recent:     recent,
 			Best:       best,
 			Worst:      worst,
+			Now:        cache.clock.Now(),
 		})
 		return
 	}
 
-	hideFromRecent := r.Form.Get("hide") == "on" || r.Form.Get("hide") == "1"
+	hideFromRecent := formFlag(r, "hide")
+	// Comparing IPv4 and IPv6 probes each dual-stack host twice more, so
+	// it is opt-in.
+	opts.DualStack = formFlag(r, "dualstack")
 
 	isJSON := wantsJSON(r)
 	key := cacheKey(targets)
+	if opts.DualStack {
+		key = "dualstack " + key
+	}
 
 	var results []http1.CheckResult
 	var usedCache bool
@@ -388,23 +508,35 @@ func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache) {
 	if cached, scannedAt, ok := cache.get(key); ok {
 		results = cached
 		usedCache = true
-		cacheAge = formatAge(time.Since(scannedAt))
+		cacheAge = formatAge(cache.clock.Now().Sub(scannedAt))
 	} else {
-		// For web mode we always use the default port behavior (no override).
+		sched.acquire(priorityInteractive, len(targets))
 		if len(targets) == 1 {
-			res := http1.CheckHTTPVersionsJSON(targets[0], "")
+			res := http1.CheckHTTPVersionsJSON(targets[0], opts)
 			results = []http1.CheckResult{res}
 		} else {
-			results = http1.CheckHTTPVersionsJSONMulti(targets, "")
+			results = http1.CheckHTTPVersionsJSONMulti(targets, opts)
+		}
+		sched.release(priorityInteractive, len(targets))
+		for i := range results {
+			results[i] = cache.certs.Result(results[i])
 		}
 		cache.set(key, results, !hideFromRecent)
 	}
+	if notes != nil {
+		results = notes.annotate(results)
+	}
 
 	if isJSON {
 		renderJSON(w, results)
 		return
 	}
 
+	var csrf string
+	if notes.writable() {
+		csrf = csrfToken(w, r)
+	}
+
 	// Build recent / best / worst snapshots for the overview.
 	const recentLimit = 12
 	recent := cache.recentSnapshots(recentLimit)
@@ -414,7 +546,9 @@ func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache) {
 	renderHTML(w, pageData{
 		TargetsRaw:     raw,
 		HideFromRecent: hideFromRecent,
+		DualStack:      opts.DualStack,
 		Results:        results,
+		Groups:         http1.GroupByLabel(results),
 		HasResults:     true,
 		UsedCache:      usedCache,
 		CacheAge:       cacheAge,
@@ -422,6 +556,9 @@ func handleScan(w http.ResponseWriter, r *http.Request, cache *resultCache) {
 		Best:           best,
 		Worst:          worst,
 		Page:           "scanner",
+		Notes:          notes.writable(),
+		CSRF:           csrf,
+		Now:            cache.clock.Now(),
 	})
 }
 
@@ -493,38 +630,40 @@ func plural(n int) string {
 	return "s"
 }
 
-func parseTargetsParam(raw string) []string {
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return nil
+// parseTargetsParam parses the targets of a web or API request, dropping
+// duplicates, and returns each as an entry for the scan functions.
+func parseTargetsParam(raw, source string) ([]string, error) {
+	parsed, err := http1.ParseTargetList(raw, source)
+	if err != nil {
+		return nil, err
 	}
-
-	parts := strings.Split(raw, ",")
-	targets := make([]string, 0, len(parts))
-	seen := make(map[string]struct{}, len(parts))
-
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
-		}
-		lower := strings.ToLower(p)
-		if _, ok := seen[lower]; ok {
-			continue
-		}
-		seen[lower] = struct{}{}
-		targets = append(targets, p)
+	targets := make([]string, 0, len(parsed))
+	for _, t := range http1.DedupeTargets(parsed) {
+		targets = append(targets, t.String())
 	}
+	return targets, nil
+}
 
-	return targets
+// formFlag reports whether the checkbox or query parameter name is set.
+func formFlag(r *http.Request, name string) bool {
+	v := r.Form.Get(name)
+	return v == "on" || v == "1"
 }
 
+// cacheKey identifies a scan of targets in the result cache: targets that
+// probe the same thing share a key, and labels, which are copied to the
+// results, are part of it.
 func cacheKey(targets []string) string {
-	normalized := make([]string, len(targets))
-	for i, t := range targets {
-		normalized[i] = strings.ToLower(strings.TrimSpace(t))
+	keys := make([]string, len(targets))
+	for i, raw := range targets {
+		t, err := http1.ParseTarget(raw)
+		if err != nil {
+			keys[i] = raw
+			continue
+		}
+		keys[i] = strings.Join(append([]string{t.Key()}, t.Labels...), " ")
 	}
-	return strings.Join(normalized, ",")
+	return strings.Join(keys, ",")
 }
 
 func wantsJSON(r *http.Request) bool {
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+func TestResultCacheExpiry(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache := newResultCache(clk)
+	cache.set("example.com", []http1.CheckResult{{Target: "example.com"}}, true)
+
+	clk.Advance(cacheTTL - time.Second)
+	if _, scannedAt, ok := cache.get("example.com"); !ok {
+		t.Fatal("entry expired before TTL")
+	} else if got := formatAge(clk.Now().Sub(scannedAt)); got != "3 hours ago" {
+		t.Errorf("age = %q, want %q", got, "3 hours ago")
+	}
+	if n := len(cache.recentSnapshots(10)); n != 1 {
+		t.Errorf("recent snapshots = %d, want 1", n)
+	}
+
+	clk.Advance(2 * time.Second)
+	if _, _, ok := cache.get("example.com"); ok {
+		t.Error("entry still cached after TTL")
+	}
+	if n := len(cache.recentSnapshots(10)); n != 0 {
+		t.Errorf("recent snapshots = %d, want 0", n)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{30 * time.Second, "30s ago"},
+		{time.Minute, "1 minute ago"},
+		{90 * time.Minute, "1 hour ago"},
+		{49 * time.Hour, "2 days ago"},
+	}
+	for _, tt := range tests {
+		if got := formatAge(tt.d); got != tt.want {
+			t.Errorf("formatAge(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCacheKeyKeepsPathCase(t *testing.T) {
+	if cacheKey([]string{"Example.com/Health"}) == cacheKey([]string{"example.com/health"}) {
+		t.Error("paths differing only in case share a cache key")
+	}
+	if cacheKey([]string{"HTTPS://Example.COM/x?Q=1"}) != cacheKey([]string{"https://example.com/x?Q=1"}) {
+		t.Error("scheme and host case should not affect the cache key")
+	}
+}
+
+func TestScanDualStackOptIn(t *testing.T) {
+	cache := newResultCache(newFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	key := cacheKey([]string{"example.com"})
+	cache.set(key, []http1.CheckResult{{Target: "example.com"}}, true)
+	cache.set("dualstack "+key, []http1.CheckResult{{Target: "example.com", DualStack: &http1.DualStackResult{Consistent: true}}}, true)
+
+	for _, tt := range []struct {
+		query string
+		want  bool
+	}{
+		{"t=example.com&format=json", false},
+		{"t=example.com&format=json&dualstack=1", true},
+	} {
+		rec := httptest.NewRecorder()
+		handleScan(rec, httptest.NewRequest("GET", "/scan?"+tt.query, nil), cache, newScheduler(scanSlots), http1.Options{}, nil)
+		var res http1.CheckResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := res.DualStack != nil; got != tt.want {
+			t.Errorf("%s: dual-stack result %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTargetsMatchCLI(t *testing.T) {
+	const list = "Example.com prod, example.com:443 eu\nhttps://a.com/X, https://A.com/X"
+	web, err := parseTargetsParam(list, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Example.com prod eu", "https://a.com/X"}; strings.Join(web, ",") != strings.Join(want, ",") {
+		t.Errorf("web targets = %q, want %q", web, want)
+	}
+	cli, err := gatherTargets(list, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cli) != len(web) {
+		t.Errorf("CLI kept %d targets, web %d", len(cli), len(web))
+	}
+}
+
+func TestGradeAPI(t *testing.T) {
+	tests := []struct {
+		method, body string
+		wantStatus   int
+		wantGrade    string
+	}{
+		{"POST", `{"h2": true, "h3": true, "tls_versions": ["TLS 1.3"], "hsts_max_age": 31536000}`, http.StatusOK, "A"},
+		{"POST", `{"h2": true, "tls_versions": ["TLS 1.2"]}`, http.StatusOK, "C"},
+		{"POST", `{"h3": true, "tls_versions": ["TLS 1.2"]}`, http.StatusUnprocessableEntity, ""},
+		{"POST", `{"http3": true}`, http.StatusBadRequest, ""},
+		{"GET", "", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleGradeAPI(rec, httptest.NewRequest(tt.method, "/api/v1/grade", strings.NewReader(tt.body)))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.body, rec.Code, tt.wantStatus)
+			continue
+		}
+		var report http1.GradeReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Grade != tt.wantGrade {
+			t.Errorf("%s: grade %q, want %q", tt.body, report.Grade, tt.wantGrade)
+		}
+	}
+}
+
+func TestJobControl(t *testing.T) {
+	clk := newFakeClock(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	store, err := newJobStore(http1.Options{}, newScheduler(scanSlots), clk, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake scan reports each target it starts on started and finishes
+	// it once the test sends on release.
+	started, release := make(chan string), make(chan struct{})
+	store.stream = func(targets <-chan string, _ http1.Options, fn func(http1.CheckResult)) {
+		for target := range targets {
+			started <- target
+			<-release
+			fn(http1.CheckResult{Target: target})
+		}
+	}
+	// finish lets the scan run until no further target starts.
+	finish := func() {
+		for {
+			select {
+			case <-started:
+				release <- struct{}{}
+			case <-time.After(100 * time.Millisecond):
+				return
+			}
+		}
+	}
+	const token = "s3cret"
+	mux := http.NewServeMux()
+	store.register(mux, token)
+	callAs := func(token, method, path, body string) (int, jobStatus) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		mux.ServeHTTP(rec, req)
+		var st jobStatus
+		_ = json.Unmarshal(rec.Body.Bytes(), &st)
+		return rec.Code, st
+	}
+	call := func(method, path, body string) (int, jobStatus) {
+		t.Helper()
+		return callAs(token, method, path, body)
+	}
+	targets := `{"targets": ["a.test", "b.test", "c.test", "d.test", "a.test"]}`
+
+	for _, bad := range []string{"", "wrong"} {
+		if code, _ := callAs(bad, "POST", "/api/v1/jobs", targets); code != http.StatusUnauthorized {
+			t.Errorf("create with token %q: status %d, want 401", bad, code)
+		}
+	}
+
+	code, job := call("POST", "/api/v1/jobs", targets)
+	if code != http.StatusCreated || job.Total != 4 {
+		t.Fatalf("create: status %d total %d, want 201 and 4", code, job.Total)
+	}
+	<-started
+	if code, _ := call("POST", "/api/v1/jobs/"+job.ID+"/pause", ""); code != http.StatusOK {
+		t.Fatalf("pause: status %d", code)
+	}
+	release <- struct{}{}
+	finish()
+	if _, st := call("GET", "/api/v1/jobs/"+job.ID, ""); st.State != jobPaused || st.Completed == 0 || st.Completed >= st.Total || len(st.Results) != st.Completed {
+		t.Errorf("paused job = %+v, want some but not all results", st)
+	}
+	if code, _ := call("POST", "/api/v1/jobs/"+job.ID+"/pause", ""); code != http.StatusConflict {
+		t.Errorf("pause twice: status %d, want 409", code)
+	}
+	if code, _ := call("POST", "/api/v1/jobs/"+job.ID+"/resume", ""); code != http.StatusOK {
+		t.Fatalf("resume: status %d", code)
+	}
+	finish()
+	if _, st := call("GET", "/api/v1/jobs/"+job.ID, ""); st.State != jobDone || st.Completed != 4 || st.Expires.IsZero() {
+		t.Errorf("resumed job = %+v, want done with 4 results and an expiry", st)
+	}
+	if code, _ := callAs("", "GET", "/api/v1/jobs/"+job.ID, ""); code != http.StatusUnauthorized {
+		t.Errorf("get without token: status %d, want 401", code)
+	}
+
+	// A finished job survives a restart, until it expires.
+	restarted, err := newJobStore(http1.Options{}, newScheduler(scanSlots), clk, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j := restarted.get(job.ID); j == nil || j.status(true).State != jobDone || len(j.status(true).Results) != 4 {
+		t.Errorf("job after restart = %+v, want it done with its results", j)
+	}
+	clk.Advance(jobTTL)
+	if code, _ := call("GET", "/api/v1/jobs/"+job.ID, ""); code != http.StatusNotFound {
+		t.Errorf("expired job: status %d, want 404", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, job.ID+".json")); !os.IsNotExist(err) {
+		t.Errorf("expired job file: %v, want it removed", err)
+	}
+
+	_, job = call("POST", "/api/v1/jobs", targets)
+	<-started
+	if code, _ := call("POST", "/api/v1/jobs/"+job.ID+"/cancel", ""); code != http.StatusOK {
+		t.Fatalf("cancel: status %d", code)
+	}
+	release <- struct{}{}
+	finish()
+	if _, st := call("GET", "/api/v1/jobs/"+job.ID, ""); st.State != jobCanceled || st.Completed == 0 || st.Completed >= st.Total {
+		t.Errorf("canceled job = %+v, want partial results", st)
+	}
+	if code, _ := call("POST", "/api/v1/jobs/"+job.ID+"/resume", ""); code != http.StatusConflict {
+		t.Errorf("resume canceled: status %d, want 409", code)
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{"GET", "/api/v1/jobs/nope", "", http.StatusNotFound},
+		{"POST", "/api/v1/jobs/nope/pause", "", http.StatusNotFound},
+		{"POST", "/api/v1/jobs", `{"targets": []}`, http.StatusUnprocessableEntity},
+		{"POST", "/api/v1/jobs", `{"hosts": ["a.test"]}`, http.StatusBadRequest},
+	} {
+		if code, _ := call(tt.method, tt.path, tt.body); code != tt.want {
+			t.Errorf("%s %s %s: status %d, want %d", tt.method, tt.path, tt.body, code, tt.want)
+		}
+	}
+}
+
+func TestJobSavedWhileRunning(t *testing.T) {
+	clk := newFakeClock(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	store, err := newJobStore(http1.Options{}, newScheduler(scanSlots), clk, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake scan finishes a target each time the test sends on next and
+	// never gets past the third, as if the server stopped there.
+	next, done := make(chan struct{}), make(chan struct{})
+	store.stream = func(targets <-chan string, _ http1.Options, fn func(http1.CheckResult)) {
+		for target := range targets {
+			<-next
+			fn(http1.CheckResult{Target: target})
+			done <- struct{}{}
+		}
+	}
+	step := func() {
+		next <- struct{}{}
+		<-done
+	}
+	j, err := store.start([]string{"a.test", "b.test", "c.test", "d.test"}, priorityBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := func() int {
+		t.Helper()
+		restarted, err := newJobStore(http1.Options{}, newScheduler(scanSlots), clk, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reloaded := restarted.get(j.id)
+		if reloaded == nil {
+			t.Fatal("job not saved")
+		}
+		st := reloaded.status(true)
+		if st.State != jobCanceled || st.Expires.IsZero() || len(st.Results) != st.Completed {
+			t.Errorf("interrupted job = %+v, want it canceled with an expiry", st)
+		}
+		return st.Completed
+	}
+
+	step()
+	if n := saved(); n != 1 {
+		t.Errorf("after the first result, %d saved, want 1", n)
+	}
+	step()
+	if n := saved(); n != 1 {
+		t.Errorf("within jobSaveInterval, %d saved, want still 1", n)
+	}
+	clk.Advance(jobSaveInterval)
+	step()
+	if n := saved(); n != 3 {
+		t.Errorf("after jobSaveInterval, %d saved, want 3", n)
+	}
+}
+
+func TestNotesAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.json")
+	clk := newFakeClock(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC))
+	const token = "s3cret"
+	store, err := newNoteStore(path, clk, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	store.register(mux)
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	const note = `{"target": "legacy.example", "text": "known legacy appliance", "acknowledged": true, "expires": "2025-06"}`
+
+	for _, method := range []string{"GET", "POST"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1/notes", strings.NewReader(note)))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without token: status %d, want 401", method, rec.Code)
+		}
+	}
+	rec := call("POST", "/api/v1/notes", note)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add: status %d: %s", rec.Code, rec.Body)
+	}
+	for _, tt := range []struct{ body string }{
+		{`{"target": "legacy.example", "text": "x", "expires": "soon"}`},
+		{`{"target": "legacy.example"}`},
+	} {
+		if rec := call("POST", "/api/v1/notes", tt.body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422", tt.body, rec.Code)
+		}
+	}
+	var got struct{ Notes []http1.Note }
+	_ = json.Unmarshal(call("GET", "/api/v1/notes?target=https://legacy.example/", "").Body.Bytes(), &got)
+	if len(got.Notes) != 1 || !got.Notes[0].Acknowledged || got.Notes[0].Added.IsZero() {
+		t.Errorf("notes = %+v, want the acknowledgement", got.Notes)
+	}
+
+	saved, err := http1.LoadNotes(path)
+	if err != nil || len(saved.Notes()) != 1 {
+		t.Fatalf("saved notes = %v, %v", saved, err)
+	}
+	res := store.annotate([]http1.CheckResult{{Target: "legacy.example"}})
+	if http1.Acknowledged(res[0], time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) == nil {
+		t.Error("result is not acknowledged in June")
+	}
+}
+
+func TestSchedulerPriorities(t *testing.T) {
+	sched := newScheduler(maxWebTargets + 2)
+	// admitted reports whether acquire returns promptly.
+	admitted := func(p scanPriority, n int) bool {
+		done := make(chan struct{})
+		go func() {
+			sched.acquire(p, n)
+			close(done)
+		}()
+		select {
+		case <-done:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}
+
+	if !admitted(priorityBackground, 2) {
+		t.Fatal("background work not admitted to an idle scheduler")
+	}
+	if admitted(priorityBatch, 1) {
+		t.Fatal("batch work took the slots kept for interactive scans")
+	}
+	// The batch acquire above is still waiting; interactive scans go
+	// ahead of it, and past the background checks still in flight.
+	if !admitted(priorityInteractive, maxWebTargets) {
+		t.Fatal("interactive scan queued behind background work")
+	}
+	sched.release(priorityBackground, 2)
+	if admitted(priorityBackground, 1) {
+		t.Error("background work admitted ahead of waiting batch work")
+	}
+	sched.release(priorityInteractive, maxWebTargets)
+	time.Sleep(50 * time.Millisecond)
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+	if sched.running[priorityBatch] != 1 || sched.running[priorityBackground] != 1 {
+		t.Errorf("running = %v, want the waiting batch and background work admitted", sched.running)
+	}
+}
+
+func TestNotesForm(t *testing.T) {
+	const token = "s3cret"
+	store, err := newNoteStore(filepath.Join(t.TempDir(), "notes.json"), newFakeClock(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	store.register(mux)
+
+	// The scan page hands out the CSRF token in a cookie and the form.
+	rec := httptest.NewRecorder()
+	csrf := csrfToken(rec, httptest.NewRequest("GET", "/scan", nil))
+	cookie := rec.Result().Cookies()[0]
+
+	for _, tt := range []struct {
+		name        string
+		csrf, token string
+		cookie      bool
+		want        int
+	}{
+		{"no cookie", csrf, token, false, http.StatusForbidden},
+		{"wrong CSRF", "0123", token, true, http.StatusForbidden},
+		{"wrong token", csrf, "guess", true, http.StatusUnauthorized},
+		{"valid", csrf, token, true, http.StatusSeeOther},
+	} {
+		form := url.Values{"target": {"legacy.example"}, "text": {"known legacy appliance"}, "csrf": {tt.csrf}, "token": {tt.token}}
+		req := httptest.NewRequest("POST", "/notes", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tt.cookie {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+	if n := len(store.book.Notes()); n != 1 {
+		t.Errorf("%d notes added, want 1", n)
+	}
+
+	// Without an API token the notes are read-only.
+	readOnly, err := newNoteStore(filepath.Join(t.TempDir(), "notes.json"), newFakeClock(time.Now()), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux = http.NewServeMux()
+	readOnly.register(mux)
+	for _, path := range []string{"/api/v1/notes", "/notes"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader("{}")))
+		if rec.Code == http.StatusCreated || rec.Code == http.StatusSeeOther {
+			t.Errorf("POST %s on read-only notes: status %d", path, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/notes", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET on notes without an API token: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"http1.dev/internal/http1"
+)
+
+// webhookTimeout bounds each webhook delivery.
+const webhookTimeout = 10 * time.Second
+
+// loadBaseline reads the results of an earlier run, written with --json or
+// --format ndjson, keyed by target. It serves --baseline and http1 diff.
+func loadBaseline(path string) (map[string]http1.CheckResult, error) {
+	results, err := readResults(path)
+	if err != nil {
+		return nil, err
+	}
+	baseline := make(map[string]http1.CheckResult, len(results))
+	for _, res := range results {
+		baseline[res.Target] = res
+	}
+	return baseline, nil
+}
+
+// readResults reads the results of an earlier run, written with --json or
+// --format ndjson, in the order they were written.
+func readResults(path string) ([]http1.CheckResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	dec := json.NewDecoder(r)
+	var results []http1.CheckResult
+	if first, err := peekNonSpace(r); err == nil && first == '[' {
+		err = dec.Decode(&results)
+		if err != nil {
+			return nil, fmt.Errorf("invalid results file %s: %w", path, err)
+		}
+	} else {
+		for {
+			var res http1.CheckResult
+			if err := dec.Decode(&res); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("invalid results file %s: %w", path, err)
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// peekNonSpace returns the first byte of r that is not white space without
+// consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		_, _ = r.ReadByte()
+	}
+}
+
+// parseWebhookEvents parses --webhook-events; empty subscribes to all.
+func parseWebhookEvents(list string) (map[string]bool, error) {
+	events := make(map[string]bool)
+	for _, e := range strings.Split(list, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		if !slices.Contains(http1.AnomalyTypes, e) {
+			return nil, fmt.Errorf("unknown webhook event %q (want %s)", e, strings.Join(http1.AnomalyTypes, ", "))
+		}
+		events[e] = true
+	}
+	if len(events) == 0 {
+		for _, e := range http1.AnomalyTypes {
+			events[e] = true
+		}
+	}
+	return events, nil
+}
+
+// webhookPayload is the JSON body POSTed for each event.
+type webhookPayload struct {
+	Event    string    `json:"event"`
+	Target   string    `json:"target"`
+	Previous string    `json:"previous"`
+	Current  string    `json:"current"`
+	Vantage  string    `json:"vantage,omitempty"`
+	Time     time.Time `json:"time"`
+	// ExpiredWaiver is the waiver of this event for the target that has
+	// run out, so the alert reaches whoever must renew or fix it.
+	ExpiredWaiver *http1.Waiver `json:"expired_waiver,omitempty"`
+}
+
+// webhookNotifier compares each result with the baseline and POSTs the
+// subscribed anomalies to a URL. Deliveries run in the background, in
+// order, so a slow endpoint does not hold up the scan.
+type webhookNotifier struct {
+	url      string
+	events   map[string]bool
+	baseline map[string]http1.CheckResult
+	// waivers silence events for a target until they expire.
+	waivers http1.Waivers
+	client  *http.Client
+
+	queue chan webhookPayload
+	done  chan struct{}
+}
+
+func newWebhookNotifier(url string, events map[string]bool, baseline map[string]http1.CheckResult) *webhookNotifier {
+	n := &webhookNotifier{
+		url:      url,
+		events:   events,
+		baseline: baseline,
+		client:   &http.Client{Timeout: webhookTimeout},
+		queue:    make(chan webhookPayload, 64),
+		done:     make(chan struct{}),
+	}
+	go n.deliver()
+	return n
+}
+
+// notify queues the events for res. target is the name to report it
+// under, which differs from res.Target with --redact.
+func (n *webhookNotifier) notify(res http1.CheckResult, target string) {
+	prev, ok := n.baseline[res.Target]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	for _, a := range http1.CompareResults(prev, res) {
+		if !n.events[a.Type] {
+			continue
+		}
+		w, waived := n.waivers.Find(res.Target, a.Type, now)
+		if waived {
+			continue
+		}
+		if w != nil {
+			expired := *w
+			expired.Target = target
+			w = &expired
+		}
+		n.queue <- webhookPayload{
+			Event:         a.Type,
+			Target:        target,
+			Previous:      a.Previous,
+			Current:       a.Current,
+			Vantage:       res.Vantage,
+			Time:          now,
+			ExpiredWaiver: w,
+		}
+	}
+}
+
+func (n *webhookNotifier) deliver() {
+	defer close(n.done)
+	for p := range n.queue {
+		if err := n.post(p); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: webhook for %s %s: %v\n", p.Event, p.Target, err)
+		}
+	}
+}
+
+func (n *webhookNotifier) post(p webhookPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// close waits for the queued deliveries to finish.
+func (n *webhookNotifier) close() {
+	close(n.queue)
+	<-n.done
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"http1.dev/internal/http1"
+)
+
+// gradeRecords grades zgrab2 or tls-scan records, given as a stream of JSON
+// objects, with the same engine as "http1 grade-import".
+func gradeRecords(data string) ([]http1.CheckResult, error) {
+	var results []http1.CheckResult
+	dec := json.NewDecoder(strings.NewReader(data))
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		obs, err := http1.ParseScanRecord(raw)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		results = append(results, http1.GradeObservation(obs))
+	}
+}
+
+// readResults decodes previously captured http1 results, written with
+// --json (an object or an array) or --ndjson.
+func readResults(data string) ([]http1.CheckResult, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	var results []http1.CheckResult
+	if strings.HasPrefix(strings.TrimSpace(data), "[") {
+		if err := dec.Decode(&results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+	for {
+		var res http1.CheckResult
+		if err := dec.Decode(&res); errors.Is(err, io.EOF) {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+}
+
+// summarize returns the CLI summary line of each result, or the prose
+// summary with plain set.
+func summarize(results []http1.CheckResult, plain bool) []string {
+	lines := make([]string, len(results))
+	for i, res := range results {
+		if plain {
+			lines[i] = http1.PlainSummary(res)
+		} else {
+			lines[i] = http1.SummaryLine(res)
+		}
+	}
+	return lines
+}
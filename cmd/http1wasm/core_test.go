@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGradeRecords(t *testing.T) {
+	records := `{"host":"example.org","ip":"192.0.2.3","port":8443,"tlsVersion":"TLSv1.2","alpn":"h2"}
+{"host":"example.net","ip":"192.0.2.4","port":443,"tlsVersion":"TLSv1.3","alpn":"h2"}
+`
+	results, err := gradeRecords(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Grade != "C" || results[1].Grade != "B" {
+		t.Fatalf("got %+v, want grades C and B", results)
+	}
+
+	if _, err := gradeRecords(`{"unknown":true}`); err == nil || !strings.HasPrefix(err.Error(), "record 1:") {
+		t.Errorf("unrecognised record: err = %v", err)
+	}
+}
+
+func TestReadResults(t *testing.T) {
+	for name, data := range map[string]string{
+		"array":  `[{"target":"a.com","grade":"A"},{"target":"b.com","grade":"F"}]`,
+		"ndjson": "{\"target\":\"a.com\",\"grade\":\"A\"}\n{\"target\":\"b.com\",\"grade\":\"F\"}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			results, err := readResults(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 2 || results[1].Target != "b.com" {
+				t.Fatalf("got %+v", results)
+			}
+			if got := summarize(results, false)[0]; !strings.Contains(got, "Grade: A") {
+				t.Errorf("summary = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"http1.dev/internal/http1"
+)
+
+// main registers the grading and parsing core as globalThis.http1 and keeps
+// the Go runtime alive for the page's calls. Every function returns an
+// object with either its result or an "error" message.
+func main() {
+	js.Global().Set("http1", js.ValueOf(map[string]any{
+		"normalize": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			t, err := http1.ParseTarget(stringArg(args, 0))
+			if err != nil {
+				return jsError(err)
+			}
+			return map[string]any{"url": t.URL, "host": t.Host, "port": t.Port}
+		}),
+		"gradeRecords": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			results, err := gradeRecords(stringArg(args, 0))
+			if err != nil {
+				return jsError(err)
+			}
+			return jsResults(results)
+		}),
+		"summarize": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			results, err := readResults(stringArg(args, 0))
+			if err != nil {
+				return jsError(err)
+			}
+			plain := len(args) > 1 && args[1].Truthy()
+			lines := summarize(results, plain)
+			out := make([]any, len(lines))
+			for i, l := range lines {
+				out[i] = l
+			}
+			return map[string]any{"lines": out}
+		}),
+	}))
+	select {}
+}
+
+// stringArg returns args[i] as a string, or "" when it is missing.
+func stringArg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+// jsResults hands results to JavaScript as parsed JSON, so the page sees the
+// same field names as the CLI's --json output.
+func jsResults(results any) any {
+	b, err := json.Marshal(results)
+	if err != nil {
+		return jsError(err)
+	}
+	return map[string]any{"results": js.Global().Get("JSON").Call("parse", string(b))}
+}
+
+func jsError(err error) any {
+	return map[string]any{"error": err.Error()}
+}
@@ -0,0 +1,15 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main explains how to build this command; it only does something useful
+// as WebAssembly.
+func main() {
+	fmt.Fprintln(os.Stderr, "http1wasm runs in the browser; build it with GOOS=js GOARCH=wasm go build -o http1.wasm ./cmd/http1wasm")
+	os.Exit(2)
+}
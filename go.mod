@@ -4,11 +4,14 @@ go 1.24
 
 toolchain go1.24.10
 
+require (
+	github.com/quic-go/quic-go v0.57.0
+	golang.org/x/net v0.43.0
+	golang.org/x/sys v0.35.0
+)
+
 require (
 	github.com/quic-go/qpack v0.6.0 // indirect
-	github.com/quic-go/quic-go v0.57.0 // indirect
 	golang.org/x/crypto v0.41.0 // indirect
-	golang.org/x/net v0.43.0 // indirect
-	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 )
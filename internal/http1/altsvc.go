@@ -0,0 +1,139 @@
+package http1
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxAltSvcProbes bounds the follow-up HTTP/3 probes per target, as Alt-Svc
+// may list any number of alternatives.
+const maxAltSvcProbes = 3
+
+// AltSvcResult reports the HTTP/3 alternatives a target advertises with
+// Alt-Svc (RFC 7838) on its HTTPS responses. Browsers look for HTTP/3 where
+// Alt-Svc points them, so alternatives on another port or host than the one
+// probed get a follow-up HTTP/3 probe of their own.
+type AltSvcResult struct {
+	// Header is the Alt-Svc field value as received.
+	Header string `json:"header"`
+	// Advertised is true when Header offers h3, and Verified when at least
+	// one advertised endpoint answered over HTTP/3.
+	Advertised bool             `json:"advertised"`
+	Verified   bool             `json:"verified"`
+	Endpoints  []AltSvcEndpoint `json:"endpoints,omitempty"`
+}
+
+// AltSvcEndpoint is one h3 alternative from an Alt-Svc header.
+type AltSvcEndpoint struct {
+	// Host is empty when the alternative is on the origin's host.
+	Host string `json:"host,omitempty"`
+	Port string `json:"port"`
+	// MaxAge is the ma parameter in seconds, 0 when absent.
+	MaxAge int `json:"max_age,omitempty"`
+	// FollowUp is true when the endpoint got its own probe; the others are
+	// the probed host and port and share the HTTP/3 probe's outcome.
+	FollowUp bool   `json:"follow_up,omitempty"`
+	Verified bool   `json:"verified"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Authority is the endpoint as Alt-Svc writes it, e.g. ":8443" or
+// "alt.example.com:443".
+func (e AltSvcEndpoint) Authority() string {
+	return net.JoinHostPort(e.Host, e.Port)
+}
+
+// parseAltSvc returns the h3 alternatives in an Alt-Svc field value, in the
+// order given and without duplicates. Other protocols, draft versions of h3
+// and malformed entries are skipped; "clear" yields none.
+func parseAltSvc(header string) []AltSvcEndpoint {
+	var out []AltSvcEndpoint
+	seen := make(map[string]bool)
+	for _, value := range strings.Split(header, ",") {
+		params := strings.Split(value, ";")
+		proto, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !ok || strings.TrimSpace(proto) != "h3" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.Trim(strings.TrimSpace(authority), `"`))
+		if n, perr := strconv.Atoi(port); err != nil || perr != nil || n < 1 || n > 65535 {
+			continue
+		}
+		e := AltSvcEndpoint{Host: strings.TrimSuffix(host, "."), Port: port}
+		for _, p := range params[1:] {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && strings.EqualFold(k, "ma") {
+				e.MaxAge, _ = strconv.Atoi(strings.Trim(v, `"`))
+			}
+		}
+		if !seen[e.Authority()] {
+			seen[e.Authority()] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// checkAltSvc builds the AltSvcResult for header, received from host, whose
+// HTTP/3 probe on port found HTTP/3 supported or not (h3). Alternatives
+// elsewhere are probed with probe, which is given their "host:port" address
+// and returns nil when HTTP/3 answered there. It returns nil when header is
+// empty.
+func checkAltSvc(header, host, port string, h3 bool, probe func(addr string) error) *AltSvcResult {
+	if header == "" {
+		return nil
+	}
+	a := &AltSvcResult{Header: header, Endpoints: parseAltSvc(header)}
+	a.Advertised = len(a.Endpoints) > 0
+	probes := 0
+	for i := range a.Endpoints {
+		e := &a.Endpoints[i]
+		altHost := e.Host
+		if altHost == "" {
+			altHost = host
+		}
+		switch {
+		case strings.EqualFold(altHost, host) && e.Port == port:
+			e.Verified = h3
+			e.Detail = "the probed endpoint"
+		case probes == maxAltSvcProbes:
+			e.Detail = "not probed: too many alternatives"
+		default:
+			probes++
+			e.FollowUp = true
+			if err := probe(net.JoinHostPort(altHost, e.Port)); err != nil {
+				e.Detail = fmt.Sprintf("no HTTP/3 answer: %v", err)
+			} else {
+				e.Verified = true
+				e.Detail = "answered over HTTP/3"
+			}
+		}
+		a.Verified = a.Verified || e.Verified
+	}
+	return a
+}
+
+// verifiedAt returns the first endpoint a follow-up probe verified, or "".
+func (a *AltSvcResult) verifiedAt() string {
+	if a == nil {
+		return ""
+	}
+	for _, e := range a.Endpoints {
+		if e.FollowUp && e.Verified {
+			return e.Authority()
+		}
+	}
+	return ""
+}
+
+// altSvcHeader is the Alt-Svc header of the HTTPS probes, preferring the
+// HTTP/2 probe's. The plain-HTTP probe's is ignored, as clients are.
+func altSvcHeader(results []VersionResult) string {
+	for _, i := range []int{2, 1} {
+		if i < len(results) && results[i].altSvc != "" {
+			return results[i].altSvc
+		}
+	}
+	return ""
+}
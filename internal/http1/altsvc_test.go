@@ -0,0 +1,65 @@
+package http1
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseAltSvc(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []AltSvcEndpoint
+	}{
+		{`h3=":443"; ma=86400`, []AltSvcEndpoint{{Port: "443", MaxAge: 86400}}},
+		{
+			`h3-29=":443", h3=":8443"; ma=3600; persist=1, h2=":443", h3="alt.example.com.:443", h3=":8443"`,
+			[]AltSvcEndpoint{{Port: "8443", MaxAge: 3600}, {Host: "alt.example.com", Port: "443"}},
+		},
+		{`h3="[2001:db8::1]:443"`, []AltSvcEndpoint{{Host: "2001:db8::1", Port: "443"}}},
+		{`clear`, nil},
+		{`h3=":0", h3="nohost", h3`, nil},
+	}
+	for _, tt := range tests {
+		if got := parseAltSvc(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseAltSvc(%q) = %+v, want %+v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAltSvc(t *testing.T) {
+	if a := checkAltSvc("", "example.com", "443", true, nil); a != nil {
+		t.Fatalf("empty header: got %+v", a)
+	}
+
+	var probed []string
+	probe := func(addr string) error {
+		probed = append(probed, addr)
+		if addr == "example.com:8443" {
+			return nil
+		}
+		return errors.New("timeout")
+	}
+	a := checkAltSvc(`h3=":443", h3=":8443", h3="alt.example.com:443"`, "example.com", "443", false, probe)
+	if want := []string{"example.com:8443", "alt.example.com:443"}; !reflect.DeepEqual(probed, want) {
+		t.Errorf("probed %v, want %v", probed, want)
+	}
+	if !a.Advertised || !a.Verified {
+		t.Errorf("Advertised, Verified = %v, %v; want true, true", a.Advertised, a.Verified)
+	}
+	if e := a.Endpoints[0]; e.FollowUp || e.Verified {
+		t.Errorf("probed endpoint = %+v, want the HTTP/3 probe's outcome", e)
+	}
+	if e := a.Endpoints[2]; !e.FollowUp || e.Verified || e.Detail != "no HTTP/3 answer: timeout" {
+		t.Errorf("alt host endpoint = %+v", e)
+	}
+	if got := a.verifiedAt(); got != ":8443" {
+		t.Errorf("verifiedAt = %q, want :8443", got)
+	}
+
+	// Only h2 advertised: nothing to verify.
+	a = checkAltSvc(`h2=":443"`, "example.com", "443", false, probe)
+	if a.Advertised || a.Verified || a.verifiedAt() != "" {
+		t.Errorf("h2 only: %+v", a)
+	}
+}
@@ -0,0 +1,62 @@
+package http1
+
+// Anomaly types reported by CompareResults.
+const (
+	AnomalyGradeChanged  = "grade_changed"
+	AnomalyH3Unreachable = "h3_unreachable"
+	AnomalyH2Unreachable = "h2_unreachable"
+	AnomalyIssuerChanged = "issuer_changed"
+	AnomalyCertChanged   = "cert_changed"
+	AnomalyTLSDowngraded = "tls_downgraded"
+)
+
+// AnomalyTypes lists every anomaly type, e.g. to validate a subscription.
+var AnomalyTypes = []string{
+	AnomalyGradeChanged,
+	AnomalyH3Unreachable,
+	AnomalyH2Unreachable,
+	AnomalyIssuerChanged,
+	AnomalyCertChanged,
+	AnomalyTLSDowngraded,
+}
+
+// Anomaly is a change between two scans of a target that automation may
+// want to act on.
+type Anomaly struct {
+	Type     string `json:"type"`
+	Target   string `json:"target"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// CompareResults lists the anomalies between prev and cur, two scans of the
+// same target. Nothing is reported for a side that was not observed, such as
+// the issuer when TLS failed altogether.
+func CompareResults(prev, cur CheckResult) []Anomaly {
+	var out []Anomaly
+	add := func(typ, p, c string) {
+		out = append(out, Anomaly{Type: typ, Target: cur.Target, Previous: p, Current: c})
+	}
+	if prev.Grade != "" && cur.Grade != "" && prev.Grade != cur.Grade {
+		add(AnomalyGradeChanged, prev.Grade, cur.Grade)
+	}
+	for _, v := range []struct{ version, typ string }{
+		{"HTTP/3.0", AnomalyH3Unreachable},
+		{"HTTP/2.0", AnomalyH2Unreachable},
+	} {
+		was, now := versionResult(prev, v.version), versionResult(cur, v.version)
+		if was.Supported && now.Version != "" && !now.Supported {
+			add(v.typ, was.Detail, now.Detail)
+		}
+	}
+	if prev.CertIssuer != "" && cur.CertIssuer != "" && prev.CertIssuer != cur.CertIssuer {
+		add(AnomalyIssuerChanged, prev.CertIssuer, cur.CertIssuer)
+	}
+	if prev.CertSHA256 != "" && cur.CertSHA256 != "" && prev.CertSHA256 != cur.CertSHA256 {
+		add(AnomalyCertChanged, prev.CertSHA256, cur.CertSHA256)
+	}
+	if c := tlsVersionValue(cur.TLSVersion); c != 0 && c < tlsVersionValue(prev.TLSVersion) {
+		add(AnomalyTLSDowngraded, prev.TLSVersion, cur.TLSVersion)
+	}
+	return out
+}
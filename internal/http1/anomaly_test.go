@@ -0,0 +1,52 @@
+package http1
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareResults(t *testing.T) {
+	base := CheckResult{
+		Target:     "example.com",
+		Grade:      "A",
+		TLSVersion: "TLS 1.3",
+		CertIssuer: "CN=R10,O=Let's Encrypt,C=US",
+		CertSHA256: "aa",
+		Results: []VersionResult{
+			{Version: "HTTP/2.0", Supported: true},
+			{Version: "HTTP/3.0", Supported: true},
+		},
+	}
+	tests := []struct {
+		name   string
+		change func(*CheckResult)
+		want   []string
+	}{
+		{"unchanged", func(*CheckResult) {}, nil},
+		{"h3 lost", func(r *CheckResult) {
+			r.Grade = "B"
+			r.Results[1] = VersionResult{Version: "HTTP/3.0", Error: true}
+		}, []string{AnomalyGradeChanged, AnomalyH3Unreachable}},
+		{"issuer", func(r *CheckResult) { r.CertIssuer = "CN=E6,O=Let's Encrypt,C=US" }, []string{AnomalyIssuerChanged}},
+		{"renewal", func(r *CheckResult) { r.CertSHA256 = "bb" }, []string{AnomalyCertChanged}},
+		{"downgrade", func(r *CheckResult) { r.TLSVersion = "TLS 1.2" }, []string{AnomalyTLSDowngraded}},
+		{"upgrade is not an anomaly", func(r *CheckResult) { r.TLSVersion = "TLS 1.3" }, nil},
+		{"TLS failed", func(r *CheckResult) {
+			r.Grade = ""
+			r.TLSVersion, r.CertIssuer = "", ""
+			r.Results = nil
+		}, nil},
+	}
+	for _, tt := range tests {
+		cur := base
+		cur.Results = slices.Clone(base.Results)
+		tt.change(&cur)
+		var got []string
+		for _, a := range CompareResults(base, cur) {
+			got = append(got, a.Type)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: anomalies %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
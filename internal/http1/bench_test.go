@@ -0,0 +1,66 @@
+package http1
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// startLocalServers starts the loopback test servers for the duration of tb.
+func startLocalServers(tb testing.TB) string {
+	tb.Helper()
+	srv, err := StartTestServers()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(srv.Close)
+	return srv.Port
+}
+
+func BenchmarkRunChecks(b *testing.B) {
+	port := startLocalServers(b)
+	target := "https://127.0.0.1:" + port
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		res := runChecks(target, Options{Port: port})
+		if res.Grade != "A" {
+			b.Fatalf("unexpected grade %q", res.Grade)
+		}
+	}
+}
+
+// BenchmarkRunChecksMulti measures worker scheduling and per-target
+// transport setup across a batch of targets sharing one server, and the
+// shared-transport low-resource profile for comparison.
+func BenchmarkRunChecksMulti(b *testing.B) {
+	port := startLocalServers(b)
+	for _, n := range []int{1, 16, 64} {
+		targets := make([]string, n)
+		for i := range targets {
+			targets[i] = fmt.Sprintf("https://127.0.0.1:%s/?n=%d", port, i)
+		}
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runChecksMulti(targets, Options{Port: port})
+			}
+		})
+		b.Run(strconv.Itoa(n)+"/low-resource", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runChecksMulti(targets, Options{Port: port, LowResource: true})
+			}
+		})
+	}
+}
+
+// BenchmarkRunChecksHostname goes through name resolution for every probe,
+// unlike the IP-literal benchmarks above.
+func BenchmarkRunChecksHostname(b *testing.B) {
+	port := startLocalServers(b)
+	target := "https://localhost:" + port
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runChecks(target, Options{Port: port})
+	}
+}
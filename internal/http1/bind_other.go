@@ -0,0 +1,16 @@
+//go:build !linux
+
+package http1
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToDevice cannot pin sockets to an interface portably outside Linux;
+// use Options.SourceIP with one of the interface's addresses instead.
+func bindToDevice(string) func(network, address string, c syscall.RawConn) error {
+	return func(string, string, syscall.RawConn) error {
+		return errors.New("binding to an interface is only supported on Linux; use a source IP instead")
+	}
+}
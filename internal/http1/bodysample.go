@@ -0,0 +1,99 @@
+package http1
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// bodySampleLimit bounds how much of each probe response is read for
+// markers; interstitials and redirects announce themselves in the head.
+const bodySampleLimit = 32 << 10
+
+var (
+	metaTag     = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	metaRefresh = regexp.MustCompile(`(?is)http-equiv\s*=\s*["']?refresh`)
+	metaToHTTPS = regexp.MustCompile(`(?is)url\s*=\s*['"]?\s*https://`)
+)
+
+// bodyMarkers are the annotations sampleBody can add, each with the
+// lower-case page fragments that trigger it.
+var bodyMarkers = []struct {
+	annotation string
+	fragments  []string
+}{
+	{"browser upgrade interstitial", []string{
+		"upgrade your browser",
+		"update your browser",
+		"browser is not supported",
+		"browser is no longer supported",
+		"unsupported browser",
+	}},
+	{"bot challenge page", []string{
+		"/cdn-cgi/challenge-platform/",
+		"_incapsula_resource",
+		"px-captcha",
+		"awswaf",
+		"checking your browser before accessing",
+	}},
+}
+
+// inspectBody records resp's status and server on vr and samples its body
+// when Options.SampleBodies asks for annotations or the status suggests a
+// challenge page, recording both on vr.
+func inspectBody(vr *VersionResult, resp *http.Response, opts Options) {
+	vr.status = resp.StatusCode
+	vr.server = resp.Header.Get("Server")
+	vr.stack = fingerprintStack(resp.Header)
+	vr.altSvc = strings.Join(resp.Header.Values("Alt-Svc"), ", ")
+	var page string
+	if opts.SampleBodies || challengeStatus(resp.StatusCode) {
+		page = readBodySample(resp)
+	}
+	if opts.SampleBodies {
+		vr.Annotations = bodyAnnotations(page)
+	}
+	vr.Challenge = detectChallenge(resp, page)
+}
+
+// readBodySample returns the start of resp's body. Bodies in encodings
+// other than gzip are not inspected and yield "".
+func readBodySample(resp *http.Response) string {
+	var r io.Reader = resp.Body
+	switch responseEncoding(resp) {
+	case "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return ""
+		}
+		r = zr
+	default:
+		return ""
+	}
+	body, _ := io.ReadAll(io.LimitReader(r, bodySampleLimit))
+	return string(body)
+}
+
+// bodyAnnotations lists the markers found in page.
+func bodyAnnotations(page string) []string {
+	var out []string
+	for _, tag := range metaTag.FindAllString(page, -1) {
+		if metaRefresh.MatchString(tag) && metaToHTTPS.MatchString(tag) {
+			out = append(out, "HTML meta refresh to HTTPS")
+			break
+		}
+	}
+	lower := strings.ToLower(page)
+	for _, m := range bodyMarkers {
+		for _, f := range m.fragments {
+			if strings.Contains(lower, f) {
+				out = append(out, m.annotation)
+				break
+			}
+		}
+	}
+	return out
+}
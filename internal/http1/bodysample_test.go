@@ -0,0 +1,45 @@
+package http1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestBodyAnnotations(t *testing.T) {
+	tests := []struct {
+		name, page string
+		want       []string
+	}{
+		{"meta refresh", `<html><head><META HTTP-EQUIV="Refresh" CONTENT="0; URL=https://example.com/"></head></html>`, []string{"HTML meta refresh to HTTPS"}},
+		{"meta refresh, content first", `<meta content="5;url='https://example.com/'" http-equiv=refresh>`, []string{"HTML meta refresh to HTTPS"}},
+		{"meta refresh to http", `<meta http-equiv="refresh" content="0; url=http://example.com/">`, nil},
+		{"upgrade browser", `<p>Please upgrade your browser to view this site.</p>`, []string{"browser upgrade interstitial"}},
+		{"challenge", `<script src="/cdn-cgi/challenge-platform/h/b/orchestrate/chl_page/v1"></script>`, []string{"bot challenge page"}},
+		{"plain page", `<html><body>Hello</body></html>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bodyAnnotations(tt.page); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleBodyGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("<p>Your browser is not supported.</p>"))
+	zw.Close()
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   io.NopCloser(&buf),
+	}
+	if got := readBodySample(resp); got != "<p>Your browser is not supported.</p>" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package http1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DefaultReferenceHosts are large, long-standing HTTP/3 deployments used to
+// check that this network can do QUIC at all before trusting h3 results.
+var DefaultReferenceHosts = []string{
+	"cloudflare.com",
+	"www.google.com",
+	"www.facebook.com",
+}
+
+// QUICCalibration is the outcome of handshaking with reference hosts that
+// are known to support HTTP/3. If none answers, UDP/443 is most likely
+// blocked or mangled on this network and "HTTP/3 not supported" findings
+// say more about the scanner than about the targets.
+type QUICCalibration struct {
+	Hosts   []string `json:"hosts"`
+	Reached []string `json:"reached,omitempty"`
+	OK      bool     `json:"ok"`
+	Detail  string   `json:"detail,omitempty"`
+}
+
+// CalibrateQUIC attempts a QUIC handshake with each reference host (as
+// "host" or "host:port", default port 443) concurrently. Calibration passes
+// if any of them completes.
+func CalibrateQUIC(hosts []string) QUICCalibration {
+	c := QUICCalibration{Hosts: hosts}
+	reached := make([]bool, len(hosts))
+	errs := make([]string, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := handshakeQUIC(h); err != nil {
+				errs[i] = fmt.Sprintf("%s: %v", h, err)
+				return
+			}
+			reached[i] = true
+		}()
+	}
+	wg.Wait()
+
+	var failures []string
+	for i, h := range hosts {
+		if reached[i] {
+			c.Reached = append(c.Reached, h)
+		} else {
+			failures = append(failures, errs[i])
+		}
+	}
+	c.OK = len(c.Reached) > 0
+	switch {
+	case len(hosts) == 0:
+		c.Detail = "no reference hosts configured"
+	case c.OK:
+		c.Detail = fmt.Sprintf("QUIC handshake with %d of %d reference hosts", len(c.Reached), len(hosts))
+	default:
+		c.Detail = "no reference host completed a QUIC handshake (" + strings.Join(failures, "; ") + ")"
+	}
+	return c
+}
+
+func handshakeQUIC(hostport string) error {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, "443"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h3Timeout)
+	defer cancel()
+	return quicHandshake(ctx, net.JoinHostPort(host, port), host)
+}
+
+// QUICHandshake completes a QUIC handshake offering h3 with the server at
+// addr ("host:port"), presenting serverName. It fails in builds without
+// HTTP/3 support; see HTTP3Available.
+func QUICHandshake(ctx context.Context, addr, serverName string) error {
+	return quicHandshake(ctx, addr, serverName)
+}
+
+// h3DisabledDetail is the HTTP/3 detail in builds without HTTP/3 support.
+const h3DisabledDetail = "not tested (disabled in this build)"
+
+// HTTP3Available reports whether this binary can probe HTTP/3. Builds with
+// the noh3 tag leave out quic-go and report HTTP/3 as not tested.
+func HTTP3Available() bool {
+	return h3Available
+}
@@ -0,0 +1,32 @@
+package http1
+
+import "testing"
+
+func TestCalibrateQUIC(t *testing.T) {
+	if !h3Available {
+		t.Skip("HTTP/3 is disabled in this build")
+	}
+	port := startLocalServers(t)
+	good := "127.0.0.1:" + port
+	bad := "calibration.invalid:443"
+
+	tests := []struct {
+		name        string
+		hosts       []string
+		wantOK      bool
+		wantReached int
+	}{
+		{"reachable", []string{good}, true, 1},
+		{"one of two", []string{bad, good}, true, 1},
+		{"unreachable", []string{bad}, false, 0},
+		{"none", nil, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CalibrateQUIC(tt.hosts)
+			if c.OK != tt.wantOK || len(c.Reached) != tt.wantReached {
+				t.Errorf("CalibrateQUIC(%v) = %+v", tt.hosts, c)
+			}
+		})
+	}
+}
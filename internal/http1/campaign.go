@@ -0,0 +1,141 @@
+package http1
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Goal is the end state of a deprecation campaign, e.g. "no HTTP/1.0
+// anywhere, HTTP/2 everywhere": a Policy every target must clear, plus
+// versions no target may still support.
+type Goal struct {
+	Policy
+	// Forbid lists versions no target may support, e.g. "HTTP/1.0".
+	Forbid []string
+}
+
+// ParseGoal builds a Goal from a minimum grade and comma-separated lists of
+// required and forbidden versions, as ParsePolicy and ParseVersions take
+// them. A goal needs at least one of them.
+func ParseGoal(minGrade, require, forbid string) (Goal, error) {
+	p, err := ParsePolicy(minGrade, require)
+	if err != nil {
+		return Goal{}, err
+	}
+	g := Goal{Policy: p}
+	if g.Forbid, err = ParseVersions(forbid); err != nil {
+		return Goal{}, err
+	}
+	if g.MinGrade == "" && len(g.Require) == 0 && len(g.Forbid) == 0 {
+		return Goal{}, fmt.Errorf("a goal needs a minimum grade, required versions or forbidden versions")
+	}
+	for _, v := range g.Forbid {
+		if slices.Contains(g.Require, v) {
+			return Goal{}, fmt.Errorf("%s is both required and forbidden", v)
+		}
+	}
+	return g, nil
+}
+
+// Misses returns the ways res falls short of g, or nil when the target is
+// done. A target that could not be checked is not done.
+func (g Goal) Misses(res CheckResult) []string {
+	if res.Grade == "" {
+		return []string{"could not be checked"}
+	}
+	out := g.Policy.Violations(res)
+	for _, vr := range res.Results {
+		if vr.Supported && slices.Contains(g.Forbid, vr.Version) {
+			out = append(out, vr.Version+" is still supported")
+		}
+	}
+	return out
+}
+
+// Snapshot is the results of one scan of a campaign's fleet.
+type Snapshot struct {
+	Time    time.Time
+	Results []CheckResult
+}
+
+// ScanTime returns when the latest of results was scanned, or the zero
+// time when none records it.
+func ScanTime(results []CheckResult) time.Time {
+	var t time.Time
+	for _, res := range results {
+		if res.ScannedAt.After(t) {
+			t = res.ScannedAt
+		}
+	}
+	return t
+}
+
+// CampaignPoint is a fleet's progress toward a Goal as of one scan.
+type CampaignPoint struct {
+	Time    time.Time `json:"time"`
+	Targets int       `json:"targets"`
+	Done    int       `json:"done"`
+	Percent float64   `json:"percent"`
+}
+
+// CampaignProgress is a campaign's burn-down: its progress at each scan,
+// oldest first, and where the trend is heading.
+type CampaignProgress struct {
+	Points []CampaignPoint `json:"points"`
+	// Remaining maps each target the latest scan found short of the goal
+	// to the reasons.
+	Remaining map[string][]string `json:"remaining,omitempty"`
+	Deadline  time.Time           `json:"deadline,omitzero"`
+	// Projected is when the fleet reaches the goal if progress continues
+	// at the pace from the first scan to the latest. It is zero when the
+	// goal is met or the pace gives no end.
+	Projected time.Time `json:"projected,omitzero"`
+	// OnTrack reports whether the goal is met, or projected to be met by
+	// the deadline (at all, without one).
+	OnTrack bool `json:"on_track"`
+}
+
+// TrackCampaign measures the progress toward g in snapshots, which it
+// orders by time, against deadline, which may be zero.
+func TrackCampaign(g Goal, snapshots []Snapshot, deadline time.Time) CampaignProgress {
+	snapshots = slices.Clone(snapshots)
+	slices.SortStableFunc(snapshots, func(a, b Snapshot) int { return a.Time.Compare(b.Time) })
+	p := CampaignProgress{Points: []CampaignPoint{}, Deadline: deadline}
+	for i, s := range snapshots {
+		pt := CampaignPoint{Time: s.Time, Targets: len(s.Results)}
+		latest := i == len(snapshots)-1
+		if latest {
+			p.Remaining = make(map[string][]string)
+		}
+		for _, res := range s.Results {
+			misses := g.Misses(res)
+			if len(misses) == 0 {
+				pt.Done++
+			} else if latest {
+				p.Remaining[res.Target] = misses
+			}
+		}
+		if pt.Targets > 0 {
+			pt.Percent = 100 * float64(pt.Done) / float64(pt.Targets)
+		}
+		p.Points = append(p.Points, pt)
+	}
+	if len(p.Points) == 0 {
+		return p
+	}
+	first, last := p.Points[0], p.Points[len(p.Points)-1]
+	if last.Targets > 0 && last.Done == last.Targets {
+		p.OnTrack = true
+		return p
+	}
+	elapsed := last.Time.Sub(first.Time)
+	gained := last.Percent - first.Percent
+	if elapsed <= 0 || gained <= 0 {
+		return p
+	}
+	rest := time.Duration(float64(elapsed) * (100 - last.Percent) / gained)
+	p.Projected = last.Time.Add(rest).Round(time.Second)
+	p.OnTrack = deadline.IsZero() || !p.Projected.After(deadline)
+	return p
+}
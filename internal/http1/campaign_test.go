@@ -0,0 +1,52 @@
+package http1
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrackCampaign(t *testing.T) {
+	goal, err := ParseGoal("", "h2", "h1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := func(target string, versions ...string) CheckResult {
+		res := CheckResult{Target: target, Grade: "C"}
+		for _, v := range versions {
+			res.Results = append(res.Results, VersionResult{Version: v, Supported: true})
+		}
+		return res
+	}
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 0, 0, 0, 0, time.UTC) }
+	snapshots := []Snapshot{
+		// Out of order on purpose: TrackCampaign sorts by time.
+		{Time: day(11), Results: []CheckResult{host("a", "HTTP/2.0"), host("b", "HTTP/2.0"), host("c", "HTTP/1.1"), host("d", "HTTP/2.0", "HTTP/1.0")}},
+		{Time: day(1), Results: []CheckResult{host("a", "HTTP/2.0"), host("b", "HTTP/1.1"), host("c", "HTTP/1.1"), {Target: "d"}}},
+	}
+	p := TrackCampaign(goal, snapshots, day(30))
+	if len(p.Points) != 2 || p.Points[0].Percent != 25 || p.Points[1].Percent != 50 {
+		t.Fatalf("points = %+v, want 25%% then 50%%", p.Points)
+	}
+	if !slices.Equal(p.Remaining["c"], []string{"HTTP/2.0 is not supported"}) || !slices.Equal(p.Remaining["d"], []string{"HTTP/1.0 is still supported"}) {
+		t.Errorf("remaining = %v", p.Remaining)
+	}
+	// 25 points in 10 days leaves 20 more days for the last 50.
+	if !p.Projected.Equal(day(31)) || p.OnTrack {
+		t.Errorf("projected %v, on track %v; want May 1 and behind", p.Projected, p.OnTrack)
+	}
+	if p := TrackCampaign(goal, snapshots, time.Time{}); !p.OnTrack {
+		t.Error("not on track without a deadline")
+	}
+
+	done := TrackCampaign(goal, []Snapshot{{Time: day(1), Results: []CheckResult{host("a", "HTTP/2.0")}}}, day(2))
+	if !done.OnTrack || !done.Projected.IsZero() || len(done.Remaining) != 0 {
+		t.Errorf("finished campaign = %+v", done)
+	}
+
+	for _, bad := range [][3]string{{"", "", ""}, {"", "h2", "h2"}, {"", "", "h4"}} {
+		if _, err := ParseGoal(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("ParseGoal(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package http1
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// CertChange reports that a host served a different leaf certificate than
+// when it was last seen.
+type CertChange struct {
+	PreviousSHA256 string `json:"previous_sha256"`
+	PreviousIssuer string `json:"previous_issuer,omitempty"`
+	// IssuerChanged is set when the new certificate also comes from a
+	// different CA, which a routine renewal does not do.
+	IssuerChanged bool   `json:"issuer_changed"`
+	Detail        string `json:"detail"`
+}
+
+// CertTracker remembers the leaf certificate each host:port served, so
+// later scans can flag a change. The fingerprints come from the probes'
+// own handshakes; tracking costs no extra connections. A CertTracker is
+// safe for concurrent use.
+type CertTracker struct {
+	mu   sync.Mutex
+	seen map[string]certSighting
+}
+
+type certSighting struct {
+	sha256, issuer string
+}
+
+// NewCertTracker returns an empty CertTracker.
+func NewCertTracker() *CertTracker {
+	return &CertTracker{seen: make(map[string]certSighting)}
+}
+
+// Remember records the certificate in res without flagging anything, e.g.
+// to prime the tracker with the results of an earlier run.
+func (t *CertTracker) Remember(res CheckResult) {
+	if key := certKey(res); key != "" && res.CertSHA256 != "" {
+		t.mu.Lock()
+		t.seen[key] = certSighting{res.CertSHA256, res.CertIssuer}
+		t.mu.Unlock()
+	}
+}
+
+// Result returns a copy of res with CertChange set if its host last served
+// a different certificate, and remembers the one in res.
+func (t *CertTracker) Result(res CheckResult) CheckResult {
+	key := certKey(res)
+	if key == "" || res.CertSHA256 == "" {
+		return res
+	}
+	t.mu.Lock()
+	prev, ok := t.seen[key]
+	t.seen[key] = certSighting{res.CertSHA256, res.CertIssuer}
+	t.mu.Unlock()
+	if !ok || prev.sha256 == res.CertSHA256 {
+		return res
+	}
+	change := &CertChange{PreviousSHA256: prev.sha256, PreviousIssuer: prev.issuer}
+	change.IssuerChanged = prev.issuer != "" && prev.issuer != res.CertIssuer
+	if change.IssuerChanged {
+		change.Detail = fmt.Sprintf("certificate and issuing CA changed since the last scan (was issued by %s)", prev.issuer)
+	} else {
+		change.Detail = "certificate changed since the last scan (same issuing CA)"
+	}
+	res.CertChange = change
+	res.Warnings = append(slices.Clip(res.Warnings), Warning{WarningCertChanged, change.Detail})
+	return res
+}
+
+// certKey is the host:port a result's certificate belongs to.
+func certKey(res CheckResult) string {
+	u, err := url.Parse(res.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,29 @@
+package http1
+
+import "testing"
+
+func TestCertTracker(t *testing.T) {
+	scan := func(sha, issuer string) CheckResult {
+		return CheckResult{URL: "https://example.com:443/", CertSHA256: sha, CertIssuer: issuer}
+	}
+	tr := NewCertTracker()
+	tr.Remember(scan("aa", "CN=R10"))
+
+	if got := tr.Result(scan("aa", "CN=R10")); got.CertChange != nil {
+		t.Errorf("unchanged certificate flagged: %+v", got.CertChange)
+	}
+	got := tr.Result(scan("bb", "CN=R10"))
+	if got.CertChange == nil || got.CertChange.PreviousSHA256 != "aa" || got.CertChange.IssuerChanged {
+		t.Errorf("renewal: CertChange = %+v, want previous aa from the same issuer", got.CertChange)
+	}
+	if len(got.Warnings) != 1 || got.Warnings[0].Code != WarningCertChanged {
+		t.Errorf("renewal: Warnings = %+v, want one %s", got.Warnings, WarningCertChanged)
+	}
+	got = tr.Result(scan("cc", "CN=Other CA"))
+	if got.CertChange == nil || got.CertChange.PreviousSHA256 != "bb" || !got.CertChange.IssuerChanged {
+		t.Errorf("new CA: CertChange = %+v, want previous bb and issuer changed", got.CertChange)
+	}
+	if got := tr.Result(CheckResult{URL: "https://example.com:443/"}); got.CertChange != nil {
+		t.Errorf("failed TLS flagged: %+v", got.CertChange)
+	}
+}
@@ -0,0 +1,68 @@
+package http1
+
+import (
+	"net/http"
+	"strings"
+)
+
+// challengeNote is appended to the detail of versions whose response was a
+// bot challenge rather than the site's content.
+const challengeNote = " (content gated by a bot challenge)"
+
+// challengeBodyMarkers map lower-case fragments of challenge interstitials
+// to the provider serving them.
+var challengeBodyMarkers = []struct {
+	fragment, provider string
+}{
+	{"/cdn-cgi/challenge-platform/", "Cloudflare"},
+	{"cf-chl-", "Cloudflare"},
+	{"_incapsula_resource", "Imperva"},
+	{"px-captcha", "PerimeterX"},
+	{"captcha-delivery.com", "DataDome"},
+	{"awswaf", "AWS WAF"},
+}
+
+// challengeStatus reports whether status is one challenge pages are served
+// with, so only those responses need their body sampled.
+func challengeStatus(status int) bool {
+	switch status {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// detectChallenge returns the provider of the bot challenge resp is, or "".
+// Headers that announce a challenge are trusted on any status; body markers
+// only on the statuses challenges are served with, since ordinary pages
+// embed the same scripts.
+func detectChallenge(resp *http.Response, page string) string {
+	if strings.EqualFold(resp.Header.Get("Cf-Mitigated"), "challenge") {
+		return "Cloudflare"
+	}
+	switch strings.ToLower(resp.Header.Get("X-Amzn-Waf-Action")) {
+	case "challenge", "captcha":
+		return "AWS WAF"
+	}
+	if !challengeStatus(resp.StatusCode) {
+		return ""
+	}
+	lower := strings.ToLower(page)
+	for _, m := range challengeBodyMarkers {
+		if strings.Contains(lower, m.fragment) {
+			return m.provider
+		}
+	}
+	return ""
+}
+
+// challengeProvider returns the provider of the first challenge among res's
+// versions, or "".
+func challengeProvider(res CheckResult) string {
+	for _, vr := range res.Results {
+		if vr.Challenge != "" {
+			return vr.Challenge
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package http1
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDetectChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		page   string
+		want   string
+	}{
+		{"cloudflare header", 403, http.Header{"Cf-Mitigated": {"challenge"}}, "", "Cloudflare"},
+		{"aws waf header", 202, http.Header{"X-Amzn-Waf-Action": {"captcha"}}, "", "AWS WAF"},
+		{"cloudflare body", 503, nil, `<script src="/cdn-cgi/challenge-platform/h/g/orchestrate/jsch/v1"></script>`, "Cloudflare"},
+		{"datadome body", 403, nil, `<iframe src="https://geo.captcha-delivery.com/captcha/"></iframe>`, "DataDome"},
+		{"marker on a 200 page", 200, nil, `<script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script>`, ""},
+		{"plain 403", 403, nil, "Forbidden", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: tt.header}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			if got := detectChallenge(resp, tt.page); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryLineChallenge(t *testing.T) {
+	res := CheckResult{
+		Target: "example.com",
+		Port:   "443",
+		Grade:  "A",
+		Score:  95,
+		Results: []VersionResult{
+			{Version: "HTTP/2.0", Supported: true, Detail: "supported" + challengeNote, Challenge: "Cloudflare"},
+		},
+	}
+	if got := SummaryLine(res); !strings.HasSuffix(got, "\tcontent gated by a Cloudflare challenge") {
+		t.Errorf("SummaryLine = %q", got)
+	}
+	tr, _ := NewTranslator("de")
+	if got := tr.Result(res).Results[0].Detail; got != "unterstützt (Inhalt hinter einer Bot-Abfrage)" {
+		t.Errorf("translated detail = %q", got)
+	}
+}
@@ -2,7 +2,10 @@ package http1
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -11,11 +14,11 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/quic-go/quic-go/http3"
-	"golang.org/x/net/http2"
 )
 
+// maxWorkers caps the number of targets scanned concurrently.
+const maxWorkers = 64
+
 const (
 	h1Timeout = 2 * time.Second
 	h2Timeout = 2 * time.Second
@@ -23,8 +26,12 @@ const (
 )
 
 // normalizeURL ensures the input has a scheme and host and defaults to https.
+// Unicode host names are converted to punycode.
 func normalizeURL(raw string) (string, error) {
-	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+	if raw == "" {
+		return "", ErrEmptyTarget
+	}
+	if lower := strings.ToLower(raw); !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
 		raw = "https://" + raw
 	}
 	u, err := url.Parse(raw)
@@ -35,40 +42,228 @@ func normalizeURL(raw string) (string, error) {
 		u.Scheme = "https"
 	}
 	if u.Host == "" {
-		return "", fmt.Errorf("missing host in URL")
+		return "", ErrMissingHost
+	}
+	host, err := asciiHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	if host != u.Hostname() {
+		if port := u.Port(); port != "" {
+			u.Host = net.JoinHostPort(host, port)
+		} else {
+			u.Host = host
+		}
 	}
 	return u.String(), nil
 }
 
+// requestPath is the path and query u requests, or "" for the root.
+func requestPath(u *url.URL) string {
+	p := u.RequestURI()
+	if p == "/" {
+		return ""
+	}
+	return p
+}
+
+// plainHTTPURL rewrites u to http://host:port, keeping its path and query so
+// the plain-HTTP probe requests the same resource as the others.
+func plainHTTPURL(u *url.URL, host, port string) string {
+	p := *u
+	p.Scheme = "http"
+	p.Host = net.JoinHostPort(host, port)
+	p.User = nil
+	p.Fragment = ""
+	return p.String()
+}
+
 // VersionResult captures the outcome for a single HTTP version.
 type VersionResult struct {
 	Version   string `json:"version"`
 	Supported bool   `json:"supported"`
 	Detail    string `json:"detail,omitempty"`
 	Error     bool   `json:"error,omitempty"`
+	// ErrorKind classifies why the probe failed, or why the server would
+	// not speak the version (ErrorKindALPNMismatch); empty when it
+	// succeeded.
+	ErrorKind ErrorKind `json:"error_kind,omitempty"`
 	// Evidence optionally contains a short string explaining why a version is
 	// (or is not) supported; used mainly for UI tooltips.
 	Evidence string `json:"evidence,omitempty"`
+	// Unreliable marks a negative finding the scanner's own network may
+	// explain, e.g. HTTP/3 "not supported" after QUIC calibration failed.
+	Unreliable bool `json:"unreliable,omitempty"`
+	// Annotations are markers found in the response body with
+	// Options.SampleBodies, e.g. "HTML meta refresh to HTTPS".
+	Annotations []string `json:"annotations,omitempty"`
+	// Challenge names the bot-challenge provider (e.g. "Cloudflare") when
+	// the response was an interstitial rather than the site's content. The
+	// protocol still counts as supported, but status codes and content say
+	// nothing about the site itself.
+	Challenge string `json:"challenge,omitempty"`
+	// Attempts is how many times the probe was sent with Options.Retries
+	// set; Evidence then says which attempt succeeded.
+	Attempts int `json:"attempts,omitempty"`
+	// DurationMS is how long the probe's last attempt took to get response
+	// headers or fail, in milliseconds.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+	// Timings breaks DurationMS down into DNS, connect, TLS and
+	// time-to-first-byte.
+	Timings *ProbeTimings `json:"timings,omitempty"`
+	// NotTested marks a version that was not probed: one left out with
+	// Options.Versions, or HTTP/3 in a build made with the noh3 tag.
+	// Supported is then meaningless.
+	NotTested bool `json:"not_tested,omitempty"`
+
+	// status is the HTTP status of the response, if any.
+	status int
+	// server is the response's Server header, and stack the server stack
+	// its headers point at.
+	server, stack string
+	// altSvc is the response's Alt-Svc header.
+	altSvc string
 }
 
 // CheckResult is the full structured result for a run.
 type CheckResult struct {
-	Target     string          `json:"target"`
-	URL        string          `json:"url"`
-	Port       string          `json:"port"`
-	Results    []VersionResult `json:"results"`
-	Score      int             `json:"score"`
-	Grade      string          `json:"grade"`
-	ALPN       string          `json:"alpn,omitempty"`
-	TLSVersion string          `json:"tls_version,omitempty"`
+	Target  string          `json:"target"`
+	Vantage string          `json:"vantage,omitempty"`
+	URL     string          `json:"url"`
+	Port    string          `json:"port"`
+	Results []VersionResult `json:"results"`
+	Score   int             `json:"score"`
+	Grade   string          `json:"grade"`
+	// GradeReasons explain Grade and Score: why the grade's letter, then
+	// each adjustment to the score.
+	GradeReasons []GradeReason `json:"grade_reasons,omitempty"`
+	ALPN         string        `json:"alpn,omitempty"`
+	TLSVersion   string        `json:"tls_version,omitempty"`
+	// Path is the path and query every probe requested, e.g.
+	// "/api/v1/health?full=1", when the target named one other than "/".
+	Path string `json:"path,omitempty"`
+	// HostUnicode and HostASCII are both forms of an internationalized
+	// target host, e.g. "bücher.example" and "xn--bcher-kva.example".
+	HostUnicode string `json:"host_unicode,omitempty"`
+	HostASCII   string `json:"host_ascii,omitempty"`
+	// Labels are the words given after the target in a targets list, e.g.
+	// "prod" and "eu" for "example.com prod eu".
+	Labels []string `json:"labels,omitempty"`
+	// ScannedAt is when the probes started, to the second; results from
+	// other sources, such as imported scan data, leave it zero.
+	ScannedAt time.Time `json:"scanned_at,omitzero"`
+	// Notes are the remarks kept on the target in a Notebook, e.g. why a
+	// legacy appliance is exempt from the policy.
+	Notes []Note `json:"notes,omitempty"`
+	// KeyExchange is "X25519MLKEM768" when the server accepts the hybrid
+	// post-quantum group, "classical" when TLS works but it does not. It is
+	// set with Options.CheckKeyExchange.
+	KeyExchange string `json:"key_exchange,omitempty"`
+	// CertIssuer is the distinguished name of the issuer of the certificate
+	// served to the HTTP/2 probe, and CertSHA256 its SHA-256 fingerprint.
+	CertIssuer string `json:"cert_issuer,omitempty"`
+	CertSHA256 string `json:"cert_sha256,omitempty"`
+	// CertChange is set by a CertTracker when the certificate differs from
+	// the one the host served before.
+	CertChange *CertChange `json:"cert_change,omitempty"`
+	// TLS details the handshakes: cipher suite, key exchange group, ALPN
+	// per probe, session resumption and a certificate summary.
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// QUICVersions lists the QUIC versions accepted when HTTP/3 works,
+	// with Options.CheckQUICVersions.
+	QUICVersions []string `json:"quic_versions,omitempty"`
+	// EarlyData reports session resumption and QUIC 0-RTT support, with
+	// Options.CheckEarlyData.
+	EarlyData *EarlyDataResult `json:"early_data,omitempty"`
+	// H2Settings is the server's initial HTTP/2 SETTINGS frame, with
+	// Options.CheckH2Settings.
+	H2Settings *H2Settings `json:"h2_settings,omitempty"`
+	// H2Origin reports, with Options.CheckH2Settings, whether the server
+	// sent an HTTP/2 ORIGIN frame and the origins it listed.
+	H2Origin *OriginFrame `json:"h2_origin,omitempty"`
+	// ExtendedConnect reports WebSocket-style Extended CONNECT support,
+	// with Options.CheckExtendedConnect.
+	ExtendedConnect *ExtendedConnectSupport `json:"extended_connect,omitempty"`
+	// ECH is set with Options.CheckECH.
+	ECH *ECHResult `json:"ech,omitempty"`
+	// Compression reports the content codings used on the h1/h2 probes.
+	Compression *CompressionResult `json:"compression,omitempty"`
+	// HTTPSRedirect describes the plain-HTTP probe's answer; HSTS is the
+	// Strict-Transport-Security policy seen on HTTPS.
+	HTTPSRedirect *HTTPSRedirect `json:"https_redirect,omitempty"`
+	HSTS          *HSTSResult    `json:"hsts,omitempty"`
+	// UDPBuffer is set when this host's UDP buffers are too small for
+	// QUIC, which can turn HTTP/3 support into a false negative.
+	UDPBuffer *UDPBufferReport `json:"udp_buffer,omitempty"`
+	// With Options.FollowRedirects, RedirectChain lists the redirects from
+	// Target and FinalTarget is the URL the rest of the result describes.
+	RedirectChain []RedirectHop `json:"redirect_chain,omitempty"`
+	FinalTarget   string        `json:"final_target,omitempty"`
+	RedirectError string        `json:"redirect_error,omitempty"`
+	// SNI is the server name presented instead of the target host, when
+	// Options.SNI overrides it.
+	SNI string `json:"sni,omitempty"`
+	// HostHeader is the Host / :authority sent instead of the target host,
+	// when Options.HostHeader overrides it.
+	HostHeader string `json:"host_header,omitempty"`
+	// DNS reports the time the target's host took to resolve and the
+	// resolver that answered; it is nil for IP address targets.
+	DNS *DNSTiming `json:"dns,omitempty"`
+	// CNAMEChain lists the aliases the target host resolves through, e.g.
+	// ["example.cdn.net", "edge.cdn.net"], which usually names the CDN or
+	// provider that terminates connections and controls protocol support.
+	CNAMEChain []string `json:"cname_chain,omitempty"`
+	// ReverseDNS lists the PTR names of an IP address target, e.g. one
+	// expanded from an address range.
+	ReverseDNS []string `json:"reverse_dns,omitempty"`
+	// Server is the Server header of the probe responses, and Stack the
+	// server or CDN terminating the connection as fingerprinted from the
+	// headers and CNAME chain, e.g. "nginx" or "CloudFront".
+	Server string `json:"server,omitempty"`
+	Stack  string `json:"stack,omitempty"`
+	// Parking is set with Options.DetectParking.
+	Parking *ParkingResult `json:"parking,omitempty"`
+	// SNIMismatch is set with Options.CheckSNIMismatch.
+	SNIMismatch *SNIMismatchResult `json:"sni_mismatch,omitempty"`
+	// Coalescing is set with Options.CheckCoalescing when HTTP/2 works.
+	Coalescing *CoalescingResult `json:"coalescing,omitempty"`
+	// DualStack is set with Options.DualStack.
+	DualStack *DualStackResult `json:"dual_stack,omitempty"`
+	// CrossCheck is set with Options.CrossCheck.
+	CrossCheck *CrossCheckResult `json:"cross_check,omitempty"`
+	// AltSvc is set when an HTTPS probe's response carried Alt-Svc and
+	// HTTP/3 was probed.
+	AltSvc *AltSvcResult `json:"alt_svc,omitempty"`
+	// DNSSEC is set with Options.CheckDNSSEC.
+	DNSSEC *DNSSECResult `json:"dnssec,omitempty"`
+	// Proxied is true when the TCP probes went through Options.Proxy; the
+	// HTTP/3 probe still went direct.
+	Proxied bool `json:"proxied,omitempty"`
+	// QUICCalibration is set when Options.QUICCalibration failed, i.e. the
+	// scanner could not reach any HTTP/3 reference host.
+	QUICCalibration *QUICCalibration `json:"quic_calibration,omitempty"`
+	// Warnings are heads-ups that are not probe outcomes, such as
+	// undersized UDP buffers, a failed QUIC calibration, rate limiting or a
+	// certificate close to expiry.
+	Warnings []Warning `json:"warnings,omitempty"`
+	// ProbeErrors lists probes that panicked or hung past the watchdog.
+	ProbeErrors []ProbeError `json:"probe_errors,omitempty"`
+	// Unscanned is true when the scan was cancelled, or Options.Deadline
+	// passed, before the target's probes could finish, or start; the result
+	// holds no findings.
+	Unscanned bool `json:"unscanned,omitempty"`
 }
 
 // statusEmoji maps a VersionResult to a simple emoji for quick visual scanning.
-// ✅ = supported, ❌ = not supported, 🟧 = error / probe failed.
+// ✅ = supported, ❌ = not supported, 🟧 = error / probe failed,
+// ➖ = not tested.
 func statusEmoji(vr VersionResult) string {
 	if vr.Supported {
 		return "✅"
 	}
+	if vr.NotTested {
+		return "➖"
+	}
 	if vr.Error {
 		return "🟧"
 	}
@@ -77,14 +272,56 @@ func statusEmoji(vr VersionResult) string {
 
 // runChecks performs the actual HTTP version checks and returns a structured result.
 // It does not print anything, so it can be used for both text and JSON output.
-func runChecks(target string, overridePort string) CheckResult {
+func runChecks(target string, opts Options) CheckResult {
+	opts = opts.forRun()
+	defer opts.endRun()
+	return checkTarget(target, opts, nil)
+}
+
+// checkTarget is runChecks with optional transports shared across targets
+// (low-resource mode); with shared nil it builds its own.
+func checkTarget(target string, opts Options, shared *probeTransports) CheckResult {
+	opts = opts.forRun()
+	if opts.stopped() {
+		return unscannedResult(target, opts, "not scanned: "+opts.stopReason()+" first")
+	}
+	if opts.checkTimeout > 0 {
+		ctx, cancel := context.WithTimeout(opts.context(), opts.checkTimeout)
+		defer cancel()
+		opts = opts.withContext(ctx)
+		opts.checkTimeout = 0
+	}
+	if opts.FollowRedirects {
+		return checkFinalTarget(target, opts, shared)
+	}
+	if opts.CrossCheck != "" {
+		return checkCrossCheck(target, opts, shared)
+	}
+	if opts.DualStack {
+		return checkDualStack(target, opts, shared)
+	}
+	rtt := newRTTTracker()
+	return guardCheck(target, opts, watchdogTimeout(opts.Retries), rtt, func() CheckResult {
+		return probeTarget(target, opts, shared, rtt)
+	})
+}
+
+// probeTarget runs every probe against one target, timing them with rtt.
+func probeTarget(target string, opts Options, shared *probeTransports, rtt *rttTracker) CheckResult {
+	overridePort := opts.Port
 	res := CheckResult{
-		Target:  target,
-		Results: make([]VersionResult, 0, 4),
+		Target:    target,
+		Vantage:   opts.Vantage,
+		ScannedAt: time.Now().UTC().Truncate(time.Second),
+		Results:   make([]VersionResult, 0, 4),
 	}
 
-	norm, err := normalizeURL(target)
+	t, err := ParseTarget(target)
 	if err != nil {
+		var te *TargetError
+		if errors.As(err, &te) && te.Target != "" {
+			res.Target, err = te.Target, te.Err
+		}
 		res.Results = append(res.Results, VersionResult{
 			Version:   "error",
 			Supported: false,
@@ -93,31 +330,24 @@ func runChecks(target string, overridePort string) CheckResult {
 		})
 		return res
 	}
-
-	// Work out the TCP/UDP port we are targeting.
-	u, err := url.Parse(norm)
-	if err != nil {
+	res.Target = t.Raw
+	res.Labels = t.Labels
+	if t.Range.IsValid() {
 		res.Results = append(res.Results, VersionResult{
-			Version:   "error",
-			Supported: false,
-			Error:     true,
-			Detail:    fmt.Sprintf("invalid URL after normalization: %v", err),
+			Version: "error",
+			Error:   true,
+			Detail:  ErrUnexpandedRange.Error(),
 		})
 		return res
 	}
+	// ParseTarget already parsed t.URL.
+	u, _ := url.Parse(t.URL)
 
-	// If the user supplied a port flag, that takes precedence.
+	// Work out the TCP/UDP port we are targeting. If the user supplied a
+	// port flag, that takes precedence.
 	port := overridePort
 	if port == "" {
-		port = u.Port()
-	}
-	if port == "" {
-		if u.Scheme == "http" {
-			port = "80"
-		} else {
-			// Default HTTPS / QUIC port
-			port = "443"
-		}
+		port = t.Port
 	}
 	res.Port = port
 
@@ -129,8 +359,11 @@ func runChecks(target string, overridePort string) CheckResult {
 	if host != "" {
 		u.Host = net.JoinHostPort(host, port)
 	}
+	// Path and query are kept so every probe requests the same resource.
+	u.Fragment = ""
 	urlWithPort := u.String()
 	res.URL = urlWithPort
+	res.Path = requestPath(u)
 
 	// For HTTP/1.0, many servers only support plain HTTP on port 80.
 	// Use http://host:portForH10 where portForH10 defaults to 80 unless overridden.
@@ -140,65 +373,112 @@ func runChecks(target string, overridePort string) CheckResult {
 	}
 	http10URL := urlWithPort
 	if host != "" {
-		http10URL = "http://" + net.JoinHostPort(host, http10Port)
+		http10URL = plainHTTPURL(u, host, http10Port)
 	}
 
-	// Shared TLS config and clients per target.
-	// We use separate TLS configs for HTTP/1.x and HTTP/2 so that HTTP/1.x
-	// probes never accidentally negotiate HTTP/2 via ALPN (which would cause
-	// "malformed HTTP response" errors when parsed as HTTP/1.x).
-	baseTLS := &tls.Config{
-		InsecureSkipVerify: true,
-	}
+	res.HostUnicode, res.HostASCII = idnForms(host)
+	serverName := opts.serverName(host)
+	res.SNI = opts.SNI
+	res.HostHeader = opts.HostHeader
+	res.Proxied = opts.proxyFor(urlWithPort) != nil
 
-	h1TLS := *baseTLS
-	h1TLS.NextProtos = []string{"http/1.1"}
-	h1Transport := &http.Transport{
-		ForceAttemptHTTP2: false,
-		TLSClientConfig:   &h1TLS,
-	}
-	h1Client := &http.Client{
-		Timeout:   h1Timeout,
-		Transport: h1Transport,
+	base := opts.baseContext()
+	res.DNS = timeDNS(base, host)
+	pt := shared
+	if pt == nil {
+		var err error
+		pt, err = newProbeTransports(opts)
+		if err != nil {
+			res.Results = append(res.Results, VersionResult{
+				Version: "error",
+				Error:   true,
+				Detail:  fmt.Sprintf("setting up transports: %v", err),
+			})
+			return res
+		}
 	}
+	defer pt.done(shared != nil)
+	h1Client, h2Client, h3Client := pt.h1, pt.h2, pt.h3
+	h2TLS, h3TLS, dial := pt.h2TLS, pt.h3TLS, pt.dial
 
-	h2TLS := *baseTLS
-	h2TLS.NextProtos = []string{"h2", "http/1.1"}
-	h2Transport := &http.Transport{
-		TLSClientConfig: &h2TLS,
-	}
-	// Enable HTTP/2 on this transport so that when servers speak h2 via ALPN
-	// we parse the response correctly as HTTP/2 instead of HTTP/1.x.
-	_ = http2.ConfigureTransport(h2Transport)
-	h2Client := &http.Client{
-		Timeout:   h2Timeout,
-		Transport: h2Transport,
+	results := make([]VersionResult, 4)
+	var hasH2, hasH3 bool
+	var tlsProto, alpn, certIssuer, certSHA256 string
+	var certNotAfter time.Time
+	var ech *ECHResult
+	var hasPQ bool
+	var quicVersions []string
+	var tlsResumed, quic0RTT bool
+	var h2Settings *H2Settings
+	var h2Origin *OriginFrame
+	var h2Connect, h3Connect *ExtendedConnectResult
+	var h11Encoding, h2Encoding string
+	var h11State, h2State, h3State *tls.ConnectionState
+	var redirect *HTTPSRedirect
+	var h11HSTS, h2HSTS *HSTSResult
+	var parking *ParkingResult
+	var dnssec *DNSSECResult
+	var mismatch *SNIMismatchResult
+	var coalescing *CoalescingResult
+	var cnames, ptr []string
+	var guard probeGuard
+	var wg sync.WaitGroup
+	wg.Add(5)
+
+	if opts.DetectParking {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard.catch("parking", nil)
+			ctx, cancel := rtt.probeContext(base, parkingTimeout)
+			defer cancel()
+			parking = probeParking(ctx, h2Client, opts, host, urlWithPort)
+		}()
 	}
 
-	h3Transport := &http3.Transport{
-		TLSClientConfig: &tls.Config{
-			NextProtos:         []string{http3.NextProtoH3},
-			InsecureSkipVerify: true,
-		},
+	if net.ParseIP(host) != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard.catch("reverse_dns", nil)
+			ctx, cancel := rtt.probeContext(base, dnsTimeout)
+			defer cancel()
+			ptr = lookupReverseDNS(ctx, host)
+		}()
 	}
-	defer h3Transport.Close()
 
-	h3Client := &http.Client{
-		Transport: h3Transport,
-		Timeout:   h3Timeout,
+	if opts.CheckDNSSEC {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard.catch("dnssec", nil)
+			ctx, cancel := rtt.probeContext(base, dnsTimeout)
+			defer cancel()
+			dnssec = probeDNSSEC(ctx, host)
+		}()
 	}
 
-	results := make([]VersionResult, 4)
-	var hasH2, hasH3 bool
-	var tlsProto, alpn string
-	var wg sync.WaitGroup
-	wg.Add(4)
+	if opts.CheckSNIMismatch && u.Scheme == "https" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard.catch("sni_mismatch", nil)
+			ctx, cancel := rtt.probeContext(base, 2*h2Timeout)
+			defer cancel()
+			mismatch = probeSNIMismatch(ctx, dial, opts, urlWithPort, host, serverName, port)
+		}()
+	}
 
 	// 1) HTTP/1.0
 	go func() {
 		defer wg.Done()
+		defer guard.catch("HTTP/1.0", &results[0])
+		if !opts.probes("HTTP/1.0") {
+			results[0] = skippedVersion("HTTP/1.0")
+			return
+		}
 		v10 := VersionResult{Version: "HTTP/1.0"}
-		req10, err := http.NewRequest("GET", http10URL, nil)
+		req10, err := http.NewRequest(opts.method(), http10URL, nil)
 		if err != nil {
 			v10.Error = true
 			v10.Detail = "request build failed"
@@ -206,13 +486,19 @@ func runChecks(target string, overridePort string) CheckResult {
 			req10.Proto = "HTTP/1.0"
 			req10.ProtoMajor = 1
 			req10.ProtoMinor = 0
+			opts.prepareRequest(req10)
 
-			resp10, err := h1Client.Do(req10)
+			resp10, err := rtt.doWithRetries(base, h1Timeout, opts.Retries, h1Client, req10, &v10)
 			if err != nil {
 				v10.Error = true
 				v10.Detail = fmt.Sprintf("not supported (or probe failed): %v", err)
+				v10.ErrorKind = errorKind(err)
 			} else {
 				defer resp10.Body.Close()
+				inspectBody(&v10, resp10, opts)
+				if resp10.TLS == nil {
+					redirect = httpsRedirectFrom(resp10)
+				}
 				// If the server speaks any HTTP/1.x in response to a 1.0 request,
 				// we treat that as HTTP/1.0 support, even if it replies with 1.1.
 				if resp10.ProtoMajor == 1 {
@@ -233,8 +519,13 @@ func runChecks(target string, overridePort string) CheckResult {
 	// 2) HTTP/1.1
 	go func() {
 		defer wg.Done()
+		defer guard.catch("HTTP/1.1", &results[1])
+		if !opts.probes("HTTP/1.1") {
+			results[1] = skippedVersion("HTTP/1.1")
+			return
+		}
 		v11 := VersionResult{Version: "HTTP/1.1"}
-		req11, err := http.NewRequest("GET", urlWithPort, nil)
+		req11, err := http.NewRequest(opts.method(), urlWithPort, nil)
 		if err != nil {
 			v11.Error = true
 			v11.Detail = "request build failed"
@@ -242,13 +533,20 @@ func runChecks(target string, overridePort string) CheckResult {
 			req11.Proto = "HTTP/1.1"
 			req11.ProtoMajor = 1
 			req11.ProtoMinor = 1
+			setAcceptEncoding(req11)
+			opts.prepareRequest(req11)
 
-			resp11, err := h1Client.Do(req11)
+			resp11, err := rtt.doWithRetries(base, h1Timeout, opts.Retries, h1Client, req11, &v11)
 			if err != nil {
 				v11.Error = true
 				v11.Detail = fmt.Sprintf("not supported (or probe failed): %v", err)
+				v11.ErrorKind = errorKind(err)
 			} else {
 				defer resp11.Body.Close()
+				inspectBody(&v11, resp11, opts)
+				h11Encoding = responseEncoding(resp11)
+				h11State = resp11.TLS
+				h11HSTS = hstsFrom(resp11)
 				if resp11.ProtoMajor == 1 && resp11.ProtoMinor == 1 {
 					v11.Supported = true
 					v11.Detail = "supported"
@@ -263,14 +561,30 @@ func runChecks(target string, overridePort string) CheckResult {
 	// 3) HTTP/2.0 (best-effort: let TLS ALPN negotiate)
 	go func() {
 		defer wg.Done()
+		defer guard.catch("HTTP/2.0", &results[2])
+		if !opts.probes("HTTP/2.0") {
+			results[2] = skippedVersion("HTTP/2.0")
+			return
+		}
 		v2 := VersionResult{Version: "HTTP/2.0"}
-		resp2, err := h2Client.Get(urlWithPort)
+		var resp2 *http.Response
+		req2, err := http.NewRequest(opts.method(), urlWithPort, nil)
+		if err == nil {
+			setAcceptEncoding(req2)
+			opts.prepareRequest(req2)
+			resp2, err = rtt.doWithRetries(base, h2Timeout, opts.Retries, h2Client, req2, &v2)
+		}
 		if err != nil {
 			v2.Error = true
 			v2.Detail = fmt.Sprintf("not supported (or probe failed): %v", err)
+			v2.ErrorKind = errorKind(err)
 		} else {
 			defer resp2.Body.Close()
+			inspectBody(&v2, resp2, opts)
+			h2Encoding = responseEncoding(resp2)
+			h2HSTS = hstsFrom(resp2)
 			cs := resp2.TLS
+			h2State = cs
 			if cs != nil {
 				switch cs.Version {
 				case tls.VersionTLS13:
@@ -285,13 +599,52 @@ func runChecks(target string, overridePort string) CheckResult {
 					tlsProto = ""
 				}
 				alpn = cs.NegotiatedProtocol
+				if len(cs.PeerCertificates) > 0 {
+					certIssuer = cs.PeerCertificates[0].Issuer.String()
+					certNotAfter = cs.PeerCertificates[0].NotAfter
+					sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+					certSHA256 = hex.EncodeToString(sum[:])
+				}
 			}
 			if resp2.ProtoMajor == 2 {
 				v2.Supported = true
 				v2.Detail = "supported"
 				hasH2 = true
+
+				// With CheckEarlyData, resume the session we just
+				// established on a fresh connection.
+				if opts.CheckEarlyData {
+					ctxRes, cancelRes := rtt.probeContext(base, h2Timeout)
+					tlsResumed, _ = probeTLSResumption(ctxRes, h2TLS, dial, urlWithPort, opts)
+					cancelRes()
+				}
+
+				// With CheckH2Settings, capture the server's SETTINGS and
+				// ORIGIN frames on a raw h2 connection and, with
+				// CheckExtendedConnect, try Extended CONNECT (RFC 8441) on
+				// it if it is enabled.
+				if opts.CheckH2Settings || opts.CheckExtendedConnect {
+					var connectAuthority string
+					if opts.CheckExtendedConnect {
+						connectAuthority = opts.authority(host, port)
+					}
+					ctxSet, cancelSet := rtt.probeContext(base, h2Timeout)
+					settings, origin, connect, _ := probeH2Session(ctxSet, dial, host, port, connectAuthority, h2TLS)
+					cancelSet()
+					if opts.CheckH2Settings {
+						h2Settings, h2Origin = settings, origin
+					}
+					h2Connect = connect
+				}
+
+				if opts.CheckCoalescing {
+					ctxCo, cancelCo := rtt.probeContext(base, h2Timeout)
+					coalescing = probeCoalescing(ctxCo, dial, opts, host, port, h2TLS)
+					cancelCo()
+				}
 			} else {
 				v2.Detail = fmt.Sprintf("server replied with %s", resp2.Proto)
+				v2.ErrorKind = ErrorKindALPNMismatch
 			}
 		}
 		results[2] = v2
@@ -300,54 +653,226 @@ func runChecks(target string, overridePort string) CheckResult {
 	// 4) HTTP/3.0
 	go func() {
 		defer wg.Done()
+		defer guard.catch("HTTP/3.0", &results[3])
+		if !opts.probes("HTTP/3.0") {
+			results[3] = skippedVersion("HTTP/3.0")
+			return
+		}
 		v3 := VersionResult{Version: "HTTP/3.0"}
-		req3, err := http.NewRequest("GET", urlWithPort, nil)
-		if err != nil {
+		req3, err := http.NewRequest(opts.method(), urlWithPort, nil)
+		if !h3Available {
+			v3.NotTested = true
+			v3.Detail = h3DisabledDetail
+		} else if err != nil {
 			// Building the request itself failed: treat as a hard error.
 			v3.Error = true
 			v3.Detail = "request build failed"
 		} else {
-			ctx3, cancel3 := context.WithTimeout(context.Background(), h3Timeout)
-			defer cancel3()
-			req3 = req3.WithContext(ctx3)
+			opts.prepareRequest(req3)
 
-			resp3, err := h3Client.Do(req3)
+			resp3, err := rtt.doWithRetries(base, h3Timeout, opts.Retries, h3Client, req3, &v3)
 			if err != nil {
 				// In practice, many sites simply don't support HTTP/3 yet, so
 				// QUIC/timeouts are treated as a normal "not supported" case
 				// (❌) instead of an error (🟧).
 				v3.Detail = fmt.Sprintf("not supported (or probe failed): %v", err)
+				v3.ErrorKind = quicErrorKind(err)
 			} else {
 				defer resp3.Body.Close()
+				inspectBody(&v3, resp3, opts)
+				h3State = resp3.TLS
 				if resp3.ProtoMajor == 3 {
 					v3.Supported = true
 					v3.Detail = "supported"
 					hasH3 = true
+
+					// With CheckQUICVersions, follow up with QUIC version
+					// enumeration now that we know the endpoint speaks QUIC.
+					if opts.CheckQUICVersions {
+						ctxVN, cancelVN := rtt.probeContext(base, h3Timeout)
+						quicVersions = probeQUICVersions(ctxVN, pt.quic, host, serverName, port)
+						cancelVN()
+					}
+
+					// With CheckEarlyData, reconnect with the cached ticket
+					// and try a 0-RTT request.
+					if opts.CheckEarlyData {
+						ctx0, cancel0 := rtt.probeContext(base, h3Timeout)
+						quic0RTT, _ = probeQUIC0RTT(ctx0, pt.quic, h3TLS, urlWithPort, opts)
+						cancel0()
+					}
+
+					// Extended CONNECT over HTTP/3 (RFC 9220).
+					if opts.CheckExtendedConnect {
+						ctxEC, cancelEC := rtt.probeContext(base, h3Timeout)
+						r := probeH3ExtendedConnect(ctxEC, pt.quic, h3TLS, host, port, opts.authority(host, port))
+						h3Connect = &r
+						cancelEC()
+					}
 				} else {
 					v3.Detail = fmt.Sprintf("server replied with %s", resp3.Proto)
+					v3.ErrorKind = ErrorKindALPNMismatch
 				}
 			}
 		}
 		results[3] = v3
 	}()
 
+	// 5) Encrypted ClientHello (HTTPS DNS record + ECH handshake)
+	if opts.CheckECH {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard.catch("ech", nil)
+			ctx, cancel := rtt.probeContext(base, echTimeout)
+			defer cancel()
+			r := probeECH(ctx, dial, host, serverName, port)
+			ech = &r
+		}()
+	}
+
+	// 6) Post-quantum hybrid key exchange (only X25519MLKEM768 offered)
+	if opts.CheckKeyExchange && u.Scheme == "https" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer guard.catch("key_exchange", nil)
+			ctx, cancel := rtt.probeContext(base, pqTimeout)
+			defer cancel()
+			hasPQ = probePQKeyExchange(ctx, dial, host, serverName, port)
+		}()
+	}
+
+	// 7) CNAME chain, to show which provider terminates the connection
+	go func() {
+		defer wg.Done()
+		defer guard.catch("cname_chain", nil)
+		ctx, cancel := rtt.probeContext(base, dnsTimeout)
+		defer cancel()
+		cnames, _ = lookupCNAMEChain(ctx, host)
+	}()
+
 	wg.Wait()
 	res.Results = results
+	res.CNAMEChain = cnames
+	res.ReverseDNS = ptr
+	res.Server, res.Stack = serverStack(results, cnames)
+	res.ProbeErrors = guard.errors
+
+	// The HTTP/3 notes below only apply when HTTP/3 was probed.
+	probedH3 := h3Available && opts.probes("HTTP/3.0")
+	if probedH3 && u.Scheme == "https" {
+		res.AltSvc = checkAltSvc(altSvcHeader(results), host, port, hasH3, func(addr string) error {
+			ctx, cancel := rtt.probeContext(base, h3Timeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, opts.method(), urlWithPort, nil)
+			if err != nil {
+				return err
+			}
+			opts.prepareRequest(req)
+			return probeH3At(ctx, pt.quic, h3TLS, addr, req)
+		})
+		if at := res.AltSvc.verifiedAt(); at != "" && !hasH3 {
+			// Browsers follow Alt-Svc, so HTTP/3 there is HTTP/3 support.
+			hasH3 = true
+			results[3].Supported, results[3].Error, results[3].ErrorKind = true, false, ""
+			results[3].Detail = "supported at the advertised endpoint " + at
+		}
+	}
+	if probedH3 {
+		if udp := CheckUDPBuffers(); !udp.Sufficient {
+			res.UDPBuffer = &udp
+			if !hasH3 {
+				results[3].Detail += " (UDP buffers on this host are undersized; see udp_buffer)"
+			}
+		}
+	}
+	for i := range results {
+		if results[i].Challenge != "" {
+			results[i].Detail += challengeNote
+		}
+	}
+	res.Parking = parking
+	res.DNSSEC = dnssec
+	res.SNIMismatch = mismatch
+	res.Coalescing = coalescing
+	if res.Proxied && probedH3 {
+		results[3].Detail += h3ProxyNote
+		if !hasH3 {
+			results[3].Unreliable = true
+		}
+	}
+	if cal := opts.QUICCalibration; cal != nil && !cal.OK && probedH3 {
+		res.QUICCalibration = cal
+		if !hasH3 {
+			results[3].Unreliable = true
+			results[3].Detail += " (unreliable: QUIC calibration against reference hosts failed)"
+		}
+	}
 
 	// Compute minimalist grade/score based solely on h2/h3 and TLS version.
 	score, grade := computeMinimalGrade(hasH3, hasH2, tlsProto)
-	res.Score = score
+	res.HTTPSRedirect = redirect
+	res.HSTS = h2HSTS
+	if res.HSTS == nil {
+		res.HSTS = h11HSTS
+	}
+	res.Score = score + transportSecurityAdjustment(res.HTTPSRedirect, res.HSTS)
 	res.Grade = grade
+	res.GradeReasons = gradeReasons(hasH3, hasH2, tlsProto, res.HTTPSRedirect, res.HSTS)
 	res.ALPN = alpn
 	res.TLSVersion = tlsProto
+	res.CertIssuer = certIssuer
+	res.CertSHA256 = certSHA256
+	res.TLS = newTLSInfo(map[string]*tls.ConnectionState{
+		"HTTP/1.1": h11State,
+		"HTTP/2.0": h2State,
+		"HTTP/3.0": h3State,
+	}, tlsResumed, hasPQ)
+	res.ECH = ech
+	res.QUICVersions = quicVersions
+	res.H2Settings = h2Settings
+	res.H2Origin = h2Origin
+	res.Compression = newCompressionResult(h11Encoding, h2Encoding)
+	if h2Connect != nil || h3Connect != nil {
+		res.ExtendedConnect = &ExtendedConnectSupport{H2: h2Connect, H3: h3Connect}
+	}
+	if opts.CheckEarlyData && (hasH2 || hasH3) {
+		ed := EarlyDataResult{TLSResumption: tlsResumed, QUIC0RTT: quic0RTT}
+		ed.Detail = earlyDataDetail(ed, hasH2, hasH3)
+		res.EarlyData = &ed
+	}
+	if hasPQ {
+		res.KeyExchange = keyExchangePQ
+	} else if opts.CheckKeyExchange && tlsProto != "" {
+		res.KeyExchange = keyExchangeClassical
+	}
+	res.Warnings = checkWarnings(&res, probedH3, certNotAfter, time.Now())
 	return res
 }
 
+// versionSkippedDetail explains a version left out with Options.Versions.
+const versionSkippedDetail = "not tested (skipped)"
+
+func skippedVersion(version string) VersionResult {
+	return VersionResult{Version: version, NotTested: true, Detail: versionSkippedDetail}
+}
+
 // CheckHTTPVersions runs the checks and prints a human-readable summary.
-func CheckHTTPVersions(target string, overridePort string) {
-	res := runChecks(target, overridePort)
+func CheckHTTPVersions(target string, opts Options) {
+	res := runChecks(target, opts)
+	fmt.Println(SummaryLine(res))
+}
 
-	// Single-line summary (same format as multi-target): statuses first, then host:port.
+// SummaryLine formats a result as the single-line human-readable summary used
+// by the CLI: statuses first, then grade and host:port. A followed redirect
+// shows as "target → final", and a bot challenge or changed certificate is
+// noted at the end.
+func SummaryLine(res CheckResult) string {
+	return summaryLine(res, nil)
+}
+
+func summaryLine(res CheckResult, t *Translator) string {
 	var b strings.Builder
 	for idx, vr := range res.Results {
 		if idx > 0 {
@@ -355,28 +880,77 @@ func CheckHTTPVersions(target string, overridePort string) {
 		}
 		fmt.Fprintf(&b, "%s %s", vr.Version, statusEmoji(vr))
 	}
+	target := summaryTarget(res)
+	line := fmt.Sprintf("%s\t%s:%s", b.String(), target, res.Port)
 	if res.Grade != "" {
-		fmt.Printf("%s\tGrade: %s (%d)\t%s:%s\n", b.String(), res.Grade, res.Score, res.Target, res.Port)
-	} else {
-		fmt.Printf("%s\t%s:%s\n", b.String(), res.Target, res.Port)
+		line = fmt.Sprintf("%s\t%s: %s (%d)\t%s:%s", b.String(), t.Message("Grade"), res.Grade, res.Score, target, res.Port)
+	}
+	for _, note := range summaryNotes(res, t) {
+		line += "\t" + note
+	}
+	return line
+}
+
+// summaryTarget is the target as the summary lines show it, with a followed
+// redirect as "target → final".
+func summaryTarget(res CheckResult) string {
+	if res.FinalTarget != "" {
+		return res.Target + " → " + res.FinalTarget
 	}
+	return res.Target
+}
+
+// summaryNotes are the remarks that end a summary line: a target the end
+// of the scan cut off, a bot challenge, a changed certificate, a disagreeing
+// cross-check or the notes kept on the target.
+func summaryNotes(res CheckResult, t *Translator) []string {
+	var notes []string
+	if res.Unscanned {
+		notes = append(notes, t.Message("not scanned before the scan ended"))
+	}
+	if c := challengeProvider(res); c != "" {
+		notes = append(notes, t.Sprintf("content gated by a %s challenge", c))
+	}
+	if res.CertChange != nil {
+		notes = append(notes, t.Message("certificate changed since the last scan"))
+	}
+	if cc := res.CrossCheck; cc != nil && !cc.Consistent && cc.Error == "" {
+		notes = append(notes, t.Sprintf("differs from %s: %s", cc.Endpoint, strings.Join(cc.Discrepancies, "; ")))
+	}
+	for _, n := range res.Notes {
+		notes = append(notes, t.Sprintf("note: %s", n.Text))
+	}
+	return notes
 }
 
 // CheckHTTPVersionsJSON runs the checks and returns a structured result suitable for JSON encoding.
-func CheckHTTPVersionsJSON(target string, overridePort string) CheckResult {
-	return runChecks(target, overridePort)
+func CheckHTTPVersionsJSON(target string, opts Options) CheckResult {
+	return runChecks(target, opts)
+}
+
+// CheckHTTPVersionsContext is CheckHTTPVersionsJSON under ctx: every DNS
+// lookup, dial, handshake and request derives its context from ctx, so its
+// values reach them (e.g. for tracing), and cancelling it or passing its
+// deadline abandons the probes and returns an Unscanned result.
+func CheckHTTPVersionsContext(ctx context.Context, target string, opts Options) CheckResult {
+	return runChecks(target, opts.withContext(ctx))
 }
 
 // runChecksMulti runs checks for multiple targets in parallel and returns the results
 // in the same order as the input targets slice.
-func runChecksMulti(targets []string, overridePort string) []CheckResult {
+func runChecksMulti(targets []string, opts Options) []CheckResult {
 	n := len(targets)
 	results := make([]CheckResult, n)
 	if n == 0 {
 		return results
 	}
 
-	workerCount := workerCountForTargets(n)
+	workerCount := opts.workerLimit(workerCountForTargets(n))
+	opts = opts.forRun()
+	defer opts.endRun()
+	shared, release := sharedTransports(opts)
+	defer release()
+	limiter := newOriginLimiter(opts)
 
 	var wg sync.WaitGroup
 	jobs := make(chan int)
@@ -386,7 +960,9 @@ func runChecksMulti(targets []string, overridePort string) []CheckResult {
 		go func() {
 			defer wg.Done()
 			for idx := range jobs {
-				results[idx] = runChecks(targets[idx], overridePort)
+				limiter.do(targets[idx], func() {
+					results[idx] = checkTarget(targets[idx], opts, shared)
+				})
 			}
 		}()
 	}
@@ -403,16 +979,83 @@ func runChecksMulti(targets []string, overridePort string) []CheckResult {
 // CheckHTTPVersionsMulti runs the checks for multiple targets and prints
 // a human-readable summary for each, printing each host as soon as its
 // result is available (results may be out of input order).
-func CheckHTTPVersionsMulti(targets []string, overridePort string) {
-	n := len(targets)
-	if n == 0 {
+func CheckHTTPVersionsMulti(targets []string, opts Options) {
+	CheckHTTPVersionsEach(targets, opts, func(res CheckResult) {
+		fmt.Println(SummaryLine(res))
+	})
+}
+
+// CheckHTTPVersionsEach runs the checks for multiple targets in parallel and
+// calls fn with each result as soon as it is available (results may be out of
+// input order). fn is always called from the caller's goroutine.
+func CheckHTTPVersionsEach(targets []string, opts Options, fn func(CheckResult)) {
+	CheckHTTPVersionsEachContext(context.Background(), targets, opts, fn)
+}
+
+// CheckHTTPVersionsEachContext is CheckHTTPVersionsEach under ctx, as
+// CheckHTTPVersionsContext is for one target: once ctx is done, probes
+// still running are abandoned and the targets left are reported Unscanned
+// without being probed.
+func CheckHTTPVersionsEachContext(ctx context.Context, targets []string, opts Options, fn func(CheckResult)) {
+	if len(targets) == 0 {
 		return
 	}
+	checkStream(feedTargets(targets), workerCountForTargets(len(targets)), opts.withContext(ctx), nil, fn)
+}
 
-	workerCount := workerCountForTargets(n)
+// CheckHTTPVersionsResults is CheckHTTPVersionsEachContext returning a
+// channel instead of taking a callback: each result is sent as soon as it is
+// available, and the channel is closed once every target is done. A caller
+// that stops reading early must cancel ctx, after which the results still
+// pending are discarded rather than sent.
+func CheckHTTPVersionsResults(ctx context.Context, targets []string, opts Options) <-chan CheckResult {
+	results := make(chan CheckResult)
+	go func() {
+		defer close(results)
+		CheckHTTPVersionsEachContext(ctx, targets, opts, func(res CheckResult) {
+			select {
+			case results <- res:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return results
+}
+
+// feedTargets sends targets on the channel it returns, then closes it.
+func feedTargets(targets []string) <-chan string {
+	feed := make(chan string)
+	go func() {
+		for _, t := range targets {
+			feed <- t
+		}
+		close(feed)
+	}()
+	return feed
+}
 
+// CheckHTTPVersionsStream is like CheckHTTPVersionsEach but reads targets
+// from a channel until it is closed, so callers can scan lists far larger
+// than they would want to hold in memory. Nothing is buffered beyond the
+// results currently in flight, except targets held back by
+// Options.MaxPerOrigin.
+func CheckHTTPVersionsStream(targets <-chan string, opts Options, fn func(CheckResult)) {
+	checkStream(targets, workerCountForTargets(maxWorkers), opts, nil, fn)
+}
+
+// checkStream checks targets on workerCount workers, with the transports
+// in shared or, when nil, those sharedTransports picks.
+func checkStream(targets <-chan string, workerCount int, opts Options, shared *probeTransports, fn func(CheckResult)) {
 	results := make(chan CheckResult)
-	jobs := make(chan int)
+	workerCount = opts.workerLimit(workerCount)
+	opts = opts.forRun()
+	defer opts.endRun()
+	if shared == nil {
+		var release func()
+		shared, release = sharedTransports(opts)
+		defer release()
+	}
+	limiter := newOriginLimiter(opts)
 
 	var wg sync.WaitGroup
 
@@ -421,47 +1064,30 @@ func CheckHTTPVersionsMulti(targets []string, overridePort string) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for idx := range jobs {
-				results <- runChecks(targets[idx], overridePort)
+			for target := range targets {
+				limiter.do(target, func() {
+					results <- checkTarget(target, opts, shared)
+				})
 			}
 		}()
 	}
 
-	// Feed jobs.
-	go func() {
-		for i := range targets {
-			jobs <- i
-		}
-		close(jobs)
-	}()
-
 	// Close results when workers are done.
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	// Print each result as soon as it is ready.
+	// Hand each result over as soon as it is ready.
 	for res := range results {
-		var b strings.Builder
-		for idx, vr := range res.Results {
-			if idx > 0 {
-				b.WriteString(" | ")
-			}
-			fmt.Fprintf(&b, "%s %s", vr.Version, statusEmoji(vr))
-		}
-		if res.Grade != "" {
-			fmt.Printf("%s\tGrade: %s (%d)\t%s:%s\n", b.String(), res.Grade, res.Score, res.Target, res.Port)
-		} else {
-			fmt.Printf("%s\t%s:%s\n", b.String(), res.Target, res.Port)
-		}
+		fn(res)
 	}
 }
 
 // CheckHTTPVersionsJSONMulti runs the checks for multiple targets and returns
 // a slice of results suitable for JSON encoding.
-func CheckHTTPVersionsJSONMulti(targets []string, overridePort string) []CheckResult {
-	return runChecksMulti(targets, overridePort)
+func CheckHTTPVersionsJSONMulti(targets []string, opts Options) []CheckResult {
+	return runChecksMulti(targets, opts)
 }
 
 // workerCountForTargets picks a reasonable worker count based on CPU count
@@ -470,7 +1096,6 @@ func workerCountForTargets(n int) int {
 	if n <= 0 {
 		return 0
 	}
-	maxWorkers := 64
 	wc := runtime.NumCPU() * 4
 	if wc > maxWorkers {
 		wc = maxWorkers
@@ -483,5 +1108,3 @@ func workerCountForTargets(n int) int {
 	}
 	return wc
 }
-
-
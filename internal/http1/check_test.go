@@ -0,0 +1,156 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlainHTTPURL(t *testing.T) {
+	tests := []struct {
+		in, host, port, want string
+	}{
+		{"https://example.com:443", "example.com", "80", "http://example.com:80"},
+		{"https://example.com:443/healthz?full=1#top", "example.com", "80", "http://example.com:80/healthz?full=1"},
+		{"https://u:p@[2001:db8::1]:8443/a", "2001:db8::1", "8080", "http://[2001:db8::1]:8080/a"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := plainHTTPURL(u, tt.host, tt.port); got != tt.want {
+			t.Errorf("plainHTTPURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProbesKeepPath(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.RequestURI())
+		mu.Unlock()
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	res := runChecks(ts.URL+"/api/v1/health?full=1#top", Options{Versions: []string{"HTTP/1.1", "HTTP/2.0"}})
+	if res.Path != "/api/v1/health?full=1" {
+		t.Errorf("Path = %q", res.Path)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) < 2 {
+		t.Fatalf("server saw %v, want both probes", seen)
+	}
+	for _, p := range seen[:2] {
+		if p != "/api/v1/health?full=1" {
+			t.Errorf("probe requested %q", p)
+		}
+	}
+
+	if res := runChecks(ts.URL, Options{Versions: []string{"HTTP/2.0"}}); res.Path != "" {
+		t.Errorf("root target: Path = %q, want empty", res.Path)
+	}
+}
+
+func TestProbesDoNotFollowRedirects(t *testing.T) {
+	// The destination would earn HSTS and TLS 1.3 credit the target
+	// itself does not deserve.
+	dest := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=63072000")
+	}))
+	dest.EnableHTTP2 = true
+	dest.StartTLS()
+	defer dest.Close()
+	origin := httptest.NewUnstartedServer(http.RedirectHandler(dest.URL, http.StatusMovedPermanently))
+	origin.EnableHTTP2 = true
+	origin.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	origin.StartTLS()
+	defer origin.Close()
+
+	res := runChecks(origin.URL, Options{Versions: []string{"HTTP/1.1", "HTTP/2.0"}})
+	if res.HSTS != nil && res.HSTS.Present {
+		t.Errorf("HSTS = %+v, want none from the redirect destination", res.HSTS)
+	}
+	if res.TLSVersion != "TLS 1.2" {
+		t.Errorf("TLSVersion = %q, want the target's TLS 1.2", res.TLSVersion)
+	}
+	if res.TLS == nil || res.TLS.Version != "TLS 1.2" {
+		t.Errorf("TLS = %+v, want the target's handshake", res.TLS)
+	}
+	for _, r := range res.GradeReasons {
+		if r.Code == "hsts" {
+			t.Errorf("grade reasons %v credit the destination's HSTS", res.GradeReasons)
+		}
+	}
+}
+
+func TestCheckHTTPVersionsContext(t *testing.T) {
+	// A server that never answers keeps the probes waiting until the
+	// context is cancelled.
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-hang }))
+	defer srv.Close()
+	defer close(hang)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	res := CheckHTTPVersionsContext(ctx, srv.URL, Options{Versions: []string{"HTTP/1.1"}})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled check took %s", elapsed)
+	}
+	if !res.Unscanned || !strings.Contains(res.Results[0].Detail, "cancelled") {
+		t.Errorf("cancelled check = %+v, want it unscanned", res)
+	}
+
+	var got []CheckResult
+	CheckHTTPVersionsEachContext(ctx, []string{"a.test", "b.test"}, Options{}, func(res CheckResult) {
+		got = append(got, res)
+	})
+	if len(got) != 2 || !got[0].Unscanned || !got[1].Unscanned {
+		t.Errorf("targets after cancellation = %+v, want both unscanned", got)
+	}
+}
+
+func TestCheckHTTPVersionsResults(t *testing.T) {
+	port := startLocalServers(t)
+	targets := []string{"https://127.0.0.1:" + port, "https://127.0.0.1:" + port + "/a"}
+
+	seen := make(map[string]bool)
+	for res := range CheckHTTPVersionsResults(context.Background(), targets, Options{Versions: []string{"HTTP/1.1"}}) {
+		if res.Grade == "" {
+			t.Errorf("result %+v not graded", res)
+		}
+		seen[res.Target] = true
+	}
+	if len(seen) != len(targets) {
+		t.Errorf("got results for %v, want %v", seen, targets)
+	}
+
+	// Abandoning the channel after cancelling ctx still lets the scan end.
+	ctx, cancel := context.WithCancel(context.Background())
+	results := CheckHTTPVersionsResults(ctx, []string{"a.test", "b.test", "c.test"}, Options{})
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for range results {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("results channel not closed after cancellation")
+	}
+}
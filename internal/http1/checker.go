@@ -0,0 +1,120 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Checker checks targets with a fixed set of options. Unlike the
+// package-level functions, which build the probe clients for every target,
+// it builds them once and reuses them, with their connection pools, TLS
+// session caches and UDP socket, for every target it checks. It is safe
+// for concurrent use; Close releases the clients.
+type Checker struct {
+	opts   Options
+	shared *probeTransports
+}
+
+// CheckerOption configures a Checker.
+type CheckerOption func(*checkerConfig)
+
+// checkerConfig is a Checker under construction.
+type checkerConfig struct {
+	opts     Options
+	timeout  time.Duration
+	versions []string
+}
+
+// WithOptions starts from opts; options after it change single fields.
+func WithOptions(opts Options) CheckerOption {
+	return func(c *checkerConfig) { c.opts = opts }
+}
+
+// WithVersions limits the probes to these versions, as ParseVersions
+// names them (e.g. "h2", "h3" or "HTTP/2.0"); see Options.Versions.
+func WithVersions(versions ...string) CheckerOption {
+	return func(c *checkerConfig) { c.versions = versions }
+}
+
+// WithTimeout bounds each target's check: probes still running after d are
+// abandoned and the result is Unscanned. 0 means no limit beyond the probes'
+// own timeouts.
+func WithTimeout(d time.Duration) CheckerOption {
+	return func(c *checkerConfig) { c.timeout = d }
+}
+
+// WithTLSConfig sets Options.TLSConfig, e.g. to present a client
+// certificate.
+func WithTLSConfig(conf *tls.Config) CheckerOption {
+	return func(c *checkerConfig) { c.opts.TLSConfig = conf }
+}
+
+// WithResolver sets Options.Resolver, to resolve target names over DoH or
+// DoT.
+func WithResolver(r *Resolver) CheckerOption {
+	return func(c *checkerConfig) { c.opts.Resolver = r }
+}
+
+// WithProxy sets Options.Proxy, e.g. to http.ProxyFromEnvironment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) CheckerOption {
+	return func(c *checkerConfig) { c.opts.Proxy = proxy }
+}
+
+// WithRetries sets Options.Retries.
+func WithRetries(n int) CheckerOption {
+	return func(c *checkerConfig) { c.opts.Retries = n }
+}
+
+// NewChecker builds a Checker and its probe clients.
+func NewChecker(options ...CheckerOption) (*Checker, error) {
+	var c checkerConfig
+	for _, o := range options {
+		o(&c)
+	}
+	if c.versions != nil {
+		versions, err := ParseVersions(strings.Join(c.versions, ","))
+		if err != nil {
+			return nil, err
+		}
+		c.opts.Versions = versions
+	}
+	// The rate limit holds across every check; the DNS cache and Deadline
+	// are set up per call.
+	opts := c.opts
+	opts.checkTimeout = c.timeout
+	if opts.Rate > 0 {
+		opts.rateLimiter = newRateLimiter(opts.Rate)
+	}
+	shared, err := newProbeTransports(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{opts: opts, shared: shared}, nil
+}
+
+// Check checks one target under ctx, as CheckHTTPVersionsContext does.
+func (c *Checker) Check(ctx context.Context, target string) CheckResult {
+	opts := c.opts.withContext(ctx).forRun()
+	defer opts.endRun()
+	return checkTarget(target, opts, c.shared)
+}
+
+// CheckEach checks targets in parallel under ctx and calls fn with each
+// result as it completes, as CheckHTTPVersionsEachContext does. Calls to fn
+// are sequential.
+func (c *Checker) CheckEach(ctx context.Context, targets []string, fn func(CheckResult)) {
+	if len(targets) == 0 {
+		return
+	}
+	checkStream(feedTargets(targets), workerCountForTargets(len(targets)), c.opts.withContext(ctx), c.shared, fn)
+}
+
+// Close releases the probe clients. The Checker must not be used after.
+func (c *Checker) Close() error {
+	c.shared.close()
+	return nil
+}
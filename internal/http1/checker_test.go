@@ -0,0 +1,64 @@
+package http1
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	port := startLocalServers(t)
+	target := "https://127.0.0.1:" + port
+
+	c, err := NewChecker(WithVersions("h1.1", "h2"), WithTimeout(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	results := make([]CheckResult, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.Check(context.Background(), target)
+		}()
+	}
+	wg.Wait()
+	for _, res := range results {
+		var supported int
+		for _, vr := range res.Results {
+			if vr.Supported {
+				supported++
+			}
+		}
+		if res.Grade == "" || supported != 2 {
+			t.Errorf("Check = %+v, want HTTP/1.1 and HTTP/2 graded", res)
+		}
+	}
+
+	var n int
+	c.CheckEach(context.Background(), []string{target, target}, func(res CheckResult) {
+		n++
+		if res.Grade == "" {
+			t.Errorf("CheckEach result %+v not graded", res)
+		}
+	})
+	if n != 2 {
+		t.Errorf("CheckEach gave %d results, want 2", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := c.Check(ctx, target); !res.Unscanned {
+		t.Errorf("Check after cancel = %+v, want unscanned", res)
+	}
+}
+
+func TestNewCheckerBadVersion(t *testing.T) {
+	if _, err := NewChecker(WithVersions("h4")); err == nil {
+		t.Error("NewChecker accepted version h4")
+	}
+}
@@ -0,0 +1,157 @@
+package http1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxHosts is how many addresses ExpandRanges expands in all by
+// default, and MaxHosts the most it will expand at all: a /16 of IPv4.
+const (
+	DefaultMaxHosts = 256
+	MaxHosts        = 1 << 16
+)
+
+// ErrTooManyHosts is returned by ExpandRanges when the ranges hold more
+// addresses than allowed.
+var ErrTooManyHosts = errors.New("too many addresses")
+
+// ErrUnexpandedRange is the probe error of an address range scanned as is
+// rather than through ExpandRanges.
+var ErrUnexpandedRange = errors.New("address ranges must be expanded into addresses before scanning")
+
+// scanRange is an address range target as parsed by parseRange.
+type scanRange struct {
+	scheme string
+	prefix netip.Prefix
+}
+
+// parseRange parses an address range target in CIDR notation, optionally
+// with a scheme: "203.0.113.0/28", "http://2001:db8::/124".
+func parseRange(raw string) (scanRange, bool) {
+	r := scanRange{scheme: "https"}
+	lower := strings.ToLower(raw)
+	for _, scheme := range []string{"http", "https"} {
+		if strings.HasPrefix(lower, scheme+"://") {
+			r.scheme, raw = scheme, raw[len(scheme)+3:]
+		}
+	}
+	p, err := netip.ParsePrefix(raw)
+	if err != nil {
+		return r, false
+	}
+	r.prefix = p.Masked()
+	return r, true
+}
+
+// hostURL is addr as the host of a URL, bracketed for IPv6.
+func hostURL(addr netip.Addr) string {
+	if addr.Is6() {
+		return "[" + addr.String() + "]"
+	}
+	return addr.String()
+}
+
+// HostCount is how many targets t stands for: the scanned addresses of a
+// range, or 1. Counts beyond MaxHosts are reported as MaxHosts+1.
+func (t Target) HostCount() int {
+	if !t.Range.IsValid() {
+		return 1
+	}
+	bits := t.Range.Addr().BitLen() - t.Range.Bits()
+	n := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if t.Range.Addr().Is4() && bits > 1 {
+		// The network and broadcast addresses are not hosts.
+		n.Sub(n, big.NewInt(2))
+	}
+	if n.Cmp(big.NewInt(MaxHosts)) > 0 {
+		return MaxHosts + 1
+	}
+	return int(n.Int64())
+}
+
+// ExpandRanges replaces each address range in targets with one target per
+// address, skipping the network and broadcast addresses of IPv4 ranges
+// wider than /31. The new targets keep the range's scheme, labels and
+// source, and are labelled with the range too, so results can be grouped
+// by it. ExpandRanges fails with ErrTooManyHosts, before expanding
+// anything, when the ranges hold more than maxHosts addresses together;
+// maxHosts is capped at MaxHosts.
+func ExpandRanges(targets []Target, maxHosts int) ([]Target, error) {
+	maxHosts = min(maxHosts, MaxHosts)
+	total := 0
+	for _, t := range targets {
+		if !t.Range.IsValid() {
+			continue
+		}
+		total += t.HostCount()
+		if total > maxHosts {
+			return nil, fmt.Errorf("%s: %w: the address ranges hold more than %d", t.Raw, ErrTooManyHosts, maxHosts)
+		}
+	}
+	if total == 0 {
+		return targets, nil
+	}
+
+	out := make([]Target, 0, len(targets)+total)
+	for _, t := range targets {
+		if !t.Range.IsValid() {
+			out = append(out, t)
+			continue
+		}
+		p := t.Range
+		first, last := p.Addr(), lastAddr(p)
+		if p.Addr().Is4() && p.Bits() < 31 {
+			first, last = first.Next(), last.Prev()
+		}
+		labels := slices.Clip(append([]string{p.String()}, t.Labels...))
+		for a := first; a.IsValid() && a.Compare(last) <= 0; a = a.Next() {
+			raw := hostURL(a)
+			if t.Scheme == "http" {
+				raw = "http://" + raw
+			}
+			out = append(out, Target{
+				Raw:    raw,
+				URL:    t.Scheme + "://" + hostURL(a),
+				Scheme: t.Scheme,
+				Host:   a.String(),
+				Port:   t.Port,
+				Labels: labels,
+				Source: t.Source,
+			})
+		}
+	}
+	return out, nil
+}
+
+// lastAddr is the highest address in p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// lookupReverseDNS returns the PTR names of an IP address host, without
+// trailing dots, or nil for host names and addresses without PTR records.
+func lookupReverseDNS(ctx context.Context, host string) []string {
+	if net.ParseIP(host) == nil {
+		return nil
+	}
+	names, err := netResolver(ctx).LookupAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for i, n := range names {
+		names[i] = strings.TrimSuffix(n, ".")
+	}
+	return names
+}
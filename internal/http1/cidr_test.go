@@ -0,0 +1,77 @@
+package http1
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTargetRange(t *testing.T) {
+	tests := []struct {
+		raw, key, host string
+		hosts          int
+	}{
+		{"203.0.113.0/28 prod", "https://203.0.113.0/28", "203.0.113.0", 14},
+		{"http://203.0.113.9/31", "http://203.0.113.8/31", "203.0.113.8", 2},
+		{"198.51.100.7/32", "https://198.51.100.7/32", "198.51.100.7", 1},
+		{"2001:db8::/126", "https://2001:db8::/126", "2001:db8::", 4},
+		{"10.0.0.0/8", "https://10.0.0.0/8", "10.0.0.0", MaxHosts + 1},
+	}
+	for _, tt := range tests {
+		tg, err := ParseTarget(tt.raw)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): %v", tt.raw, err)
+			continue
+		}
+		if tg.Key() != tt.key || tg.Host != tt.host || tg.HostCount() != tt.hosts {
+			t.Errorf("ParseTarget(%q) = key %q, host %q, %d hosts; want %q, %q, %d", tt.raw, tg.Key(), tg.Host, tg.HostCount(), tt.key, tt.host, tt.hosts)
+		}
+	}
+	if tg, _ := ParseTarget("203.0.113.5/health"); tg.Range.IsValid() {
+		t.Error("a path was taken for a prefix length")
+	}
+}
+
+func TestExpandRanges(t *testing.T) {
+	parse := func(raw string) Target {
+		tg, err := ParseTarget(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tg
+	}
+	targets := []Target{parse("example.com"), parse("http://203.0.113.0/30 lab"), parse("2001:db8::/127")}
+	got, err := ExpandRanges(targets, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raws []string
+	for _, tg := range got {
+		raws = append(raws, tg.String())
+	}
+	want := []string{
+		"example.com",
+		"http://203.0.113.1 203.0.113.0/30 lab",
+		"http://203.0.113.2 203.0.113.0/30 lab",
+		"[2001:db8::] 2001:db8::/127",
+		"[2001:db8::1] 2001:db8::/127",
+	}
+	if !reflect.DeepEqual(raws, want) {
+		t.Errorf("ExpandRanges = %q, want %q", raws, want)
+	}
+	if tg := got[1]; tg.Port != "80" || tg.URL != "http://203.0.113.1" {
+		t.Errorf("expanded target = %+v", tg)
+	}
+	if tg := parse(got[3].String()); tg.Host != "2001:db8::" || tg.Range.IsValid() {
+		t.Errorf("expanded IPv6 target reads back as %+v", tg)
+	}
+
+	if _, err := ExpandRanges(targets, 3); !errors.Is(err, ErrTooManyHosts) {
+		t.Errorf("over the limit: err = %v, want ErrTooManyHosts", err)
+	}
+
+	res := runChecks("203.0.113.0/30", Options{})
+	if len(res.Results) != 1 || !res.Results[0].Error || res.Results[0].Detail != ErrUnexpandedRange.Error() {
+		t.Errorf("unexpanded range result = %+v", res.Results)
+	}
+}
@@ -0,0 +1,50 @@
+package http1
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxCNAMEChain bounds how many aliases are followed, guarding against
+// loops in broken zones.
+const maxCNAMEChain = 16
+
+// lookupCNAMEChain returns the aliases host resolves through, in order,
+// e.g. ["example.cdn.net", "edge.cdn.net"] for www.example.com. Recursive
+// resolvers return the whole chain in the answer to an A query. A host
+// without CNAMEs, or an IP literal, yields nil.
+func lookupCNAMEChain(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return nil, nil
+	}
+	msg, err := dnsQuery(ctx, host, dnsmessage.TypeA)
+	if err != nil {
+		return nil, err
+	}
+	return cnameChain(host, msg.Answers), nil
+}
+
+// cnameChain follows the CNAME records in answers starting at host.
+func cnameChain(host string, answers []dnsmessage.Resource) []string {
+	aliases := make(map[string]string)
+	for _, ans := range answers {
+		if c, ok := ans.Body.(*dnsmessage.CNAMEResource); ok {
+			aliases[strings.ToLower(ans.Header.Name.String())] = c.CNAME.String()
+		}
+	}
+
+	var chain []string
+	name := strings.ToLower(dnsFQDN(host))
+	for len(chain) < maxCNAMEChain {
+		next, ok := aliases[name]
+		if !ok {
+			break
+		}
+		chain = append(chain, strings.TrimSuffix(next, "."))
+		name = strings.ToLower(next)
+	}
+	return chain
+}
@@ -0,0 +1,45 @@
+package http1
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestCNAMEChain(t *testing.T) {
+	cname := func(from, to string) dnsmessage.Resource {
+		return dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(from), Type: dnsmessage.TypeCNAME},
+			Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(to)},
+		}
+	}
+	a := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("edge.cdn.net."), Type: dnsmessage.TypeA},
+		Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+	}
+
+	tests := []struct {
+		name    string
+		answers []dnsmessage.Resource
+		want    []string
+	}{
+		{"chain", []dnsmessage.Resource{cname("WWW.example.com.", "example.cdn.net."), cname("example.cdn.net.", "edge.cdn.net."), a}, []string{"example.cdn.net", "edge.cdn.net"}},
+		{"no cname", []dnsmessage.Resource{a}, nil},
+		{"loop", []dnsmessage.Resource{cname("www.example.com.", "a.example.net."), cname("a.example.net.", "www.example.com.")}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cnameChain("www.example.com", tt.answers)
+			if tt.name == "loop" {
+				if len(got) != maxCNAMEChain {
+					t.Errorf("loop followed %d aliases, want the %d cap", len(got), maxCNAMEChain)
+				}
+				return
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package http1
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// CoalescingResult reports whether an HTTP/2 connection opened for the
+// target also serves another host its certificate covers. Browsers reuse
+// such connections (connection coalescing, RFC 9113 §9.1.1) to save a
+// handshake per hostname, and servers that cannot route the request answer
+// 421 Misdirected Request.
+type CoalescingResult struct {
+	// Authority is the other host requested on the target's connection,
+	// taken from the certificate; empty when it names no other host.
+	Authority string `json:"authority,omitempty"`
+	// SameAddress reports whether Authority resolves to an address of the
+	// target, which Chrome and Safari require before coalescing.
+	SameAddress bool `json:"same_address"`
+	// Status is the server's answer to the request for Authority.
+	Status int `json:"status,omitempty"`
+	// Coalesced is true when that request got an answer other than 421.
+	Coalesced bool `json:"coalesced"`
+	// Misdirected is true when it got 421 Misdirected Request.
+	Misdirected bool   `json:"misdirected"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// probeCoalescing requests the target and then a second host named in the
+// certificate on one raw h2 connection.
+func probeCoalescing(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), opts Options, host, port string, base *tls.Config) *CoalescingResult {
+	sess, err := dialH2(ctx, dial, host, port, base)
+	if err != nil {
+		return &CoalescingResult{Detail: fmt.Sprintf("no HTTP/2 connection: %v", err)}
+	}
+	defer sess.Close()
+
+	res := &CoalescingResult{}
+	if certs := sess.conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		res.Authority = coalescingCandidate(certs[0], host)
+	}
+	if res.Authority == "" {
+		res.Detail = "certificate names no other host"
+		return res
+	}
+	if _, err := sess.request(1, opts.method(), opts.authority(host, port), opts.userAgent()); err != nil {
+		res.Detail = fmt.Sprintf("request for the target failed: %v", err)
+		return res
+	}
+	authority := res.Authority
+	if port != "443" {
+		authority = net.JoinHostPort(authority, port)
+	}
+	res.Status, err = sess.request(3, opts.method(), authority, opts.userAgent())
+	if err != nil {
+		res.Detail = fmt.Sprintf("request for %s failed: %v", res.Authority, err)
+		return res
+	}
+	res.SameAddress = sameAddress(ctx, host, res.Authority)
+	res.Misdirected = res.Status == http.StatusMisdirectedRequest
+	res.Coalesced = !res.Misdirected
+	switch {
+	case res.Misdirected:
+		res.Detail = fmt.Sprintf("%s answered 421 Misdirected Request on the target's connection", res.Authority)
+	case res.SameAddress:
+		res.Detail = fmt.Sprintf("%s served on the target's connection (status %d)", res.Authority, res.Status)
+	default:
+		res.Detail = fmt.Sprintf("%s served on the target's connection (status %d), but browsers will not coalesce it because it resolves elsewhere", res.Authority, res.Status)
+	}
+	return res
+}
+
+// coalescingCandidate picks a host other than host that leaf is valid for,
+// preferring exact names to a "www" label under a wildcard.
+func coalescingCandidate(leaf *x509.Certificate, host string) string {
+	var wildcard string
+	for _, name := range leaf.DNSNames {
+		if base, ok := strings.CutPrefix(name, "*."); ok {
+			if name := "www." + base; wildcard == "" && !strings.EqualFold(name, host) {
+				wildcard = name
+			}
+			continue
+		}
+		if !strings.EqualFold(name, host) {
+			return name
+		}
+	}
+	return wildcard
+}
+
+// sameAddress reports whether a and b share a resolved address.
+func sameAddress(ctx context.Context, a, b string) bool {
+	r := netResolver(ctx)
+	addrs := func(host string) []string {
+		if net.ParseIP(host) != nil {
+			return []string{host}
+		}
+		out, _ := r.LookupHost(ctx, host)
+		return out
+	}
+	seen := make(map[string]bool)
+	for _, addr := range addrs(a) {
+		seen[addr] = true
+	}
+	for _, addr := range addrs(b) {
+		if seen[addr] {
+			return true
+		}
+	}
+	return false
+}
+
+// request sends a bodyless request for authority on streamID of an
+// established raw h2 session and returns the response status, resetting the
+// stream once the headers arrive.
+func (s *h2Session) request(streamID uint32, method, authority, userAgent string) (int, error) {
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: method},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":path", Value: "/"},
+		{Name: ":authority", Value: authority},
+		{Name: "user-agent", Value: userAgent},
+	} {
+		_ = enc.WriteField(f)
+	}
+	if err := s.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: block.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	}); err != nil {
+		return 0, err
+	}
+	defer func() { _ = s.framer.WriteRSTStream(streamID, http2.ErrCodeCancel) }()
+
+	if s.framer.ReadMetaHeaders == nil {
+		s.framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	}
+	for {
+		f, err := s.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		switch f := f.(type) {
+		case *http2.MetaHeadersFrame:
+			if f.StreamID != streamID {
+				continue
+			}
+			status, err := strconv.Atoi(f.PseudoValue("status"))
+			if err != nil {
+				return 0, fmt.Errorf("invalid :status %q", f.PseudoValue("status"))
+			}
+			if status < 200 {
+				continue // informational, e.g. 103 Early Hints
+			}
+			return status, nil
+		case *http2.RSTStreamFrame:
+			if f.StreamID == streamID {
+				return 0, fmt.Errorf("stream reset by server: %v", f.ErrCode)
+			}
+		case *http2.GoAwayFrame:
+			return 0, fmt.Errorf("server sent GOAWAY: %v", f.ErrCode)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeCoalescing(t *testing.T) {
+	for _, misdirect := range []bool{false, true} {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if misdirect && strings.HasPrefix(r.Host, "example.com") {
+				w.WriteHeader(http.StatusMisdirectedRequest)
+			}
+		}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+		// The httptest certificate covers 127.0.0.1 and example.com.
+		res := probeCoalescing(context.Background(), (&net.Dialer{}).DialContext, Options{}, host, port, &tls.Config{InsecureSkipVerify: true})
+		srv.Close()
+		if res.Authority != "example.com" {
+			t.Fatalf("misdirect=%v: authority = %q (%s), want example.com", misdirect, res.Authority, res.Detail)
+		}
+		if res.Coalesced == misdirect || res.Misdirected != misdirect {
+			t.Errorf("misdirect=%v: coalesced=%v misdirected=%v status=%d (%s)", misdirect, res.Coalesced, res.Misdirected, res.Status, res.Detail)
+		}
+	}
+}
+
+func TestCoalescingCandidate(t *testing.T) {
+	tests := []struct {
+		names []string
+		host  string
+		want  string
+	}{
+		{[]string{"example.com", "www.example.com"}, "example.com", "www.example.com"},
+		{[]string{"*.example.com", "example.com", "cdn.example.net"}, "example.com", "cdn.example.net"},
+		{[]string{"example.com", "*.example.com"}, "example.com", "www.example.com"},
+		{[]string{"*.example.com"}, "www.example.com", ""},
+		{[]string{"Example.com"}, "example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := coalescingCandidate(&x509.Certificate{DNSNames: tt.names}, tt.host); got != tt.want {
+			t.Errorf("coalescingCandidate(%v, %q) = %q, want %q", tt.names, tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package http1
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used by ColumnLine.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiDim    = "\x1b[2m"
+)
+
+// statusWords are the English status words of ColumnLine, in the order
+// supported, not supported, error, not tested.
+var statusWords = [...]string{"yes", "no", "error", "skipped"}
+
+// statusWord is statusEmoji as a word and the ANSI color it is shown in.
+func statusWord(vr VersionResult) (string, string) {
+	switch {
+	case vr.Supported:
+		return statusWords[0], ansiGreen
+	case vr.NotTested:
+		return statusWords[3], ansiDim
+	case vr.Error:
+		return statusWords[2], ansiYellow
+	}
+	return statusWords[1], ansiRed
+}
+
+// gradeColor is the ANSI color of a letter grade: green for A, red for F
+// and yellow in between.
+func gradeColor(grade string) string {
+	switch grade {
+	case "A":
+		return ansiGreen
+	case "F":
+		return ansiRed
+	}
+	return ansiYellow
+}
+
+// ColumnLine formats a result like SummaryLine, but with status words in
+// fixed-width columns instead of emoji, which many terminals draw at
+// inconsistent widths. With color the statuses and grade get ANSI colors.
+func ColumnLine(res CheckResult, color bool) string {
+	return columnLine(res, nil, color)
+}
+
+// ColumnLine is ColumnLine with its labels translated.
+func (t *Translator) ColumnLine(res CheckResult, color bool) string {
+	return columnLine(res, t, color)
+}
+
+func columnLine(res CheckResult, t *Translator, color bool) string {
+	paint := func(s, code string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+	width := 0
+	for _, w := range statusWords {
+		width = max(width, utf8.RuneCountInString(t.Message(w)))
+	}
+
+	var b strings.Builder
+	for _, vr := range res.Results {
+		word, code := statusWord(vr)
+		word = t.Message(word)
+		fmt.Fprintf(&b, "%-8s %s%s  ", vr.Version, paint(word, code), strings.Repeat(" ", width-utf8.RuneCountInString(word)))
+	}
+	if res.Grade != "" {
+		grade := fmt.Sprintf("%s (%d)", res.Grade, res.Score)
+		fmt.Fprintf(&b, "%s: %s%s  ", t.Message("Grade"), paint(grade, gradeColor(res.Grade)), strings.Repeat(" ", max(0, 7-len(grade))))
+	}
+	fmt.Fprintf(&b, "%s:%s", summaryTarget(res), res.Port)
+	for _, note := range summaryNotes(res, t) {
+		b.WriteString("  " + paint(note, ansiYellow))
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package http1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColumnLine(t *testing.T) {
+	res := CheckResult{
+		Target: "example.com",
+		Port:   "443",
+		Grade:  "B",
+		Score:  90,
+		Results: []VersionResult{
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2", Supported: true},
+			{Version: "HTTP/3.0", Error: true},
+		},
+	}
+	want := "HTTP/1.1 yes      HTTP/2   yes      HTTP/3.0 error    Grade: B (90)   example.com:443"
+	if got := ColumnLine(res, false); got != want {
+		t.Errorf("ColumnLine = %q\nwant          %q", got, want)
+	}
+	colored := ColumnLine(res, true)
+	if !strings.Contains(colored, ansiGreen+"yes"+ansiReset) || !strings.Contains(colored, ansiYellow+"B (90)"+ansiReset) {
+		t.Errorf("colored ColumnLine = %q", colored)
+	}
+
+	// Every version's column is as wide in German, where the words differ
+	// in length.
+	de, err := NewTranslator("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Results[0] = VersionResult{Version: "HTTP/1.1", NotTested: true}
+	got := de.ColumnLine(res, false)
+	if !strings.HasPrefix(got, "HTTP/1.1 übersprungen  HTTP/2   ja            HTTP/3.0 Fehler        Note: B (90)") {
+		t.Errorf("German ColumnLine = %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package http1
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// offeredEncodings is the Accept-Encoding sent on the HTTP/1.1 and HTTP/2
+// probes. Setting the header explicitly also stops net/http from
+// transparently decompressing, so Content-Encoding reaches us intact.
+var offeredEncodings = []string{"gzip", "br", "zstd"}
+
+// CompressionResult reports which content codings the server applied to the
+// probe responses when offered gzip, br and zstd.
+type CompressionResult struct {
+	Offered []string `json:"offered"`
+	// Encodings lists the distinct codings seen across probes, or is empty
+	// when every response was sent uncompressed.
+	Encodings []string `json:"encodings"`
+	HTTP11    string   `json:"http11,omitempty"`
+	HTTP2     string   `json:"http2,omitempty"`
+}
+
+// setAcceptEncoding advertises offeredEncodings on req.
+func setAcceptEncoding(req *http.Request) {
+	req.Header.Set("Accept-Encoding", strings.Join(offeredEncodings, ", "))
+}
+
+// responseEncoding returns the normalised Content-Encoding of resp, or
+// "identity" when the body was not encoded.
+func responseEncoding(resp *http.Response) string {
+	enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if enc == "" {
+		return "identity"
+	}
+	return enc
+}
+
+// newCompressionResult combines the per-protocol encodings; empty strings
+// mean the corresponding probe did not get a response.
+func newCompressionResult(h11, h2 string) *CompressionResult {
+	if h11 == "" && h2 == "" {
+		return nil
+	}
+	c := &CompressionResult{
+		Offered:   offeredEncodings,
+		Encodings: []string{},
+		HTTP11:    h11,
+		HTTP2:     h2,
+	}
+	for _, enc := range []string{h11, h2} {
+		if enc != "" && enc != "identity" && !slices.Contains(c.Encodings, enc) {
+			c.Encodings = append(c.Encodings, enc)
+		}
+	}
+	return c
+}
@@ -0,0 +1,27 @@
+package http1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCompressionResult(t *testing.T) {
+	tests := []struct {
+		h11, h2 string
+		want    []string
+	}{
+		{"identity", "identity", []string{}},
+		{"gzip", "br", []string{"gzip", "br"}},
+		{"br", "br", []string{"br"}},
+		{"", "zstd", []string{"zstd"}},
+	}
+	for _, tt := range tests {
+		got := newCompressionResult(tt.h11, tt.h2)
+		if !reflect.DeepEqual(got.Encodings, tt.want) {
+			t.Errorf("newCompressionResult(%q, %q).Encodings = %v, want %v", tt.h11, tt.h2, got.Encodings, tt.want)
+		}
+	}
+	if got := newCompressionResult("", ""); got != nil {
+		t.Errorf("newCompressionResult with no responses = %+v, want nil", got)
+	}
+}
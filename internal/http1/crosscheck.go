@@ -0,0 +1,153 @@
+package http1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCrossCheckEndpoint is the hosted http1 web instance used by
+// --cross-check.
+const DefaultCrossCheckEndpoint = "https://http1.dev"
+
+// crossCheckTimeout bounds the remote scan, which runs every probe itself.
+const crossCheckTimeout = 60 * time.Second
+
+// crossCheckMaxBody bounds the remote instance's JSON answer.
+const crossCheckMaxBody = 1 << 20
+
+// CrossCheckResult compares the local findings with those of a remote http1
+// web instance probing the same target, so differences caused by the local
+// network, such as an intercepting proxy, blocked UDP or split-horizon DNS,
+// stand out.
+type CrossCheckResult struct {
+	// Endpoint is the remote instance, e.g. "https://http1.dev".
+	Endpoint string `json:"endpoint"`
+	// Vantage is the remote result's vantage label, if it sets one.
+	Vantage string `json:"vantage,omitempty"`
+	// Grade and Supported are the remote grade and supported versions.
+	Grade     string   `json:"grade,omitempty"`
+	Supported []string `json:"supported,omitempty"`
+	// Consistent is false when the grades differ or a version works from
+	// one side only. Versions either side did not test are not compared.
+	Consistent    bool     `json:"consistent"`
+	Discrepancies []string `json:"discrepancies,omitempty"`
+	// Error is set when the remote instance could not be asked; the other
+	// fields are then empty.
+	Error string `json:"error,omitempty"`
+}
+
+// checkCrossCheck checks target locally while the remote instance at
+// opts.CrossCheck checks it too, and records how the two compare.
+func checkCrossCheck(target string, opts Options, shared *probeTransports) CheckResult {
+	endpoint := opts.CrossCheck
+	opts.CrossCheck = ""
+
+	var remote *CheckResult
+	var remoteErr error
+	var wg sync.WaitGroup
+	remoteTarget := target
+	if t, err := ParseTarget(target); err == nil {
+		// The remote instance has no port override, so the URL carries it.
+		remoteTarget = t.URL
+		if opts.Port != "" {
+			if u, err := url.Parse(t.URL); err == nil {
+				u.Host = net.JoinHostPort(u.Hostname(), opts.Port)
+				remoteTarget = u.String()
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(opts.baseContext(), crossCheckTimeout)
+			defer cancel()
+			remote, remoteErr = fetchRemoteResult(ctx, endpoint, remoteTarget, opts)
+		}()
+	}
+	res := checkTarget(target, opts, shared)
+	wg.Wait()
+	if res.Grade == "" {
+		return res
+	}
+	cc := &CrossCheckResult{Endpoint: endpoint}
+	res.CrossCheck = cc
+	if remoteErr != nil {
+		cc.Error = remoteErr.Error()
+		return res
+	}
+	cc.Vantage, cc.Grade = remote.Vantage, remote.Grade
+	for _, vr := range remote.Results {
+		if vr.Supported {
+			cc.Supported = append(cc.Supported, vr.Version)
+		}
+	}
+	cc.Discrepancies = crossCheckDiscrepancies(res, *remote)
+	cc.Consistent = len(cc.Discrepancies) == 0
+	if !cc.Consistent {
+		res.Warnings = append(res.Warnings, Warning{
+			Code:    WarningCrossCheck,
+			Message: fmt.Sprintf("results differ from %s: %s", endpoint, strings.Join(cc.Discrepancies, "; ")),
+		})
+	}
+	return res
+}
+
+// fetchRemoteResult asks the http1 web instance at endpoint to check target
+// and decodes its JSON result.
+func fetchRemoteResult(ctx context.Context, endpoint, target string, opts Options) (*CheckResult, error) {
+	q := url.Values{"t": {target}, "format": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpoint, "/")+"/scan?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", opts.userAgent())
+	tr := &http.Transport{Proxy: opts.Proxy}
+	defer tr.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", endpoint, resp.Status)
+	}
+	var remote CheckResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, crossCheckMaxBody)).Decode(&remote); err != nil {
+		return nil, fmt.Errorf("%s sent no result: %v", endpoint, err)
+	}
+	if remote.Grade == "" {
+		return nil, fmt.Errorf("%s could not check the target", endpoint)
+	}
+	return &remote, nil
+}
+
+// crossCheckDiscrepancies lists the differences between the local and the
+// remote result: the grade, then versions in the order of local.Results.
+func crossCheckDiscrepancies(local, remote CheckResult) []string {
+	var out []string
+	if local.Grade != remote.Grade {
+		out = append(out, fmt.Sprintf("grade %s locally but %s remotely", local.Grade, remote.Grade))
+	}
+	for _, vr := range local.Results {
+		i := slices.IndexFunc(remote.Results, func(r VersionResult) bool { return r.Version == vr.Version })
+		if i < 0 || vr.NotTested || remote.Results[i].NotTested {
+			continue
+		}
+		switch rv := remote.Results[i]; {
+		case vr.Supported && !rv.Supported:
+			out = append(out, vr.Version+" works locally but not remotely")
+		case rv.Supported && !vr.Supported:
+			out = append(out, vr.Version+" works remotely but not locally")
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package http1
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCrossCheck(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	// The remote instance sees HTTP/3 working and did not test HTTP/2.
+	var asked string
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked = r.URL.Query().Get("t")
+		_ = json.NewEncoder(w).Encode(CheckResult{
+			Vantage: "eu-west",
+			Grade:   "A",
+			Results: []VersionResult{
+				{Version: "HTTP/1.1", Supported: true},
+				{Version: "HTTP/2", NotTested: true},
+				{Version: "HTTP/3.0", Supported: true},
+			},
+		})
+	}))
+	defer remote.Close()
+
+	res := runChecks("http://127.0.0.1:"+port, Options{CrossCheck: remote.URL})
+	cc := res.CrossCheck
+	if cc == nil {
+		t.Fatalf("no cross-check result: %+v", res.Results)
+	}
+	if asked != "http://127.0.0.1:"+port {
+		t.Errorf("remote asked for %q", asked)
+	}
+	if cc.Consistent || cc.Error != "" || cc.Vantage != "eu-west" {
+		t.Errorf("cross-check = %+v", cc)
+	}
+	// A noh3 build does not test HTTP/3, so it has nothing to compare.
+	if h3Available != slices.Contains(cc.Discrepancies, "HTTP/3.0 works remotely but not locally") {
+		t.Errorf("discrepancies = %v, want an HTTP/3 one: %v", cc.Discrepancies, h3Available)
+	}
+	if slices.ContainsFunc(cc.Discrepancies, func(d string) bool { return strings.HasPrefix(d, "HTTP/2") }) {
+		t.Errorf("HTTP/2 was not tested remotely but compared: %v", cc.Discrepancies)
+	}
+	if !slices.ContainsFunc(res.Warnings, func(w Warning) bool { return w.Code == WarningCrossCheck }) {
+		t.Errorf("no %s warning in %+v", WarningCrossCheck, res.Warnings)
+	}
+
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer busy.Close()
+	res = runChecks("http://127.0.0.1:"+port, Options{CrossCheck: busy.URL})
+	if cc := res.CrossCheck; cc == nil || !strings.Contains(cc.Error, "503") {
+		t.Errorf("unavailable remote: %+v", cc)
+	}
+}
@@ -0,0 +1,61 @@
+package http1
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Difference is one way a target's result changed between two scans, e.g.
+// HTTP/3 support lost or the grade going from B to A.
+type Difference struct {
+	Target string `json:"target"`
+	// Aspect is "grade", "score", "tls_version" or a protocol version such
+	// as "HTTP/3.0".
+	Aspect string `json:"aspect"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	// Regression is true for a change for the worse.
+	Regression bool `json:"regression"`
+}
+
+// DiffResults lists the differences between old and cur, two scans of the
+// same target: the grade, the score within an unchanged grade, support for
+// each protocol version and the TLS version. Versions either scan did not
+// test and TLS versions either did not see are left out.
+func DiffResults(old, cur CheckResult) []Difference {
+	var out []Difference
+	add := func(aspect, o, n string, regression bool) {
+		out = append(out, Difference{Target: cur.Target, Aspect: aspect, Old: o, New: n, Regression: regression})
+	}
+	if old.Grade != cur.Grade {
+		add("grade", gradeLabel(old.Grade), gradeLabel(cur.Grade), gradeRank(cur) > gradeRank(old))
+	} else if old.Score != cur.Score {
+		add("score", fmt.Sprint(old.Score), fmt.Sprint(cur.Score), cur.Score < old.Score)
+	}
+	for _, was := range old.Results {
+		i := slices.IndexFunc(cur.Results, func(vr VersionResult) bool { return vr.Version == was.Version })
+		if i < 0 || was.NotTested || cur.Results[i].NotTested || was.Supported == cur.Results[i].Supported {
+			continue
+		}
+		now := cur.Results[i]
+		add(was.Version, supportLabel(was.Supported), supportLabel(now.Supported), was.Supported)
+	}
+	if o, n := tlsVersionValue(old.TLSVersion), tlsVersionValue(cur.TLSVersion); o != 0 && n != 0 && o != n {
+		add("tls_version", old.TLSVersion, cur.TLSVersion, n < o)
+	}
+	return out
+}
+
+func gradeLabel(grade string) string {
+	if grade == "" {
+		return "none"
+	}
+	return grade
+}
+
+func supportLabel(supported bool) string {
+	if supported {
+		return "supported"
+	}
+	return "not supported"
+}
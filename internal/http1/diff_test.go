@@ -0,0 +1,50 @@
+package http1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	old := CheckResult{
+		Target:     "example.com",
+		Grade:      "A",
+		Score:      95,
+		TLSVersion: "TLS 1.3",
+		Results: []VersionResult{
+			{Version: "HTTP/1.0"},
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2.0", Supported: true},
+			{Version: "HTTP/3.0", Supported: true},
+		},
+	}
+	cur := old
+	cur.Grade, cur.Score, cur.TLSVersion = "C", 80, "TLS 1.2"
+	cur.Results = []VersionResult{
+		{Version: "HTTP/1.0", Supported: true},
+		{Version: "HTTP/1.1", Supported: true},
+		{Version: "HTTP/2.0", Supported: true},
+		{Version: "HTTP/3.0"},
+	}
+	want := []Difference{
+		{"example.com", "grade", "A", "C", true},
+		{"example.com", "HTTP/1.0", "not supported", "supported", false},
+		{"example.com", "HTTP/3.0", "supported", "not supported", true},
+		{"example.com", "tls_version", "TLS 1.3", "TLS 1.2", true},
+	}
+	if got := DiffResults(old, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResults =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Same grade: the score is compared; untested versions are not.
+	cur = old
+	cur.Score = 98
+	cur.Results = []VersionResult{{Version: "HTTP/3.0", NotTested: true}}
+	want = []Difference{{"example.com", "score", "95", "98", false}}
+	if got := DiffResults(old, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResults = %+v, want %+v", got, want)
+	}
+	if got := DiffResults(old, old); len(got) != 0 {
+		t.Errorf("identical results differ: %+v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package http1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultCTEndpoint is the Certificate Transparency search service
+// DiscoverSubdomains asks by default.
+const DefaultCTEndpoint = "https://crt.sh"
+
+// ctTimeout bounds a Certificate Transparency search, which takes a while
+// for domains with many certificates.
+const ctTimeout = 90 * time.Second
+
+// ctMaxBody bounds the search's JSON answer.
+const ctMaxBody = 64 << 20
+
+// ctEntry is the part of a crt.sh JSON entry DiscoverSubdomains reads:
+// the certificate's names, one per line.
+type ctEntry struct {
+	NameValue string `json:"name_value"`
+}
+
+// DiscoverSubdomains lists the names under domain, domain included, that
+// certificates logged to Certificate Transparency cover, as the crt.sh
+// compatible search at endpoint (DefaultCTEndpoint when empty) reports
+// them. Wildcard names count as the name they are under; the names are
+// lower-cased, deduplicated and sorted.
+func DiscoverSubdomains(ctx context.Context, domain, endpoint string, opts Options) ([]string, error) {
+	t, err := ParseTarget(domain)
+	if err != nil {
+		return nil, err
+	}
+	domain = strings.TrimSuffix(strings.ToLower(t.Host), ".")
+	if net.ParseIP(domain) != nil || t.Range.IsValid() {
+		return nil, fmt.Errorf("%s is not a domain name", domain)
+	}
+	if endpoint == "" {
+		endpoint = DefaultCTEndpoint
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ctTimeout)
+	defer cancel()
+	q := url.Values{"q": {"%." + domain}, "output": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpoint, "/")+"/?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", opts.userAgent())
+	tr := &http.Transport{Proxy: opts.Proxy}
+	defer tr.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", endpoint, resp.Status)
+	}
+	var entries []ctEntry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, ctMaxBody)).Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s sent no certificate list: %v", endpoint, err)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, e := range entries {
+		for _, name := range strings.Split(e.NameValue, "\n") {
+			name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+			name = strings.TrimPrefix(name, "*.")
+			if seen[name] || !underDomain(name, domain) || strings.ContainsAny(name, " @*") {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// underDomain reports whether name is domain or a name under it.
+func underDomain(name, domain string) bool {
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
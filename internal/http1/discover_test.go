@@ -0,0 +1,48 @@
+package http1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestDiscoverSubdomains(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"name_value": "example.com\nwww.example.com"},
+			{"name_value": "*.API.example.com"},
+			{"name_value": "api.example.com\nmail.example.com."},
+			{"name_value": "hostmaster@example.com"},
+			{"name_value": "example.com.evil.test\nnotexample.com"}
+		]`))
+	}))
+	defer srv.Close()
+
+	names, err := DiscoverSubdomains(context.Background(), "https://Example.com/", srv.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "%.example.com" {
+		t.Errorf("query = %q, want %%.example.com", query)
+	}
+	want := []string{"api.example.com", "example.com", "mail.example.com", "www.example.com"}
+	if !slices.Equal(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+
+	if _, err := DiscoverSubdomains(context.Background(), "192.0.2.1", srv.URL, Options{}); err == nil {
+		t.Error("DiscoverSubdomains accepted an IP address")
+	}
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusBadGateway)
+	}))
+	defer down.Close()
+	if _, err := DiscoverSubdomains(context.Background(), "example.com", down.URL, Options{}); err == nil {
+		t.Error("DiscoverSubdomains ignored a 502")
+	}
+}
@@ -0,0 +1,186 @@
+package http1
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const dnsTimeout = 2 * time.Second
+
+// typeHTTPS is the HTTPS resource record type (RFC 9460). dnsmessage does not
+// know about it, so answers of this type come back as UnknownResource.
+const typeHTTPS dnsmessage.Type = 65
+
+// errNoNameserver is returned when resolv.conf names no nameserver.
+var errNoNameserver = errors.New("no nameserver in resolv.conf")
+
+// systemNameserver returns the first nameserver from /etc/resolv.conf, read
+// once per process. It fails when the file is missing or names none rather
+// than guess at a local resolver. The standard library resolver does not
+// let us ask for arbitrary record types, so the few probes that need them
+// talk to the resolver directly.
+var systemNameserver = sync.OnceValues(func() (string, error) {
+	return readNameserver("/etc/resolv.conf")
+})
+
+// readNameserver returns the first nameserver in the resolv.conf at path.
+func readNameserver(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", path, errNoNameserver)
+}
+
+// dnsQuery sends a single recursive query for name/qtype to the system
+// resolver, or the Resolver carried by ctx, and returns the parsed response.
+func dnsQuery(ctx context.Context, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dnsTimeout)
+		defer cancel()
+	}
+
+	query, err := newDNSQuery(name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	return dnsSend(ctx, query)
+}
+
+// newDNSQuery builds a recursive query for name/qtype with a random ID.
+func newDNSQuery(name string, qtype dnsmessage.Type) (dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(dnsFQDN(name))
+	if err != nil {
+		return dnsmessage.Message{}, fmt.Errorf("invalid DNS name %q: %w", name, err)
+	}
+	return dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               uint16(rand.Intn(1 << 16)),
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}, nil
+}
+
+// dnsSend sends query and returns the parsed response, retrying truncated
+// UDP answers over TCP.
+func dnsSend(ctx context.Context, query dnsmessage.Message) (*dnsmessage.Message, error) {
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := dnsExchange(ctx, "udp", packed)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Truncated {
+		resp, err = dnsExchange(ctx, "tcp", packed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.ID != query.ID {
+		return nil, fmt.Errorf("DNS response ID mismatch")
+	}
+	return resp, nil
+}
+
+func dnsExchange(ctx context.Context, network string, packed []byte) (*dnsmessage.Message, error) {
+	var buf []byte
+	var err error
+	if r := resolverFrom(ctx); r != nil {
+		buf, err = r.exchange(ctx, packed)
+	} else {
+		buf, err = systemExchange(ctx, network, packed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(buf); err != nil {
+		return nil, fmt.Errorf("malformed DNS response: %w", err)
+	}
+	return &msg, nil
+}
+
+// systemExchange sends packed to the system nameserver over network.
+func systemExchange(ctx context.Context, network string, packed []byte) ([]byte, error) {
+	addr, err := systemNameserver()
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		return dnsStreamExchange(conn, packed)
+	}
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// dnsStreamExchange sends packed over a stream connection (TCP or TLS),
+// where every message is prefixed with a two-byte length.
+func dnsStreamExchange(conn net.Conn, packed []byte) ([]byte, error) {
+	framed := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(framed, uint16(len(packed)))
+	copy(framed[2:], packed)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// dnsFQDN makes sure name ends with a trailing dot as dnsmessage expects.
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
@@ -0,0 +1,173 @@
+package http1
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsCacheMinTTL is the least time an answer is kept, so all probes of a
+// target connect to the same addresses even when the records have a TTL of
+// zero.
+const dnsCacheMinTTL = 10 * time.Second
+
+// dnsCache answers the standard library resolver's queries for one run, so
+// the probes of a target, and targets sharing a host, resolve each name
+// once and connect to the same addresses. Answers are kept for their TTL;
+// failures other than NXDOMAIN are not kept. Queries go upstream to the
+// scan's Resolver or the system nameserver; /etc/hosts is still consulted
+// first by the standard library.
+type dnsCache struct {
+	net *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+	sweepAt int
+}
+
+type dnsCacheEntry struct {
+	ready   chan struct{}
+	resp    []byte
+	err     error
+	expires time.Time
+}
+
+func newDNSCache() *dnsCache {
+	c := &dnsCache{entries: make(map[string]*dnsCacheEntry), sweepAt: 1024}
+	c.net = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &exchangeConn{ctx: ctx, exchange: c.exchange}, nil
+		},
+	}
+	return c
+}
+
+// exchange answers the packed query from the cache, asking upstream on a
+// miss. Concurrent misses for the same question share one upstream query.
+func (c *dnsCache) exchange(ctx context.Context, packed []byte) ([]byte, error) {
+	var query dnsmessage.Message
+	if err := query.Unpack(packed); err != nil {
+		return nil, err
+	}
+	if len(query.Questions) != 1 {
+		return c.fetch(ctx, query)
+	}
+	q := query.Questions[0]
+	key := strings.ToLower(q.Name.String()) + "/" + q.Type.String()
+
+	c.mu.Lock()
+	e := c.entries[key]
+	if e == nil || (e.resp != nil && time.Now().After(e.expires)) {
+		e = &dnsCacheEntry{ready: make(chan struct{})}
+		c.entries[key] = e
+		c.sweep()
+		c.mu.Unlock()
+		// The query outlives a canceled caller; others may be waiting on it.
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dnsTimeout)
+		c.fill(fetchCtx, key, e, query)
+		cancel()
+	} else {
+		c.mu.Unlock()
+	}
+
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	resp := append([]byte(nil), e.resp...)
+	resp[0], resp[1] = packed[0], packed[1]
+	return resp, nil
+}
+
+// fill resolves e's question upstream and publishes the answer.
+func (c *dnsCache) fill(ctx context.Context, key string, e *dnsCacheEntry, query dnsmessage.Message) {
+	defer close(e.ready)
+	resp, err := c.fetch(ctx, query)
+	var msg dnsmessage.Message
+	if err == nil {
+		err = msg.Unpack(resp)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || (msg.RCode != dnsmessage.RCodeSuccess && msg.RCode != dnsmessage.RCodeNameError) {
+		e.err = err
+		if e.err == nil {
+			e.err = &net.DNSError{Err: "server misbehaving: " + msg.RCode.String(), Name: query.Questions[0].Name.String(), IsTemporary: true}
+		}
+		delete(c.entries, key)
+		return
+	}
+	e.resp = resp
+	e.expires = time.Now().Add(answerTTL(msg))
+}
+
+func (c *dnsCache) fetch(ctx context.Context, query dnsmessage.Message) ([]byte, error) {
+	resp, err := dnsSend(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Pack()
+}
+
+// sweep drops expired answers once the cache has doubled since the last
+// sweep, so long streaming runs do not keep every name they ever saw.
+func (c *dnsCache) sweep() {
+	if len(c.entries) < c.sweepAt {
+		return
+	}
+	now := time.Now()
+	for k, e := range c.entries {
+		if e.resp != nil && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.sweepAt = max(2*len(c.entries), 1024)
+}
+
+// answerTTL is how long msg may be cached: the lowest TTL among its answers
+// or, for a negative answer, its SOA's, and at least dnsCacheMinTTL.
+func answerTTL(msg dnsmessage.Message) time.Duration {
+	ttl := uint32(0)
+	have := false
+	lower := func(t uint32) {
+		if !have || t < ttl {
+			ttl, have = t, true
+		}
+	}
+	for _, rr := range msg.Answers {
+		lower(rr.Header.TTL)
+	}
+	if !have {
+		for _, rr := range msg.Authorities {
+			if soa, ok := rr.Body.(*dnsmessage.SOAResource); ok {
+				lower(min(rr.Header.TTL, soa.MinTTL))
+			}
+		}
+	}
+	return max(time.Duration(ttl)*time.Second, dnsCacheMinTTL)
+}
+
+// dnsCacheKey is the context key under which withDNSCache stores the run's
+// dnsCache.
+type dnsCacheKey struct{}
+
+func withDNSCache(ctx context.Context, c *dnsCache) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, dnsCacheKey{}, c)
+}
+
+func dnsCacheFrom(ctx context.Context) *dnsCache {
+	c, _ := ctx.Value(dnsCacheKey{}).(*dnsCache)
+	return c
+}
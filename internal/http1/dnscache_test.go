@@ -0,0 +1,73 @@
+package http1
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSCache(t *testing.T) {
+	var queries atomic.Int32
+	r := dohServer(t, func(q dnsmessage.Message) dnsmessage.Message {
+		queries.Add(1)
+		return answerA(q)
+	})
+	cache := newDNSCache()
+	ctx := withDNSCache(withResolver(context.Background(), r), cache)
+
+	// Every probe of a target resolves its host; they should share one
+	// A and one AAAA query.
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ips, err := netResolver(ctx).LookupNetIP(ctx, "ip", "www.example.test")
+			if err != nil || len(ips) != 1 || ips[0].String() != "192.0.2.7" {
+				t.Errorf("LookupNetIP = %v, %v; want [192.0.2.7]", ips, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := queries.Load(); n != 2 {
+		t.Errorf("%d upstream queries, want 2", n)
+	}
+
+	cache.mu.Lock()
+	for _, e := range cache.entries {
+		e.expires = time.Now().Add(-time.Second)
+	}
+	cache.mu.Unlock()
+	if _, err := netResolver(ctx).LookupNetIP(ctx, "ip", "www.example.test"); err != nil {
+		t.Fatal(err)
+	}
+	if n := queries.Load(); n != 4 {
+		t.Errorf("%d upstream queries after expiry, want 4", n)
+	}
+}
+
+func TestAnswerTTL(t *testing.T) {
+	a := func(ttl uint32) dnsmessage.Resource {
+		return dnsmessage.Resource{Header: dnsmessage.ResourceHeader{TTL: ttl}, Body: &dnsmessage.AResource{}}
+	}
+	soa := dnsmessage.Resource{Header: dnsmessage.ResourceHeader{TTL: 3600}, Body: &dnsmessage.SOAResource{MinTTL: 300}}
+	tests := []struct {
+		name string
+		msg  dnsmessage.Message
+		want time.Duration
+	}{
+		{"lowest answer", dnsmessage.Message{Answers: []dnsmessage.Resource{a(300), a(60)}}, time.Minute},
+		{"zero TTL", dnsmessage.Message{Answers: []dnsmessage.Resource{a(0)}}, dnsCacheMinTTL},
+		{"negative", dnsmessage.Message{Authorities: []dnsmessage.Resource{soa}}, 5 * time.Minute},
+		{"empty", dnsmessage.Message{}, dnsCacheMinTTL},
+	}
+	for _, tt := range tests {
+		if got := answerTTL(tt.msg); got != tt.want {
+			t.Errorf("%s: answerTTL = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
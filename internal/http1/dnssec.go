@@ -0,0 +1,89 @@
+package http1
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// typeRRSIG is the DNSSEC signature record type (RFC 4034), unknown to
+// dnsmessage.
+const typeRRSIG dnsmessage.Type = 46
+
+// dnssecPayloadSize is the EDNS0 UDP payload size we advertise, the value
+// recommended by DNS Flag Day 2020.
+const dnssecPayloadSize = 1232
+
+// DNSSECResult reports whether a target's name is DNSSEC-signed and whether
+// the recursive resolver validated it. Validation is the resolver's (its AD
+// bit), so use a validating resolver such as --doh https://1.1.1.1/dns-query.
+type DNSSECResult struct {
+	// Signed is set when the answer carries RRSIG records.
+	Signed bool `json:"signed"`
+	// Validated is set when the resolver marked the answer authenticated.
+	Validated bool `json:"validated"`
+	// Bogus is set when validation failed: the resolver refused the answer
+	// but returns it with checking disabled.
+	Bogus  bool   `json:"bogus,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// probeDNSSEC asks for host's A records with the DO bit set, and on
+// SERVFAIL asks again with checking disabled to tell a failed validation
+// from a broken zone.
+func probeDNSSEC(ctx context.Context, host string) *DNSSECResult {
+	if net.ParseIP(host) != nil {
+		return &DNSSECResult{Detail: "not applicable to IP address targets"}
+	}
+	resp, err := dnssecQuery(ctx, host, false)
+	if err != nil {
+		return &DNSSECResult{Detail: "lookup failed: " + err.Error()}
+	}
+	if resp.RCode == dnsmessage.RCodeServerFailure {
+		cd, err := dnssecQuery(ctx, host, true)
+		if err == nil && cd.RCode == dnsmessage.RCodeSuccess {
+			return &DNSSECResult{Signed: hasRRSIG(cd), Bogus: true, Detail: "signed, but validation failed (bogus)"}
+		}
+		return &DNSSECResult{Detail: "lookup failed: SERVFAIL"}
+	}
+	return dnssecResult(resp)
+}
+
+func dnssecQuery(ctx context.Context, host string, checkingDisabled bool) (*dnsmessage.Message, error) {
+	query, err := newDNSQuery(host, dnsmessage.TypeA)
+	if err != nil {
+		return nil, err
+	}
+	query.AuthenticData = true
+	query.CheckingDisabled = checkingDisabled
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(dnssecPayloadSize, dnsmessage.RCodeSuccess, true); err != nil {
+		return nil, err
+	}
+	query.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+	return dnsSend(ctx, query)
+}
+
+// dnssecResult reads the signing and validation status off a response.
+func dnssecResult(resp *dnsmessage.Message) *DNSSECResult {
+	res := &DNSSECResult{Signed: hasRRSIG(resp), Validated: resp.AuthenticData}
+	switch {
+	case res.Validated:
+		res.Detail = "signed and validated"
+	case res.Signed:
+		res.Detail = "signed, but the resolver did not validate it"
+	default:
+		res.Detail = "not signed"
+	}
+	return res
+}
+
+func hasRRSIG(resp *dnsmessage.Message) bool {
+	for _, ans := range resp.Answers {
+		if ans.Header.Type == typeRRSIG {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package http1
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestProbeDNSSEC(t *testing.T) {
+	rrsig := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("www.example.test."), Type: typeRRSIG, Class: dnsmessage.ClassINET},
+		Body:   &dnsmessage.UnknownResource{Type: typeRRSIG, Data: []byte{0}},
+	}
+	signed := func(q dnsmessage.Message) dnsmessage.Message {
+		resp := answerA(q)
+		resp.Answers = append(resp.Answers, rrsig)
+		return resp
+	}
+
+	tests := []struct {
+		name   string
+		answer func(q dnsmessage.Message) dnsmessage.Message
+		want   DNSSECResult
+	}{
+		{"unsigned", answerA, DNSSECResult{Detail: "not signed"}},
+		{"signed, not validated", signed, DNSSECResult{Signed: true, Detail: "signed, but the resolver did not validate it"}},
+		{"validated", func(q dnsmessage.Message) dnsmessage.Message {
+			resp := signed(q)
+			resp.AuthenticData = true
+			return resp
+		}, DNSSECResult{Signed: true, Validated: true, Detail: "signed and validated"}},
+		{"bogus", func(q dnsmessage.Message) dnsmessage.Message {
+			if !q.CheckingDisabled {
+				return dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeServerFailure}}
+			}
+			return signed(q)
+		}, DNSSECResult{Signed: true, Bogus: true, Detail: "signed, but validation failed (bogus)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(withResolver(context.Background(), dohServer(t, tt.answer)), dnsTimeout)
+			defer cancel()
+			got := probeDNSSEC(ctx, "www.example.test")
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package http1
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSTiming reports how long resolving the target's host took and which
+// resolver answered, so a slow check can be blamed on DNS rather than the
+// target.
+type DNSTiming struct {
+	// DurationMS is the lookup time in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+	// Resolver is the Options.Resolver endpoint, e.g.
+	// "https://1.1.1.1/dns-query", or the system nameserver's address.
+	Resolver string `json:"resolver"`
+	// Cached is true when the run's DNS cache already held the answer,
+	// because an earlier target shared the host.
+	Cached    bool     `json:"cached,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// timeDNS resolves host before the probes start, so they connect from the
+// run's DNS cache and the lookup is timed on its own. It returns nil for IP
+// address targets, and when there is no Options.Resolver and the system
+// nameserver is unknown, since the timing could not say who answered.
+func timeDNS(ctx context.Context, host string) *DNSTiming {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	res := &DNSTiming{}
+	if r := resolverFrom(ctx); r != nil {
+		res.Resolver = r.String()
+	} else if addr, err := systemNameserver(); err == nil {
+		res.Resolver = addr
+	} else {
+		return nil
+	}
+	if c := dnsCacheFrom(ctx); c != nil {
+		res.Cached = c.holds(host)
+	}
+	start := time.Now()
+	addrs, err := netResolver(ctx).LookupHost(ctx, host)
+	res.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.Addresses = addrs
+	return res
+}
+
+// holds reports whether c has fresh A and AAAA answers for name.
+func (c *dnsCache) holds(name string) bool {
+	prefix := strings.ToLower(dnsFQDN(name)) + "/"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		e := c.entries[prefix+t.String()]
+		if e == nil || e.resp == nil || time.Now().After(e.expires) {
+			return false
+		}
+	}
+	return true
+}
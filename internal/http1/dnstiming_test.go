@@ -0,0 +1,84 @@
+package http1
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestTimeDNS(t *testing.T) {
+	r := dohServer(t, answerA)
+	ctx := withDNSCache(withResolver(context.Background(), r), newDNSCache())
+
+	first := timeDNS(ctx, "www.example.test")
+	if first == nil || first.Error != "" {
+		t.Fatalf("timeDNS = %+v", first)
+	}
+	if first.Resolver != r.String() || first.Cached || !slices.Equal(first.Addresses, []string{"192.0.2.7"}) {
+		t.Errorf("first lookup = %+v, want an uncached answer from %s", first, r)
+	}
+	if again := timeDNS(ctx, "WWW.example.test"); !again.Cached {
+		t.Errorf("second lookup = %+v, want it cached", again)
+	}
+	if res := timeDNS(ctx, "192.0.2.1"); res != nil {
+		t.Errorf("timeDNS(IP) = %+v, want nil", res)
+	}
+}
+
+func TestTimeDNSError(t *testing.T) {
+	r := dohServer(t, func(q dnsmessage.Message) dnsmessage.Message {
+		q.RCode = dnsmessage.RCodeNameError
+		return q
+	})
+	ctx := withDNSCache(withResolver(context.Background(), r), newDNSCache())
+	if res := timeDNS(ctx, "missing.example.test"); res.Error == "" || len(res.Addresses) != 0 {
+		t.Errorf("timeDNS = %+v, want an error", res)
+	}
+}
+
+func TestReadNameserver(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name, conf string
+		want       string
+		wantErr    error
+	}{
+		{"first", "# local\nsearch example\nnameserver 192.0.2.53\nnameserver 192.0.2.54\n", "192.0.2.53:53", nil},
+		{"ipv6", "nameserver 2001:db8::53", "[2001:db8::53]:53", nil},
+		{"none", "search example\nnameserver\n", "", errNoNameserver},
+		{"missing", "", "", os.ErrNotExist},
+	} {
+		path := filepath.Join(dir, tt.name)
+		if tt.name != "missing" {
+			if err := os.WriteFile(path, []byte(tt.conf), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := readNameserver(path)
+		if got != tt.want || (tt.wantErr == nil) != (err == nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("%s: readNameserver = %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTimeDNSNoNameserver(t *testing.T) {
+	saved := systemNameserver
+	defer func() { systemNameserver = saved }()
+	systemNameserver = func() (string, error) { return "", errNoNameserver }
+
+	if res := timeDNS(context.Background(), "www.example.test"); res != nil {
+		t.Errorf("timeDNS without a nameserver = %+v, want nil", res)
+	}
+	if _, err := systemExchange(context.Background(), "udp", nil); !errors.Is(err, errNoNameserver) {
+		t.Errorf("systemExchange without a nameserver: %v, want errNoNameserver", err)
+	}
+	r := dohServer(t, answerA)
+	if res := timeDNS(withResolver(context.Background(), r), "www.example.test"); res == nil || res.Resolver != r.String() {
+		t.Errorf("timeDNS with a Resolver = %+v, want it timed", res)
+	}
+}
@@ -0,0 +1,130 @@
+package http1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+)
+
+// DualStackResult compares the probes over IPv4 and IPv6, which are often
+// served by different load balancers or CDN configurations.
+type DualStackResult struct {
+	// Consistent is false when a version works over one family but not the
+	// other. A target with only one family is consistent.
+	Consistent bool `json:"consistent"`
+	// IPv4 and IPv6 list the versions supported over each family. They are
+	// only filled in for hosts with addresses in both.
+	IPv4          []string `json:"ipv4,omitempty"`
+	IPv6          []string `json:"ipv6,omitempty"`
+	Discrepancies []string `json:"discrepancies,omitempty"`
+	Detail        string   `json:"detail,omitempty"`
+}
+
+// ipVersionKey is the context key under which withIPVersion stores the IP
+// version probes are restricted to.
+type ipVersionKey struct{}
+
+func withIPVersion(ctx context.Context, v int) context.Context {
+	if v == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, ipVersionKey{}, v)
+}
+
+func ipVersionFrom(ctx context.Context) int {
+	v, _ := ctx.Value(ipVersionKey{}).(int)
+	return v
+}
+
+// ipNetwork narrows network ("tcp", "udp" or "ip") to the IP version
+// carried by ctx, e.g. "tcp" to "tcp6".
+func ipNetwork(ctx context.Context, network string) string {
+	switch ipVersionFrom(ctx) {
+	case 4:
+		return network + "4"
+	case 6:
+		return network + "6"
+	}
+	return network
+}
+
+// checkDualStack checks target as usual and then, if its host has both
+// IPv4 and IPv6 addresses, once over each family, recording how they
+// compare.
+func checkDualStack(target string, opts Options, shared *probeTransports) CheckResult {
+	opts.DualStack = false
+	res := checkTarget(target, opts, shared)
+	if res.Grade == "" {
+		return res
+	}
+	host := targetHost(res.URL)
+	ds := &DualStackResult{Consistent: true}
+	res.DualStack = ds
+	if net.ParseIP(host) != nil {
+		ds.Detail = "not applicable to IP address targets"
+		return res
+	}
+
+	// The per-family runs only need the protocol probes.
+	family := opts
+	family.DetectParking = false
+	family.CheckDNSSEC = false
+	family.CheckSNIMismatch = false
+	family.CheckCoalescing = false
+
+	ctx, cancel := context.WithTimeout(opts.baseContext(), dnsTimeout)
+	defer cancel()
+	var fams []int
+	for _, v := range []int{4, 6} {
+		if ips, err := netResolver(ctx).LookupIP(ctx, fmt.Sprintf("ip%d", v), host); err == nil && len(ips) > 0 {
+			fams = append(fams, v)
+		}
+	}
+	if len(fams) < 2 {
+		ds.Detail = "not dual-stack: the host only has one address family"
+		return res
+	}
+
+	supported := make([][]string, len(fams))
+	var wg sync.WaitGroup
+	for i, v := range fams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o := family
+			o.IPVersion = v
+			for _, vr := range checkTarget(target, o, shared).Results {
+				if vr.Supported {
+					supported[i] = append(supported[i], vr.Version)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	ds.IPv4, ds.IPv6 = supported[0], supported[1]
+	ds.Discrepancies = dualStackDiscrepancies(res.Results, ds.IPv4, ds.IPv6)
+	ds.Consistent = len(ds.Discrepancies) == 0
+	if ds.Consistent {
+		ds.Detail = "IPv4 and IPv6 support the same versions"
+	}
+	return res
+}
+
+// dualStackDiscrepancies lists, in the order of results, the versions
+// supported over only one family.
+func dualStackDiscrepancies(results []VersionResult, v4, v6 []string) []string {
+	var out []string
+	for _, vr := range results {
+		on4, on6 := slices.Contains(v4, vr.Version), slices.Contains(v6, vr.Version)
+		switch {
+		case on4 && !on6:
+			out = append(out, vr.Version+" works over IPv4 but not IPv6")
+		case on6 && !on4:
+			out = append(out, vr.Version+" works over IPv6 but not IPv4")
+		}
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package http1
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDualStack(t *testing.T) {
+	// The server only listens on IPv4, while DNS also publishes ::1.
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	resolver := dohServer(t, func(q dnsmessage.Message) dnsmessage.Message {
+		var resp dnsmessage.Message
+		h := dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: q.Questions[0].Type, Class: dnsmessage.ClassINET, TTL: 60}
+		switch q.Questions[0].Type {
+		case dnsmessage.TypeA:
+			resp.Answers = []dnsmessage.Resource{{Header: h, Body: &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}}}
+		case dnsmessage.TypeAAAA:
+			resp.Answers = []dnsmessage.Resource{{Header: h, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}}}}
+		}
+		return resp
+	})
+
+	res := runChecks("http://dual.example.test:"+port, Options{Port: port, Resolver: resolver, DualStack: true})
+	ds := res.DualStack
+	if ds == nil {
+		t.Fatalf("no dual-stack result: %+v", res.Results)
+	}
+	if ds.Consistent {
+		t.Errorf("consistent despite IPv6 being down: %+v", ds)
+	}
+	if !slices.Contains(ds.IPv4, "HTTP/1.1") || len(ds.IPv6) != 0 {
+		t.Errorf("IPv4 %v, IPv6 %v", ds.IPv4, ds.IPv6)
+	}
+	if !slices.Contains(ds.Discrepancies, "HTTP/1.1 works over IPv4 but not IPv6") {
+		t.Errorf("discrepancies = %v", ds.Discrepancies)
+	}
+}
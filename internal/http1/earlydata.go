@@ -0,0 +1,73 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// sessionCacheSize is the per-target TLS session cache capacity; the probes
+// only ever talk to a single server name.
+const sessionCacheSize = 4
+
+// EarlyDataResult reports session resumption and 0-RTT behaviour observed
+// on follow-up connections made after a successful HTTP/2 or HTTP/3 probe.
+type EarlyDataResult struct {
+	// TLSResumption is true when a second TLS connection resumed the session
+	// from the HTTP/2 probe. Go's TLS client cannot send early data over
+	// TCP, so resumption is the strongest TCP-side signal available.
+	TLSResumption bool `json:"tls_resumption"`
+	// QUIC0RTT is true when a resumed QUIC connection had its 0-RTT request
+	// accepted by the server.
+	QUIC0RTT bool   `json:"quic_0rtt"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// probeTLSResumption opens a fresh connection with tlsConf, whose session
+// cache was primed by an earlier probe, and reports whether it resumed.
+func probeTLSResumption(ctx context.Context, tlsConf *tls.Config, dial func(ctx context.Context, network, addr string) (net.Conn, error), url string, opts Options) (bool, error) {
+	// The transport adjusts its config's NextProtos on first use; tlsConf is
+	// shared with concurrent probes, so hand it a clone, which keeps the
+	// session cache.
+	tr := &http.Transport{
+		TLSClientConfig:   tlsConf.Clone(),
+		DialContext:       dial,
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, opts.method(), url, nil)
+	if err != nil {
+		return false, err
+	}
+	opts.prepareRequest(req)
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.TLS != nil && resp.TLS.DidResume, nil
+}
+
+// earlyDataDetail summarises an EarlyDataResult for humans.
+func earlyDataDetail(r EarlyDataResult, probedTLS, probedQUIC bool) string {
+	var parts []string
+	if probedTLS {
+		if r.TLSResumption {
+			parts = append(parts, "TLS session resumed")
+		} else {
+			parts = append(parts, "TLS session not resumed")
+		}
+	}
+	if probedQUIC {
+		if r.QUIC0RTT {
+			parts = append(parts, "QUIC 0-RTT accepted")
+		} else {
+			parts = append(parts, "QUIC 0-RTT not accepted")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,74 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeTLSResumption(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		tickets bool
+	}{
+		{"resumed", true},
+		{"tickets disabled", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			ts.TLS = &tls.Config{SessionTicketsDisabled: !tt.tickets}
+			ts.EnableHTTP2 = true
+			ts.StartTLS()
+			defer ts.Close()
+
+			tlsConf := &tls.Config{InsecureSkipVerify: true, ClientSessionCache: tls.NewLRUClientSessionCache(sessionCacheSize)}
+			dial := (&net.Dialer{}).DialContext
+			// The first connection primes the session cache, as the
+			// HTTP/2 probe does.
+			if _, err := probeTLSResumption(context.Background(), tlsConf, dial, ts.URL, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			resumed, err := probeTLSResumption(context.Background(), tlsConf, dial, ts.URL, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resumed != tt.tickets {
+				t.Errorf("resumed = %v, want %v", resumed, tt.tickets)
+			}
+		})
+	}
+}
+
+func TestEarlyDataDetail(t *testing.T) {
+	tests := []struct {
+		r               EarlyDataResult
+		probedTLS, quic bool
+		want            string
+	}{
+		{EarlyDataResult{TLSResumption: true, QUIC0RTT: true}, true, true, "TLS session resumed; QUIC 0-RTT accepted"},
+		{EarlyDataResult{}, true, true, "TLS session not resumed; QUIC 0-RTT not accepted"},
+		{EarlyDataResult{QUIC0RTT: true}, false, true, "QUIC 0-RTT accepted"},
+		{EarlyDataResult{}, true, false, "TLS session not resumed"},
+	}
+	for _, tt := range tests {
+		if got := earlyDataDetail(tt.r, tt.probedTLS, tt.quic); got != tt.want {
+			t.Errorf("earlyDataDetail(%+v, %v, %v) = %q, want %q", tt.r, tt.probedTLS, tt.quic, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEarlyDataOption(t *testing.T) {
+	port := startLocalServers(t)
+	target := "https://127.0.0.1:" + port
+	versions := []string{"HTTP/2.0"}
+	if res := runChecks(target, Options{Port: port, Versions: versions}); res.EarlyData != nil || res.TLS == nil || res.TLS.Resumed {
+		t.Errorf("EarlyData = %+v, TLS = %+v without CheckEarlyData", res.EarlyData, res.TLS)
+	}
+	res := runChecks(target, Options{Port: port, Versions: versions, CheckEarlyData: true})
+	if res.EarlyData == nil || !res.EarlyData.TLSResumption || !res.TLS.Resumed {
+		t.Errorf("EarlyData = %+v, want the session resumed", res.EarlyData)
+	}
+}
@@ -0,0 +1,163 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const echTimeout = 3 * time.Second
+
+// svcParamECH is the SvcParamKey carrying an ECHConfigList (RFC 9460 §14.3.2).
+const svcParamECH = 5
+
+// ECHResult describes whether a target publishes an Encrypted ClientHello
+// configuration in DNS and whether the server accepts it.
+type ECHResult struct {
+	// Advertised is true when the HTTPS DNS record carries an "ech" parameter.
+	Advertised bool `json:"advertised"`
+	// Accepted is true when a TLS handshake using that config negotiated ECH.
+	Accepted bool   `json:"accepted"`
+	Detail   string `json:"detail,omitempty"`
+	Error    bool   `json:"error,omitempty"`
+}
+
+// probeECH looks up the HTTPS record for serverName and, if it advertises an
+// ECH config, attempts a TLS 1.3 handshake with host using it.
+func probeECH(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), host, serverName, port string) ECHResult {
+	var res ECHResult
+
+	// HTTPS records only exist for names.
+	if net.ParseIP(serverName) != nil {
+		res.Detail = "not applicable to IP address targets"
+		return res
+	}
+
+	// Non-default ports use the port-prefixed owner name (RFC 9460 §2.3).
+	qname := serverName
+	if port != "443" {
+		qname = "_" + port + "._https." + serverName
+	}
+
+	configList, err := lookupECHConfig(ctx, qname)
+	if err != nil {
+		res.Error = true
+		res.Detail = fmt.Sprintf("HTTPS record lookup failed: %v", err)
+		return res
+	}
+	if configList == nil {
+		res.Detail = "no ECH config in HTTPS DNS record"
+		return res
+	}
+	res.Advertised = true
+
+	conn, err := dialTLS(ctx, dial, net.JoinHostPort(host, port), &tls.Config{
+		ServerName:                     serverName,
+		MinVersion:                     tls.VersionTLS13,
+		NextProtos:                     []string{"h2", "http/1.1"},
+		EncryptedClientHelloConfigList: configList,
+		InsecureSkipVerify:             true,
+	})
+	if err != nil {
+		var rejection *tls.ECHRejectionError
+		if errors.As(err, &rejection) {
+			res.Detail = "advertised but rejected by server"
+			return res
+		}
+		res.Error = true
+		res.Detail = fmt.Sprintf("ECH handshake failed: %v", err)
+		return res
+	}
+	defer conn.Close()
+
+	if conn.ConnectionState().ECHAccepted {
+		res.Accepted = true
+		res.Detail = "accepted"
+	} else {
+		res.Detail = "advertised but not accepted"
+	}
+	return res
+}
+
+// lookupECHConfig returns the ECHConfigList from the first ServiceMode HTTPS
+// record for name, or nil if none is published.
+func lookupECHConfig(ctx context.Context, name string) ([]byte, error) {
+	msg, err := dnsQuery(ctx, name, typeHTTPS)
+	if err != nil {
+		return nil, err
+	}
+	for _, ans := range msg.Answers {
+		if ans.Header.Type != typeHTTPS {
+			continue
+		}
+		unknown, ok := ans.Body.(*dnsmessage.UnknownResource)
+		if !ok {
+			continue
+		}
+		cfg, err := parseHTTPSRecordECH(unknown.Data)
+		if err != nil {
+			return nil, err
+		}
+		if cfg != nil {
+			return cfg, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseHTTPSRecordECH extracts the "ech" SvcParam from raw HTTPS RDATA.
+// AliasMode records (priority 0) carry no parameters and yield nil.
+func parseHTTPSRecordECH(data []byte) ([]byte, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("short HTTPS record")
+	}
+	priority := binary.BigEndian.Uint16(data)
+	if priority == 0 {
+		return nil, nil
+	}
+	off := 2
+
+	// TargetName is an uncompressed sequence of length-prefixed labels.
+	for {
+		if off >= len(data) {
+			return nil, fmt.Errorf("truncated HTTPS target name")
+		}
+		l := int(data[off])
+		off++
+		if l == 0 {
+			break
+		}
+		off += l
+	}
+
+	// SvcParams must be in strictly increasing key order (RFC 9460 §2.2),
+	// so a repeated key makes the record malformed.
+	var ech []byte
+	prev := -1
+	for off < len(data) {
+		if off+4 > len(data) {
+			return nil, fmt.Errorf("truncated HTTPS SvcParam")
+		}
+		key := int(binary.BigEndian.Uint16(data[off:]))
+		length := int(binary.BigEndian.Uint16(data[off+2:]))
+		off += 4
+		if off+length > len(data) {
+			return nil, fmt.Errorf("truncated HTTPS SvcParam")
+		}
+		if key <= prev {
+			return nil, fmt.Errorf("HTTPS SvcParam key %d out of order", key)
+		}
+		prev = key
+		if key == svcParamECH {
+			ech = data[off : off+length]
+		}
+		off += length
+	}
+	return ech, nil
+}
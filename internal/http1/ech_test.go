@@ -0,0 +1,83 @@
+package http1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// httpsRDATA builds HTTPS record RDATA with the root as TargetName and
+// the given SvcParams, alternating keys and values, in the order given.
+func httpsRDATA(priority uint16, params ...any) []byte {
+	b := binary.BigEndian.AppendUint16(nil, priority)
+	b = append(b, 0) // TargetName "."
+	for i := 0; i+1 < len(params); i += 2 {
+		value := params[i+1].([]byte)
+		b = binary.BigEndian.AppendUint16(b, uint16(params[i].(int)))
+		b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+		b = append(b, value...)
+	}
+	return b
+}
+
+func TestParseHTTPSRecordECH(t *testing.T) {
+	ech := []byte{0x00, 0x04, 0xfe, 0x0d, 0x00, 0x00}
+	alpn := []byte{2, 'h', '2'}
+	withTarget := append([]byte{0, 1, 3, 'c', 'd', 'n', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0}, httpsRDATA(1, svcParamECH, ech)[3:]...)
+
+	for _, tt := range []struct {
+		name    string
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"ech", httpsRDATA(1, 1, alpn, svcParamECH, ech), ech, false},
+		{"ech with target name", withTarget, ech, false},
+		{"no ech", httpsRDATA(1, 1, alpn), nil, false},
+		{"no params", httpsRDATA(1), nil, false},
+		{"alias mode", httpsRDATA(0, svcParamECH, ech), nil, false},
+
+		{"empty", nil, nil, true},
+		{"short priority", []byte{0}, nil, true},
+		{"no target name", []byte{0, 1}, nil, true},
+		{"target label past end", []byte{0, 1, 5, 'a', 'b'}, nil, true},
+		{"truncated key", append(httpsRDATA(1), 0, 5), nil, true},
+		{"truncated length", append(httpsRDATA(1), 0, 5, 0), nil, true},
+		{"value past end", httpsRDATA(1, svcParamECH, ech)[:len(httpsRDATA(1, svcParamECH, ech))-1], nil, true},
+		{"length past end", append(httpsRDATA(1), 0, 5, 0xff, 0xff, 1), nil, true},
+		{"duplicate ech", httpsRDATA(1, svcParamECH, ech, svcParamECH, ech), nil, true},
+		{"duplicate other key", httpsRDATA(1, 1, alpn, 1, alpn, svcParamECH, ech), nil, true},
+		{"out of order", httpsRDATA(1, svcParamECH, ech, 1, alpn), nil, true},
+	} {
+		got, err := parseHTTPSRecordECH(tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: ech = %x, want %x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func FuzzParseHTTPSRecordECH(f *testing.F) {
+	f.Add(httpsRDATA(1, 1, []byte{2, 'h', '3'}, svcParamECH, []byte{0, 4, 0xfe, 0x0d, 0, 0}))
+	f.Add(httpsRDATA(0))
+	f.Add(httpsRDATA(1, svcParamECH, []byte{1}, svcParamECH, []byte{2}))
+	f.Add([]byte{0, 1, 63})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ech, err := parseHTTPSRecordECH(data)
+		if err != nil {
+			if ech != nil {
+				t.Fatalf("parseHTTPSRecordECH(%x) = %x with error %v", data, ech, err)
+			}
+			return
+		}
+		if len(data) >= 2 && binary.BigEndian.Uint16(data) == 0 && ech != nil {
+			t.Fatalf("parseHTTPSRecordECH(%x) = %x for an AliasMode record", data, ech)
+		}
+		if ech != nil && !bytes.Contains(data, ech) {
+			t.Fatalf("parseHTTPSRecordECH(%x) = %x, not part of the record", data, ech)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package http1
+
+import (
+	"context"
+	"net"
+)
+
+// pinsEgress reports whether the probes' sockets are bound to a source
+// address or interface.
+func (o Options) pinsEgress() bool {
+	return o.SourceIP != nil || o.Interface != ""
+}
+
+// dialer returns the dialer for the probes' TCP connections.
+func (o Options) dialer() *net.Dialer {
+	d := &net.Dialer{}
+	if o.SourceIP != nil {
+		d.LocalAddr = &net.TCPAddr{IP: o.SourceIP}
+	}
+	if o.Interface != "" {
+		d.Control = bindToDevice(o.Interface)
+	}
+	return d
+}
+
+// listenUDP opens the UDP socket the QUIC probes share, on the source
+// address and interface if set.
+func (o Options) listenUDP() (*net.UDPConn, error) {
+	var lc net.ListenConfig
+	if o.Interface != "" {
+		lc.Control = bindToDevice(o.Interface)
+	}
+	addr := ":0"
+	if o.SourceIP != nil {
+		addr = net.JoinHostPort(o.SourceIP.String(), "0")
+	}
+	pc, err := lc.ListenPacket(context.Background(), "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return pc.(*net.UDPConn), nil
+}
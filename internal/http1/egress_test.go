@@ -0,0 +1,49 @@
+package http1
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestSourceIP(t *testing.T) {
+	src := net.ParseIP("127.0.0.2")
+	probe, err := net.ListenTCP("tcp", &net.TCPAddr{IP: src})
+	if err != nil {
+		t.Skipf("127.0.0.2 not usable here: %v", err)
+	}
+	probe.Close()
+
+	var mu sync.Mutex
+	var peers []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		mu.Lock()
+		peers = append(peers, host)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	opts := Options{Port: port, SourceIP: src}
+	runChecks("http://127.0.0.1:"+port, opts)
+	if len(peers) == 0 {
+		t.Fatal("no probe reached the server")
+	}
+	for _, p := range peers {
+		if p != "127.0.0.2" {
+			t.Errorf("probe came from %s, want 127.0.0.2", p)
+		}
+	}
+
+	udp, err := opts.listenUDP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	if got := udp.LocalAddr().(*net.UDPAddr).IP; !got.Equal(src) {
+		t.Errorf("UDP socket bound to %s, want %s", got, src)
+	}
+}
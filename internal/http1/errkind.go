@@ -0,0 +1,94 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrorKind classifies why a probe failed, for automation that would
+// otherwise have to match Detail's text. The human-readable Detail is kept
+// alongside it.
+type ErrorKind string
+
+const (
+	// ErrorKindDNSNXDomain: the target's name does not exist.
+	ErrorKindDNSNXDomain ErrorKind = "dns_nxdomain"
+	// ErrorKindTimeout: the probe ran out of time.
+	ErrorKindTimeout ErrorKind = "timeout"
+	// ErrorKindCanceled: the run was canceled, through the context given to
+	// a Context function, before the probe finished.
+	ErrorKindCanceled ErrorKind = "canceled"
+	// ErrorKindConnRefused: nothing listens on the target's TCP port.
+	ErrorKindConnRefused ErrorKind = "conn_refused"
+	// ErrorKindTLSHandshake: the TLS handshake failed, e.g. on a
+	// certificate, version or cipher the client rejects, or an alert.
+	ErrorKindTLSHandshake ErrorKind = "tls_handshake"
+	// ErrorKindALPNMismatch: the server would not speak the probed
+	// version, refusing its ALPN protocol or replying with another one.
+	ErrorKindALPNMismatch ErrorKind = "alpn_mismatch"
+	// ErrorKindReset: the server closed or reset the connection.
+	ErrorKindReset ErrorKind = "reset"
+	// ErrorKindQUICUnreachable: the HTTP/3 probe got no QUIC answer at all,
+	// usually UDP that is filtered or not served.
+	ErrorKindQUICUnreachable ErrorKind = "quic_unreachable"
+	// ErrorKindOther: any other failure.
+	ErrorKindOther ErrorKind = "other"
+)
+
+// errorKind classifies a probe's error; nil has no kind.
+func errorKind(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return ErrorKindDNSNXDomain
+	}
+	if strings.Contains(err.Error(), "no application protocol") {
+		return ErrorKindALPNMismatch
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorKindConnRefused
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorKindReset
+	}
+	// probeContext ends a probe that outlives its timeout by cancelling it
+	// with errProbeTimeout; other cancellations come from the caller.
+	var netErr net.Error
+	if errors.Is(err, errProbeTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorKindTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCanceled
+	}
+	var (
+		recordErr tls.RecordHeaderError
+		alertErr  tls.AlertError
+		verifyErr *tls.CertificateVerificationError
+		authErr   x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authErr) || errors.As(err, &hostErr) || strings.Contains(err.Error(), "tls: ") {
+		return ErrorKindTLSHandshake
+	}
+	return ErrorKindOther
+}
+
+// quicErrorKind is errorKind for the HTTP/3 probe, where a timeout,
+// refusal or reset means no QUIC endpoint answered.
+func quicErrorKind(err error) ErrorKind {
+	switch kind := errorKind(err); kind {
+	case ErrorKindTimeout, ErrorKindConnRefused, ErrorKindReset:
+		return ErrorKindQUICUnreachable
+	default:
+		return kind
+	}
+}
@@ -0,0 +1,52 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrorKind(t *testing.T) {
+	opErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.test", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{nil, ""},
+		{opErr(&net.DNSError{Err: "no such host", Name: "nx.test", IsNotFound: true}), ErrorKindDNSNXDomain},
+		{opErr(&os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}), ErrorKindConnRefused},
+		{opErr(&os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}), ErrorKindReset},
+		{fmt.Errorf("Get: %w", io.EOF), ErrorKindReset},
+		{fmt.Errorf("Get: %w", context.DeadlineExceeded), ErrorKindTimeout},
+		{fmt.Errorf("Get: %w", context.Canceled), ErrorKindCanceled},
+		{probeTimeoutError{fmt.Errorf("Get: %w", context.Canceled)}, ErrorKindTimeout},
+		{opErr(tls.AlertError(40)), ErrorKindTLSHandshake},
+		{errors.New("tls: failed to verify certificate: x509: certificate signed by unknown authority"), ErrorKindTLSHandshake},
+		{errors.New("CRYPTO_ERROR 0x178 (remote): tls: no application protocol"), ErrorKindALPNMismatch},
+		{errors.New("something else"), ErrorKindOther},
+	}
+	for _, tt := range tests {
+		if got := errorKind(tt.err); got != tt.want {
+			t.Errorf("errorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+
+	if got := quicErrorKind(context.DeadlineExceeded); got != ErrorKindQUICUnreachable {
+		t.Errorf("quicErrorKind(timeout) = %q, want %q", got, ErrorKindQUICUnreachable)
+	}
+	if got := quicErrorKind(context.Canceled); got != ErrorKindCanceled {
+		t.Errorf("quicErrorKind(canceled) = %q, want %q", got, ErrorKindCanceled)
+	}
+	if got := quicErrorKind(tls.AlertError(40)); got != ErrorKindTLSHandshake {
+		t.Errorf("quicErrorKind(alert) = %q, want %q", got, ErrorKindTLSHandshake)
+	}
+}
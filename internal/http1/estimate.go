@@ -0,0 +1,82 @@
+package http1
+
+import (
+	"math"
+	"time"
+)
+
+// Rough per-target costs behind EstimateScan. A check sends about a dozen
+// connections, handshakes and DNS queries; most carry a TLS handshake with a
+// certificate chain, and a target takes about as long as its slowest probe.
+const (
+	estimateProbesPerTarget = 9
+	estimateBytesPerProbe   = 6 << 10
+	estimateTimePerTarget   = 2 * time.Second
+)
+
+// estimateVersionProbes is the share of estimateProbesPerTarget each
+// version's probe accounts for, follow-up probes included.
+var estimateVersionProbes = map[string]int{
+	"HTTP/1.0": 1,
+	"HTTP/1.1": 1,
+	"HTTP/2.0": 1,
+	"HTTP/3.0": 2,
+}
+
+// ScanEstimate is a rough forecast of what a scan will cost, shown before
+// large scans so a mistyped targets file does not start a mass scan.
+type ScanEstimate struct {
+	Targets int
+	// Probes counts the connections, handshakes and DNS queries sent.
+	Probes int
+	// Bytes is the expected traffic, both directions.
+	Bytes int64
+	// Duration is the expected wall time with opts' workers and Rate.
+	Duration time.Duration
+}
+
+// EstimateScan forecasts scanning targets targets with opts.
+func EstimateScan(targets int, opts Options) ScanEstimate {
+	perTarget := estimateProbesPerTarget
+	for version, probes := range estimateVersionProbes {
+		if !opts.probes(version) {
+			perTarget -= probes
+		}
+	}
+	if opts.DualStack {
+		perTarget *= 3
+	}
+	for _, extra := range []struct {
+		on     bool
+		probes int
+	}{
+		{opts.FollowRedirects, 2},
+		{opts.DetectParking, 4},
+		{opts.CheckDNSSEC, 1},
+		{opts.CheckSNIMismatch, 2},
+		{opts.CheckCoalescing, 1},
+		{opts.CheckKeyExchange, 1},
+		{opts.CheckQUICVersions && opts.probes("HTTP/3.0"), 1},
+		{opts.CheckEarlyData && opts.probes("HTTP/2.0"), 1},
+		{opts.CheckEarlyData && opts.probes("HTTP/3.0"), 1},
+		{(opts.CheckH2Settings || opts.CheckExtendedConnect) && opts.probes("HTTP/2.0"), 1},
+		{opts.CrossCheck != "", 1},
+	} {
+		if extra.on {
+			perTarget += extra.probes
+		}
+	}
+
+	est := ScanEstimate{Targets: targets, Probes: targets * perTarget}
+	est.Bytes = int64(est.Probes) * estimateBytesPerProbe
+	workers := opts.workerLimit(workerCountForTargets(targets))
+	if workers > 0 {
+		rounds := (targets + workers - 1) / workers
+		est.Duration = time.Duration(rounds) * estimateTimePerTarget
+	}
+	if opts.Rate > 0 {
+		limited := time.Duration(math.Ceil(float64(est.Probes)/opts.Rate)) * time.Second
+		est.Duration = max(est.Duration, limited)
+	}
+	return est
+}
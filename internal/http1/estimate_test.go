@@ -0,0 +1,32 @@
+package http1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateScan(t *testing.T) {
+	base := EstimateScan(1000, Options{})
+	if base.Targets != 1000 || base.Probes != 1000*estimateProbesPerTarget || base.Bytes != int64(base.Probes)*estimateBytesPerProbe {
+		t.Errorf("EstimateScan = %+v", base)
+	}
+	if base.Duration <= 0 {
+		t.Errorf("Duration = %v, want > 0", base.Duration)
+	}
+	if more := EstimateScan(1000, Options{DualStack: true, DetectParking: true}); more.Probes <= 3*base.Probes {
+		t.Errorf("dual stack and parking: %d probes, want more than %d", more.Probes, 3*base.Probes)
+	}
+	if h23 := EstimateScan(1000, Options{Versions: []string{"HTTP/2.0", "HTTP/3.0"}}); h23.Probes != base.Probes-2000 {
+		t.Errorf("h2 and h3 only: %d probes, want %d", h23.Probes, base.Probes-2000)
+	}
+	if low := EstimateScan(1000, Options{LowResource: true}); low.Duration < base.Duration {
+		t.Errorf("low-resource scan estimated faster (%v) than default (%v)", low.Duration, base.Duration)
+	}
+	// At one probe per second the rate, not the workers, sets the pace.
+	if rated := EstimateScan(10, Options{Rate: 1}); rated.Duration != time.Duration(rated.Probes)*time.Second {
+		t.Errorf("rated Duration = %v, want %v", rated.Duration, time.Duration(rated.Probes)*time.Second)
+	}
+	if empty := EstimateScan(0, Options{}); empty.Duration != 0 || empty.Probes != 0 {
+		t.Errorf("EstimateScan(0) = %+v", empty)
+	}
+}
@@ -0,0 +1,87 @@
+package http1
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// ExtendedConnectResult reports Extended CONNECT support (the mechanism
+// behind WebSockets over HTTP/2 and HTTP/3) for one protocol version.
+type ExtendedConnectResult struct {
+	// Advertised is true when the server's SETTINGS enable the CONNECT
+	// protocol (SETTINGS_ENABLE_CONNECT_PROTOCOL = 1).
+	Advertised bool `json:"advertised"`
+	// Accepted is true when a websocket Extended CONNECT got a 2xx reply.
+	Accepted bool   `json:"accepted"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ExtendedConnectSupport groups the per-protocol Extended CONNECT results:
+// RFC 8441 for HTTP/2 and RFC 9220 for HTTP/3.
+type ExtendedConnectSupport struct {
+	H2 *ExtendedConnectResult `json:"h2,omitempty"`
+	H3 *ExtendedConnectResult `json:"h3,omitempty"`
+}
+
+// extendedConnect sends a websocket Extended CONNECT on stream 1 of an
+// established raw h2 session and waits for the response headers.
+func (s *h2Session) extendedConnect(authority string) ExtendedConnectResult {
+	res := ExtendedConnectResult{Advertised: true}
+
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: http.MethodConnect},
+		{Name: ":protocol", Value: "websocket"},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":path", Value: "/"},
+		{Name: ":authority", Value: authority},
+		{Name: "sec-websocket-version", Value: "13"},
+	} {
+		_ = enc.WriteField(f)
+	}
+
+	const streamID = 1
+	if err := s.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: block.Bytes(),
+		EndHeaders:    true,
+	}); err != nil {
+		res.Detail = fmt.Sprintf("failed to send CONNECT: %v", err)
+		return res
+	}
+	defer func() { _ = s.framer.WriteRSTStream(streamID, http2.ErrCodeCancel) }()
+
+	s.framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	for {
+		f, err := s.readFrame()
+		if err != nil {
+			res.Detail = fmt.Sprintf("no response to CONNECT: %v", err)
+			return res
+		}
+		switch f := f.(type) {
+		case *http2.MetaHeadersFrame:
+			if f.StreamID != streamID {
+				continue
+			}
+			res.Status, _ = strconv.Atoi(f.PseudoValue("status"))
+			res.Accepted = res.Status >= 200 && res.Status < 300
+			res.Detail = fmt.Sprintf("CONNECT answered with status %d", res.Status)
+			return res
+		case *http2.RSTStreamFrame:
+			if f.StreamID == streamID {
+				res.Detail = fmt.Sprintf("CONNECT reset by server: %v", f.ErrCode)
+				return res
+			}
+		case *http2.GoAwayFrame:
+			res.Detail = fmt.Sprintf("server sent GOAWAY: %v", f.ErrCode)
+			return res
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestProbeH2ExtendedConnect(t *testing.T) {
+	enabled := http2.Setting{ID: http2.SettingEnableConnectProtocol, Val: 1}
+	tests := []struct {
+		name      string
+		settings  []http2.Setting
+		authority string
+		want      *ExtendedConnectResult
+	}{
+		{"not checked", []http2.Setting{enabled}, "", nil},
+		{"not enabled", nil, "example.com", &ExtendedConnectResult{Detail: "not enabled in HTTP/2 SETTINGS"}},
+		{"disabled", []http2.Setting{{ID: http2.SettingEnableConnectProtocol, Val: 0}}, "example.com", &ExtendedConnectResult{Detail: "not enabled in HTTP/2 SETTINGS"}},
+		{"enabled", []http2.Setting{enabled}, "example.com", &ExtendedConnectResult{Advertised: true, Accepted: true, Status: 200, Detail: "CONNECT answered with status 200"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, _ := net.SplitHostPort(serveRawH2(t, nil, tt.settings...))
+			_, _, got, err := probeH2Session(context.Background(), (&net.Dialer{}).DialContext, host, port, tt.authority, &tls.Config{InsecureSkipVerify: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("extended CONNECT = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
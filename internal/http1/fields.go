@@ -0,0 +1,118 @@
+package http1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultFields is the projection used for flat (CSV) output when no explicit
+// field list is given.
+var DefaultFields = []string{
+	"target",
+	"port",
+	"grade",
+	"score",
+	"tls_version",
+	"alpn",
+	"results.HTTP/1.0.supported",
+	"results.HTTP/1.1.supported",
+	"results.HTTP/2.0.supported",
+	"results.HTTP/3.0.supported",
+}
+
+// ParseFields splits a comma-separated --fields list, dropping blanks.
+func ParseFields(spec string) []string {
+	var fields []string
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Row is a flat projection of a CheckResult: one value per requested field,
+// in the order the fields were requested.
+type Row struct {
+	Fields []string
+	Values []any
+}
+
+// ProjectFields picks the given dotted field paths out of res. Paths use the
+// JSON field names; per-version results are addressed by version, e.g.
+// "results.HTTP/3.0.supported". Missing fields yield nil.
+func ProjectFields(res CheckResult, fields []string) Row {
+	m := resultFields(res)
+	row := Row{Fields: fields, Values: make([]any, len(fields))}
+	for i, f := range fields {
+		row.Values[i] = lookupField(m, f)
+	}
+	return row
+}
+
+// Strings renders each value for flat text formats such as CSV.
+func (r Row) Strings() []string {
+	out := make([]string, len(r.Values))
+	for i, v := range r.Values {
+		switch x := v.(type) {
+		case nil:
+			out[i] = ""
+		case string:
+			out[i] = x
+		case float64, bool:
+			out[i] = fmt.Sprint(x)
+		default:
+			data, _ := json.Marshal(x)
+			out[i] = string(data)
+		}
+	}
+	return out
+}
+
+// MarshalJSON encodes the row as a flat object, preserving field order.
+func (r Row) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range r.Fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(f)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(r.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// lookupField resolves a dotted path against a generic JSON map. Because
+// version keys such as "HTTP/3.0" contain dots themselves, each step prefers
+// the longest dotted prefix that names an existing key.
+func lookupField(v any, path string) any {
+	if path == "" {
+		return v
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	parts := strings.Split(path, ".")
+	for i := len(parts); i > 0; i-- {
+		key := strings.Join(parts[:i], ".")
+		if child, ok := m[key]; ok {
+			return lookupField(child, strings.Join(parts[i:], "."))
+		}
+	}
+	return nil
+}
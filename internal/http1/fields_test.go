@@ -0,0 +1,34 @@
+package http1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProjectFields(t *testing.T) {
+	res := CheckResult{
+		Target:     "example.com",
+		Grade:      "B",
+		TLSVersion: "TLS 1.3",
+		Results: []VersionResult{
+			{Version: "HTTP/2.0", Supported: true},
+			{Version: "HTTP/3.0", Supported: false},
+		},
+	}
+
+	row := ProjectFields(res, []string{"target", "grade", "results.HTTP/2.0.supported", "results.HTTP/3.0.supported", "missing.field"})
+
+	got, err := json.Marshal(row)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"target":"example.com","grade":"B","results.HTTP/2.0.supported":true,"results.HTTP/3.0.supported":false,"missing.field":null}`
+	if string(got) != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	strs := row.Strings()
+	if strs[2] != "true" || strs[4] != "" {
+		t.Fatalf("unexpected string values %q", strs)
+	}
+}
@@ -1,5 +1,7 @@
 package http1
 
+import "fmt"
+
 // computeMinimalGrade implements the minimalist grading logic for v1.
 // It uses only:
 //   - whether HTTP/3 was successfully negotiated (hasH3),
@@ -43,4 +45,102 @@ func computeMinimalGrade(hasH3, hasH2 bool, tlsVersion string) (int, string) {
 	return 40, "F"
 }
 
+// transportSecurityAdjustment nudges the score within a grade band based on
+// how the site steers clients to HTTPS. The letter grade stays a pure
+// protocol signal; the adjustment only orders sites within it:
+//   - +3 for HSTS with a max-age of at least six months,
+//   - +2 when plain HTTP redirects to HTTPS,
+//   - -5 when plain HTTP serves content without redirecting.
+func transportSecurityAdjustment(redirect *HTTPSRedirect, hsts *HSTSResult) int {
+	adj := 0
+	for _, r := range steeringReasons(redirect, hsts) {
+		adj += r.Points
+	}
+	return adj
+}
+
+// GradeReason is one reason for a result's grade and score: a stable code
+// for automation, a human-readable detail, and the points it adds to or
+// takes off the score within the grade band.
+type GradeReason struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+	Points int    `json:"points,omitempty"`
+}
+
+// String formats r for people, e.g. "plain HTTP redirects to HTTPS: +2".
+func (r GradeReason) String() string {
+	if r.Points == 0 {
+		return r.Detail
+	}
+	return fmt.Sprintf("%s: %+d", r.Detail, r.Points)
+}
+
+// gradeReasons explains computeMinimalGrade and transportSecurityAdjustment
+// for the same inputs: first the reason for the letter grade, then one per
+// score adjustment.
+func gradeReasons(hasH3, hasH2 bool, tlsVersion string, redirect *HTTPSRedirect, hsts *HSTSResult) []GradeReason {
+	var letter GradeReason
+	switch {
+	case hasH3:
+		letter = GradeReason{Code: "h3_supported", Detail: "HTTP/3 supported: grade A"}
+	case hasH2 && tlsVersion == "TLS 1.3":
+		letter = GradeReason{Code: "no_h3", Detail: "HTTP/2 with TLS 1.3 but no HTTP/3: grade B"}
+	case hasH2 && tlsVersion != "":
+		letter = GradeReason{Code: "no_h3_old_tls", Detail: fmt.Sprintf("HTTP/2 with %s but no HTTP/3: grade C", tlsVersion)}
+	case hasH2:
+		letter = GradeReason{Code: "no_h3_tls_unknown", Detail: "HTTP/2 with an unknown TLS version but no HTTP/3: grade C"}
+	default:
+		letter = GradeReason{Code: "no_h2_h3", Detail: "neither HTTP/2 nor HTTP/3: grade F"}
+	}
+	return append([]GradeReason{letter}, steeringReasons(redirect, hsts)...)
+}
+
+// steeringReasons are the reasons behind transportSecurityAdjustment.
+func steeringReasons(redirect *HTTPSRedirect, hsts *HSTSResult) []GradeReason {
+	var reasons []GradeReason
+	if redirect != nil {
+		switch {
+		case redirect.Redirects:
+			reasons = append(reasons, GradeReason{Code: "https_redirect", Detail: "plain HTTP redirects to HTTPS", Points: 2})
+		case redirect.Status >= 200 && redirect.Status < 300:
+			reasons = append(reasons, GradeReason{Code: "http_served", Detail: "plain HTTP serves content without redirecting", Points: -5})
+		}
+	}
+	if hsts != nil && hsts.Present {
+		switch {
+		case hsts.MaxAge >= hstsMinMaxAge:
+			reasons = append(reasons, GradeReason{Code: "hsts", Detail: "HSTS max-age of at least 180 days", Points: 3})
+		case hsts.MaxAge > 0:
+			reasons = append(reasons, GradeReason{Code: "hsts_short", Detail: "HSTS max-age under 180 days: no bonus"})
+		}
+	}
+	return reasons
+}
+
+// Regrade recomputes res's score and grade from its recorded probe outcomes,
+// TLS version and HTTPS steering, the way a live scan grades them. Replaying
+// a recorded session with it shows what the current grading makes of the
+// same observations.
+func Regrade(res CheckResult) CheckResult {
+	if res.Grade == "" {
+		// The target could not be checked at all.
+		return res
+	}
+	var hasH2, hasH3 bool
+	for _, vr := range res.Results {
+		switch vr.Version {
+		case "HTTP/2.0":
+			hasH2 = hasH2 || vr.Supported
+		case "HTTP/3.0":
+			hasH3 = hasH3 || vr.Supported
+		}
+	}
+	score, grade := computeMinimalGrade(hasH3, hasH2, res.TLSVersion)
+	res.Score = score + transportSecurityAdjustment(res.HTTPSRedirect, res.HSTS)
+	res.Grade = grade
+	res.GradeReasons = gradeReasons(hasH3, hasH2, res.TLSVersion, res.HTTPSRedirect, res.HSTS)
+	return res
+}
+
 
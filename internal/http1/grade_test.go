@@ -1,6 +1,9 @@
 package http1
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestComputeMinimalGrade(t *testing.T) {
 	tests := []struct {
@@ -57,4 +60,75 @@ func TestComputeMinimalGrade(t *testing.T) {
 	}
 }
 
+func TestTransportSecurityAdjustment(t *testing.T) {
+	longHSTS := &HSTSResult{Present: true, MaxAge: 63072000}
+	shortHSTS := &HSTSResult{Present: true, MaxAge: 300}
+	tests := []struct {
+		name     string
+		redirect *HTTPSRedirect
+		hsts     *HSTSResult
+		want     int
+	}{
+		{"nothing probed", nil, nil, 0},
+		{"redirect and long hsts", &HTTPSRedirect{Redirects: true, Status: 301}, longHSTS, 5},
+		{"short hsts only", nil, shortHSTS, 0},
+		{"plain http served", &HTTPSRedirect{Status: 200}, longHSTS, -2},
+		{"redirect elsewhere", &HTTPSRedirect{Status: 302}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transportSecurityAdjustment(tt.redirect, tt.hsts); got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegrade(t *testing.T) {
+	res := CheckResult{
+		Grade:         "F",
+		Score:         40,
+		TLSVersion:    "TLS 1.3",
+		HTTPSRedirect: &HTTPSRedirect{Redirects: true, Status: 301},
+		Results: []VersionResult{
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2.0", Supported: true},
+			{Version: "HTTP/3.0"},
+		},
+	}
+	if got := Regrade(res); got.Grade != "B" || got.Score != 92 {
+		t.Errorf("Regrade = %s (%d), want B (92)", got.Grade, got.Score)
+	}
+	if got := Regrade(CheckResult{Target: "bad"}); got.Grade != "" {
+		t.Errorf("unchecked target graded %q", got.Grade)
+	}
+}
+
+func TestGradeReasons(t *testing.T) {
+	reasons := gradeReasons(false, true, "TLS 1.2", &HTTPSRedirect{Status: 200}, &HSTSResult{Present: true, MaxAge: 3600})
+	var codes []string
+	points := 0
+	for _, r := range reasons {
+		codes = append(codes, r.Code)
+		points += r.Points
+	}
+	if got := strings.Join(codes, ","); got != "no_h3_old_tls,http_served,hsts_short" {
+		t.Errorf("codes = %s", got)
+	}
+	if points != -5 {
+		t.Errorf("points add up to %d, want -5", points)
+	}
+	if got := reasons[0].String(); got != "HTTP/2 with TLS 1.2 but no HTTP/3: grade C" {
+		t.Errorf("letter reason = %q", got)
+	}
+	if got := reasons[1].String(); got != "plain HTTP serves content without redirecting: -5" {
+		t.Errorf("adjustment reason = %q", got)
+	}
+
+	got := Regrade(CheckResult{Grade: "F", Results: []VersionResult{{Version: "HTTP/3.0", Supported: true}}})
+	if len(got.GradeReasons) != 1 || got.GradeReasons[0].Code != "h3_supported" {
+		t.Errorf("Regrade reasons = %+v", got.GradeReasons)
+	}
+}
+
 
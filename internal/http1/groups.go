@@ -0,0 +1,108 @@
+package http1
+
+import (
+	"slices"
+	"strings"
+)
+
+// Unlabeled is the LabelGroup label of results without labels.
+const Unlabeled = "(unlabeled)"
+
+// LabelGroup is the grade distribution of the results carrying one label,
+// e.g. a team or environment named after the targets in a targets list, so
+// remediation can go to whoever owns them.
+type LabelGroup struct {
+	Label   string `json:"label"`
+	Targets int    `json:"targets"`
+	// Grades counts the results per grade, and Errors those that could not
+	// be graded.
+	Grades map[string]int `json:"grades"`
+	Errors int            `json:"errors,omitempty"`
+	// Worst is the lowest grade in the group.
+	Worst string `json:"worst,omitempty"`
+}
+
+// LabelTally groups results by label as they arrive. A result with several
+// labels counts in each of their groups. The zero value is ready to use.
+type LabelTally struct {
+	groups   map[string]*LabelGroup
+	labelled bool
+}
+
+// Add counts res in the groups of its labels, or in Unlabeled.
+func (t *LabelTally) Add(res CheckResult) {
+	if t.groups == nil {
+		t.groups = make(map[string]*LabelGroup)
+	}
+	labels := res.Labels
+	if len(labels) == 0 {
+		labels = []string{Unlabeled}
+	} else {
+		t.labelled = true
+	}
+	for _, l := range labels {
+		g := t.groups[l]
+		if g == nil {
+			g = &LabelGroup{Label: l, Grades: make(map[string]int)}
+			t.groups[l] = g
+		}
+		g.Targets++
+		if res.Grade == "" {
+			g.Errors++
+			continue
+		}
+		g.Grades[res.Grade]++
+		if g.Worst == "" || gradeRank(res) > gradeRank(CheckResult{Grade: g.Worst}) {
+			g.Worst = res.Grade
+		}
+	}
+}
+
+// Groups returns the groups sorted by label, Unlabeled last. It returns nil
+// when no result had a label, as one group of everything says nothing new.
+func (t *LabelTally) Groups() []LabelGroup {
+	if !t.labelled {
+		return nil
+	}
+	out := make([]LabelGroup, 0, len(t.groups))
+	for _, g := range t.groups {
+		out = append(out, *g)
+	}
+	slices.SortFunc(out, func(a, b LabelGroup) int {
+		if (a.Label == Unlabeled) != (b.Label == Unlabeled) {
+			if a.Label == Unlabeled {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Label, b.Label)
+	})
+	return out
+}
+
+// GroupByLabel is LabelTally over a finished set of results.
+func GroupByLabel(results []CheckResult) []LabelGroup {
+	var t LabelTally
+	for _, res := range results {
+		t.Add(res)
+	}
+	return t.Groups()
+}
+
+// GradeCounts lists g's grade counts best grade first, as grade/count pairs
+// for display.
+func (g LabelGroup) GradeCounts() []GradeCount {
+	var out []GradeCount
+	for _, grade := range gradeOrder {
+		if n := g.Grades[grade]; n > 0 {
+			out = append(out, GradeCount{grade, n})
+		}
+	}
+	return out
+}
+
+// GradeCount is one entry of LabelGroup.GradeCounts.
+type GradeCount struct {
+	Grade string
+	Count int
+}
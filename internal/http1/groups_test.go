@@ -0,0 +1,31 @@
+package http1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByLabel(t *testing.T) {
+	if g := GroupByLabel([]CheckResult{{Target: "a.com", Grade: "A"}}); g != nil {
+		t.Errorf("no labels: got %+v, want nil", g)
+	}
+
+	results := []CheckResult{
+		{Target: "a.com", Grade: "A", Labels: []string{"payments", "prod"}},
+		{Target: "b.com", Grade: "F", Labels: []string{"payments"}},
+		{Target: "c.com", Labels: []string{"prod"}},
+		{Target: "d.com", Grade: "B"},
+	}
+	want := []LabelGroup{
+		{Label: "payments", Targets: 2, Grades: map[string]int{"A": 1, "F": 1}, Worst: "F"},
+		{Label: "prod", Targets: 2, Grades: map[string]int{"A": 1}, Errors: 1, Worst: "A"},
+		{Label: Unlabeled, Targets: 1, Grades: map[string]int{"B": 1}, Worst: "B"},
+	}
+	got := GroupByLabel(results)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GroupByLabel =\n%+v\nwant\n%+v", got, want)
+	}
+	if c := got[0].GradeCounts(); !reflect.DeepEqual(c, []GradeCount{{"A", 1}, {"F", 1}}) {
+		t.Errorf("GradeCounts = %+v", c)
+	}
+}
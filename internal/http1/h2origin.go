@@ -0,0 +1,75 @@
+package http1
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/net/http2"
+)
+
+// frameOrigin is the ORIGIN frame type (RFC 8336), which x/net/http2 reads
+// as an UnknownFrame.
+const frameOrigin http2.FrameType = 0xc
+
+// OriginFrame reports the HTTP/2 ORIGIN frame (RFC 8336), with which a
+// server lists the origins a connection is authoritative for so clients can
+// coalesce requests onto it without matching DNS answers.
+type OriginFrame struct {
+	// Received is true when the server sent an ORIGIN frame at the start of
+	// the connection, i.e. before acknowledging our first PING.
+	Received bool `json:"received"`
+	// Origins is the advertised origin set, e.g. "https://cdn.example.com".
+	Origins []string `json:"origins,omitempty"`
+}
+
+// readFrame reads the next frame, collecting any ORIGIN frame on the way.
+func (s *h2Session) readFrame() (http2.Frame, error) {
+	f, err := s.framer.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	if uf, ok := f.(*http2.UnknownFrame); ok && uf.Type == frameOrigin && uf.StreamID == 0 {
+		s.sawOrigin = true
+		s.origins = append(s.origins, parseOriginFrame(uf.Payload())...)
+	}
+	return f, nil
+}
+
+// awaitOrigin sends a PING and reads until it is acknowledged. Servers
+// send ORIGIN right after their SETTINGS, so it arrives before the ack.
+func (s *h2Session) awaitOrigin() (*OriginFrame, error) {
+	data := [8]byte{'h', 't', 't', 'p', '1'}
+	if err := s.framer.WritePing(false, data); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch f := f.(type) {
+		case *http2.PingFrame:
+			if f.IsAck() && f.Data == data {
+				return &OriginFrame{Received: s.sawOrigin, Origins: s.origins}, nil
+			}
+		case *http2.GoAwayFrame:
+			return nil, fmt.Errorf("server sent GOAWAY: %v", f.ErrCode)
+		}
+	}
+}
+
+// parseOriginFrame decodes the Origin-Len / ASCII-Origin pairs of an ORIGIN
+// frame payload, dropping a truncated last entry.
+func parseOriginFrame(p []byte) []string {
+	var origins []string
+	for len(p) >= 2 {
+		n := int(binary.BigEndian.Uint16(p))
+		p = p[2:]
+		if n > len(p) {
+			break
+		}
+		origins = append(origins, string(p[:n]))
+		p = p[n:]
+	}
+	return origins
+}
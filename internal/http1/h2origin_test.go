@@ -0,0 +1,97 @@
+package http1
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// serveRawH2 accepts one h2 connection, sends SETTINGS with settings and,
+// if origins is not nil, an ORIGIN frame listing them, then answers PINGs
+// and answers requests with status 200.
+func serveRawH2(t *testing.T, origins []string, settings ...http2.Setting) string {
+	t.Helper()
+	ts := httptest.NewTLSServer(nil)
+	t.Cleanup(ts.Close)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: ts.TLS.Certificates, NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if _, err := io.ReadFull(c, make([]byte, len(http2.ClientPreface))); err != nil {
+			return
+		}
+		fr := http2.NewFramer(c, c)
+		_ = fr.WriteSettings(settings...)
+		if origins != nil {
+			var payload []byte
+			for _, o := range origins {
+				payload = append(payload, byte(len(o)>>8), byte(len(o)))
+				payload = append(payload, o...)
+			}
+			_ = fr.WriteRawFrame(frameOrigin, 0, 0, payload)
+		}
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := f.(type) {
+			case *http2.PingFrame:
+				if !f.IsAck() {
+					_ = fr.WritePing(true, f.Data)
+				}
+			case *http2.HeadersFrame:
+				var block bytes.Buffer
+				_ = hpack.NewEncoder(&block).WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+				_ = fr.WriteHeaders(http2.HeadersFrameParam{StreamID: f.StreamID, BlockFragment: block.Bytes(), EndHeaders: true})
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestProbeH2SessionOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		want    *OriginFrame
+	}{
+		{"none", nil, &OriginFrame{}},
+		{"empty", []string{}, &OriginFrame{Received: true}},
+		{"origins", []string{"https://example.com", "https://cdn.example.com"}, &OriginFrame{Received: true, Origins: []string{"https://example.com", "https://cdn.example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, _ := net.SplitHostPort(serveRawH2(t, tt.origins))
+			_, got, _, err := probeH2Session(context.Background(), (&net.Dialer{}).DialContext, host, port, "example.com", &tls.Config{InsecureSkipVerify: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil || got.Received != tt.want.Received || !slices.Equal(got.Origins, tt.want.Origins) {
+				t.Errorf("origin = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOriginFrame(t *testing.T) {
+	payload := []byte("\x00\x13https://example.com\x00\x20https://trunc")
+	if got := parseOriginFrame(payload); !slices.Equal(got, []string{"https://example.com"}) {
+		t.Errorf("parseOriginFrame = %q", got)
+	}
+}
@@ -0,0 +1,143 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/http2"
+)
+
+// H2Settings holds the values from the server's initial HTTP/2 SETTINGS
+// frame. Fields are nil when the server did not send that setting, in which
+// case the protocol default applies.
+type H2Settings struct {
+	HeaderTableSize      *uint32 `json:"header_table_size,omitempty"`
+	EnablePush           *uint32 `json:"enable_push,omitempty"`
+	MaxConcurrentStreams *uint32 `json:"max_concurrent_streams,omitempty"`
+	InitialWindowSize    *uint32 `json:"initial_window_size,omitempty"`
+	MaxFrameSize         *uint32 `json:"max_frame_size,omitempty"`
+	MaxHeaderListSize    *uint32 `json:"max_header_list_size,omitempty"`
+	// EnableConnectProtocol is SETTINGS_ENABLE_CONNECT_PROTOCOL (RFC 8441).
+	EnableConnectProtocol *uint32 `json:"enable_connect_protocol,omitempty"`
+}
+
+// h2Session is a raw HTTP/2 connection used by probes that need to see
+// frames the regular client hides from us.
+type h2Session struct {
+	conn     *tls.Conn
+	framer   *http2.Framer
+	settings *http2.SettingsFrame
+
+	// sawOrigin and origins collect ORIGIN frames seen by readFrame.
+	sawOrigin bool
+	origins   []string
+}
+
+// dialH2 opens a TLS connection negotiating h2, sends the client preface and
+// an empty SETTINGS frame, and reads until the server's SETTINGS arrives.
+func dialH2(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), host, port string, base *tls.Config) (*h2Session, error) {
+	raw, err := dial(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := base.Clone()
+	cfg.NextProtos = []string{http2.NextProtoTLS}
+	if cfg.ServerName == "" && net.ParseIP(host) == nil {
+		cfg.ServerName = host
+	}
+	conn := tls.Client(raw, cfg)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := conn.HandshakeContext(ctx); err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+	if p := conn.ConnectionState().NegotiatedProtocol; p != http2.NextProtoTLS {
+		_ = conn.Close()
+		return nil, fmt.Errorf("server negotiated %q instead of h2", p)
+	}
+
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// The server's connection preface must start with a SETTINGS frame.
+	f, err := framer.ReadFrame()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	sf, ok := f.(*http2.SettingsFrame)
+	if !ok || sf.IsAck() {
+		_ = conn.Close()
+		return nil, fmt.Errorf("expected server SETTINGS, got %v", f.Header().Type)
+	}
+	if err := framer.WriteSettingsAck(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &h2Session{conn: conn, framer: framer, settings: sf}, nil
+}
+
+func (s *h2Session) Close() error { return s.conn.Close() }
+
+// probeH2Session reads the server's initial SETTINGS frame over a fresh h2
+// connection and waits for a PING round trip to catch an ORIGIN frame. With
+// an authority, it also reports Extended CONNECT support, trying a
+// websocket Extended CONNECT for authority on the same connection when the
+// server enables the CONNECT protocol; without one, that result is nil.
+func probeH2Session(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), host, port, authority string, base *tls.Config) (*H2Settings, *OriginFrame, *ExtendedConnectResult, error) {
+	sess, err := dialH2(ctx, dial, host, port, base)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer sess.Close()
+
+	settings := h2SettingsFromFrame(sess.settings)
+	origin, _ := sess.awaitOrigin()
+	if authority == "" {
+		return settings, origin, nil, nil
+	}
+	connect := &ExtendedConnectResult{Detail: "not enabled in HTTP/2 SETTINGS"}
+	if settings.EnableConnectProtocol != nil && *settings.EnableConnectProtocol == 1 {
+		r := sess.extendedConnect(authority)
+		connect = &r
+	}
+	return settings, origin, connect, nil
+}
+
+func h2SettingsFromFrame(sf *http2.SettingsFrame) *H2Settings {
+	var out H2Settings
+	_ = sf.ForeachSetting(func(s http2.Setting) error {
+		v := s.Val
+		switch s.ID {
+		case http2.SettingHeaderTableSize:
+			out.HeaderTableSize = &v
+		case http2.SettingEnablePush:
+			out.EnablePush = &v
+		case http2.SettingMaxConcurrentStreams:
+			out.MaxConcurrentStreams = &v
+		case http2.SettingInitialWindowSize:
+			out.InitialWindowSize = &v
+		case http2.SettingMaxFrameSize:
+			out.MaxFrameSize = &v
+		case http2.SettingMaxHeaderListSize:
+			out.MaxHeaderListSize = &v
+		case http2.SettingEnableConnectProtocol:
+			out.EnableConnectProtocol = &v
+		}
+		return nil
+	})
+	return &out
+}
@@ -0,0 +1,72 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestProbeH2SessionSettings(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	if err := http2.ConfigureServer(ts.Config, &http2.Server{
+		MaxConcurrentStreams:     42,
+		MaxUploadBufferPerStream: 1 << 17,
+		MaxReadFrameSize:         1 << 15,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var conns atomic.Int32
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.TLS = ts.Config.TLSConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	got, _, _, err := probeH2Session(context.Background(), (&net.Dialer{}).DialContext, host, port, "", &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		v    *uint32
+		want uint32
+	}{
+		{"max_concurrent_streams", got.MaxConcurrentStreams, 42},
+		{"initial_window_size", got.InitialWindowSize, 1 << 17},
+		{"max_frame_size", got.MaxFrameSize, 1 << 15},
+	} {
+		if tt.v == nil || *tt.v != tt.want {
+			t.Errorf("%s = %v, want %d", tt.name, tt.v, tt.want)
+		}
+	}
+	if got.EnablePush != nil {
+		t.Errorf("enable_push = %d, want it not sent", *got.EnablePush)
+	}
+
+	res := runChecks(ts.URL, Options{Versions: []string{"HTTP/2.0"}, CheckH2Settings: true})
+	if s := res.H2Settings; s == nil || s.MaxConcurrentStreams == nil || *s.MaxConcurrentStreams != 42 {
+		t.Errorf("CheckResult.H2Settings = %+v, want max_concurrent_streams 42", s)
+	}
+
+	// Without the option, the HTTP/2 probe is the only connection.
+	conns.Store(0)
+	res = runChecks(ts.URL, Options{Versions: []string{"HTTP/2.0"}})
+	if res.H2Settings != nil || res.H2Origin != nil {
+		t.Errorf("H2Settings = %+v, H2Origin = %+v without CheckH2Settings", res.H2Settings, res.H2Origin)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("%d connections without CheckH2Settings, want 1", n)
+	}
+}
@@ -0,0 +1,292 @@
+//go:build !noh3 && !wasm
+
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// h3Available reports whether this build includes the HTTP/3 probes; the
+// noh3 build tag (implied on WebAssembly) leaves out quic-go and with it
+// everything in this file.
+const h3Available = true
+
+// lowResourceQUICConfig is the QUIC configuration of low-resource mode.
+var lowResourceQUICConfig = &quic.Config{
+	InitialStreamReceiveWindow:     64 << 10,
+	MaxStreamReceiveWindow:         256 << 10,
+	InitialConnectionReceiveWindow: 128 << 10,
+	MaxConnectionReceiveWindow:     512 << 10,
+}
+
+// setupH3 adds the HTTP/3 client to pt. A scan in low-resource mode, or
+// with a pinned egress path, sends all QUIC traffic over one UDP socket
+// bound here.
+func (pt *probeTransports) setupH3(opts Options, cacheSize int) error {
+	if opts.LowResource || opts.pinsEgress() {
+		// A pinned egress path needs a socket we bound ourselves.
+		udp, err := opts.listenUDP()
+		if err != nil {
+			return err
+		}
+		tr := &quic.Transport{Conn: udp}
+		pt.quic.tr = tr
+		pt.closers = append(pt.closers, tr.Close, udp.Close)
+	}
+
+	pt.h3TLS = opts.tlsConfig()
+	pt.h3TLS.NextProtos = []string{http3.NextProtoH3}
+	pt.h3TLS.ClientSessionCache = tls.NewLRUClientSessionCache(cacheSize)
+	h3Transport := &http3.Transport{
+		TLSClientConfig: pt.h3TLS,
+		Dial:            pt.quic.dialEarly,
+	}
+	if opts.LowResource {
+		h3Transport.QUICConfig = lowResourceQUICConfig.Clone()
+	}
+	pt.h3 = &http.Client{
+		Transport:     h3Transport,
+		CheckRedirect: noRedirects,
+	}
+	pt.h3Idle = h3Transport.CloseIdleConnections
+	pt.closers = append([]func() error{h3Transport.Close}, pt.closers...)
+	return nil
+}
+
+// quicDialer dials QUIC connections, over one shared UDP socket when tr is
+// set and over a fresh socket per connection otherwise.
+type quicDialer struct {
+	tr *quic.Transport
+}
+
+func (d *quicDialer) dial(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (*quic.Conn, error) {
+	if d.tr == nil {
+		if netResolver(ctx) != net.DefaultResolver || ipVersionFrom(ctx) != 0 {
+			var err error
+			if addr, tlsConf, err = resolveForQUIC(ctx, addr, tlsConf); err != nil {
+				return nil, err
+			}
+		}
+		return quic.DialAddr(ctx, addr, tlsConf, conf)
+	}
+	udpAddr, err := d.resolve(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return d.tr.Dial(ctx, udpAddr, tlsConf, conf)
+}
+
+func (d *quicDialer) dialEarly(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (*quic.Conn, error) {
+	if d.tr == nil {
+		if resolverFrom(ctx) != nil || ipVersionFrom(ctx) != 0 {
+			var err error
+			if addr, tlsConf, err = resolveForQUIC(ctx, addr, tlsConf); err != nil {
+				return nil, err
+			}
+		}
+		return timeHandshake(ctx, time.Now())(quic.DialAddrEarly(ctx, addr, tlsConf, conf))
+	}
+	udpAddr, err := d.resolve(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return timeHandshake(ctx, time.Now())(d.tr.DialEarly(ctx, udpAddr, tlsConf, conf))
+}
+
+// timeHandshake records the handshake of a connection dialed at start on
+// ctx's probe timer, if any. An early connection is returned before its
+// handshake completes, so a timed dial waits for it here, and the
+// handshake is recorded before the probe reads its timings. Timed probes
+// thus never send 0-RTT data; probeQUIC0RTT dials without a timer.
+func timeHandshake(ctx context.Context, start time.Time) func(*quic.Conn, error) (*quic.Conn, error) {
+	return func(conn *quic.Conn, err error) (*quic.Conn, error) {
+		pt := probeTimerFrom(ctx)
+		if pt == nil || err != nil {
+			return conn, err
+		}
+		select {
+		case <-conn.HandshakeComplete():
+			pt.handshake(start)
+			return conn, nil
+		case <-conn.Context().Done():
+			return nil, context.Cause(conn.Context())
+		case <-ctx.Done():
+			_ = conn.CloseWithError(0, "")
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// resolve resolves addr for the shared socket, preferring IPv4 when the
+// socket is bound to an IPv4 address and cannot reach IPv6 peers.
+func (d *quicDialer) resolve(ctx context.Context, addr string) (*net.UDPAddr, error) {
+	network := ipNetwork(ctx, "ip")
+	if local, _ := d.tr.Conn.LocalAddr().(*net.UDPAddr); local != nil && local.IP.To4() != nil {
+		network = "ip4"
+	}
+	return resolveUDPAddr(ctx, network, addr)
+}
+
+// resolveForQUIC resolves addr with the context's Resolver and IP version,
+// which quic.DialAddr would ignore. The host name stays the TLS server name.
+func resolveForQUIC(ctx context.Context, addr string, tlsConf *tls.Config) (string, *tls.Config, error) {
+	udpAddr, err := resolveUDPAddr(ctx, ipNetwork(ctx, "ip"), addr)
+	if err != nil {
+		return "", nil, err
+	}
+	if tlsConf.ServerName == "" {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName = host
+	}
+	return udpAddr.String(), tlsConf, nil
+}
+
+// quicHandshake completes a QUIC handshake offering h3 with the server at
+// addr and closes the connection.
+func quicHandshake(ctx context.Context, addr, serverName string) error {
+	tlsConf := &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         []string{http3.NextProtoH3},
+		InsecureSkipVerify: true,
+	}
+	conn, err := (&quicDialer{}).dial(ctx, addr, tlsConf, nil)
+	if err != nil {
+		return err
+	}
+	return conn.CloseWithError(0, "")
+}
+
+// probeQUIC0RTT opens a fresh QUIC connection with tlsConf, whose session
+// cache was primed by the HTTP/3 probe, sends a GET (or HEAD) as 0-RTT data and
+// reports whether the server accepted it.
+func probeQUIC0RTT(ctx context.Context, qd *quicDialer, tlsConf *tls.Config, url string, opts Options) (bool, error) {
+	var conn *quic.Conn
+	tr := &http3.Transport{
+		TLSClientConfig: tlsConf,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			c, err := qd.dialEarly(ctx, addr, tlsCfg, cfg)
+			conn = c
+			return c, err
+		},
+	}
+	defer tr.Close()
+
+	req, err := http.NewRequestWithContext(ctx, opts.method0RTT(), url, nil)
+	if err != nil {
+		return false, err
+	}
+	opts.prepareRequest(req)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return conn != nil && conn.ConnectionState().Used0RTT, nil
+}
+
+// probeH3At sends req over HTTP/3 to the QUIC endpoint addr rather than the
+// request URL's host, as a client following Alt-Svc does: TLS and
+// :authority still name the origin.
+func probeH3At(ctx context.Context, qd *quicDialer, base *tls.Config, addr string, req *http.Request) error {
+	tlsConf := base.Clone()
+	tlsConf.NextProtos = []string{http3.NextProtoH3}
+	if host := req.URL.Hostname(); tlsConf.ServerName == "" && net.ParseIP(host) == nil {
+		tlsConf.ServerName = host
+	}
+	tr := &http3.Transport{
+		TLSClientConfig: tlsConf,
+		Dial: func(ctx context.Context, _ string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			return qd.dialEarly(ctx, addr, tlsCfg, cfg)
+		},
+	}
+	defer tr.Close()
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// probeH3ExtendedConnect opens a fresh QUIC connection, waits for the
+// server's HTTP/3 SETTINGS and, if Extended CONNECT is enabled, attempts a
+// websocket CONNECT.
+func probeH3ExtendedConnect(ctx context.Context, qd *quicDialer, base *tls.Config, host, port, authority string) ExtendedConnectResult {
+	var res ExtendedConnectResult
+
+	tlsConf := base.Clone()
+	tlsConf.NextProtos = []string{http3.NextProtoH3}
+	if tlsConf.ServerName == "" && net.ParseIP(host) == nil {
+		tlsConf.ServerName = host
+	}
+	conn, err := qd.dial(ctx, net.JoinHostPort(host, port), tlsConf, &quic.Config{})
+	if err != nil {
+		res.Detail = fmt.Sprintf("QUIC connection failed: %v", err)
+		return res
+	}
+	defer func() { _ = conn.CloseWithError(0, "") }()
+
+	tr := &http3.Transport{}
+	cc := tr.NewClientConn(conn)
+	select {
+	case <-cc.ReceivedSettings():
+	case <-ctx.Done():
+		res.Detail = "no HTTP/3 SETTINGS received"
+		return res
+	}
+	if !cc.Settings().EnableExtendedConnect {
+		res.Detail = "not enabled in HTTP/3 SETTINGS"
+		return res
+	}
+	res.Advertised = true
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodConnect, "https://"+net.JoinHostPort(host, port)+"/", nil)
+	if err != nil {
+		res.Detail = "request build failed"
+		return res
+	}
+	req.Host = authority
+	// quic-go sends req.Proto as the :protocol pseudo-header for CONNECT.
+	req.Proto = "websocket"
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		res.Detail = fmt.Sprintf("CONNECT failed: %v", err)
+		return res
+	}
+	_ = resp.Body.Close()
+	res.Status = resp.StatusCode
+	res.Accepted = resp.StatusCode >= 200 && resp.StatusCode < 300
+	res.Detail = fmt.Sprintf("CONNECT answered with status %d", resp.StatusCode)
+	return res
+}
+
+// method0RTT picks the 0-RTT variant for the early data probe. Only GET and
+// HEAD are replay-safe enough to send as early data, so other methods fall
+// back to HEAD.
+func (o Options) method0RTT() string {
+	if o.method() == http.MethodGet {
+		return http3.MethodGet0RTT
+	}
+	return http3.MethodHead0RTT
+}
+
+// serveH3 serves handler over HTTP/3 on udp for TestServers.
+func serveH3(udp *net.UDPConn, handler http.Handler, certs []tls.Certificate) io.Closer {
+	h3 := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: certs}),
+	}
+	go func() { _ = h3.Serve(udp) }()
+	return h3
+}
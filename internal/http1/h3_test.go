@@ -0,0 +1,180 @@
+//go:build !noh3 && !wasm
+
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestMethod0RTT(t *testing.T) {
+	tests := []struct {
+		method, want string
+	}{
+		{"", http3.MethodGet0RTT},
+		{"GET", http3.MethodGet0RTT},
+		{"HEAD", http3.MethodHead0RTT},
+		{"OPTIONS", http3.MethodHead0RTT},
+		{http.MethodPost, http3.MethodHead0RTT},
+	}
+	for _, tt := range tests {
+		o := Options{Method: tt.method}
+		if got := o.method0RTT(); got != tt.want {
+			t.Errorf("method0RTT(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestAltSvcFollowUp(t *testing.T) {
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("no UDP: %v", err)
+	}
+	altPort := udp.LocalAddr().(*net.UDPAddr).Port
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"; ma=60`, altPort))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+	h3 := serveH3(udp, ts.Config.Handler, ts.TLS.Certificates)
+	defer h3.Close()
+
+	res := runChecks(ts.URL, Options{Versions: []string{"HTTP/2.0", "HTTP/3.0"}})
+	a := res.AltSvc
+	if a == nil || !a.Advertised || !a.Verified {
+		t.Fatalf("AltSvc = %+v, want advertised and verified", a)
+	}
+	if e := a.Endpoints[0]; !e.FollowUp || e.Port != fmt.Sprint(altPort) {
+		t.Errorf("endpoint = %+v", e)
+	}
+	if vr := res.Results[3]; !vr.Supported || vr.Detail != fmt.Sprintf("supported at the advertised endpoint :%d", altPort) {
+		t.Errorf("HTTP/3 result = %+v", vr)
+	}
+}
+
+// serveH3NoExtendedConnect accepts QUIC connections on udp and sends only
+// an empty HTTP/3 SETTINGS frame on each, so Extended CONNECT is not
+// enabled.
+func serveH3NoExtendedConnect(t *testing.T, udp *net.UDPConn, certs []tls.Certificate) {
+	t.Helper()
+	ln, err := quic.Listen(udp, &tls.Config{Certificates: certs, NextProtos: []string{http3.NextProtoH3}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			str, err := conn.OpenUniStream()
+			if err != nil {
+				continue
+			}
+			// A control stream (type 0x00) with a SETTINGS frame (type
+			// 0x04) of length 0.
+			_, _ = str.Write([]byte{0x00, 0x04, 0x00})
+		}
+	}()
+}
+
+func TestProbeH3ExtendedConnect(t *testing.T) {
+	ts := httptest.NewTLSServer(nil)
+	defer ts.Close()
+	certs := ts.TLS.Certificates
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    ExtendedConnectResult
+	}{
+		{"not enabled", false, ExtendedConnectResult{Detail: "not enabled in HTTP/3 SETTINGS"}},
+		{"enabled", true, ExtendedConnectResult{Advertised: true, Accepted: true, Status: 200, Detail: "CONNECT answered with status 200"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Skipf("no UDP: %v", err)
+			}
+			if tt.enabled {
+				// quic-go's server enables Extended CONNECT.
+				h3 := serveH3(udp, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodConnect {
+						w.WriteHeader(http.StatusBadRequest)
+					}
+				}), certs)
+				defer h3.Close()
+			} else {
+				serveH3NoExtendedConnect(t, udp, certs)
+			}
+			port := fmt.Sprint(udp.LocalAddr().(*net.UDPAddr).Port)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			got := probeH3ExtendedConnect(ctx, &quicDialer{}, &tls.Config{InsecureSkipVerify: true}, "127.0.0.1", port, "example.com")
+			if got != tt.want {
+				t.Errorf("extended CONNECT = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeQUIC0RTT(t *testing.T) {
+	ts := httptest.NewTLSServer(nil)
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		name  string
+		allow bool
+	}{
+		{"accepted", true},
+		{"rejected", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Skipf("no UDP: %v", err)
+			}
+			h3 := &http3.Server{
+				Handler:    http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+				TLSConfig:  http3.ConfigureTLSConfig(&tls.Config{Certificates: ts.TLS.Certificates}),
+				QUICConfig: &quic.Config{Allow0RTT: tt.allow},
+			}
+			go func() { _ = h3.Serve(udp) }()
+			defer h3.Close()
+			url := fmt.Sprintf("https://127.0.0.1:%d/", udp.LocalAddr().(*net.UDPAddr).Port)
+
+			// Prime the session cache with a regular request, as the
+			// HTTP/3 probe does.
+			tlsConf := &tls.Config{InsecureSkipVerify: true, ClientSessionCache: tls.NewLRUClientSessionCache(sessionCacheSize)}
+			tr := &http3.Transport{TLSClientConfig: tlsConf}
+			resp, err := (&http.Client{Transport: tr}).Get(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			_ = tr.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			accepted, err := probeQUIC0RTT(ctx, &quicDialer{}, tlsConf, url, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if accepted != tt.allow {
+				t.Errorf("0-RTT accepted = %v, want %v", accepted, tt.allow)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package http1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HowtoStep is how to make one change on a server stack. Change matches a
+// Projection's, e.g. "Enabling HTTP/3".
+type HowtoStep struct {
+	Change string `json:"change"`
+	// Steps says what to do, Config is the configuration it takes, if any,
+	// and Docs links the stack's documentation.
+	Steps  string `json:"steps"`
+	Config string `json:"config,omitempty"`
+	Docs   string `json:"docs,omitempty"`
+}
+
+// Changes HowtoStep covers.
+const (
+	changeHTTP2 = "Enabling HTTP/2"
+	changeHTTP3 = "Enabling HTTP/3"
+	changeTLS13 = "Enabling TLS 1.3"
+)
+
+// howtos is the knowledge base: per stack, the steps for each change.
+var howtos = map[string][]HowtoStep{
+	StackNginx: {
+		{
+			Change: changeHTTP2,
+			Steps:  "Turn on HTTP/2 in the server block (nginx 1.25.1 or later; older versions take \"listen 443 ssl http2;\" instead).",
+			Config: "server {\n    listen 443 ssl;\n    http2 on;\n}",
+			Docs:   "https://nginx.org/en/docs/http/ngx_http_v2_module.html",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "Listen for QUIC next to TCP and advertise HTTP/3 with Alt-Svc (nginx 1.25.0 or later, built with ngx_http_v3_module), then open UDP port 443 in the firewall.",
+			Config: "server {\n    listen 443 ssl;\n    listen 443 quic reuseport;\n    http2 on;\n    add_header Alt-Svc 'h3=\":443\"; ma=86400';\n}",
+			Docs:   "https://nginx.org/en/docs/http/ngx_http_v3_module.html",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "Allow TLS 1.3 (needs OpenSSL 1.1.1 or later).",
+			Config: "ssl_protocols TLSv1.2 TLSv1.3;",
+			Docs:   "https://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_protocols",
+		},
+	},
+	StackApache: {
+		{
+			Change: changeHTTP2,
+			Steps:  "Load mod_http2 and offer h2 ahead of HTTP/1.1. Use the event or worker MPM; prefork cannot serve HTTP/2.",
+			Config: "LoadModule http2_module modules/mod_http2.so\nProtocols h2 http/1.1",
+			Docs:   "https://httpd.apache.org/docs/2.4/mod/mod_http2.html",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "Apache httpd does not speak HTTP/3. Put a QUIC-capable proxy or CDN in front of it, e.g. nginx, HAProxy, Caddy or CloudFront, and follow its instructions.",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "Allow TLS 1.3 (needs httpd 2.4.36 and OpenSSL 1.1.1 or later).",
+			Config: "SSLProtocol -all +TLSv1.2 +TLSv1.3",
+			Docs:   "https://httpd.apache.org/docs/2.4/mod/mod_ssl.html#sslprotocol",
+		},
+	},
+	StackHAProxy: {
+		{
+			Change: changeHTTP2,
+			Steps:  "Offer h2 in ALPN on the HTTPS bind line.",
+			Config: "frontend www\n    bind :443 ssl crt /etc/haproxy/site.pem alpn h2,http/1.1",
+			Docs:   "https://docs.haproxy.org/",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "Add a QUIC bind line and advertise HTTP/3 with Alt-Svc (HAProxy 2.6 or later, built against a TLS library with QUIC support), then open UDP port 443 in the firewall.",
+			Config: "frontend www\n    bind :443 ssl crt /etc/haproxy/site.pem alpn h2,http/1.1\n    bind quic4@:443 ssl crt /etc/haproxy/site.pem alpn h3\n    http-response set-header alt-svc \"h3=\\\":443\\\"; ma=86400\"",
+			Docs:   "https://docs.haproxy.org/",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "HAProxy offers TLS 1.3 when built with OpenSSL 1.1.1 or later; make sure no ssl-max-ver caps it.",
+			Config: "global\n    ssl-default-bind-options ssl-min-ver TLSv1.2",
+			Docs:   "https://docs.haproxy.org/",
+		},
+	},
+	StackIIS: {
+		{
+			Change: changeHTTP2,
+			Steps:  "IIS 10 (Windows Server 2016 and later) serves HTTP/2 over HTTPS bindings by default. Check that the site has an HTTPS binding and that HTTP/2 has not been turned off in the registry.",
+			Config: "reg add HKLM\\SYSTEM\\CurrentControlSet\\Services\\HTTP\\Parameters /v EnableHttp2Tls /t REG_DWORD /d 1 /f",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "On Windows Server 2022 or later, turn on HTTP/3 and Alt-Svc in HTTP.sys, restart the HTTP service (or reboot) and open UDP port 443 in the firewall.",
+			Config: "reg add HKLM\\SYSTEM\\CurrentControlSet\\Services\\HTTP\\Parameters /v EnableHttp3 /t REG_DWORD /d 1 /f\nreg add HKLM\\SYSTEM\\CurrentControlSet\\Services\\HTTP\\Parameters /v EnableAltSvc /t REG_DWORD /d 1 /f",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "TLS 1.3 needs Windows Server 2022 or later, where it is on by default; older versions cannot offer it.",
+		},
+	},
+	StackCaddy: {
+		{
+			Change: changeHTTP2,
+			Steps:  "Caddy serves HTTP/2 by default; make sure the protocols global option does not leave h2 out.",
+			Config: "{\n    servers {\n        protocols h1 h2 h3\n    }\n}",
+			Docs:   "https://caddyserver.com/docs/caddyfile/options#protocols",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "Caddy serves HTTP/3 by default; make sure the protocols global option does not leave h3 out and that UDP port 443 is open, including in container port mappings.",
+			Config: "{\n    servers {\n        protocols h1 h2 h3\n    }\n}",
+			Docs:   "https://caddyserver.com/docs/caddyfile/options#protocols",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "Caddy offers TLS 1.3 by default; check that no tls directive caps protocols at tls1.2.",
+			Config: "tls {\n    protocols tls1.2 tls1.3\n}",
+			Docs:   "https://caddyserver.com/docs/caddyfile/directives/tls",
+		},
+	},
+	StackCloudFront: {
+		{
+			Change: changeHTTP2,
+			Steps:  "In the distribution's settings, set Supported HTTP versions to include HTTP/2 (HttpVersion http2 or http2and3 in the API).",
+			Docs:   "https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/distribution-web-values-specify.html",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "In the distribution's settings, set Supported HTTP versions to HTTP/2 and HTTP/3 (HttpVersion http2and3 in the API). CloudFront advertises HTTP/3 itself.",
+			Docs:   "https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/distribution-web-values-specify.html",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "CloudFront negotiates TLS 1.3 with clients that offer it; choose a current security policy such as TLSv1.2_2021 for the viewer certificate.",
+			Docs:   "https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/secure-connections-supported-viewer-protocols-ciphers.html",
+		},
+	},
+	StackFastly: {
+		{
+			Change: changeHTTP2,
+			Steps:  "Fastly serves HTTP/2 on its TLS configurations; check the domain's TLS configuration in the control panel and that it is not pinned to HTTP/1.1.",
+			Docs:   "https://docs.fastly.com/",
+		},
+		{
+			Change: changeHTTP3,
+			Steps:  "Advertise HTTP/3 from the service by calling h3.alt_svc() in vcl_recv (or enabling HTTP/3 for the service in the control panel).",
+			Config: "sub vcl_recv {\n    h3.alt_svc();\n}",
+			Docs:   "https://docs.fastly.com/",
+		},
+		{
+			Change: changeTLS13,
+			Steps:  "Fastly offers TLS 1.3 on its current TLS configurations; move the domain to one if it is on a legacy configuration.",
+			Docs:   "https://docs.fastly.com/",
+		},
+	},
+}
+
+// Stacks lists the server stacks Howto has instructions for.
+func Stacks() []string {
+	return []string{StackNginx, StackApache, StackHAProxy, StackIIS, StackCaddy, StackCloudFront, StackFastly}
+}
+
+// ParseStack returns the stack named name, in any case, or an error naming
+// the known ones.
+func ParseStack(name string) (string, error) {
+	for _, s := range Stacks() {
+		if strings.EqualFold(s, strings.TrimSpace(name)) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown server stack %q (want %s)", name, strings.Join(Stacks(), ", "))
+}
+
+// HowtoChanges lists the changes res still needs for a better grade, in the
+// order Howto gives them: HTTP/2, HTTP/3, then TLS 1.3. Versions that were
+// not tested are left out.
+func HowtoChanges(res CheckResult) []string {
+	var changes []string
+	for _, vr := range res.Results {
+		if vr.Supported || vr.NotTested {
+			continue
+		}
+		switch vr.Version {
+		case "HTTP/2.0":
+			changes = append(changes, changeHTTP2)
+		case "HTTP/3.0":
+			changes = append(changes, changeHTTP3)
+		}
+	}
+	if res.TLSVersion != "" && res.TLSVersion != "TLS 1.3" {
+		changes = append(changes, changeTLS13)
+	}
+	return changes
+}
+
+// Howto returns the steps to make res's missing changes on stack, one of
+// Stacks. It returns nil when nothing is missing or the stack is unknown.
+func Howto(res CheckResult, stack string) []HowtoStep {
+	var steps []HowtoStep
+	for _, change := range HowtoChanges(res) {
+		for _, step := range howtos[stack] {
+			if step.Change == change {
+				steps = append(steps, step)
+			}
+		}
+	}
+	return steps
+}
@@ -0,0 +1,57 @@
+package http1
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFingerprintStack(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"nginx", http.Header{"Server": {"nginx/1.25.3"}}, StackNginx},
+		{"openresty", http.Header{"Server": {"openresty"}}, StackNginx},
+		{"iis", http.Header{"Server": {"Microsoft-IIS/10.0"}}, StackIIS},
+		{"cloudfront over nginx", http.Header{"Server": {"nginx"}, "Via": {"1.1 abc.cloudfront.net (CloudFront)"}}, StackCloudFront},
+		{"fastly", http.Header{"X-Served-By": {"cache-fra-etou8220033-FRA"}}, StackFastly},
+		{"unknown", http.Header{"Server": {"gws"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := fingerprintStack(tt.header); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	results := []VersionResult{{}, {server: "Apache", stack: StackApache}, {}, {}}
+	if server, stack := serverStack(results, []string{"d111.cloudfront.net."}); server != "Apache" || stack != StackCloudFront {
+		t.Errorf("serverStack = %q, %q", server, stack)
+	}
+}
+
+func TestHowto(t *testing.T) {
+	res := CheckResult{
+		TLSVersion: "TLS 1.2",
+		Results: []VersionResult{
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2.0", Supported: true},
+			{Version: "HTTP/3.0"},
+		},
+	}
+	steps := Howto(res, StackNginx)
+	if len(steps) != 2 || steps[0].Change != "Enabling HTTP/3" || steps[1].Change != "Enabling TLS 1.3" {
+		t.Fatalf("steps = %+v", steps)
+	}
+	for _, stack := range Stacks() {
+		if got := len(howtos[stack]); got != 3 {
+			t.Errorf("%s has %d steps, want one per change", stack, got)
+		}
+	}
+	if s, err := ParseStack("cloudfront"); err != nil || s != StackCloudFront {
+		t.Errorf("ParseStack(cloudfront) = %q, %v", s, err)
+	}
+	if _, err := ParseStack("lighttpd"); err == nil {
+		t.Error("unknown stack accepted")
+	}
+}
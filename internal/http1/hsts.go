@@ -0,0 +1,88 @@
+package http1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hstsMinMaxAge is the max-age from which HSTS counts towards the score;
+// the same six-month threshold SSL Labs and the preload list use.
+const hstsMinMaxAge = 180 * 24 * 60 * 60
+
+// HTTPSRedirect describes how the plain-HTTP endpoint answered.
+type HTTPSRedirect struct {
+	// Redirects is true when plain HTTP answered with a 3xx pointing at an
+	// https:// URL.
+	Redirects bool   `json:"redirects"`
+	Status    int    `json:"status"`
+	Location  string `json:"location,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// HSTSResult is the parsed Strict-Transport-Security header of an HTTPS
+// response (RFC 6797).
+type HSTSResult struct {
+	Present           bool  `json:"present"`
+	MaxAge            int64 `json:"max_age"`
+	IncludeSubDomains bool  `json:"include_subdomains"`
+	Preload           bool  `json:"preload"`
+}
+
+// httpsRedirectFrom inspects the response to a plain-HTTP request.
+func httpsRedirectFrom(resp *http.Response) *HTTPSRedirect {
+	r := &HTTPSRedirect{Status: resp.StatusCode}
+	if resp.StatusCode < 300 || resp.StatusCode > 399 {
+		r.Detail = fmt.Sprintf("served %d over plain HTTP without redirecting", resp.StatusCode)
+		return r
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		r.Detail = "redirect without a usable Location header"
+		return r
+	}
+	r.Location = loc.String()
+	if loc.Scheme == "https" {
+		r.Redirects = true
+		r.Detail = "redirects to HTTPS"
+	} else {
+		r.Detail = "redirects, but not to HTTPS"
+	}
+	return r
+}
+
+// hstsFrom parses Strict-Transport-Security from an HTTPS response. Headers
+// sent over plain HTTP must be ignored, so those yield nil.
+func hstsFrom(resp *http.Response) *HSTSResult {
+	if resp.TLS == nil {
+		return nil
+	}
+	return parseHSTS(resp.Header.Get("Strict-Transport-Security"))
+}
+
+// parseHSTS parses a Strict-Transport-Security header value. A header
+// without a valid max-age directive is treated as absent, as browsers do.
+func parseHSTS(value string) *HSTSResult {
+	h := &HSTSResult{}
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			n, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(arg), `"`), 10, 64)
+			if err != nil || n < 0 {
+				return &HSTSResult{}
+			}
+			h.MaxAge = n
+			h.Present = true
+		case "includesubdomains":
+			h.IncludeSubDomains = true
+		case "preload":
+			h.Preload = true
+		}
+	}
+	if !h.Present {
+		return &HSTSResult{}
+	}
+	return h
+}
@@ -0,0 +1,25 @@
+package http1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHSTS(t *testing.T) {
+	tests := []struct {
+		value string
+		want  HSTSResult
+	}{
+		{"", HSTSResult{}},
+		{"max-age=31536000", HSTSResult{Present: true, MaxAge: 31536000}},
+		{`max-age="63072000"; includeSubDomains; preload`, HSTSResult{Present: true, MaxAge: 63072000, IncludeSubDomains: true, Preload: true}},
+		{"MAX-AGE=0; INCLUDESUBDOMAINS", HSTSResult{Present: true, IncludeSubDomains: true}},
+		{"includeSubDomains; preload", HSTSResult{}},
+		{"max-age=soon", HSTSResult{}},
+	}
+	for _, tt := range tests {
+		if got := parseHSTS(tt.value); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("parseHSTS(%q) = %+v, want %+v", tt.value, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package http1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Configuration describes a server setup to grade without scanning it, to
+// answer "what grade would I get if I enabled X?".
+type Configuration struct {
+	HTTP2 bool `json:"h2"`
+	HTTP3 bool `json:"h3"`
+	// TLSVersions lists the enabled versions, e.g. ["TLS 1.2", "TLS 1.3"];
+	// clients negotiate the highest.
+	TLSVersions []string `json:"tls_versions"`
+	// HSTSMaxAge is the Strict-Transport-Security max-age in seconds; 0
+	// means no HSTS header.
+	HSTSMaxAge int64 `json:"hsts_max_age,omitempty"`
+	// HTTPSRedirect, when set, says whether plain HTTP redirects to HTTPS
+	// (true) or serves content directly (false).
+	HTTPSRedirect *bool `json:"https_redirect,omitempty"`
+}
+
+// GradeReport is the grade a Configuration would get and why.
+type GradeReport struct {
+	Score   int      `json:"score"`
+	Grade   string   `json:"grade"`
+	Reasons []string `json:"reasons"`
+}
+
+// GradeConfiguration grades c with the same rules as a live scan. It
+// rejects configurations no server could have, such as HTTP/3 without
+// TLS 1.3.
+func GradeConfiguration(c Configuration) (GradeReport, error) {
+	tlsVersion, err := highestTLSVersion(c.TLSVersions)
+	if err != nil {
+		return GradeReport{}, err
+	}
+	if (c.HTTP2 || c.HTTP3) && tlsVersion == "" {
+		return GradeReport{}, fmt.Errorf("h2 and h3 need at least one TLS version in tls_versions")
+	}
+	if c.HTTP3 && tlsVersion != "TLS 1.3" {
+		return GradeReport{}, fmt.Errorf("h3 requires TLS 1.3 (QUIC has no older handshake)")
+	}
+	if c.HSTSMaxAge < 0 {
+		return GradeReport{}, fmt.Errorf("hsts_max_age must not be negative")
+	}
+
+	var r GradeReport
+	r.Score, r.Grade = computeMinimalGrade(c.HTTP3, c.HTTP2, tlsVersion)
+	var redirect *HTTPSRedirect
+	if c.HTTPSRedirect != nil {
+		if *c.HTTPSRedirect {
+			redirect = &HTTPSRedirect{Redirects: true, Status: 301}
+		} else {
+			redirect = &HTTPSRedirect{Status: 200}
+		}
+	}
+	hsts := &HSTSResult{Present: c.HSTSMaxAge > 0, MaxAge: c.HSTSMaxAge}
+	for _, reason := range gradeReasons(c.HTTP3, c.HTTP2, tlsVersion, redirect, hsts) {
+		r.Reasons = append(r.Reasons, reason.String())
+	}
+	r.Score += transportSecurityAdjustment(redirect, hsts)
+	return r, nil
+}
+
+// highestTLSVersion normalizes names such as "TLS 1.3", "tls1.2" or "1.2"
+// and returns the highest, or "" for an empty list.
+func highestTLSVersion(versions []string) (string, error) {
+	rank := map[string]int{"TLS 1.0": 1, "TLS 1.1": 2, "TLS 1.2": 3, "TLS 1.3": 4}
+	best := ""
+	for _, v := range versions {
+		name := strings.TrimSpace(v)
+		if !strings.HasPrefix(strings.ToLower(name), "tls") {
+			name = "TLS " + name
+		}
+		name = tlsVersionName(0, name)
+		if rank[name] == 0 {
+			return "", fmt.Errorf("unknown TLS version %q", v)
+		}
+		if rank[name] > rank[best] {
+			best = name
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,37 @@
+package http1
+
+import "testing"
+
+func TestGradeConfiguration(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name      string
+		cfg       Configuration
+		wantGrade string
+		wantScore int
+		wantErr   bool
+	}{
+		{"h3 with hsts and redirect", Configuration{HTTP2: true, HTTP3: true, TLSVersions: []string{"TLS 1.2", "TLS 1.3"}, HSTSMaxAge: 31536000, HTTPSRedirect: &yes}, "A", 100, false},
+		{"h2 tls13", Configuration{HTTP2: true, TLSVersions: []string{"1.3"}}, "B", 90, false},
+		{"h2 tls12 serving plain http", Configuration{HTTP2: true, TLSVersions: []string{"tls1.2"}, HTTPSRedirect: &no}, "C", 75, false},
+		{"short hsts", Configuration{HTTP2: true, TLSVersions: []string{"TLS 1.3"}, HSTSMaxAge: 300}, "B", 90, false},
+		{"http1 only", Configuration{}, "F", 40, false},
+		{"h3 without tls13", Configuration{HTTP3: true, TLSVersions: []string{"TLS 1.2"}}, "", 0, true},
+		{"h2 without tls", Configuration{HTTP2: true}, "", 0, true},
+		{"unknown tls version", Configuration{TLSVersions: []string{"SSL 3.0"}}, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := GradeConfiguration(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if r.Grade != tt.wantGrade || r.Score != tt.wantScore || len(r.Reasons) == 0 {
+				t.Fatalf("got %+v, want %s (%d)", r, tt.wantGrade, tt.wantScore)
+			}
+		})
+	}
+}
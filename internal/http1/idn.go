@@ -0,0 +1,45 @@
+package http1
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// asciiHost converts a Unicode host name such as "bücher.example" to the
+// punycode form DNS and TLS expect, "xn--bcher-kva.example". ASCII hosts
+// are returned unchanged, case and all.
+func asciiHost(host string) (string, error) {
+	if isASCII(host) {
+		return host, nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not a valid internationalized name: %v", ErrInvalidHost, host, err)
+	}
+	return ascii, nil
+}
+
+// idnForms returns the Unicode and ASCII forms of host when it is an
+// internationalized name, and "", "" otherwise.
+func idnForms(host string) (unicode, ascii string) {
+	ascii, err := asciiHost(host)
+	if err != nil || !strings.Contains(strings.ToLower(ascii), "xn--") {
+		return "", ""
+	}
+	unicode, err = idna.Display.ToUnicode(ascii)
+	if err != nil || unicode == ascii {
+		return "", ""
+	}
+	return unicode, ascii
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
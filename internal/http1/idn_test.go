@@ -0,0 +1,44 @@
+package http1
+
+import "testing"
+
+func TestNormalizeURLIDN(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "bücher.example", want: "https://xn--bcher-kva.example"},
+		{in: "https://Bücher.example:8443/päth", want: "https://xn--bcher-kva.example:8443/p%C3%A4th"},
+		{in: "xn--bcher-kva.example", want: "https://xn--bcher-kva.example"},
+		{in: "Example.COM", want: "https://Example.COM"},
+		{in: "a‍b.example", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := normalizeURL(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDNForms(t *testing.T) {
+	tests := []struct {
+		host, unicode, ascii string
+	}{
+		{"bücher.example", "bücher.example", "xn--bcher-kva.example"},
+		{"xn--bcher-kva.example", "bücher.example", "xn--bcher-kva.example"},
+		{"example.com", "", ""},
+		{"192.0.2.1", "", ""},
+	}
+	for _, tt := range tests {
+		u, a := idnForms(tt.host)
+		if u != tt.unicode || a != tt.ascii {
+			t.Errorf("idnForms(%q) = %q, %q; want %q, %q", tt.host, u, a, tt.unicode, tt.ascii)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package http1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Observation is the protocol data a third-party scanner recorded for one
+// endpoint: enough to grade it without probing again.
+type Observation struct {
+	Target     string
+	Port       string
+	ALPN       string
+	TLSVersion string // "TLS 1.3", "TLS 1.2", ...
+	// HTTPProto is the HTTP version of a recorded response, e.g. "HTTP/1.1".
+	HTTPProto string
+}
+
+// zgrab2Record covers the parts of zgrab2 output we use, from either the
+// tls module (data.tls) or the http module with TLS (data.http).
+type zgrab2Record struct {
+	IP     string `json:"ip"`
+	Domain string `json:"domain"`
+	Data   struct {
+		TLS *struct {
+			Result struct {
+				HandshakeLog zgrab2Handshake `json:"handshake_log"`
+			} `json:"result"`
+		} `json:"tls"`
+		HTTP *struct {
+			Result struct {
+				Response struct {
+					Protocol struct {
+						Name string `json:"name"`
+					} `json:"protocol"`
+					Request struct {
+						TLSLog struct {
+							HandshakeLog zgrab2Handshake `json:"handshake_log"`
+						} `json:"tls_log"`
+					} `json:"request"`
+				} `json:"response"`
+			} `json:"result"`
+		} `json:"http"`
+	} `json:"data"`
+}
+
+type zgrab2Handshake struct {
+	ServerHello *struct {
+		Version           zgrab2Version `json:"version"`
+		SupportedVersions *struct {
+			Selected zgrab2Version `json:"selected_version"`
+		} `json:"supported_versions"`
+		ALPN string `json:"alpn_protocol"`
+	} `json:"server_hello"`
+}
+
+type zgrab2Version struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// tlsScanRecord is one line of tls-scan JSON output.
+type tlsScanRecord struct {
+	Host       string `json:"host"`
+	IP         string `json:"ip"`
+	Port       int    `json:"port"`
+	TLSVersion string `json:"tlsVersion"`
+	ALPN       string `json:"alpn"`
+}
+
+// ParseScanRecord decodes one zgrab2 or tls-scan JSON record.
+func ParseScanRecord(data []byte) (Observation, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Observation{}, err
+	}
+	if _, ok := probe["data"]; ok {
+		return parseZgrab2(data)
+	}
+	if _, ok := probe["tlsVersion"]; ok {
+		return parseTLSScan(data)
+	}
+	return Observation{}, fmt.Errorf("unrecognised record: expected zgrab2 (\"data\") or tls-scan (\"tlsVersion\") JSON")
+}
+
+func parseZgrab2(data []byte) (Observation, error) {
+	var rec zgrab2Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Observation{}, err
+	}
+	obs := Observation{Target: rec.Domain, Port: "443"}
+	if obs.Target == "" {
+		obs.Target = rec.IP
+	}
+
+	var hs zgrab2Handshake
+	switch {
+	case rec.Data.HTTP != nil:
+		r := rec.Data.HTTP.Result.Response
+		obs.HTTPProto = r.Protocol.Name
+		hs = r.Request.TLSLog.HandshakeLog
+	case rec.Data.TLS != nil:
+		hs = rec.Data.TLS.Result.HandshakeLog
+	default:
+		return Observation{}, fmt.Errorf("zgrab2 record for %q has neither tls nor http data", obs.Target)
+	}
+	if sh := hs.ServerHello; sh != nil {
+		obs.ALPN = sh.ALPN
+		// TLS 1.3 keeps 1.2 in the legacy version field.
+		v := sh.Version
+		if sh.SupportedVersions != nil && sh.SupportedVersions.Selected.Value != 0 {
+			v = sh.SupportedVersions.Selected
+		}
+		obs.TLSVersion = tlsVersionName(v.Value, v.Name)
+	}
+	return obs, nil
+}
+
+func parseTLSScan(data []byte) (Observation, error) {
+	var rec tlsScanRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Observation{}, err
+	}
+	obs := Observation{
+		Target:     rec.Host,
+		Port:       "443",
+		ALPN:       rec.ALPN,
+		TLSVersion: tlsVersionName(0, rec.TLSVersion),
+	}
+	if obs.Target == "" {
+		obs.Target = rec.IP
+	}
+	if rec.Port != 0 {
+		obs.Port = strconv.Itoa(rec.Port)
+	}
+	return obs, nil
+}
+
+// tlsVersionName maps a wire version or a scanner's name for it ("TLSv1.3",
+// "TLS 1.3", "tls1_3") onto the labels used in CheckResult.
+func tlsVersionName(value int, name string) string {
+	switch value {
+	case 0x0304:
+		return "TLS 1.3"
+	case 0x0303:
+		return "TLS 1.2"
+	case 0x0302:
+		return "TLS 1.1"
+	case 0x0301:
+		return "TLS 1.0"
+	}
+	n := strings.NewReplacer("v", "", " ", "", "_", ".").Replace(strings.ToLower(name))
+	switch n {
+	case "tls1.3":
+		return "TLS 1.3"
+	case "tls1.2":
+		return "TLS 1.2"
+	case "tls1.1":
+		return "TLS 1.1"
+	case "tls1.0", "tls1":
+		return "TLS 1.0"
+	}
+	return ""
+}
+
+// GradeObservation grades imported scan data with the same engine as a live
+// scan. HTTP/1.0 and HTTP/3 cannot be observed from a TLS handshake, so they
+// are reported as unknown and the grade tops out at B.
+func GradeObservation(obs Observation) CheckResult {
+	res := CheckResult{
+		Target:     obs.Target,
+		Port:       obs.Port,
+		URL:        "https://" + net.JoinHostPort(obs.Target, obs.Port),
+		ALPN:       obs.ALPN,
+		TLSVersion: obs.TLSVersion,
+	}
+
+	unknown := func(version string) VersionResult {
+		return VersionResult{Version: version, Error: true, Detail: "not present in imported data"}
+	}
+	h11 := unknown("HTTP/1.1")
+	switch {
+	case obs.ALPN == "http/1.1" || obs.HTTPProto == "HTTP/1.1":
+		h11 = VersionResult{Version: "HTTP/1.1", Supported: true, Detail: "imported"}
+	case obs.ALPN == "h2":
+		h11.Detail = "server chose h2 via ALPN; HTTP/1.1 not observed"
+	}
+	hasH2 := obs.ALPN == "h2" || obs.HTTPProto == "HTTP/2.0"
+	h2 := VersionResult{Version: "HTTP/2.0", Detail: "not negotiated in imported data"}
+	if obs.ALPN == "" && obs.HTTPProto == "" {
+		// The scanner did not offer ALPN, so h2 support is unknown.
+		h2 = unknown("HTTP/2.0")
+	}
+	if hasH2 {
+		h2 = VersionResult{Version: "HTTP/2.0", Supported: true, Detail: "imported"}
+	}
+	res.Results = []VersionResult{unknown("HTTP/1.0"), h11, h2, unknown("HTTP/3.0")}
+
+	res.Score, res.Grade = computeMinimalGrade(false, hasH2, obs.TLSVersion)
+	res.GradeReasons = gradeReasons(false, hasH2, obs.TLSVersion, nil, nil)
+	return res
+}
@@ -0,0 +1,49 @@
+package http1
+
+import "testing"
+
+func TestParseScanRecord(t *testing.T) {
+	tests := []struct {
+		name      string
+		record    string
+		want      Observation
+		wantGrade string
+	}{
+		{
+			name:      "zgrab2 tls module, tls 1.3 via supported_versions",
+			record:    `{"ip":"192.0.2.1","domain":"example.com","data":{"tls":{"status":"success","result":{"handshake_log":{"server_hello":{"version":{"name":"TLSv1.2","value":771},"supported_versions":{"selected_version":{"name":"TLSv1.3","value":772}},"alpn_protocol":"h2"}}}}}}`,
+			want:      Observation{Target: "example.com", Port: "443", ALPN: "h2", TLSVersion: "TLS 1.3"},
+			wantGrade: "B",
+		},
+		{
+			name:      "zgrab2 http module",
+			record:    `{"ip":"192.0.2.2","data":{"http":{"result":{"response":{"protocol":{"name":"HTTP/1.1"},"request":{"tls_log":{"handshake_log":{"server_hello":{"version":{"name":"TLSv1.2","value":771},"alpn_protocol":"http/1.1"}}}}}}}}}`,
+			want:      Observation{Target: "192.0.2.2", Port: "443", ALPN: "http/1.1", TLSVersion: "TLS 1.2", HTTPProto: "HTTP/1.1"},
+			wantGrade: "F",
+		},
+		{
+			name:      "tls-scan",
+			record:    `{"host":"example.org","ip":"192.0.2.3","port":8443,"tlsVersion":"TLSv1.2","alpn":"h2"}`,
+			want:      Observation{Target: "example.org", Port: "8443", ALPN: "h2", TLSVersion: "TLS 1.2"},
+			wantGrade: "C",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs, err := ParseScanRecord([]byte(tt.record))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if obs != tt.want {
+				t.Fatalf("got %+v, want %+v", obs, tt.want)
+			}
+			if got := GradeObservation(obs).Grade; got != tt.wantGrade {
+				t.Fatalf("grade %q, want %q", got, tt.wantGrade)
+			}
+		})
+	}
+
+	if _, err := ParseScanRecord([]byte(`{"foo":1}`)); err == nil {
+		t.Error("expected error for unrecognised record")
+	}
+}
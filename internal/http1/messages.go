@@ -0,0 +1,243 @@
+package http1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// messages maps the English CLI strings to their translations, per
+// language. Keys are the exact English text (format strings included), so
+// an untranslated string simply stays English.
+var messages = map[string]map[string]string{
+	"de": {
+		"Grade":                                "Note",
+		"supported":                            "unterstützt",
+		"not supported (or probe failed)":      "nicht unterstützt (oder Test fehlgeschlagen)",
+		"replied with":                         "antwortete mit",
+		"server replied with":                  "Server antwortete mit",
+		"request build failed":                 "Anfrage konnte nicht erstellt werden",
+		"invalid URL":                          "ungültige URL",
+		"invalid URL after normalization":      "ungültige URL nach der Normalisierung",
+		"setting up transports":                "Einrichten der Transporte fehlgeschlagen",
+		"supported at the advertised endpoint": "unterstützt am angekündigten Endpunkt",
+		" (UDP buffers on this host are undersized; see udp_buffer)":                              " (UDP-Puffer dieses Hosts sind zu klein; siehe udp_buffer)",
+		" (unreliable: QUIC calibration against reference hosts failed)":                          " (unzuverlässig: QUIC-Kalibrierung gegen Referenzhosts fehlgeschlagen)",
+		"Scanning %d host(s)... (✅ supported, ❌ not supported, 🟧 error/probe failed)":             "Prüfe %d Host(s)... (✅ unterstützt, ❌ nicht unterstützt, 🟧 Fehler/Test fehlgeschlagen)",
+		"Scanning hosts as they are read... (✅ supported, ❌ not supported, 🟧 error/probe failed)": "Prüfe Hosts beim Einlesen... (✅ unterstützt, ❌ nicht unterstützt, 🟧 Fehler/Test fehlgeschlagen)",
+		"Scanning %d host(s)...":                  "Prüfe %d Host(s)...",
+		"Scanning hosts as they are read...":      "Prüfe Hosts beim Einlesen...",
+		h3ProxyNote:                               " (HTTP/3 kann nicht über einen HTTP-Proxy laufen; direkt getestet)",
+		"Scanned %d host(s) in %s":                "%d Host(s) in %s geprüft",
+		" (%d matched --where)":                   " (%d passend zu --where)",
+		challengeNote:                             " (Inhalt hinter einer Bot-Abfrage)",
+		"content gated by a %s challenge":         "Inhalt hinter einer %s-Abfrage",
+		"certificate changed since the last scan": "Zertifikat seit dem letzten Scan geändert",
+		"differs from %s: %s":                     "weicht von %s ab: %s",
+		"note: %s":                                "Notiz: %s",
+		"not scanned before the scan ended":       "nicht geprüft, bevor der Scan endete",
+		"yes":                                     "ja",
+		"no":                                      "nein",
+		"error":                                   "Fehler",
+		"skipped":                                 "übersprungen",
+		"Grades by label:":                        "Noten nach Label:",
+		"Summary of %d host(s):":                  "Zusammenfassung von %d Host(s):",
+		"Grades":                                  "Noten",
+		"Target":                                  "Ziel",
+		"Time":                                    "Zeit",
+		"Slowest":                                 "Am langsamsten",
+		"%d host(s)":                              "%d Host(s)",
+		Unlabeled:                                 "(ohne Label)",
+	},
+	"es": {
+		"Grade":                                "Nota",
+		"supported":                            "compatible",
+		"not supported (or probe failed)":      "no compatible (o la prueba falló)",
+		"replied with":                         "respondió con",
+		"server replied with":                  "el servidor respondió con",
+		"request build failed":                 "no se pudo crear la petición",
+		"invalid URL":                          "URL no válida",
+		"invalid URL after normalization":      "URL no válida tras normalizar",
+		"setting up transports":                "error al preparar los transportes",
+		"supported at the advertised endpoint": "compatible en el endpoint anunciado",
+		" (UDP buffers on this host are undersized; see udp_buffer)":                              " (los búferes UDP de este equipo son demasiado pequeños; ver udp_buffer)",
+		" (unreliable: QUIC calibration against reference hosts failed)":                          " (poco fiable: falló la calibración QUIC con los hosts de referencia)",
+		"Scanning %d host(s)... (✅ supported, ❌ not supported, 🟧 error/probe failed)":             "Analizando %d host(s)... (✅ compatible, ❌ no compatible, 🟧 error/prueba fallida)",
+		"Scanning hosts as they are read... (✅ supported, ❌ not supported, 🟧 error/probe failed)": "Analizando hosts a medida que se leen... (✅ compatible, ❌ no compatible, 🟧 error/prueba fallida)",
+		"Scanning %d host(s)...":                  "Analizando %d host(s)...",
+		"Scanning hosts as they are read...":      "Analizando hosts a medida que se leen...",
+		h3ProxyNote:                               " (HTTP/3 no puede pasar por un proxy HTTP; probado directamente)",
+		"Scanned %d host(s) in %s":                "%d host(s) analizados en %s",
+		" (%d matched --where)":                   " (%d coinciden con --where)",
+		challengeNote:                             " (contenido tras un desafío anti-bots)",
+		"content gated by a %s challenge":         "contenido tras un desafío de %s",
+		"certificate changed since the last scan": "certificado cambiado desde el último escaneo",
+		"differs from %s: %s":                     "difiere de %s: %s",
+		"note: %s":                                "nota: %s",
+		"not scanned before the scan ended":       "no analizado antes de que terminara el escaneo",
+		"yes":                                     "sí",
+		"no":                                      "no",
+		"error":                                   "error",
+		"skipped":                                 "omitido",
+		"Grades by label:":                        "Notas por etiqueta:",
+		"Summary of %d host(s):":                  "Resumen de %d host(s):",
+		"Grades":                                  "Notas",
+		"Target":                                  "Destino",
+		"Time":                                    "Tiempo",
+		"Slowest":                                 "Más lentos",
+		"%d host(s)":                              "%d host(s)",
+		Unlabeled:                                 "(sin etiqueta)",
+	},
+	"fr": {
+		"Grade":                                "Note",
+		"supported":                            "pris en charge",
+		"not supported (or probe failed)":      "non pris en charge (ou test échoué)",
+		"replied with":                         "a répondu en",
+		"server replied with":                  "le serveur a répondu en",
+		"request build failed":                 "impossible de construire la requête",
+		"invalid URL":                          "URL invalide",
+		"invalid URL after normalization":      "URL invalide après normalisation",
+		"setting up transports":                "échec de la préparation des transports",
+		"supported at the advertised endpoint": "pris en charge sur le point de terminaison annoncé",
+		" (UDP buffers on this host are undersized; see udp_buffer)":                              " (tampons UDP de cette machine trop petits ; voir udp_buffer)",
+		" (unreliable: QUIC calibration against reference hosts failed)":                          " (peu fiable : échec de la calibration QUIC sur les hôtes de référence)",
+		"Scanning %d host(s)... (✅ supported, ❌ not supported, 🟧 error/probe failed)":             "Analyse de %d hôte(s)... (✅ pris en charge, ❌ non pris en charge, 🟧 erreur/test échoué)",
+		"Scanning hosts as they are read... (✅ supported, ❌ not supported, 🟧 error/probe failed)": "Analyse des hôtes au fil de la lecture... (✅ pris en charge, ❌ non pris en charge, 🟧 erreur/test échoué)",
+		"Scanning %d host(s)...":                  "Analyse de %d hôte(s)...",
+		"Scanning hosts as they are read...":      "Analyse des hôtes au fil de la lecture...",
+		h3ProxyNote:                               " (HTTP/3 ne peut pas passer par un proxy HTTP ; testé directement)",
+		"Scanned %d host(s) in %s":                "%d hôte(s) analysé(s) en %s",
+		" (%d matched --where)":                   " (%d correspondent à --where)",
+		challengeNote:                             " (contenu derrière un défi anti-robots)",
+		"content gated by a %s challenge":         "contenu derrière un défi %s",
+		"certificate changed since the last scan": "certificat modifié depuis la dernière analyse",
+		"differs from %s: %s":                     "diffère de %s : %s",
+		"note: %s":                                "note : %s",
+		"not scanned before the scan ended":       "non analysé avant la fin de l’analyse",
+		"yes":                                     "oui",
+		"no":                                      "non",
+		"error":                                   "erreur",
+		"skipped":                                 "ignoré",
+		"Grades by label:":                        "Notes par étiquette :",
+		"Summary of %d host(s):":                  "Résumé de %d hôte(s) :",
+		"Grades":                                  "Notes",
+		"Target":                                  "Cible",
+		"Time":                                    "Durée",
+		"Slowest":                                 "Plus lents",
+		"%d host(s)":                              "%d hôte(s)",
+		Unlabeled:                                 "(sans étiquette)",
+	},
+}
+
+// detailPrefixes are the fixed leading phrases of VersionResult details,
+// longest first so "server replied with" wins over "replied with".
+var detailPrefixes = func() []string {
+	p := []string{
+		"supported",
+		"not supported (or probe failed)",
+		"replied with",
+		"server replied with",
+		"request build failed",
+		"invalid URL",
+		"invalid URL after normalization",
+		"setting up transports",
+		"supported at the advertised endpoint",
+	}
+	sort.Slice(p, func(i, j int) bool { return len(p[i]) > len(p[j]) })
+	return p
+}()
+
+// detailNotes are annotations appended to details after probing.
+var detailNotes = []string{
+	" (UDP buffers on this host are undersized; see udp_buffer)",
+	" (unreliable: QUIC calibration against reference hosts failed)",
+	h3ProxyNote,
+	challengeNote,
+}
+
+// Languages lists the languages a Translator supports besides English.
+func Languages() []string {
+	langs := make([]string, 0, len(messages))
+	for l := range messages {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Translator localizes the human-readable parts of results and CLI
+// messages. A nil *Translator leaves everything in English.
+type Translator struct {
+	msgs map[string]string
+}
+
+// NewTranslator returns a Translator for lang, which may carry a region or
+// encoding ("de", "de-AT", "de_DE.UTF-8"). English yields nil.
+func NewTranslator(lang string) (*Translator, error) {
+	base := strings.ToLower(lang)
+	if i := strings.IndexAny(base, "-_."); i >= 0 {
+		base = base[:i]
+	}
+	if base == "" || base == "en" {
+		return nil, nil
+	}
+	msgs, ok := messages[base]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q (want en or %s)", lang, strings.Join(Languages(), ", "))
+	}
+	return &Translator{msgs: msgs}, nil
+}
+
+// Message returns the translation of an English message, or msg itself.
+func (t *Translator) Message(msg string) string {
+	if t == nil {
+		return msg
+	}
+	if m, ok := t.msgs[msg]; ok {
+		return m
+	}
+	return msg
+}
+
+// Sprintf formats the translation of an English format string.
+func (t *Translator) Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(t.Message(format), args...)
+}
+
+// Detail translates a VersionResult detail: its fixed leading phrase and
+// any appended notes. Embedded error text from the network stack is kept.
+func (t *Translator) Detail(detail string) string {
+	if t == nil || detail == "" {
+		return detail
+	}
+	for _, p := range detailPrefixes {
+		rest, ok := strings.CutPrefix(detail, p)
+		if ok && (rest == "" || rest[0] == ':' || rest[0] == ' ') {
+			detail = t.Message(p) + rest
+			break
+		}
+	}
+	for _, n := range detailNotes {
+		detail = strings.Replace(detail, n, t.Message(n), 1)
+	}
+	return detail
+}
+
+// Result returns a copy of res with its version details translated.
+func (t *Translator) Result(res CheckResult) CheckResult {
+	if t == nil {
+		return res
+	}
+	out := res
+	out.Results = make([]VersionResult, len(res.Results))
+	for i, vr := range res.Results {
+		vr.Detail = t.Detail(vr.Detail)
+		out.Results[i] = vr
+	}
+	return out
+}
+
+// SummaryLine is SummaryLine with its labels translated.
+func (t *Translator) SummaryLine(res CheckResult) string {
+	return summaryLine(res, t)
+}
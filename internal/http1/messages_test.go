@@ -0,0 +1,40 @@
+package http1
+
+import "testing"
+
+func TestTranslatorDetail(t *testing.T) {
+	de, err := NewTranslator("de_DE.UTF-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"supported", "unterstützt"},
+		{"server replied with HTTP/1.1", "Server antwortete mit HTTP/1.1"},
+		{"replied with HTTP/1.1", "antwortete mit HTTP/1.1"},
+		{"not supported (or probe failed): dial tcp: i/o timeout", "nicht unterstützt (oder Test fehlgeschlagen): dial tcp: i/o timeout"},
+		{"invalid URL after normalization: bad", "ungültige URL nach der Normalisierung: bad"},
+		{
+			"not supported (or probe failed): timeout (unreliable: QUIC calibration against reference hosts failed)",
+			"nicht unterstützt (oder Test fehlgeschlagen): timeout (unzuverlässig: QUIC-Kalibrierung gegen Referenzhosts fehlgeschlagen)",
+		},
+		{"supportedness unknown", "supportedness unknown"},
+	}
+	for _, tt := range tests {
+		if got := de.Detail(tt.in); got != tt.want {
+			t.Errorf("Detail(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	var en *Translator
+	if got := en.Detail("supported"); got != "supported" {
+		t.Errorf("nil translator changed %q", got)
+	}
+	if tr, err := NewTranslator("en-GB"); tr != nil || err != nil {
+		t.Errorf("NewTranslator(en-GB) = %v, %v; want nil, nil", tr, err)
+	}
+	if _, err := NewTranslator("xx"); err == nil {
+		t.Error("NewTranslator(xx) succeeded")
+	}
+}
@@ -0,0 +1,171 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Behaviors of a server sent a TLS server name and Host that disagree.
+const (
+	mismatchHandshakeFailed = "handshake_failed"
+	mismatchMisdirected     = "misdirected_request"
+	mismatchRejected        = "rejected"
+	mismatchServed          = "served"
+	mismatchError           = "error"
+)
+
+// SNIMismatchResult reports how a server handles requests whose TLS server
+// name and Host disagree, which CDNs and multi-tenant hosts resolve in
+// different ways (RFC 9110 §15.5.20 and RFC 6066 §3).
+type SNIMismatchResult struct {
+	// UnknownSNI is a handshake naming a host the server does not know,
+	// followed by a request for the target.
+	UnknownSNI MismatchOutcome `json:"unknown_sni"`
+	// UnknownHost is a handshake naming the target, followed by a request
+	// for a host the server does not know.
+	UnknownHost MismatchOutcome `json:"unknown_host"`
+	Detail      string          `json:"detail,omitempty"`
+}
+
+// MismatchOutcome is the server's answer to one mismatched request.
+type MismatchOutcome struct {
+	// Behavior is "handshake_failed", "misdirected_request" (421),
+	// "rejected" (another 4xx or 5xx), "served" (2xx or 3xx) or "error".
+	Behavior string `json:"behavior"`
+	Status   int    `json:"status,omitempty"`
+	// CertMatchesTarget reports whether the certificate the server chose
+	// covers the target host; CertSubject names it when it does not.
+	CertMatchesTarget bool   `json:"cert_matches_target"`
+	CertSubject       string `json:"cert_subject,omitempty"`
+	Detail            string `json:"detail,omitempty"`
+}
+
+// probeSNIMismatch sends two requests to the target whose server name and
+// Host disagree: an unknown SNI with the target's authority, and the target's
+// serverName with an unknown authority.
+func probeSNIMismatch(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), opts Options, rawURL, host, serverName, port string) *SNIMismatchResult {
+	unknown := fmt.Sprintf("http1-mismatch-%08x.invalid", rand.Uint32())
+	authority := opts.authority(host, port)
+	res := &SNIMismatchResult{
+		UnknownSNI:  mismatchRequest(ctx, dial, opts, rawURL, host, unknown, authority),
+		UnknownHost: mismatchRequest(ctx, dial, opts, rawURL, host, serverName, unknown),
+	}
+	res.Detail = mismatchDetail(res)
+	return res
+}
+
+// mismatchRequest connects presenting serverName, requests rawURL with Host
+// set to authority and classifies the answer. host is the target host the
+// certificate is checked against.
+func mismatchRequest(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), opts Options, rawURL, host, serverName, authority string) MismatchOutcome {
+	var out MismatchOutcome
+	var state *tls.ConnectionState
+	var handshakeFailed bool
+	tr := &http.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			raw, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			conn := tls.Client(raw, &tls.Config{
+				ServerName:         serverName,
+				NextProtos:         []string{"h2", "http/1.1"},
+				InsecureSkipVerify: true,
+			})
+			if err := conn.HandshakeContext(ctx); err != nil {
+				_ = raw.Close()
+				handshakeFailed = true
+				return nil, err
+			}
+			cs := conn.ConnectionState()
+			state = &cs
+			return conn, nil
+		},
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, opts.method(), rawURL, nil)
+	if err != nil {
+		out.Behavior = mismatchError
+		out.Detail = "request build failed"
+		return out
+	}
+	opts.prepareRequest(req)
+	req.Host = authority
+
+	resp, err := tr.RoundTrip(req)
+	if state != nil && len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		out.CertMatchesTarget = leaf.VerifyHostname(host) == nil
+		if !out.CertMatchesTarget {
+			out.CertSubject = leaf.Subject.CommonName
+			if out.CertSubject == "" && len(leaf.DNSNames) > 0 {
+				out.CertSubject = leaf.DNSNames[0]
+			}
+		}
+	}
+	if err != nil {
+		out.Behavior = mismatchError
+		if handshakeFailed {
+			out.Behavior = mismatchHandshakeFailed
+		}
+		out.Detail = err.Error()
+		return out
+	}
+	_ = resp.Body.Close()
+	out.Status = resp.StatusCode
+	switch {
+	case resp.StatusCode == http.StatusMisdirectedRequest:
+		out.Behavior = mismatchMisdirected
+	case resp.StatusCode >= 400:
+		out.Behavior = mismatchRejected
+	default:
+		out.Behavior = mismatchServed
+	}
+	return out
+}
+
+// mismatchDetail summarises r for humans.
+func mismatchDetail(r *SNIMismatchResult) string {
+	var parts []string
+	switch sni := r.UnknownSNI; sni.Behavior {
+	case mismatchHandshakeFailed:
+		parts = append(parts, "unknown SNI refused in the handshake")
+	case mismatchMisdirected:
+		parts = append(parts, "unknown SNI answered 421 Misdirected Request")
+	case mismatchRejected:
+		parts = append(parts, fmt.Sprintf("unknown SNI rejected with status %d", sni.Status))
+	case mismatchServed:
+		part := "target served despite an unknown SNI"
+		if !sni.CertMatchesTarget {
+			part += " (with a certificate for " + orUnnamed(sni.CertSubject) + ")"
+		}
+		parts = append(parts, part)
+	}
+	switch host := r.UnknownHost; host.Behavior {
+	case mismatchMisdirected:
+		parts = append(parts, "unknown Host answered 421 Misdirected Request")
+	case mismatchRejected:
+		parts = append(parts, fmt.Sprintf("unknown Host rejected with status %d", host.Status))
+	case mismatchServed:
+		parts = append(parts, fmt.Sprintf("unknown Host served by a default virtual host (status %d)", host.Status))
+	}
+	if len(parts) == 0 {
+		return "mismatched requests failed"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func orUnnamed(name string) string {
+	if name == "" {
+		return "an unnamed host"
+	}
+	return name
+}
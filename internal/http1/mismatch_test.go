@@ -0,0 +1,92 @@
+package http1
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeSNIMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantSNI  string
+		wantHost string
+	}{
+		{
+			name:     "default vhost",
+			handler:  func(w http.ResponseWriter, r *http.Request) {},
+			wantSNI:  mismatchServed,
+			wantHost: mismatchServed,
+		},
+		{
+			name: "strict",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.TLS.ServerName != "example.com" {
+					w.WriteHeader(http.StatusMisdirectedRequest)
+					return
+				}
+				if !strings.HasPrefix(r.Host, "example.com") {
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+			wantSNI:  mismatchMisdirected,
+			wantHost: mismatchRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(tt.handler)
+			srv.EnableHTTP2 = true
+			srv.StartTLS()
+			defer srv.Close()
+			addr := srv.Listener.Addr().String()
+			dial := func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			}
+
+			res := probeSNIMismatch(context.Background(), dial, Options{}, "https://example.com/", "example.com", "example.com", "443")
+			if res.UnknownSNI.Behavior != tt.wantSNI {
+				t.Errorf("unknown SNI behavior = %q (%s), want %q", res.UnknownSNI.Behavior, res.UnknownSNI.Detail, tt.wantSNI)
+			}
+			if res.UnknownHost.Behavior != tt.wantHost {
+				t.Errorf("unknown Host behavior = %q (%s), want %q", res.UnknownHost.Behavior, res.UnknownHost.Detail, tt.wantHost)
+			}
+			// httptest certificates cover example.com.
+			if !res.UnknownSNI.CertMatchesTarget {
+				t.Errorf("certificate should match the target, got subject %q", res.UnknownSNI.CertSubject)
+			}
+			if res.Detail == "" {
+				t.Error("missing detail")
+			}
+		})
+	}
+}
+
+func TestProbeSNIMismatchHandshakeFailed(t *testing.T) {
+	// A listener that closes every connection fails the handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.Close()
+		}
+	}()
+	dial := func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, ln.Addr().String())
+	}
+	res := probeSNIMismatch(context.Background(), dial, Options{}, "https://example.com/", "example.com", "example.com", "443")
+	if res.UnknownSNI.Behavior != mismatchHandshakeFailed {
+		t.Errorf("behavior = %q, want %q", res.UnknownSNI.Behavior, mismatchHandshakeFailed)
+	}
+}
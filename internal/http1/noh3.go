@@ -0,0 +1,56 @@
+//go:build noh3 || wasm
+
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// h3Available reports whether this build includes the HTTP/3 probes. This
+// build was made with the noh3 tag, or for WebAssembly where there are no
+// UDP sockets, so quic-go is left out and every HTTP/3 probe below is a stub
+// that is never reached from runChecks.
+const h3Available = false
+
+// errH3Disabled is returned by QUIC operations in builds without HTTP/3.
+var errH3Disabled = errors.New("HTTP/3 is disabled in this build")
+
+// quicDialer stands in for the QUIC dialer so probeTransports keeps its shape.
+type quicDialer struct{}
+
+// setupH3 leaves pt without an HTTP/3 client.
+func (pt *probeTransports) setupH3(Options, int) error {
+	pt.h3TLS = &tls.Config{}
+	pt.h3Idle = func() {}
+	return nil
+}
+
+func quicHandshake(context.Context, string, string) error {
+	return errH3Disabled
+}
+
+func probeQUICVersions(context.Context, *quicDialer, string, string, string) []string {
+	return nil
+}
+
+func probeQUIC0RTT(context.Context, *quicDialer, *tls.Config, string, Options) (bool, error) {
+	return false, errH3Disabled
+}
+
+func probeH3At(context.Context, *quicDialer, *tls.Config, string, *http.Request) error {
+	return errH3Disabled
+}
+
+func probeH3ExtendedConnect(context.Context, *quicDialer, *tls.Config, string, string, string) ExtendedConnectResult {
+	return ExtendedConnectResult{Detail: errH3Disabled.Error()}
+}
+
+// serveH3 serves nothing: TestServers only answers over TCP in this build.
+func serveH3(*net.UDPConn, http.Handler, []tls.Certificate) io.Closer {
+	return nil
+}
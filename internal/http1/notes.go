@@ -0,0 +1,186 @@
+package http1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Note is a free-form remark attached to a target's results, e.g. "known
+// legacy appliance, exception until 2025-06". A note that acknowledges the
+// target's findings excuses its policy violations until it expires.
+type Note struct {
+	// Target is the target the note is about. Notes match results for any
+	// spelling of the same target, e.g. "example.com" and
+	// "https://example.com:443/".
+	Target string    `json:"target"`
+	Text   string    `json:"text"`
+	Author string    `json:"author,omitempty"`
+	Added  time.Time `json:"added,omitzero"`
+	// Acknowledged marks the target's findings as known and accepted until
+	// Expires; a zero Expires never ends it.
+	Acknowledged bool      `json:"acknowledged,omitempty"`
+	Expires      time.Time `json:"expires,omitzero"`
+}
+
+// Acknowledges reports whether n excuses the target's policy violations at
+// time now.
+func (n Note) Acknowledges(now time.Time) bool {
+	return n.Acknowledged && (n.Expires.IsZero() || now.Before(n.Expires))
+}
+
+// ParseExpiry parses the expiry of an acknowledgement: an RFC 3339 time, a
+// date ("2025-06-30"), which lasts through that day, or a month
+// ("2025-06"), which lasts through that month. Dates are in UTC.
+func ParseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return t.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q (want a date like 2025-06-30, a month like 2025-06, or an RFC 3339 time)", s)
+}
+
+// Notebook holds the notes on a set of targets. It is safe for concurrent
+// use.
+type Notebook struct {
+	mu    sync.RWMutex
+	notes []Note
+}
+
+// NewNotebook returns an empty Notebook.
+func NewNotebook() *Notebook {
+	return &Notebook{}
+}
+
+// ReadNotes reads a notebook written by Notebook.Save: a JSON array of
+// notes. Empty input is an empty notebook.
+func ReadNotes(r io.Reader) (*Notebook, error) {
+	var notes []Note
+	if err := json.NewDecoder(r).Decode(&notes); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	b := NewNotebook()
+	for _, n := range notes {
+		if _, err := b.Add(n); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// LoadNotes reads the notebook at path. A missing file is an empty
+// notebook, so notes added later can start it.
+func LoadNotes(path string) (*Notebook, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewNotebook(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ReadNotes(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notes file %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the notebook to path as a JSON array, replacing the file only
+// once it is fully written.
+func (b *Notebook) Save(path string) error {
+	data, err := json.MarshalIndent(b.Notes(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Add stores n and returns it as stored. The target must parse and the
+// text must not be empty.
+func (b *Notebook) Add(n Note) (Note, error) {
+	n.Target, n.Text = strings.TrimSpace(n.Target), strings.TrimSpace(n.Text)
+	if _, err := ParseTarget(n.Target); err != nil {
+		return Note{}, err
+	}
+	if n.Text == "" {
+		return Note{}, errors.New("a note needs text")
+	}
+	b.mu.Lock()
+	b.notes = append(b.notes, n)
+	b.mu.Unlock()
+	return n, nil
+}
+
+// Notes returns every note, oldest first.
+func (b *Notebook) Notes() []Note {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Note(nil), b.notes...)
+}
+
+// For returns the notes on target, oldest first.
+func (b *Notebook) For(target string) []Note {
+	key := noteKey(target)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var out []Note
+	for _, n := range b.notes {
+		if noteKey(n.Target) == key {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Annotate returns res with the notes on its target attached.
+func (b *Notebook) Annotate(res CheckResult) CheckResult {
+	res.Notes = b.For(res.Target)
+	return res
+}
+
+// Acknowledged returns the first note on res that acknowledges its findings
+// at time now, or nil.
+func Acknowledged(res CheckResult, now time.Time) *Note {
+	for i, n := range res.Notes {
+		if n.Acknowledges(now) {
+			return &res.Notes[i]
+		}
+	}
+	return nil
+}
+
+// noteKey is the form of target notes are matched by: its Key when it
+// parses, so different spellings of one target match.
+func noteKey(target string) string {
+	t, err := ParseTarget(target)
+	if err != nil {
+		return strings.TrimSpace(target)
+	}
+	return t.Key()
+}
@@ -0,0 +1,80 @@
+package http1
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2025-06", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-12", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-06-30", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-06-30T12:00:00Z", time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseExpiry(tt.in)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseExpiry(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseExpiry("next june"); err == nil {
+		t.Error("ParseExpiry accepted \"next june\"")
+	}
+}
+
+func TestNotebook(t *testing.T) {
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	expires, _ := ParseExpiry("2025-06")
+	b := NewNotebook()
+	if _, err := b.Add(Note{Target: "legacy.example", Text: "known legacy appliance", Acknowledged: true, Expires: expires}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Add(Note{Target: "https://other.example", Text: "owned by the web team"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Add(Note{Target: "other.example"}); err == nil {
+		t.Error("Add accepted a note without text")
+	}
+	if _, err := b.Add(Note{Target: "http://", Text: "x"}); err == nil {
+		t.Error("Add accepted an invalid target")
+	}
+
+	res := b.Annotate(CheckResult{Target: "https://LEGACY.example:443/", Grade: "F"})
+	if len(res.Notes) != 1 || res.Notes[0].Text != "known legacy appliance" {
+		t.Fatalf("Notes = %+v, want the legacy note", res.Notes)
+	}
+	if n := Acknowledged(res, now); n == nil {
+		t.Error("Acknowledged = nil before the expiry")
+	}
+	if n := Acknowledged(res, expires); n != nil {
+		t.Errorf("Acknowledged = %+v at the expiry, want nil", n)
+	}
+	other := b.Annotate(CheckResult{Target: "other.example", Grade: "F"})
+	if len(other.Notes) != 1 || Acknowledged(other, now) != nil {
+		t.Errorf("other.example: Notes = %+v, want one plain note", other.Notes)
+	}
+	if line := summaryLine(res, nil); !strings.Contains(line, "note: known legacy appliance") {
+		t.Errorf("summary line %q lacks the note", line)
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.json")
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNotes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Notes(); len(got) != 2 || !got[0].Expires.Equal(expires) {
+		t.Errorf("loaded notes = %+v", got)
+	}
+	if empty, err := LoadNotes(filepath.Join(t.TempDir(), "missing.json")); err != nil || len(empty.Notes()) != 0 {
+		t.Errorf("LoadNotes(missing) = %v, %v; want an empty notebook", empty, err)
+	}
+}
@@ -0,0 +1,304 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Options tune how targets are probed. The zero value probes each target on
+// the port from its URL with no extra request headers.
+type Options struct {
+	// Port, when set, overrides the port taken from each target URL and the
+	// port 80 used by the plain-HTTP probe.
+	Port string
+	// Headers are added to every probe request, e.g. to reach targets behind
+	// header-based routing or an auth gateway. They replace any header of
+	// the same name the probe would set itself. Go's HTTP client ignores a
+	// Host entry here; use HostHeader.
+	Headers http.Header
+	// LowResource trades scan speed for a small footprint: a few workers,
+	// transports and one UDP socket shared by all targets, no keep-alive
+	// pools and smaller I/O and QUIC buffers.
+	LowResource bool
+	// UserAgent replaces DefaultUserAgent on every probe. Some WAFs block
+	// Go's stock "Go-http-client" agent outright, which makes every probe
+	// look like a failure.
+	UserAgent string
+	// Method is the request method for the probes; empty means GET. HEAD or
+	// OPTIONS avoid fetching heavy pages or triggering side effects. Any
+	// response proves protocol support, whatever its status (a 405 counts).
+	Method string
+	// Versions limits the protocol probes to these versions ("HTTP/1.0",
+	// "HTTP/1.1", "HTTP/2.0", "HTTP/3.0"; see ParseVersions); empty probes
+	// all four. Skipped versions are reported as NotTested. Skipping
+	// HTTP/1.0 also skips the plain-HTTP redirect check, and the grade only
+	// counts what was probed.
+	Versions []string
+	// FollowRedirects chases up to maxRedirects redirects from each target
+	// and grades the final destination instead of the redirector.
+	FollowRedirects bool
+	// QUICCalibration is the result of CalibrateQUIC for this scan. When it
+	// failed, HTTP/3 negatives are marked Unreliable.
+	QUICCalibration *QUICCalibration
+	// SNI, when set, is the TLS server name presented on every handshake
+	// instead of the target host, e.g. to validate a staging load balancer
+	// by IP before DNS points at it.
+	SNI string
+	// Vantage labels where the scan ran from (e.g. "office", "aws-eu") and
+	// is recorded on every result, so differences between environments are
+	// not mistaken for server changes.
+	Vantage string
+	// HostHeader, when set, replaces the Host header (:authority in h2/h3)
+	// while connections still go to the target, e.g. to probe an origin
+	// directly for a site normally served through a CDN.
+	HostHeader string
+	// Proxy selects an HTTP or SOCKS5 proxy for the TCP probes, as
+	// http.Transport's Proxy does (e.g. http.ProxyFromEnvironment). HTTP/1.x
+	// requests go through it directly and TLS probes through CONNECT or
+	// SOCKS5 tunnels; HTTP/3 cannot be proxied and is still probed directly.
+	// nil means no proxy.
+	Proxy func(*http.Request) (*url.URL, error)
+	// DetectParking checks each target for wildcard DNS, parking-service
+	// nameservers and parking page markers (fetched with GET regardless of
+	// Method) and reports them in CheckResult.Parking.
+	DetectParking bool
+	// SampleBodies reads up to bodySampleLimit bytes of each probe response
+	// and annotates markers that explain odd results, such as a meta refresh
+	// to HTTPS or a browser upgrade interstitial.
+	SampleBodies bool
+	// MaxPerOrigin caps how many targets resolving to the same IP address
+	// are checked at once, so a scan of one company's many subdomains does
+	// not hammer its load balancer. Held-back targets wait while workers
+	// move on to other origins. 0 means no limit.
+	MaxPerOrigin int
+	// CheckDNSSEC reports whether each target's name is DNSSEC-signed and
+	// validates, in CheckResult.DNSSEC.
+	CheckDNSSEC bool
+	// CheckSNIMismatch sends requests whose TLS server name and Host
+	// disagree and reports how the server handles them (a wrong
+	// certificate, 421 Misdirected Request or a default virtual host) in
+	// CheckResult.SNIMismatch.
+	CheckSNIMismatch bool
+	// CheckECH looks up each target's HTTPS DNS record and, if it
+	// advertises an Encrypted ClientHello config, attempts a handshake
+	// with it, reporting both in CheckResult.ECH.
+	CheckECH bool
+
+	// CheckCoalescing requests a second host named in the certificate over
+	// the target's HTTP/2 connection and reports whether the server serves
+	// it or answers 421 Misdirected Request, in CheckResult.Coalescing.
+	CheckCoalescing bool
+	// CheckExtendedConnect reports whether the server's HTTP/2 and HTTP/3
+	// SETTINGS enable Extended CONNECT and, if so, whether it accepts a
+	// websocket CONNECT, in CheckResult.ExtendedConnect.
+	CheckExtendedConnect bool
+	// CheckKeyExchange attempts one more TLS 1.3 handshake per HTTPS
+	// target offering only the hybrid post-quantum X25519MLKEM768 group,
+	// and reports whether it completes in CheckResult.KeyExchange.
+	CheckKeyExchange bool
+	// CheckQUICVersions enumerates the QUIC versions a target accepts when
+	// HTTP/3 works, in CheckResult.QUICVersions. It costs one more QUIC
+	// handshake per HTTP/3 target, offering only QUIC v2.
+	CheckQUICVersions bool
+	// CheckEarlyData reconnects after a successful HTTP/2 probe to see
+	// whether the TLS session resumes, and after a successful HTTP/3 probe
+	// to send the request again as QUIC 0-RTT early data, reporting both in
+	// CheckResult.EarlyData. That is up to two more connections per
+	// target, and the 0-RTT one repeats the probe request, which the server
+	// may act on twice.
+	CheckEarlyData bool
+	// CheckH2Settings opens one more TLS connection after a successful
+	// HTTP/2 probe to read the server's initial SETTINGS frame and any
+	// ORIGIN frame, reported in CheckResult.H2Settings and H2Origin.
+	// CheckExtendedConnect shares that connection.
+	CheckH2Settings bool
+	// SourceIP, when set, is the local address of the probes' TCP and UDP
+	// sockets, to choose the egress path of a multi-homed scanner.
+	SourceIP net.IP
+	// Interface, when set, binds the probes' sockets to this network
+	// interface. It is only supported on Linux.
+	Interface string
+	// IPVersion restricts the probes to IPv4 (4) or IPv6 (6); 0 lets the
+	// system choose.
+	IPVersion int
+	// DualStack additionally probes targets with both IPv4 and IPv6
+	// addresses over each family and reports differences in
+	// CheckResult.DualStack.
+	DualStack bool
+	// CrossCheck, when set, is the base URL of an http1 web instance, such
+	// as DefaultCrossCheckEndpoint, asked to check each target too; the
+	// differences are reported in CheckResult.CrossCheck.
+	CrossCheck string
+	// Resolver, when set, resolves target names over DoH or DoT instead of
+	// the system resolver.
+	Resolver *Resolver
+	// Retries is how many more times a protocol probe that failed to get
+	// any response is sent, after a jittered exponential backoff, so one
+	// dropped packet does not mark a version unsupported.
+	Retries int
+	// Rate caps how many probes start per second across all workers of a
+	// run, so large scans stay below IDS/WAF rate limits. Each probe
+	// (roughly one request or handshake) counts once; 0 means no limit.
+	Rate float64
+	// Deadline, when set, ends a run: probes still running then are
+	// abandoned, and targets not started are not probed at all. Both come
+	// back as Unscanned results, so the output still lists every target.
+	// Cancelling the context given to a Context function does the same.
+	Deadline time.Time
+	// TLSConfig, when set, is the base of the HTTP/1.x, HTTP/2 and HTTP/3
+	// probes' TLS configs, e.g. to present a client certificate to an mTLS
+	// gateway. The probes set ServerName (from SNI), NextProtos and
+	// InsecureSkipVerify themselves.
+	TLSConfig *tls.Config
+
+	// dnsCache and rateLimiter are shared by every check of a run; see
+	// forRun.
+	dnsCache    *dnsCache
+	rateLimiter *rateLimiter
+	// ctx is the run's context, from the caller of a Context function and
+	// limited to Deadline, the parent of every probe context; stopRun
+	// releases the Deadline at the end of the run.
+	ctx     context.Context
+	stopRun context.CancelFunc
+	// checkTimeout bounds each target's check, for WithTimeout.
+	checkTimeout time.Duration
+}
+
+// withContext returns o with ctx as the context of its runs.
+func (o Options) withContext(ctx context.Context) Options {
+	o.ctx = ctx
+	return o
+}
+
+// forRun returns o with the state shared by the checks of a run, the DNS
+// cache, the Rate limiter and the Deadline, unless it already has it.
+func (o Options) forRun() Options {
+	if o.dnsCache == nil {
+		o.dnsCache = newDNSCache()
+	}
+	if o.rateLimiter == nil && o.Rate > 0 {
+		o.rateLimiter = newRateLimiter(o.Rate)
+	}
+	if o.stopRun == nil && !o.Deadline.IsZero() {
+		o.ctx, o.stopRun = context.WithDeadline(o.context(), o.Deadline)
+	}
+	return o
+}
+
+// endRun releases the state forRun set up for a run that is over.
+func (o Options) endRun() {
+	if o.stopRun != nil {
+		o.stopRun()
+	}
+}
+
+// context is the run's context, context.Background() by default.
+func (o Options) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// stopped reports whether the run's context is done: cancelled by the
+// caller, or past its deadline or Deadline.
+func (o Options) stopped() bool {
+	return o.context().Err() != nil
+}
+
+// stopReason says why the run stopped, for the results of the targets it
+// cut off.
+func (o Options) stopReason() string {
+	if errors.Is(o.context().Err(), context.DeadlineExceeded) {
+		return "the scan deadline passed"
+	}
+	return "the scan was cancelled"
+}
+
+// baseContext is the parent of every probe context, carrying the run's
+// context, DNS cache, rate limiter, Resolver and IP version.
+func (o Options) baseContext() context.Context {
+	ctx := withDNSCache(withResolver(o.context(), o.Resolver), o.dnsCache)
+	ctx = withRateLimiter(ctx, o.rateLimiter)
+	return withIPVersion(ctx, o.IPVersion)
+}
+
+// tlsConfig is the base TLS config of the probe clients, for them to set
+// NextProtos on.
+func (o Options) tlsConfig() *tls.Config {
+	conf := &tls.Config{}
+	if o.TLSConfig != nil {
+		conf = o.TLSConfig.Clone()
+	}
+	// An empty ServerName lets each client derive SNI from the target host.
+	conf.ServerName = o.SNI
+	conf.InsecureSkipVerify = true
+	return conf
+}
+
+// prepareRequest sets the User-Agent and any extra headers on a probe
+// request. Headers win over UserAgent, so -H "User-Agent: ..." also works.
+func (o Options) prepareRequest(req *http.Request) {
+	req.Header.Set("User-Agent", o.userAgent())
+	if o.HostHeader != "" {
+		req.Host = o.HostHeader
+	}
+	for name, values := range o.Headers {
+		req.Header.Del(name)
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+}
+
+// workerLimit caps a worker pool size for the chosen resource profile.
+func (o Options) workerLimit(n int) int {
+	if o.LowResource && n > lowResourceMaxWorkers {
+		return lowResourceMaxWorkers
+	}
+	return n
+}
+
+// authority is the Host / :authority value for host:port.
+func (o Options) authority(host, port string) string {
+	if o.HostHeader != "" {
+		return o.HostHeader
+	}
+	if port != "443" {
+		return net.JoinHostPort(host, port)
+	}
+	return host
+}
+
+// serverName is the TLS server name to present when connecting to host.
+func (o Options) serverName(host string) string {
+	if o.SNI != "" {
+		return o.SNI
+	}
+	return host
+}
+
+func (o Options) userAgent() string {
+	if o.UserAgent == "" {
+		return DefaultUserAgent()
+	}
+	return o.UserAgent
+}
+
+// probes reports whether the probe for version runs.
+func (o Options) probes(version string) bool {
+	return len(o.Versions) == 0 || slices.Contains(o.Versions, version)
+}
+
+func (o Options) method() string {
+	if o.Method == "" {
+		return http.MethodGet
+	}
+	return o.Method
+}
@@ -0,0 +1,62 @@
+package http1
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPrepareRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		wantUA string
+	}{
+		{"default", Options{}, DefaultUserAgent()},
+		{"user agent option", Options{UserAgent: "scanner/1"}, "scanner/1"},
+		{"host header", Options{HostHeader: "origin.example.com"}, DefaultUserAgent()},
+		{"header wins", Options{UserAgent: "scanner/1", Headers: http.Header{"User-Agent": {"curl/8"}}}, "curl/8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "https://example.com/", nil)
+			tt.opts.prepareRequest(req)
+			if got := req.Header.Get("User-Agent"); got != tt.wantUA {
+				t.Fatalf("User-Agent = %q, want %q", got, tt.wantUA)
+			}
+			if got, want := req.Host, tt.opts.authority("example.com", "443"); got != want {
+				t.Fatalf("Host = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMethod(t *testing.T) {
+	tests := []struct {
+		method, want string
+	}{
+		{"", http.MethodGet},
+		{"GET", http.MethodGet},
+		{"HEAD", http.MethodHead},
+		{"OPTIONS", http.MethodOptions},
+	}
+	for _, tt := range tests {
+		o := Options{Method: tt.method}
+		if got := o.method(); got != tt.want {
+			t.Errorf("method(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	port := startLocalServers(t)
+	res := runChecks("https://127.0.0.1:"+port, Options{Port: port, Versions: []string{"HTTP/2.0"}})
+	for _, vr := range res.Results {
+		skipped := vr.Version != "HTTP/2.0"
+		if vr.NotTested != skipped || (skipped && vr.Detail != versionSkippedDetail) {
+			t.Errorf("%s: NotTested=%v detail %q", vr.Version, vr.NotTested, vr.Detail)
+		}
+	}
+	if !res.Results[2].Supported {
+		t.Errorf("HTTP/2.0 not supported: %s", res.Results[2].Detail)
+	}
+}
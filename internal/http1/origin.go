@@ -0,0 +1,90 @@
+package http1
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+)
+
+// originLimiter caps how many targets sharing an origin IP are checked at
+// once. A worker that draws a target whose origin is at the cap queues it
+// and moves on, so other origins keep going; the queue is worked off by the
+// workers already on that origin as they finish.
+type originLimiter struct {
+	limit int
+	base  context.Context
+
+	mu      sync.Mutex
+	active  map[string]int
+	pending map[string][]func()
+}
+
+// newOriginLimiter returns nil, which does not limit, unless
+// Options.MaxPerOrigin is set.
+func newOriginLimiter(opts Options) *originLimiter {
+	if opts.MaxPerOrigin <= 0 {
+		return nil
+	}
+	return &originLimiter{
+		limit:   opts.MaxPerOrigin,
+		base:    opts.baseContext(),
+		active:  make(map[string]int),
+		pending: make(map[string][]func()),
+	}
+}
+
+// do runs fn, the check of target, now or once a slot for target's origin
+// frees up; in the latter case it returns at once and a worker already on
+// that origin runs fn later.
+func (l *originLimiter) do(target string, fn func()) {
+	if l == nil {
+		fn()
+		return
+	}
+	key := l.origin(target)
+	l.mu.Lock()
+	if l.active[key] >= l.limit {
+		l.pending[key] = append(l.pending[key], fn)
+		l.mu.Unlock()
+		return
+	}
+	l.active[key]++
+	l.mu.Unlock()
+
+	for fn != nil {
+		fn()
+		l.mu.Lock()
+		if q := l.pending[key]; len(q) > 0 {
+			fn = q[0]
+			l.pending[key] = q[1:]
+		} else {
+			fn = nil
+			if l.active[key]--; l.active[key] == 0 {
+				delete(l.active, key)
+				delete(l.pending, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// origin names the server target connects to: the lowest of its host's
+// addresses, so a name whose answers rotate keeps the same key. Hosts that
+// do not resolve are keyed by name; their checks fail fast anyway.
+func (l *originLimiter) origin(target string) string {
+	host := targetHost(target)
+	if host == "" {
+		return target
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	ctx, cancel := context.WithTimeout(l.base, dnsTimeout)
+	defer cancel()
+	addrs, err := netResolver(ctx).LookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return host
+	}
+	return slices.Min(addrs)
+}
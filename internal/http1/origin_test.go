@@ -0,0 +1,64 @@
+package http1
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOriginLimiter(t *testing.T) {
+	l := newOriginLimiter(Options{MaxPerOrigin: 2})
+	origins := []string{"192.0.2.1", "192.0.2.2"}
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	peak := map[string]int{}
+	var done atomic.Int32
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				origin := target[len("https://") : len(target)-len("/x")]
+				l.do(target, func() {
+					mu.Lock()
+					running[origin]++
+					peak[origin] = max(peak[origin], running[origin])
+					mu.Unlock()
+					time.Sleep(5 * time.Millisecond)
+					mu.Lock()
+					running[origin]--
+					mu.Unlock()
+					done.Add(1)
+				})
+			}
+		}()
+	}
+	for i := range 20 {
+		jobs <- fmt.Sprintf("https://%s/x", origins[i%2])
+	}
+	close(jobs)
+	wg.Wait()
+
+	if got := done.Load(); got != 20 {
+		t.Errorf("%d checks ran, want 20", got)
+	}
+	for _, o := range origins {
+		if peak[o] != 2 {
+			t.Errorf("origin %s peaked at %d concurrent checks, want 2", o, peak[o])
+		}
+	}
+}
+
+func TestOriginLimiterNil(t *testing.T) {
+	ran := false
+	newOriginLimiter(Options{}).do("example.com", func() { ran = true })
+	if !ran {
+		t.Error("unlimited do did not run fn")
+	}
+}
@@ -0,0 +1,425 @@
+package http1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PAC limits: the largest script LoadPAC accepts, how long it waits for a
+// PAC URL, and how many answers Proxy remembers.
+const (
+	pacMaxSize      = 1 << 20
+	pacFetchTimeout = 10 * time.Second
+	pacMaxDecisions = 4096
+)
+
+// PAC is a proxy auto-config script (a .pac or wpad.dat file), whose
+// FindProxyForURL function picks the proxy for each URL on managed
+// networks. Its Proxy method plugs into Options.Proxy, so each target's
+// TCP probes take the route a browser on the same machine would.
+//
+// Scripts run in a small interpreter for the subset of JavaScript listed in
+// pacjs.go, with the standard helpers (shExpMatch, dnsDomainIs, isInNet,
+// dnsResolve, myIpAddress, weekdayRange, timeRange and so on) but not
+// dateRange. ParsePAC rejects scripts outside the subset with an error
+// naming the construct and its line.
+type PAC struct {
+	script *pacScript
+
+	mu sync.Mutex
+	// decisions caches Proxy's answer per URL passed to the script, which
+	// for https is just the origin. It is emptied when it reaches
+	// pacMaxDecisions, so a long scan of plain-HTTP URLs does not grow it
+	// without bound.
+	decisions map[string]pacDecision
+}
+
+type pacDecision struct {
+	proxy *url.URL
+	err   error
+}
+
+// ParsePAC compiles a PAC script and runs its top-level code.
+func ParsePAC(src string) (*PAC, error) {
+	script, err := compilePAC(src)
+	if err != nil {
+		return nil, fmt.Errorf("PAC: %w", err)
+	}
+	if fn, _ := script.globals.lookup("FindProxyForURL"); !pacCallable(fn) {
+		return nil, errors.New("PAC: script does not define FindProxyForURL")
+	}
+	return &PAC{script: script, decisions: make(map[string]pacDecision)}, nil
+}
+
+// LoadPAC reads a PAC script from an http://, https:// or file:// URL or a
+// local path and compiles it. URLs are fetched directly, never through a
+// proxy, as browsers do.
+func LoadPAC(ctx context.Context, location string) (*PAC, error) {
+	data, err := readPAC(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePAC(string(data))
+}
+
+func readPAC(ctx context.Context, location string) ([]byte, error) {
+	var body io.Reader
+	u, err := url.Parse(location)
+	switch {
+	case err == nil && (u.Scheme == "http" || u.Scheme == "https"):
+		ctx, cancel := context.WithTimeout(ctx, pacFetchTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		tr := &http.Transport{}
+		defer tr.CloseIdleConnections()
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching PAC: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching PAC: %s", resp.Status)
+		}
+		body = resp.Body
+	default:
+		path := location
+		if err == nil && u.Scheme == "file" {
+			path = u.Path
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		body = f
+	}
+	data, err := io.ReadAll(io.LimitReader(body, pacMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading PAC: %w", err)
+	}
+	if len(data) > pacMaxSize {
+		return nil, fmt.Errorf("PAC file is larger than %d KiB", pacMaxSize>>10)
+	}
+	return data, nil
+}
+
+// FindProxyForURL runs the script for rawURL and returns its answer, e.g.
+// "PROXY proxy.corp:8080; DIRECT". As in browsers, https URLs are passed to
+// the script without their path.
+func (p *PAC) FindProxyForURL(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	v, err := p.script.call(ctx, "FindProxyForURL", pacScriptURL(u), u.Hostname())
+	if err != nil {
+		return "", fmt.Errorf("PAC: FindProxyForURL: %w", err)
+	}
+	answer, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("PAC: FindProxyForURL returned %s, not a string", pacTypeof(v))
+	}
+	return answer, nil
+}
+
+// Proxy returns the proxy the script picks for req, or nil for DIRECT, in
+// the form Options.Proxy and http.Transport expect. Only the first usable
+// entry of the answer counts: a probe does not fail over to later ones.
+func (p *PAC) Proxy(req *http.Request) (*url.URL, error) {
+	key := pacScriptURL(req.URL)
+	p.mu.Lock()
+	d, ok := p.decisions[key]
+	p.mu.Unlock()
+	if ok {
+		return d.proxy, d.err
+	}
+	answer, err := p.FindProxyForURL(req.Context(), key)
+	if err == nil {
+		d.proxy, d.err = parsePACAnswer(answer)
+	} else {
+		d.err = err
+	}
+	p.mu.Lock()
+	if len(p.decisions) >= pacMaxDecisions {
+		clear(p.decisions)
+	}
+	p.decisions[key] = d
+	p.mu.Unlock()
+	return d.proxy, d.err
+}
+
+func pacScriptURL(u *url.URL) string {
+	if u.Scheme == "https" {
+		return "https://" + u.Host + "/"
+	}
+	return u.String()
+}
+
+// parsePACAnswer returns the first entry of a FindProxyForURL answer the
+// probes can use: DIRECT (nil), PROXY or HTTP, HTTPS, or SOCKS and SOCKS5.
+func parsePACAnswer(answer string) (*url.URL, error) {
+	if strings.TrimSpace(answer) == "" {
+		return nil, nil // browsers take an empty answer as DIRECT
+	}
+	for _, entry := range strings.Split(answer, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 1 && strings.EqualFold(fields[0], "DIRECT") {
+			return nil, nil
+		}
+		if len(fields) != 2 {
+			continue
+		}
+		var scheme string
+		switch strings.ToUpper(fields[0]) {
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS5":
+			scheme = "socks5"
+		default:
+			continue // e.g. SOCKS4, which the probes cannot speak
+		}
+		return &url.URL{Scheme: scheme, Host: fields[1]}, nil
+	}
+	return nil, fmt.Errorf("PAC: no usable proxy in %q", answer)
+}
+
+// pacScript is a compiled script whose top-level code has run.
+type pacScript struct {
+	globals *pacEnv
+}
+
+func compilePAC(src string) (*pacScript, error) {
+	toks, err := lexPAC(src)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := (&pacParser{toks: toks}).parseProgram()
+	if err != nil {
+		return nil, err
+	}
+	builtins := &pacEnv{vars: make(map[string]any, len(pacBuiltins)), frozen: true}
+	for name, fn := range pacBuiltins {
+		builtins.vars[name] = fn
+	}
+	globals := &pacEnv{vars: make(map[string]any), parent: builtins}
+	r := &pacRun{ctx: context.Background(), globals: globals}
+	if _, _, err := prog.exec(r, globals); err != nil {
+		return nil, err
+	}
+	globals.frozen = true
+	return &pacScript{globals: globals}, nil
+}
+
+// call runs the script's function name. It is safe for concurrent use.
+func (s *pacScript) call(ctx context.Context, name string, args ...any) (any, error) {
+	fn, _ := s.globals.lookup(name)
+	if !pacCallable(fn) {
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+	r := &pacRun{ctx: ctx, globals: &pacEnv{vars: make(map[string]any), parent: s.globals}}
+	return r.call(fn, args)
+}
+
+// pacBuiltins are the functions the PAC standard gives scripts. The DNS
+// helpers use the run's resolver, so they follow --doh.
+var pacBuiltins = map[string]pacBuiltin{
+	"isPlainHostName": func(_ *pacRun, args []any) (any, error) {
+		return !strings.Contains(pacArgString(args, 0), "."), nil
+	},
+	"dnsDomainIs": func(_ *pacRun, args []any) (any, error) {
+		return strings.HasSuffix(strings.ToLower(pacArgString(args, 0)), strings.ToLower(pacArgString(args, 1))), nil
+	},
+	"localHostOrDomainIs": func(_ *pacRun, args []any) (any, error) {
+		host, hostdom := strings.ToLower(pacArgString(args, 0)), strings.ToLower(pacArgString(args, 1))
+		return host == hostdom || !strings.Contains(host, ".") && strings.HasPrefix(hostdom, host+"."), nil
+	},
+	"dnsDomainLevels": func(_ *pacRun, args []any) (any, error) {
+		return float64(strings.Count(pacArgString(args, 0), ".")), nil
+	},
+	"isResolvable": func(r *pacRun, args []any) (any, error) {
+		_, ok := r.resolve(pacArgString(args, 0))
+		return ok, nil
+	},
+	"dnsResolve": func(r *pacRun, args []any) (any, error) {
+		if ip, ok := r.resolve(pacArgString(args, 0)); ok {
+			return ip, nil
+		}
+		return nil, nil
+	},
+	"isInNet":      pacIsInNet,
+	"myIpAddress":  pacMyIPAddress,
+	"shExpMatch":   pacShExpMatch,
+	"weekdayRange": pacWeekdayRange,
+	"timeRange":    pacTimeRange,
+	"alert":        func(*pacRun, []any) (any, error) { return nil, nil },
+}
+
+func pacArgString(args []any, i int) string { return pacToString(pacArg(args, i)) }
+
+// resolve returns an address of host, preferring IPv4 as dnsResolve does.
+func (r *pacRun) resolve(host string) (string, bool) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), true
+	}
+	ips, err := netResolver(r.ctx).LookupIP(r.ctx, "ip", host)
+	if err != nil || len(ips) == 0 {
+		return "", false
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String(), true
+		}
+	}
+	return ips[0].String(), true
+}
+
+// pacIsInNet reports whether host, resolved if need be, lies in the IPv4
+// network given by a pattern and mask such as "10.0.0.0", "255.0.0.0".
+func pacIsInNet(r *pacRun, args []any) (any, error) {
+	addr, ok := r.resolve(pacArgString(args, 0))
+	if !ok {
+		return false, nil
+	}
+	ip := net.ParseIP(addr).To4()
+	pattern := net.ParseIP(pacArgString(args, 1)).To4()
+	mask := net.ParseIP(pacArgString(args, 2)).To4()
+	if ip == nil || pattern == nil || mask == nil {
+		return false, nil
+	}
+	m := net.IPMask(mask)
+	return ip.Mask(m).Equal(pattern.Mask(m)), nil
+}
+
+// pacMyIPAddress returns the local address of the default route, found by
+// connecting a UDP socket, which sends nothing.
+func pacMyIPAddress(*pacRun, []any) (any, error) {
+	conn, err := net.Dial("udp4", "192.0.2.1:53")
+	if err != nil {
+		return "127.0.0.1", nil
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// pacShExpMatch matches a string against a shell pattern with * and ?.
+func pacShExpMatch(r *pacRun, args []any) (any, error) {
+	s, pattern := []rune(pacArgString(args, 0)), []rune(pacArgString(args, 1))
+	// Each * may rescan the rest of s.
+	if err := r.spend(len(s) * (strings.Count(string(pattern), "*") + 1) / 16); err != nil {
+		return nil, err
+	}
+	return shellMatch(s, pattern), nil
+}
+
+// shellMatch matches s against a pattern where * stands for any run of
+// characters and ? for one, backtracking only to the latest *.
+func shellMatch(s, pattern []rune) bool {
+	i, j := 0, 0
+	star, starAt := -1, 0
+	for i < len(s) {
+		switch {
+		case j < len(pattern) && pattern[j] == '*':
+			star, starAt = j, i
+			j++
+		case j < len(pattern) && (pattern[j] == '?' || pattern[j] == s[i]):
+			i++
+			j++
+		case star >= 0:
+			starAt++
+			i, j = starAt, star+1
+		default:
+			return false
+		}
+	}
+	for j < len(pattern) && pattern[j] == '*' {
+		j++
+	}
+	return j == len(pattern)
+}
+
+var pacWeekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// pacWeekdayRange implements weekdayRange(day[, day][, "GMT"]).
+func pacWeekdayRange(_ *pacRun, args []any) (any, error) {
+	now, args := pacNow(args)
+	if len(args) == 0 || len(args) > 2 {
+		return nil, errors.New("weekdayRange takes one or two days")
+	}
+	var days []int
+	for _, a := range args {
+		d := slices.Index(pacWeekdays, strings.ToUpper(pacToString(a)))
+		if d < 0 {
+			return nil, fmt.Errorf("weekdayRange: unknown day %q", pacToString(a))
+		}
+		days = append(days, d)
+	}
+	today := int(now.Weekday())
+	if len(days) == 1 {
+		return today == days[0], nil
+	}
+	if days[0] <= days[1] {
+		return days[0] <= today && today <= days[1], nil
+	}
+	return today >= days[0] || today <= days[1], nil // e.g. FRI to MON
+}
+
+// pacTimeRange implements timeRange with hours, hours and minutes, or
+// hours, minutes and seconds, each optionally followed by "GMT". A range
+// includes its start and excludes its end: timeRange(9, 17) holds from
+// 9:00 until 16:59:59.
+func pacTimeRange(_ *pacRun, args []any) (any, error) {
+	now, args := pacNow(args)
+	n := make([]int, len(args))
+	for i, a := range args {
+		n[i] = int(pacToNumber(a))
+	}
+	sec := now.Hour()*3600 + now.Minute()*60 + now.Second()
+	var from, to int
+	switch len(n) {
+	case 1:
+		return now.Hour() == n[0], nil
+	case 2:
+		from, to = n[0]*3600, n[1]*3600
+	case 4:
+		from, to = n[0]*3600+n[1]*60, n[2]*3600+n[3]*60
+	case 6:
+		from, to = n[0]*3600+n[1]*60+n[2], n[3]*3600+n[4]*60+n[5]
+	default:
+		return nil, errors.New("timeRange takes 1, 2, 4 or 6 numbers")
+	}
+	return pacInRange(sec, from, to), nil
+}
+
+// pacNow returns the time the range helpers compare against, in UTC when
+// the last argument is "GMT", and the other arguments.
+func pacNow(args []any) (time.Time, []any) {
+	now := time.Now()
+	if n := len(args); n > 0 && pacToString(args[n-1]) == "GMT" {
+		return now.UTC(), args[:n-1]
+	}
+	return now, args
+}
+
+// pacInRange reports whether v lies in [from, to), where a range with to
+// before from wraps around midnight, e.g. 22:00 to 6:00.
+func pacInRange(v, from, to int) bool {
+	if from <= to {
+		return from <= v && v < to
+	}
+	return v >= from || v < to
+}
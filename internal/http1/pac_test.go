@@ -0,0 +1,246 @@
+package http1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPAC = `
+// Corporate routing.
+var direct = ["*.corp.example", "intranet"];
+var hits = 0;
+
+function isDirect(host) {
+	for (var i = 0; i < direct.length; i++) {
+		if (shExpMatch(host, direct[i])) return true
+	}
+	return isPlainHostName(host) || isInNet(host, "10.0.0.0", "255.0.0.0");
+}
+
+function FindProxyForURL(url, host) {
+	hits++;
+	host = host.toLowerCase();
+	if (isDirect(host))
+		return "DIRECT";
+	if (dnsDomainIs(host, ".socks.example") && url.substring(0, 6) == "https:")
+		return "SOCKS5 socks.corp.example:1080; DIRECT";
+	if (url.indexOf("/path") >= 0)
+		return "SOCKS4 old.corp.example:1080; PROXY " + "path.corp.example:" + (3000 + 128);
+	if (hits > 0)
+		return "PROXY proxy.corp.example:8080; DIRECT";
+	return "DIRECT";
+}
+`
+
+func TestPACProxy(t *testing.T) {
+	pac, err := ParsePAC(testPAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://WWW.CORP.EXAMPLE/", ""},
+		{"http://intranet/", ""},
+		{"https://10.1.2.3:8443/", ""},
+		{"https://a.socks.example/ignored/path", "socks5://socks.corp.example:1080"},
+		{"http://a.socks.example/", "http://proxy.corp.example:8080"},
+		{"http://example.com/path", "http://path.corp.example:3128"},
+		{"https://example.com/path", "http://proxy.corp.example:8080"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		pu, err := pac.Proxy(&http.Request{URL: u})
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		got := ""
+		if pu != nil {
+			got = pu.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: proxy %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPACDecisionsBounded(t *testing.T) {
+	pac, err := ParsePAC(`function FindProxyForURL(url, host) { return "DIRECT"; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pacMaxDecisions + 10 {
+		u := &url.URL{Scheme: "http", Host: "example.com", Path: fmt.Sprintf("/%d", i)}
+		if _, err := pac.Proxy(&http.Request{URL: u}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(pac.decisions); n > pacMaxDecisions {
+		t.Errorf("%d cached decisions, want at most %d", n, pacMaxDecisions)
+	}
+}
+
+func TestParsePACAnswer(t *testing.T) {
+	tests := []struct {
+		answer, want string
+	}{
+		{"", ""},
+		{"DIRECT", ""},
+		{"PROXY p:3128; DIRECT", "http://p:3128"},
+		{"HTTPS p:443", "https://p:443"},
+		{"SOCKS4 s:1080; SOCKS s:1080", "socks5://s:1080"},
+	}
+	for _, tt := range tests {
+		pu, err := parsePACAnswer(tt.answer)
+		if err != nil {
+			t.Errorf("%q: %v", tt.answer, err)
+			continue
+		}
+		got := ""
+		if pu != nil {
+			got = pu.String()
+		}
+		if got != tt.want {
+			t.Errorf("%q = %q, want %q", tt.answer, got, tt.want)
+		}
+	}
+	if _, err := parsePACAnswer("SOCKS4 s:1080"); err == nil {
+		t.Error("an answer with no usable proxy should fail")
+	}
+}
+
+func TestPACScriptErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"syntax", "function FindProxyForURL(url, host) { return (", "line 1"},
+		{"missing", "function f() {}", "does not define FindProxyForURL"},
+		{"unsupported", "function FindProxyForURL(url, host) {\n switch (host) {} }", "line 2: switch is not supported"},
+		{"top-level", "var x = undefinedHelper();", "line 1: undefinedHelper is not defined"},
+		{"while", "function FindProxyForURL(url, host) {\n while (true) {} }", "line 2: while is not supported"},
+		{"ternary", "function FindProxyForURL(url, host) {\n return host ? 'DIRECT' : ''; }", "line 2: the ternary operator is not supported"},
+		{"typeof", "function FindProxyForURL(url, host) {\n\n if (typeof host == 'string') return ''; }", "line 3: typeof is not supported"},
+		{"regex literal", "function FindProxyForURL(url, host) {\n if (/corp/.test(host)) return ''; }", "line 2: regular expression literals are not supported"},
+		{"regex after return", "function FindProxyForURL(url, host) {\n return /x/; }", "line 2: regular expression literals are not supported"},
+		{"division", "function FindProxyForURL(url, host) {\n return host.length / 2; }", "line 2: the / operator is not supported"},
+		{"multiplication", "var n = 2 * 3;", "line 1: the * operator is not supported"},
+		{"object literal", "var proxies = {\n a: 1 };", "line 1: object literals are not supported"},
+		{"unknown method", "function FindProxyForURL(url, host) {\n return host.match('x'); }", "line 2: the match method is not supported"},
+		{"global object", "function FindProxyForURL(url, host) {\n return String(host); }", "line 2: String is not defined"},
+		{"dateRange", "function FindProxyForURL(url, host) {\n if (dateRange('JAN', 'MAR')) return ''; }", "line 2: dateRange is not supported"},
+		{"closure", "function FindProxyForURL(url, host) { var f = function() {}; }", "function expressions are not supported"},
+		{"nested function", "function FindProxyForURL(url, host) { function f() {} }", "declared at the top level"},
+		{"nesting", "var x = " + strings.Repeat("(", 1000) + "1" + strings.Repeat(")", 1000) + ";", "too deeply nested"},
+	}
+	for _, tt := range tests {
+		if _, err := ParsePAC(tt.src); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Errors while the script runs name the line they happened on.
+	pac, err := ParsePAC("var list;\nfunction FindProxyForURL(url, host) {\n\treturn list.length;\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pac.FindProxyForURL(context.Background(), "https://example.com/"); err == nil || !strings.Contains(err.Error(), "line 3: cannot read length of undefined") {
+		t.Errorf("runtime error: %v", err)
+	}
+
+	pac, err = ParsePAC("function FindProxyForURL(url, host) { for (;;) {} }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pac.FindProxyForURL(context.Background(), "https://example.com/"); err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("runaway loop: %v", err)
+	}
+
+	// Large strings count against the budget too, however few steps use them.
+	pac, err = ParsePAC(`var s = "*a"; for (var i = 0; i < 19; i++) s += s;
+function FindProxyForURL(url, host) { for (var i = 0; i < 100; i++) shExpMatch(s, s); }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pac.FindProxyForURL(context.Background(), "https://example.com/"); err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("large strings: %v", err)
+	}
+}
+
+func TestPACExpressions(t *testing.T) {
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`"a" + 1 + 2`, "a12"},
+		{`1 + 2 - -4`, float64(7)},
+		{`(n -= 4) + (n += 1)`, float64(13)},
+		{`"10" == 10 && "10" !== 10`, true},
+		{`"www.example.com".split(".").length`, float64(3)},
+		{`["a", "b"].indexOf("b") + "x".toUpperCase()`, "1X"},
+		{`"example.com".substr(-3)`, "com"},
+		{`dnsDomainLevels("a.b.c") == 2 && localHostOrDomainIs("www", "www.example.com")`, true},
+		{`isInNet("192.168.1.7", "192.168.0.0", "255.255.0.0") && !isInNet("192.169.1.7", "192.168.0.0", "255.255.0.0")`, true},
+		{`shExpMatch("http://a.example.com/x", "*.example.com/*") && !shExpMatch("a.example.org", "*.example.com")`, true},
+		{`weekdayRange("SUN", "SAT") && timeRange(0, 24)`, true},
+		{`shExpMatch("a.b", "a?b") && !shExpMatch("ab", "a?b") && shExpMatch("", "*") && shExpMatch("aXbXc", "a*b*c")`, true},
+	}
+	for _, tt := range tests {
+		script, err := compilePAC("var n = 10;\nfunction f() { return " + tt.expr + "; }")
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		got, err := script.call(context.Background(), "f")
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %#v (%v), want %#v", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadPAC(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wpad.dat" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testPAC))
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "proxy.pac")
+	if err := os.WriteFile(path, []byte(testPAC), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, loc := range []string{srv.URL + "/wpad.dat", path, "file://" + path} {
+		if _, err := LoadPAC(context.Background(), loc); err != nil {
+			t.Errorf("LoadPAC(%s): %v", loc, err)
+		}
+	}
+	if _, err := LoadPAC(context.Background(), srv.URL+"/missing.pac"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing PAC URL: %v", err)
+	}
+}
+
+func FuzzPAC(f *testing.F) {
+	f.Add(testPAC, "https://www.corp.example/")
+	f.Add("function FindProxyForURL(url, host) { return url.substring(0, 5) + host.split('.')[0]; }", "http://a.b/")
+	f.Add("var a = [1, [2, '3']]; function FindProxyForURL(u, h) { for (var i = 0; i < 3; i++) a += i; return a; }", "x")
+	f.Fuzz(func(t *testing.T, src, rawURL string) {
+		pac, err := ParsePAC(src)
+		if err != nil {
+			return
+		}
+		// Helpers that resolve names or read the clock are fine to call;
+		// scripts only have to finish without panicking.
+		_, _ = pac.FindProxyForURL(context.Background(), rawURL)
+	})
+}
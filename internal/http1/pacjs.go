@@ -0,0 +1,1540 @@
+package http1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// This file is a small interpreter for the JavaScript subset PAC files
+// generated by proxy management consoles, and the hand-written ones in
+// wpad deployments we have seen, are written in: lists of domains or
+// networks checked with the helpers in pac.go. The subset is
+//
+//   - statements: function declarations at the top level, var, let and
+//     const, if/else, for (;;) loops with break, return and blocks;
+//   - values: strings, numbers, true, false, null, undefined and array
+//     literals, read with [i] and .length;
+//   - operators: ! + - == != === !== < <= > >= && || = += -= ++ --;
+//   - methods: the string methods in pacMethods and arrays' indexOf.
+//
+// Anything else, such as while loops, the ternary operator, typeof,
+// regular expressions, objects, closures, exceptions or an identifier the
+// script never defines, fails to load with an error naming it and its
+// line, rather than being approximated. Errors while the script runs name
+// the line too.
+
+// Limits on one script, so a runaway loop or recursion fails the lookup
+// instead of hanging the probes waiting for it, and deeply nested source
+// fails to parse instead of exhausting the stack.
+const (
+	pacMaxSteps  = 1_000_000
+	pacMaxDepth  = 100
+	pacMaxString = pacMaxSize
+)
+
+type pacTokKind int
+
+const (
+	pacTokEOF pacTokKind = iota
+	pacTokIdent
+	pacTokString
+	pacTokNumber
+	pacTokPunct
+)
+
+type pacTok struct {
+	kind pacTokKind
+	text string
+	num  float64
+	line int
+}
+
+// pacPuncts lists the punctuators, longer ones first.
+var pacPuncts = []string{
+	"===", "!==", "==", "!=", "<=", ">=", "&&", "||", "++", "--", "+=", "-=",
+	"{", "}", "(", ")", "[", "]", ";", ",", "=", "!", "<", ">", "+", "-", ".", "?", ":",
+}
+
+// pacKeywords are words that cannot start an expression; pacUnsupported
+// are JavaScript features outside the subset.
+var (
+	pacKeywords = map[string]bool{
+		"if": true, "else": true, "for": true, "return": true, "break": true,
+		"var": true, "let": true, "const": true, "function": true,
+	}
+	pacUnsupported = map[string]bool{
+		"while": true, "continue": true, "typeof": true,
+		"switch": true, "case": true, "default": true, "do": true, "try": true, "catch": true,
+		"finally": true, "throw": true, "new": true, "delete": true, "in": true, "instanceof": true,
+		"this": true, "class": true, "with": true, "void": true, "yield": true, "dateRange": true,
+	}
+	// pacMethods are the string methods scripts may call.
+	pacMethods = map[string]bool{
+		"toLowerCase": true, "toUpperCase": true, "indexOf": true, "lastIndexOf": true,
+		"substring": true, "substr": true, "split": true,
+	}
+)
+
+func lexPAC(src string) ([]pacTok, error) {
+	var toks []pacTok
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			s, n, err := lexPACString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			toks = append(toks, pacTok{kind: pacTokString, text: s, line: line})
+			i += n
+		case isPACDigit(c) || c == '.' && i+1 < len(src) && isPACDigit(src[i+1]):
+			j := i
+			for j < len(src) && (isPACIdentPart(src[j]) || src[j] == '.') {
+				j++
+			}
+			f, err := parsePACNumber(src[i:j])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid number %q", line, src[i:j])
+			}
+			toks = append(toks, pacTok{kind: pacTokNumber, text: src[i:j], num: f, line: line})
+			i = j
+		case isPACIdentStart(c):
+			j := i + 1
+			for j < len(src) && isPACIdentPart(src[j]) {
+				j++
+			}
+			toks = append(toks, pacTok{kind: pacTokIdent, text: src[i:j], line: line})
+			i = j
+		default:
+			punct := ""
+			for _, p := range pacPuncts {
+				if strings.HasPrefix(src[i:], p) {
+					punct = p
+					break
+				}
+			}
+			switch {
+			case punct != "":
+			case c == '/' && !pacOperandEnds(toks):
+				return nil, fmt.Errorf("line %d: regular expression literals are not supported in PAC scripts", line)
+			case strings.IndexByte("*/%&|^~", c) >= 0:
+				return nil, fmt.Errorf("line %d: the %c operator is not supported in PAC scripts", line, c)
+			default:
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+			toks = append(toks, pacTok{kind: pacTokPunct, text: punct, line: line})
+			i += len(punct)
+		}
+	}
+	return append(toks, pacTok{kind: pacTokEOF, line: line}), nil
+}
+
+// pacOperandEnds reports whether the last token lexed ends an operand, so
+// that a / after it would be division rather than start a regular
+// expression.
+func pacOperandEnds(toks []pacTok) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	t := toks[len(toks)-1]
+	switch t.kind {
+	case pacTokPunct:
+		return t.text == ")" || t.text == "]"
+	case pacTokIdent:
+		return !pacKeywords[t.text] && !pacUnsupported[t.text]
+	}
+	return true
+}
+
+// lexPACString decodes the quoted string at the start of s and returns it
+// with the number of bytes it took up.
+func lexPACString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, errors.New("unterminated string")
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'x', 'u':
+				n := 2
+				if e == 'u' {
+					n = 4
+				}
+				if i+n >= len(s) {
+					return "", 0, errors.New("unterminated string")
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid escape \\%c%s", e, s[i+1:i+1+n])
+				}
+				b.WriteRune(rune(r))
+				i += n
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+func parsePACNumber(s string) (float64, error) {
+	if hex, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		n, err := strconv.ParseUint(hex, 16, 64)
+		return float64(n), err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func isPACDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isPACIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isPACIdentPart(c byte) bool { return isPACIdentStart(c) || isPACDigit(c) }
+
+type pacParser struct {
+	toks []pacTok
+	pos  int
+	// depth counts the statements and expressions being parsed.
+	depth int
+	// declared holds the names the script defines anywhere, and refs the
+	// identifiers it reads, checked against them once it is parsed.
+	declared map[string]bool
+	refs     []pacTok
+}
+
+// nest enters a nested statement or expression; the caller defers the
+// returned function.
+func (p *pacParser) nest() (func(), error) {
+	if p.depth >= pacMaxDepth {
+		return nil, p.errorf("too deeply nested")
+	}
+	p.depth++
+	return func() { p.depth-- }, nil
+}
+
+func (p *pacParser) peek() pacTok { return p.toks[p.pos] }
+
+func (p *pacParser) next() pacTok {
+	t := p.toks[p.pos]
+	if t.kind != pacTokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *pacParser) is(punct string) bool {
+	t := p.peek()
+	return t.kind == pacTokPunct && t.text == punct
+}
+
+func (p *pacParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == pacTokIdent && t.text == kw
+}
+
+func (p *pacParser) accept(punct string) bool {
+	if p.is(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *pacParser) expect(punct string) error {
+	if !p.accept(punct) {
+		return p.errorf("expected %s", punct)
+	}
+	return nil
+}
+
+func (p *pacParser) errorf(format string, args ...any) error {
+	t := p.peek()
+	found := "end of script"
+	if t.kind != pacTokEOF {
+		found = strconv.Quote(t.text)
+	}
+	return fmt.Errorf("line %d: %s, found %s", t.line, fmt.Sprintf(format, args...), found)
+}
+
+func (p *pacParser) parseProgram() (pacBlock, error) {
+	p.declared = make(map[string]bool)
+	var prog pacBlock
+	for p.peek().kind != pacTokEOF {
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		prog = append(prog, s)
+	}
+	// Names are checked across the whole script rather than per scope, so
+	// this catches helpers and globals outside the subset, such as Math or
+	// dateRange, when the script loads instead of when a lookup runs into
+	// them.
+	for _, t := range p.refs {
+		if _, builtin := pacBuiltins[t.text]; !builtin && !p.declared[t.text] {
+			return nil, fmt.Errorf("line %d: %s is not defined", t.line, t.text)
+		}
+	}
+	return prog, nil
+}
+
+// parseStatement parses a statement, which reports errors while it runs
+// at the line it starts on.
+func (p *pacParser) parseStatement() (pacStmt, error) {
+	line := p.peek().line
+	s, err := p.parseStatementBody()
+	if err != nil {
+		return nil, err
+	}
+	switch s.(type) {
+	case pacFuncDecl, pacBlock:
+		return s, nil
+	}
+	return pacAt{line: line, stmt: s}, nil
+}
+
+func (p *pacParser) parseStatementBody() (pacStmt, error) {
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	t := p.peek()
+	switch {
+	case p.is("{"):
+		return p.parseBlock()
+	case p.accept(";"):
+		return pacBlock(nil), nil
+	case t.kind != pacTokIdent:
+		// An expression statement, below.
+	case t.text == "function":
+		if p.depth > 1 {
+			return nil, fmt.Errorf("line %d: functions must be declared at the top level", t.line)
+		}
+		p.next()
+		fn, err := p.parseFunction()
+		if err != nil {
+			return nil, err
+		}
+		return pacFuncDecl{fn}, nil
+	case t.text == "var" || t.text == "let" || t.text == "const":
+		p.next()
+		decl, err := p.parseVar()
+		if err != nil {
+			return nil, err
+		}
+		p.accept(";")
+		return decl, nil
+	case t.text == "if":
+		p.next()
+		cond, err := p.parseParenExpr()
+		if err != nil {
+			return nil, err
+		}
+		then, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		var els pacStmt
+		if p.isKeyword("else") {
+			p.next()
+			if els, err = p.parseStatement(); err != nil {
+				return nil, err
+			}
+		}
+		return pacIf{cond: cond, then: then, els: els}, nil
+	case t.text == "for":
+		p.next()
+		return p.parseFor()
+	case t.text == "return":
+		p.next()
+		var x pacExpr
+		// A line break ends a bare return, as automatic semicolon
+		// insertion has it.
+		if n := p.peek(); n.kind != pacTokEOF && n.line == t.line && !p.is(";") && !p.is("}") {
+			var err error
+			if x, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		p.accept(";")
+		return pacReturnStmt{x}, nil
+	case t.text == "break":
+		p.next()
+		p.accept(";")
+		return pacJump(pacBreak), nil
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.accept(";")
+	return pacExprStmt{x}, nil
+}
+
+func (p *pacParser) parseBlock() (pacBlock, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var block pacBlock
+	for !p.accept("}") {
+		if p.peek().kind == pacTokEOF {
+			return nil, p.errorf("expected }")
+		}
+		s, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		block = append(block, s)
+	}
+	return block, nil
+}
+
+// parseFunction parses a function declaration after its keyword.
+func (p *pacParser) parseFunction() (*pacFunc, error) {
+	if p.peek().kind != pacTokIdent {
+		return nil, p.errorf("expected function name")
+	}
+	fn := &pacFunc{name: p.next().text}
+	p.declared[fn.name] = true
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.accept(")") {
+		if len(fn.params) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek().kind != pacTokIdent {
+			return nil, p.errorf("expected parameter name")
+		}
+		fn.params = append(fn.params, p.next().text)
+		p.declared[fn.params[len(fn.params)-1]] = true
+	}
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	fn.body = body
+	return fn, nil
+}
+
+// parseVar parses the declarations after var, let or const.
+func (p *pacParser) parseVar() (pacVarDecl, error) {
+	var decl pacVarDecl
+	for {
+		if p.peek().kind != pacTokIdent {
+			return nil, p.errorf("expected variable name")
+		}
+		name := p.next().text
+		p.declared[name] = true
+		var init pacExpr
+		if p.accept("=") {
+			var err error
+			if init, err = p.parseAssign(); err != nil {
+				return nil, err
+			}
+		}
+		decl = append(decl, pacVarInit{name: name, init: init})
+		if !p.accept(",") {
+			return decl, nil
+		}
+	}
+}
+
+// parseFor parses a for loop after its keyword.
+func (p *pacParser) parseFor() (pacStmt, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var loop pacLoop
+	var err error
+	switch {
+	case p.is(";"):
+	case p.isKeyword("var") || p.isKeyword("let") || p.isKeyword("const"):
+		p.next()
+		loop.init, err = p.parseVar()
+	default:
+		var x pacExpr
+		x, err = p.parseExpr()
+		loop.init = pacExprStmt{x}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if !p.is(";") {
+		if loop.cond, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if !p.is(")") {
+		if loop.post, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if loop.body, err = p.parseStatement(); err != nil {
+		return nil, err
+	}
+	return loop, nil
+}
+
+func (p *pacParser) parseParenExpr() (pacExpr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (p *pacParser) parseExpr() (pacExpr, error) { return p.parseAssign() }
+
+func (p *pacParser) parseAssign() (pacExpr, error) {
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	left, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); p.is("?") {
+		return nil, fmt.Errorf("line %d: the ternary operator is not supported in PAC scripts", t.line)
+	}
+	if t := p.peek(); t.kind == pacTokPunct && (t.text == "=" || t.text == "+=" || t.text == "-=") {
+		id, ok := left.(pacIdent)
+		if !ok {
+			return nil, p.errorf("can only assign to a variable")
+		}
+		p.next()
+		p.declared[string(id)] = true
+		value, err := p.parseAssign()
+		if err != nil {
+			return nil, err
+		}
+		return pacAssign{op: t.text, name: string(id), value: value}, nil
+	}
+	return left, nil
+}
+
+// pacPrecedence ranks the binary operators; higher binds tighter.
+var pacPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "===": 3, "!==": 3,
+	"<": 4, ">": 4, "<=": 4, ">=": 4,
+	"+": 5, "-": 5,
+}
+
+func (p *pacParser) parseBinary(minPrec int) (pacExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := pacPrecedence[t.text]
+		if t.kind != pacTokPunct || !ok || prec < minPrec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = pacBinary{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *pacParser) parseUnary() (pacExpr, error) {
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	t := p.peek()
+	switch {
+	case t.kind == pacTokPunct && (t.text == "!" || t.text == "-" || t.text == "+"):
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return pacUnary{op: t.text, x: x}, nil
+	case t.kind == pacTokPunct && (t.text == "++" || t.text == "--"):
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		id, ok := x.(pacIdent)
+		if !ok {
+			return nil, fmt.Errorf("line %d: %s needs a variable", t.line, t.text)
+		}
+		return pacIncDec{name: string(id), delta: incDelta(t.text), prefix: true}, nil
+	}
+	x, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	if n := p.peek(); n.kind == pacTokPunct && (n.text == "++" || n.text == "--") {
+		if id, ok := x.(pacIdent); ok {
+			p.next()
+			return pacIncDec{name: string(id), delta: incDelta(n.text)}, nil
+		}
+	}
+	return x, nil
+}
+
+func incDelta(op string) float64 {
+	if op == "--" {
+		return -1
+	}
+	return 1
+}
+
+func (p *pacParser) parseCall() (pacExpr, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("("):
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			x = pacCall{fn: x, args: args}
+		case p.accept("."):
+			name := p.peek()
+			if name.kind != pacTokIdent {
+				return nil, p.errorf("expected property name")
+			}
+			p.next()
+			if p.is("(") && !pacMethods[name.text] {
+				return nil, fmt.Errorf("line %d: the %s method is not supported in PAC scripts", name.line, name.text)
+			}
+			x = pacMember{x: x, name: name.text}
+		case p.accept("["):
+			i, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = pacIndex{x: x, i: i}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// parseList parses comma-separated expressions up to close, allowing a
+// trailing comma.
+func (p *pacParser) parseList(close string) ([]pacExpr, error) {
+	var list []pacExpr
+	for !p.accept(close) {
+		if len(list) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept(close) {
+				break
+			}
+		}
+		x, err := p.parseAssign()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+	}
+	return list, nil
+}
+
+func (p *pacParser) parsePrimary() (pacExpr, error) {
+	t := p.peek()
+	switch t.kind {
+	case pacTokString:
+		p.next()
+		return pacLit{t.text}, nil
+	case pacTokNumber:
+		p.next()
+		return pacLit{t.num}, nil
+	case pacTokIdent:
+		switch {
+		case pacUnsupported[t.text]:
+			return nil, fmt.Errorf("line %d: %s is not supported in PAC scripts", t.line, t.text)
+		case t.text == "function":
+			return nil, fmt.Errorf("line %d: function expressions are not supported in PAC scripts", t.line)
+		case pacKeywords[t.text]:
+			return nil, p.errorf("expected an expression")
+		}
+		p.next()
+		switch t.text {
+		case "true":
+			return pacLit{true}, nil
+		case "false":
+			return pacLit{false}, nil
+		case "null", "undefined":
+			return pacLit{nil}, nil
+		}
+		p.refs = append(p.refs, t)
+		return pacIdent(t.text), nil
+	case pacTokPunct:
+		switch {
+		case p.accept("("):
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case p.accept("["):
+			elems, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return pacArray(elems), nil
+		case p.is("{"):
+			return nil, fmt.Errorf("line %d: object literals are not supported in PAC scripts", t.line)
+		}
+	}
+	return nil, p.errorf("expected an expression")
+}
+
+// pacEnv is one scope. A script's globals are frozen after its top-level
+// code has run and shared by all calls; assignments to them during a call
+// land in the call's own globals scope, so concurrent calls do not race.
+type pacEnv struct {
+	vars   map[string]any
+	parent *pacEnv
+	frozen bool
+}
+
+func (e *pacEnv) lookup(name string) (any, bool) {
+	for ; e != nil; e = e.parent {
+		if v, ok := e.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// pacRun is the state of one evaluation.
+type pacRun struct {
+	ctx     context.Context
+	globals *pacEnv
+	steps   int
+	depth   int
+}
+
+func (r *pacRun) step() error {
+	r.steps++
+	if r.steps > pacMaxSteps {
+		return errors.New("script ran too long")
+	}
+	return nil
+}
+
+// charge counts the strings and arrays an operation took or built against
+// the step budget, a step per 16 bytes or elements, so scripts that churn
+// through large values fail like runaway loops do.
+func (r *pacRun) charge(vals ...any) error {
+	n := 0
+	for _, v := range vals {
+		switch v := v.(type) {
+		case string:
+			n += len(v)
+		case []any:
+			n += len(v)
+		}
+	}
+	return r.spend(n / 16)
+}
+
+// spend takes n steps, and one more, from the budget.
+func (r *pacRun) spend(n int) error {
+	r.steps += n
+	return r.step()
+}
+
+// assign sets name in the nearest scope declaring it, or as a global.
+func (r *pacRun) assign(env *pacEnv, name string, v any) {
+	for e := env; e != nil && !e.frozen; e = e.parent {
+		if _, ok := e.vars[name]; ok {
+			e.vars[name] = v
+			return
+		}
+	}
+	r.globals.vars[name] = v
+}
+
+// pacFunc is a function defined by the script. Functions are declared at
+// the top level only, so their scope is always the run's globals.
+type pacFunc struct {
+	name   string
+	params []string
+	body   pacBlock
+}
+
+// pacBuiltin is a function provided to scripts, such as shExpMatch.
+type pacBuiltin func(r *pacRun, args []any) (any, error)
+
+func pacCallable(v any) bool {
+	switch v.(type) {
+	case *pacFunc, pacBuiltin:
+		return true
+	}
+	return false
+}
+
+func (r *pacRun) call(fn any, args []any) (any, error) {
+	switch fn := fn.(type) {
+	case pacBuiltin:
+		return fn(r, args)
+	case *pacFunc:
+		if r.depth >= pacMaxDepth {
+			return nil, errors.New("too much recursion")
+		}
+		r.depth++
+		defer func() { r.depth-- }()
+		env := &pacEnv{vars: make(map[string]any, len(fn.params)), parent: r.globals}
+		for i, name := range fn.params {
+			var v any
+			if i < len(args) {
+				v = args[i]
+			}
+			env.vars[name] = v
+		}
+		flow, v, err := fn.body.exec(r, env)
+		if err != nil || flow != pacReturn {
+			return nil, err
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is not a function", pacTypeof(fn))
+}
+
+type pacFlow int
+
+const (
+	pacNext pacFlow = iota
+	pacReturn
+	pacBreak
+)
+
+type pacStmt interface {
+	exec(r *pacRun, env *pacEnv) (pacFlow, any, error)
+}
+
+type pacBlock []pacStmt
+
+func (b pacBlock) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	// Function declarations are hoisted, so they can be called before
+	// the statement defining them.
+	for _, s := range b {
+		if d, ok := s.(pacFuncDecl); ok {
+			d.declare(env)
+		}
+	}
+	for _, s := range b {
+		if err := r.step(); err != nil {
+			return pacNext, nil, err
+		}
+		if flow, v, err := s.exec(r, env); err != nil || flow != pacNext {
+			return flow, v, err
+		}
+	}
+	return pacNext, nil, nil
+}
+
+// pacAt is a statement and the line it starts on, which errors it runs
+// into are reported at unless a statement nested in it already placed them.
+type pacAt struct {
+	line int
+	stmt pacStmt
+}
+
+func (s pacAt) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	flow, v, err := s.stmt.exec(r, env)
+	if err != nil {
+		var le *pacLineError
+		if !errors.As(err, &le) {
+			err = &pacLineError{line: s.line, err: err}
+		}
+	}
+	return flow, v, err
+}
+
+// pacLineError is an error a script ran into, with the line it was on.
+type pacLineError struct {
+	line int
+	err  error
+}
+
+func (e *pacLineError) Error() string { return fmt.Sprintf("line %d: %v", e.line, e.err) }
+
+func (e *pacLineError) Unwrap() error { return e.err }
+
+type pacFuncDecl struct{ fn *pacFunc }
+
+func (d pacFuncDecl) declare(env *pacEnv) {
+	env.vars[d.fn.name] = d.fn
+}
+
+func (pacFuncDecl) exec(*pacRun, *pacEnv) (pacFlow, any, error) { return pacNext, nil, nil }
+
+type pacVarInit struct {
+	name string
+	init pacExpr
+}
+
+type pacVarDecl []pacVarInit
+
+func (d pacVarDecl) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	for _, v := range d {
+		if v.init == nil {
+			if _, ok := env.vars[v.name]; !ok {
+				env.vars[v.name] = nil
+			}
+			continue
+		}
+		x, err := v.init.eval(r, env)
+		if err != nil {
+			return pacNext, nil, err
+		}
+		env.vars[v.name] = x
+	}
+	return pacNext, nil, nil
+}
+
+type pacIf struct {
+	cond      pacExpr
+	then, els pacStmt
+}
+
+func (s pacIf) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	c, err := s.cond.eval(r, env)
+	if err != nil {
+		return pacNext, nil, err
+	}
+	if pacTruthy(c) {
+		return s.then.exec(r, env)
+	}
+	if s.els != nil {
+		return s.els.exec(r, env)
+	}
+	return pacNext, nil, nil
+}
+
+// pacLoop is a for loop.
+type pacLoop struct {
+	init       pacStmt
+	cond, post pacExpr
+	body       pacStmt
+}
+
+func (l pacLoop) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	if l.init != nil {
+		if _, _, err := l.init.exec(r, env); err != nil {
+			return pacNext, nil, err
+		}
+	}
+	for {
+		if err := r.step(); err != nil {
+			return pacNext, nil, err
+		}
+		if l.cond != nil {
+			c, err := l.cond.eval(r, env)
+			if err != nil {
+				return pacNext, nil, err
+			}
+			if !pacTruthy(c) {
+				return pacNext, nil, nil
+			}
+		}
+		flow, v, err := l.body.exec(r, env)
+		switch {
+		case err != nil:
+			return pacNext, nil, err
+		case flow == pacReturn:
+			return flow, v, nil
+		case flow == pacBreak:
+			return pacNext, nil, nil
+		}
+		if l.post != nil {
+			if _, err := l.post.eval(r, env); err != nil {
+				return pacNext, nil, err
+			}
+		}
+	}
+}
+
+type pacReturnStmt struct{ x pacExpr }
+
+func (s pacReturnStmt) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	if s.x == nil {
+		return pacReturn, nil, nil
+	}
+	v, err := s.x.eval(r, env)
+	return pacReturn, v, err
+}
+
+// pacJump is break.
+type pacJump pacFlow
+
+func (j pacJump) exec(*pacRun, *pacEnv) (pacFlow, any, error) { return pacFlow(j), nil, nil }
+
+type pacExprStmt struct{ x pacExpr }
+
+func (s pacExprStmt) exec(r *pacRun, env *pacEnv) (pacFlow, any, error) {
+	_, err := s.x.eval(r, env)
+	return pacNext, nil, err
+}
+
+// pacExpr evaluates to a script value: nil (undefined or null), bool,
+// float64, string, []any, *pacFunc or pacBuiltin.
+type pacExpr interface {
+	eval(r *pacRun, env *pacEnv) (any, error)
+}
+
+type pacLit struct{ v any }
+
+func (n pacLit) eval(*pacRun, *pacEnv) (any, error) { return n.v, nil }
+
+type pacIdent string
+
+func (n pacIdent) eval(_ *pacRun, env *pacEnv) (any, error) {
+	if v, ok := env.lookup(string(n)); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is not defined", string(n))
+}
+
+type pacArray []pacExpr
+
+func (n pacArray) eval(r *pacRun, env *pacEnv) (any, error) {
+	out := make([]any, len(n))
+	for i, x := range n {
+		v, err := x.eval(r, env)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+type pacUnary struct {
+	op string
+	x  pacExpr
+}
+
+func (n pacUnary) eval(r *pacRun, env *pacEnv) (any, error) {
+	v, err := n.x.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		return !pacTruthy(v), nil
+	case "-":
+		return -pacToNumber(v), nil
+	}
+	return pacToNumber(v), nil
+}
+
+type pacBinary struct {
+	op          string
+	left, right pacExpr
+}
+
+func (n pacBinary) eval(r *pacRun, env *pacEnv) (any, error) {
+	l, err := n.left.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "&&":
+		if !pacTruthy(l) {
+			return l, nil
+		}
+		return n.right.eval(r, env)
+	case "||":
+		if pacTruthy(l) {
+			return l, nil
+		}
+		return n.right.eval(r, env)
+	}
+	rv, err := n.right.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := pacOperate(n.op, l, rv)
+	if err != nil {
+		return nil, err
+	}
+	return v, r.charge(l, rv, v)
+}
+
+func pacOperate(op string, l, r any) (any, error) {
+	v := pacOperator(op, l, r)
+	if s, ok := v.(string); ok && len(s) > pacMaxString {
+		return nil, errors.New("string too long")
+	}
+	return v, nil
+}
+
+func pacOperator(op string, l, r any) any {
+	switch op {
+	case "==":
+		return pacLooseEqual(l, r)
+	case "!=":
+		return !pacLooseEqual(l, r)
+	case "===":
+		return pacStrictEqual(l, r)
+	case "!==":
+		return !pacStrictEqual(l, r)
+	case "+":
+		if pacStringish(l) || pacStringish(r) {
+			return pacToString(l) + pacToString(r)
+		}
+		return pacToNumber(l) + pacToNumber(r)
+	case "-":
+		return pacToNumber(l) - pacToNumber(r)
+	}
+	// Ordering compares two strings as strings and anything else as numbers.
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return orderHolds(op, strings.Compare(ls, rs))
+		}
+	}
+	lf, rf := pacToNumber(l), pacToNumber(r)
+	if math.IsNaN(lf) || math.IsNaN(rf) {
+		return false
+	}
+	return orderHolds(op, compareFloat(lf, rf))
+}
+
+type pacAssign struct {
+	op    string
+	name  string
+	value pacExpr
+}
+
+func (n pacAssign) eval(r *pacRun, env *pacEnv) (any, error) {
+	v, err := n.value.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op != "=" {
+		old, err := pacIdent(n.name).eval(r, env)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = pacOperate(n.op[:1], old, v); err != nil {
+			return nil, err
+		}
+		if err := r.charge(old, v); err != nil {
+			return nil, err
+		}
+	}
+	r.assign(env, n.name, v)
+	return v, nil
+}
+
+type pacIncDec struct {
+	name   string
+	delta  float64
+	prefix bool
+}
+
+func (n pacIncDec) eval(r *pacRun, env *pacEnv) (any, error) {
+	old, err := pacIdent(n.name).eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	f := pacToNumber(old)
+	r.assign(env, n.name, f+n.delta)
+	if n.prefix {
+		return f + n.delta, nil
+	}
+	return f, nil
+}
+
+type pacMember struct {
+	x    pacExpr
+	name string
+}
+
+func (n pacMember) eval(r *pacRun, env *pacEnv) (any, error) {
+	v, err := n.x.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	if n.name == "length" {
+		switch v := v.(type) {
+		case string:
+			return float64(len(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		}
+	}
+	if v == nil {
+		return nil, fmt.Errorf("cannot read %s of undefined", n.name)
+	}
+	return nil, nil
+}
+
+type pacIndex struct{ x, i pacExpr }
+
+func (n pacIndex) eval(r *pacRun, env *pacEnv) (any, error) {
+	v, err := n.x.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := n.i.eval(r, env)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := iv.(string); ok {
+		return pacMember{x: pacLit{v}, name: name}.eval(r, env)
+	}
+	i := pacToNumber(iv)
+	switch v := v.(type) {
+	case []any:
+		if i >= 0 && i < float64(len(v)) && i == math.Trunc(i) {
+			return v[int(i)], nil
+		}
+	case string:
+		if i >= 0 && i < float64(len(v)) && i == math.Trunc(i) {
+			return v[int(i) : int(i)+1], nil
+		}
+	case nil:
+		return nil, errors.New("cannot index undefined")
+	}
+	return nil, nil
+}
+
+type pacCall struct {
+	fn   pacExpr
+	args []pacExpr
+}
+
+func (n pacCall) eval(r *pacRun, env *pacEnv) (any, error) {
+	if err := r.step(); err != nil {
+		return nil, err
+	}
+	var recv, fn any
+	var err error
+	m, isMethod := n.fn.(pacMember)
+	if isMethod {
+		recv, err = m.x.eval(r, env)
+	} else {
+		fn, err = n.fn.eval(r, env)
+	}
+	if err != nil {
+		return nil, err
+	}
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		if args[i], err = a.eval(r, env); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.charge(append(args, recv)...); err != nil {
+		return nil, err
+	}
+	if isMethod {
+		v, err := pacMethod(recv, m.name, args)
+		if err != nil {
+			return nil, err
+		}
+		return v, r.charge(v)
+	}
+	if !pacCallable(fn) {
+		if id, ok := n.fn.(pacIdent); ok {
+			return nil, fmt.Errorf("%s is not a function", string(id))
+		}
+	}
+	return r.call(fn, args)
+}
+
+// pacMethod calls the string or array method name on recv.
+func pacMethod(recv any, name string, args []any) (any, error) {
+	arg := func(i int) string { return pacToString(pacArg(args, i)) }
+	switch s := recv.(type) {
+	case string:
+		switch name {
+		case "toLowerCase":
+			return strings.ToLower(s), nil
+		case "toUpperCase":
+			return strings.ToUpper(s), nil
+		case "indexOf":
+			return float64(strings.Index(s, arg(0))), nil
+		case "lastIndexOf":
+			return float64(strings.LastIndex(s, arg(0))), nil
+		case "substring":
+			start, end := pacIndexArg(args, 0, 0, len(s)), pacIndexArg(args, 1, len(s), len(s))
+			if start > end {
+				start, end = end, start
+			}
+			return s[start:end], nil
+		case "substr":
+			start := pacSliceArg(args, 0, 0, len(s))
+			end := min(start+pacIndexArg(args, 1, len(s), len(s)), len(s))
+			return s[start:end], nil
+		case "split":
+			var out []any
+			for _, part := range strings.Split(s, arg(0)) {
+				out = append(out, part)
+			}
+			return out, nil
+		}
+	case []any:
+		switch name {
+		case "indexOf":
+			for i, v := range s {
+				if pacStrictEqual(v, pacArg(args, 0)) {
+					return float64(i), nil
+				}
+			}
+			return float64(-1), nil
+		}
+	case nil:
+		return nil, fmt.Errorf("cannot call %s of undefined", name)
+	}
+	return nil, fmt.Errorf("%s has no method %s", pacTypeof(recv), name)
+}
+
+func pacArg(args []any, i int) any {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+// pacIndexArg returns args[i] as an index clamped to [0, n], or def when
+// it is missing.
+func pacIndexArg(args []any, i, def, n int) int {
+	if i >= len(args) || args[i] == nil {
+		return def
+	}
+	f := pacToNumber(args[i])
+	switch {
+	case math.IsNaN(f) || f < 0:
+		return 0
+	case f > float64(n):
+		return n
+	}
+	return int(f)
+}
+
+// pacSliceArg is pacIndexArg where negative indexes count from the end.
+func pacSliceArg(args []any, i, def, n int) int {
+	if i < len(args) && pacToNumber(args[i]) < 0 {
+		return max(n+int(pacToNumber(args[i])), 0)
+	}
+	return pacIndexArg(args, i, def, n)
+}
+
+func pacTruthy(v any) bool {
+	if f, ok := v.(float64); ok && math.IsNaN(f) {
+		return false
+	}
+	return truthy(v)
+}
+
+func pacTypeof(v any) string {
+	switch v.(type) {
+	case nil:
+		return "undefined"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case *pacFunc, pacBuiltin:
+		return "function"
+	}
+	return "object"
+}
+
+func pacStringish(v any) bool {
+	switch v.(type) {
+	case string, []any:
+		return true
+	}
+	return false
+}
+
+func pacToString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "undefined"
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "NaN"
+		case math.IsInf(x, 1):
+			return "Infinity"
+		case math.IsInf(x, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []any:
+		var b strings.Builder
+		pacArrayString(&b, x, 0)
+		return b.String()
+	}
+	return "function"
+}
+
+// pacArrayString writes an array as its elements joined by commas. Arrays
+// can contain themselves many times over, so it stops writing at
+// pacMaxString bytes, and at pacMaxDepth levels of nesting.
+func pacArrayString(b *strings.Builder, a []any, depth int) {
+	for i, e := range a {
+		if b.Len() > pacMaxString || depth >= pacMaxDepth {
+			return
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		switch e := e.(type) {
+		case nil:
+		case []any:
+			pacArrayString(b, e, depth+1)
+		default:
+			b.WriteString(pacToString(e))
+		}
+	}
+}
+
+func pacToNumber(v any) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case string:
+		s := strings.TrimSpace(x)
+		if s == "" {
+			return 0
+		}
+		if f, err := parsePACNumber(s); err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
+
+func pacStrictEqual(a, b any) bool {
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case []any:
+		// Arrays are equal only to themselves.
+		y, ok := b.([]any)
+		return ok && len(x) > 0 && len(y) > 0 && &x[0] == &y[0]
+	case *pacFunc:
+		y, ok := b.(*pacFunc)
+		return ok && x == y
+	}
+	return false
+}
+
+func pacLooseEqual(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if pacStrictEqual(a, b) {
+		return true
+	}
+	_, aArr := a.([]any)
+	_, bArr := b.([]any)
+	if aArr || bArr {
+		// An array equals a primitive with the same string form.
+		return aArr != bArr && pacToString(a) == pacToString(b)
+	}
+	_, aStr := a.(string)
+	_, bStr := b.(string)
+	if aStr && bStr || pacCallable(a) || pacCallable(b) {
+		return false
+	}
+	return pacToNumber(a) == pacToNumber(b)
+}
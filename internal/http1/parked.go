@@ -0,0 +1,141 @@
+package http1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	parkingTimeout = 4 * time.Second
+	// parkingBodyLimit bounds how much of the landing page is fingerprinted;
+	// parking landers put their markers near the top.
+	parkingBodyLimit = 64 << 10
+)
+
+// parkingNameservers are DNS hosts of parking services; a domain delegated
+// to them is parked by definition.
+var parkingNameservers = []string{
+	"sedoparking.com",
+	"parkingcrew.net",
+	"bodis.com",
+	"above.com",
+	"parklogic.com",
+	"dan.com",
+	"afternic.com",
+	"hugedomains.com",
+	"uniregistrymarket.link",
+}
+
+// parkingPageMarkers are lower-case fragments of parking and for-sale
+// landing pages.
+var parkingPageMarkers = []string{
+	"sedoparking",
+	"parkingcrew",
+	"bodis.com",
+	"this domain may be for sale",
+	"this domain is for sale",
+	"buy this domain",
+	"domain is parked",
+	"parked free, courtesy of",
+	"hugedomains.com",
+	"afternic.com",
+	"dan.com/buy-domain",
+}
+
+// ParkingResult reports signs that a target is a parked domain rather than
+// a real site, so bulk scans of domain portfolios can set those aside.
+type ParkingResult struct {
+	// LikelyParked is set when a parking fingerprint matched.
+	LikelyParked bool `json:"likely_parked"`
+	// WildcardDNS is set when a random subdomain of the target resolves.
+	// Parking servers usually answer for every name, but so do plenty of
+	// real sites, so on its own this does not mark a domain as parked.
+	WildcardDNS bool   `json:"wildcard_dns"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// probeParking checks host for wildcard DNS and parking nameservers, and
+// fetches rawURL with GET (following redirects) to look for parking page
+// markers.
+func probeParking(ctx context.Context, client *http.Client, opts Options, host, rawURL string) *ParkingResult {
+	res := &ParkingResult{}
+	if net.ParseIP(host) != nil {
+		res.Detail = "not applicable to IP address targets"
+		return res
+	}
+
+	label := fmt.Sprintf("http1-wildcard-%08x", rand.Uint32())
+	if addrs, err := netResolver(ctx).LookupHost(ctx, label+"."+host); err == nil && len(addrs) > 0 {
+		res.WildcardDNS = true
+	}
+
+	if ns := parkingNameserver(ctx, host); ns != "" {
+		res.LikelyParked = true
+		res.Fingerprint = "nameserver " + ns
+	} else if marker := parkingPageMarker(ctx, client, opts, rawURL); marker != "" {
+		res.LikelyParked = true
+		res.Fingerprint = "page contains " + fmt.Sprintf("%q", marker)
+	}
+
+	switch {
+	case res.LikelyParked:
+		res.Detail = "likely parked domain (" + res.Fingerprint + ")"
+	case res.WildcardDNS:
+		res.Detail = "wildcard DNS, but no parking fingerprint"
+	default:
+		res.Detail = "no signs of parking"
+	}
+	return res
+}
+
+// parkingNameserver returns the parking service host delegated for host or
+// its nearest parent zone, or "".
+func parkingNameserver(ctx context.Context, host string) string {
+	name := strings.TrimSuffix(host, ".")
+	for strings.Count(name, ".") >= 1 {
+		nss, err := netResolver(ctx).LookupNS(ctx, name)
+		if err == nil && len(nss) > 0 {
+			for _, ns := range nss {
+				nsHost := strings.ToLower(strings.TrimSuffix(ns.Host, "."))
+				for _, p := range parkingNameservers {
+					if nsHost == p || strings.HasSuffix(nsHost, "."+p) {
+						return p
+					}
+				}
+			}
+			return ""
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+	return ""
+}
+
+// parkingPageMarker fetches the start of the page at rawURL and returns the
+// first parking marker it contains, or "".
+func parkingPageMarker(ctx context.Context, client *http.Client, opts Options, rawURL string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return ""
+	}
+	opts.prepareRequest(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, parkingBodyLimit))
+	page := strings.ToLower(string(body))
+	for _, m := range parkingPageMarkers {
+		if strings.Contains(page, m) {
+			return m
+		}
+	}
+	return ""
+}
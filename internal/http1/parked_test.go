@@ -0,0 +1,38 @@
+package http1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParkingPageMarker(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parked", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><h1>This Domain May Be For Sale</h1></html>`))
+	})
+	mux.Handle("/lander", http.RedirectHandler("/parked", http.StatusFound))
+	mux.HandleFunc("/site", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><h1>Welcome to our shop</h1></html>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/parked", "this domain may be for sale"},
+		{"/lander", "this domain may be for sale"},
+		{"/site", ""},
+	}
+	for _, tt := range tests {
+		if got := parkingPageMarker(context.Background(), srv.Client(), Options{}, srv.URL+tt.path); got != tt.want {
+			t.Errorf("%s: marker %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if res := probeParking(context.Background(), srv.Client(), Options{}, "127.0.0.1", srv.URL+"/parked"); res.LikelyParked {
+		t.Errorf("IP target flagged as parked: %+v", res)
+	}
+}
@@ -0,0 +1,112 @@
+package http1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlainSummary describes a result in one plain sentence for screen readers
+// and pagers, without emoji or tabular layout, e.g. "example.com on port
+// 443 supports HTTP/1.1, HTTP/2 and HTTP/3 and does not support HTTP/1.0;
+// grade A, score 95."
+func PlainSummary(res CheckResult) string {
+	subject := res.Target
+	if res.FinalTarget != "" {
+		subject = fmt.Sprintf("%s, which redirects to %s,", res.Target, res.FinalTarget)
+	}
+	if res.Port != "" {
+		subject += " on port " + res.Port
+	}
+	if res.Grade == "" {
+		detail := "no result"
+		if len(res.Results) > 0 {
+			detail = res.Results[0].Detail
+		}
+		return fmt.Sprintf("%s could not be checked: %s.", subject, detail)
+	}
+
+	var supported, unsupported, failed, untested []string
+	var notes []string
+	for _, vr := range res.Results {
+		name := plainVersionName(vr.Version)
+		switch {
+		case vr.Supported:
+			supported = append(supported, name)
+		case vr.Error:
+			failed = append(failed, name)
+		case vr.NotTested:
+			untested = append(untested, name)
+		default:
+			unsupported = append(unsupported, name)
+		}
+		if vr.Unreliable {
+			notes = append(notes, fmt.Sprintf("the %s result may be unreliable from this network", name))
+		}
+	}
+
+	if ds := res.DualStack; ds != nil && !ds.Consistent {
+		notes = append(notes, "IPv4 and IPv6 differ: "+strings.Join(ds.Discrepancies, ", "))
+	}
+	if cc := res.CrossCheck; cc != nil && !cc.Consistent && cc.Error == "" {
+		notes = append(notes, "the results from "+cc.Endpoint+" differ: "+strings.Join(cc.Discrepancies, ", "))
+	}
+	if a := res.AltSvc; a != nil && a.Advertised && !a.Verified {
+		notes = append(notes, "it advertises HTTP/3 with Alt-Svc, but nothing answers there")
+	}
+	if res.CertChange != nil {
+		notes = append(notes, "its "+res.CertChange.Detail)
+	}
+	if c := challengeProvider(res); c != "" {
+		notes = append(notes, "its content is gated by a "+c+" bot challenge")
+	}
+	if res.Parking != nil && res.Parking.LikelyParked {
+		notes = append(notes, "it is likely a parked domain")
+	}
+	for _, n := range res.Notes {
+		notes = append(notes, "noted: "+n.Text)
+	}
+
+	var clauses []string
+	if len(supported) > 0 {
+		clauses = append(clauses, "supports "+joinPlain(supported))
+	}
+	if len(unsupported) > 0 {
+		clauses = append(clauses, "does not support "+joinPlain(unsupported))
+	}
+	if len(failed) > 0 {
+		clauses = append(clauses, "could not be probed for "+joinPlain(failed))
+	}
+	if len(untested) > 0 {
+		clauses = append(clauses, "was not tested for "+joinPlain(untested))
+	}
+	if len(clauses) == 0 {
+		clauses = append(clauses, "returned no protocol results")
+	}
+	s := fmt.Sprintf("%s %s; grade %s, score %d", subject, joinPlain(clauses), res.Grade, res.Score)
+	if len(notes) > 0 {
+		s += "; " + strings.Join(notes, "; ")
+	}
+	return s + "."
+}
+
+// plainVersionName reads "HTTP/2.0" as the more familiar "HTTP/2".
+func plainVersionName(v string) string {
+	switch v {
+	case "HTTP/2.0":
+		return "HTTP/2"
+	case "HTTP/3.0":
+		return "HTTP/3"
+	}
+	return v
+}
+
+// joinPlain joins items as "a", "a and b" or "a, b and c".
+func joinPlain(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
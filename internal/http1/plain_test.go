@@ -0,0 +1,51 @@
+package http1
+
+import "testing"
+
+func TestPlainSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		res  CheckResult
+		want string
+	}{
+		{
+			"graded",
+			CheckResult{Target: "example.com", Port: "443", Grade: "A", Score: 95, Results: []VersionResult{
+				{Version: "HTTP/1.0"},
+				{Version: "HTTP/1.1", Supported: true},
+				{Version: "HTTP/2.0", Supported: true},
+				{Version: "HTTP/3.0", Supported: true},
+			}},
+			"example.com on port 443 supports HTTP/1.1, HTTP/2 and HTTP/3 and does not support HTTP/1.0; grade A, score 95.",
+		},
+		{
+			"failed probes and unreliable h3",
+			CheckResult{Target: "a.com", Port: "443", Grade: "C", Score: 80, Results: []VersionResult{
+				{Version: "HTTP/1.0", Error: true},
+				{Version: "HTTP/2.0", Supported: true},
+				{Version: "HTTP/3.0", Unreliable: true},
+			}},
+			"a.com on port 443 supports HTTP/2, does not support HTTP/3 and could not be probed for HTTP/1.0; grade C, score 80; the HTTP/3 result may be unreliable from this network.",
+		},
+		{
+			"h3 not tested",
+			CheckResult{Target: "b.com", Port: "443", Grade: "B", Score: 90, Results: []VersionResult{
+				{Version: "HTTP/2.0", Supported: true},
+				{Version: "HTTP/3.0", NotTested: true, Detail: h3DisabledDetail},
+			}},
+			"b.com on port 443 supports HTTP/2 and was not tested for HTTP/3; grade B, score 90.",
+		},
+		{
+			"invalid target",
+			CheckResult{Target: "::bad", Results: []VersionResult{{Version: "error", Error: true, Detail: "invalid URL: bad port"}}},
+			"::bad could not be checked: invalid URL: bad port.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainSummary(tt.res); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
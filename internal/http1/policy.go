@@ -0,0 +1,136 @@
+package http1
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// gradeOrder lists the grades from best to worst.
+var gradeOrder = []string{"A", "B", "C", "F"}
+
+// versionAliases maps the short names accepted by ParseVersions to result
+// versions.
+var versionAliases = map[string]string{
+	"h1.0": "HTTP/1.0", "1.0": "HTTP/1.0", "http/1.0": "HTTP/1.0",
+	"h1": "HTTP/1.1", "h1.1": "HTTP/1.1", "1.1": "HTTP/1.1", "http/1.1": "HTTP/1.1",
+	"h2": "HTTP/2.0", "2": "HTTP/2.0", "http/2": "HTTP/2.0", "http/2.0": "HTTP/2.0",
+	"h3": "HTTP/3.0", "3": "HTTP/3.0", "http/3": "HTTP/3.0", "http/3.0": "HTTP/3.0",
+}
+
+// Policy is a bar every target must clear, which lets a scan act as a CI
+// gate, e.g. "no HTTP/1.x-only services in production".
+type Policy struct {
+	// MinGrade is the lowest acceptable grade; empty accepts any.
+	MinGrade string
+	// Require lists versions every target must support, e.g. "HTTP/2.0".
+	Require []string
+	// Waivers exempt targets from rules until they expire.
+	Waivers Waivers
+}
+
+// PolicyFinding is one way a result misses a Policy.
+type PolicyFinding struct {
+	// Rule is the rule missed, as a Waiver names it, e.g. "require_h3".
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Waiver is the waiver of the finding, if any, and Waived whether it
+	// still applies rather than having expired.
+	Waiver *Waiver `json:"waiver,omitempty"`
+	Waived bool    `json:"waived,omitempty"`
+}
+
+// String describes the finding, noting the waiver that has expired, e.g.
+// "grade F is below B (waiver approved by alice expired 2025-07-01)".
+func (f PolicyFinding) String() string {
+	if f.Waiver != nil && !f.Waived {
+		return fmt.Sprintf("%s (waiver approved by %s expired %s)", f.Message, f.Waiver.Approver, f.Waiver.Expires.Format(time.DateOnly))
+	}
+	return f.Message
+}
+
+// ParsePolicy builds a Policy from a minimum grade ("B") and a
+// comma-separated list of required versions ("h2,h3"). Either may be empty.
+func ParsePolicy(minGrade, require string) (Policy, error) {
+	var p Policy
+	if minGrade != "" {
+		p.MinGrade = strings.ToUpper(strings.TrimSpace(minGrade))
+		if !slices.Contains(gradeOrder, p.MinGrade) {
+			return Policy{}, fmt.Errorf("unknown grade %q (want %s)", minGrade, strings.Join(gradeOrder, ", "))
+		}
+	}
+	versions, err := ParseVersions(require)
+	if err != nil {
+		return Policy{}, err
+	}
+	p.Require = versions
+	return p, nil
+}
+
+// ParseVersions turns a comma-separated list of protocol versions, as short
+// names ("h2", "3", "h1.1") or result names ("HTTP/2.0"), into result
+// version names, dropping duplicates.
+func ParseVersions(list string) ([]string, error) {
+	var versions []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		version, ok := versionAliases[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown protocol %q (want h1.0, h1.1, h2 or h3)", name)
+		}
+		if !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+// Violations returns the ways res misses p, or nil when it clears the bar.
+// A result without a grade, e.g. an invalid target, misses any MinGrade.
+// Findings waived at the current time are left out.
+func (p Policy) Violations(res CheckResult) []string {
+	var out []string
+	for _, f := range p.Findings(res, time.Now()) {
+		if !f.Waived {
+			out = append(out, f.String())
+		}
+	}
+	return out
+}
+
+// Findings returns the ways res misses p, each with the waiver of it, if
+// any, as of time now.
+func (p Policy) Findings(res CheckResult, now time.Time) []PolicyFinding {
+	var out []PolicyFinding
+	add := func(rule, msg string) {
+		f := PolicyFinding{Rule: rule, Message: msg}
+		f.Waiver, f.Waived = p.Waivers.Find(res.Target, rule, now)
+		out = append(out, f)
+	}
+	if p.MinGrade != "" {
+		got := slices.Index(gradeOrder, res.Grade)
+		if got < 0 || got > slices.Index(gradeOrder, p.MinGrade) {
+			grade := res.Grade
+			if grade == "" {
+				grade = "none"
+			}
+			add(RuleMinGrade, fmt.Sprintf("grade %s is below %s", grade, p.MinGrade))
+		}
+	}
+	for _, version := range p.Require {
+		supported := false
+		for _, r := range res.Results {
+			if r.Version == version && r.Supported {
+				supported = true
+			}
+		}
+		if !supported {
+			add(requireRules[version], version+" is not supported")
+		}
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package http1
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("b", "h2, HTTP/3,h2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MinGrade != "B" || !slices.Equal(p.Require, []string{"HTTP/2.0", "HTTP/3.0"}) {
+		t.Errorf("ParsePolicy = %+v", p)
+	}
+	if p, _ := ParsePolicy("", ""); p.MinGrade != "" || p.Require != nil {
+		t.Errorf("empty policy = %+v, want zero", p)
+	}
+	for _, bad := range [][2]string{{"E", ""}, {"", "h4"}} {
+		if _, err := ParsePolicy(bad[0], bad[1]); err == nil {
+			t.Errorf("ParsePolicy(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	res := func(grade string, versions ...string) CheckResult {
+		r := CheckResult{Grade: grade}
+		for _, v := range versions {
+			r.Results = append(r.Results, VersionResult{Version: v, Supported: true})
+		}
+		return r
+	}
+	p := Policy{MinGrade: "B", Require: []string{"HTTP/2.0"}}
+	tests := []struct {
+		name string
+		res  CheckResult
+		want []string
+	}{
+		{"passes", res("A", "HTTP/2.0", "HTTP/3.0"), nil},
+		{"at the bar", res("B", "HTTP/2.0"), nil},
+		{"below", res("C", "HTTP/2.0"), []string{"grade C is below B"}},
+		{"h1 only", res("F", "HTTP/1.1"), []string{"grade F is below B", "HTTP/2.0 is not supported"}},
+		{"no grade", res(""), []string{"grade none is below B", "HTTP/2.0 is not supported"}},
+	}
+	for _, tt := range tests {
+		if got := p.Violations(tt.res); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Violations = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	got, err := ParseVersions("2,h3, HTTP/2.0")
+	if err != nil || !slices.Equal(got, []string{"HTTP/2.0", "HTTP/3.0"}) {
+		t.Errorf("ParseVersions = %q, %v", got, err)
+	}
+	if _, err := ParseVersions("2,h4"); err == nil {
+		t.Error("ParseVersions accepted h4")
+	}
+}
+
+func TestPolicyWaivers(t *testing.T) {
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	ws, err := ReadWaivers(strings.NewReader(`[
+		{"target": "legacy.example", "rule": "require_h2", "expires": "2025-06", "approver": "alice", "reason": "appliance replaced in Q3"},
+		{"target": "old.example", "rule": "min_grade", "expires": "2025-05-31", "approver": "bob"},
+		{"target": "old.example", "rule": "h3_unreachable", "expires": "2026-01", "approver": "bob"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Policy{MinGrade: "B", Require: []string{"HTTP/2.0"}, Waivers: ws}
+	h1 := CheckResult{Grade: "F", Results: []VersionResult{{Version: "HTTP/1.1", Supported: true}}}
+
+	legacy := h1
+	legacy.Target = "https://legacy.example/"
+	got := p.Findings(legacy, now)
+	if len(got) != 2 || got[0].Waived || got[0].Rule != RuleMinGrade || !got[1].Waived || got[1].Waiver.Approver != "alice" {
+		t.Errorf("legacy findings = %+v, want min_grade open and require_h2 waived", got)
+	}
+	if got := p.Findings(legacy, now.AddDate(0, 1, 0)); got[1].Waived || got[1].Waiver == nil {
+		t.Errorf("findings after expiry = %+v, want require_h2 open with its expired waiver", got)
+	}
+
+	old := h1
+	old.Target = "old.example"
+	got = p.Findings(old, now)
+	want := "grade F is below B (waiver approved by bob expired 2025-06-01)"
+	if got[0].Waived || got[0].String() != want {
+		t.Errorf("old min_grade = %q, want %q", got[0].String(), want)
+	}
+	if w, ok := ws.Find("old.example", AnomalyH3Unreachable, now); !ok || w.Approver != "bob" {
+		t.Errorf("Find(h3_unreachable) = %+v, %v", w, ok)
+	}
+	if expired := ws.Expired(now); len(expired) != 1 || expired[0].Rule != RuleMinGrade {
+		t.Errorf("Expired = %+v, want the old min_grade waiver", expired)
+	}
+
+	for _, bad := range []string{
+		`[{"target": "a.example", "rule": "grade", "expires": "2025-06", "approver": "alice"}]`,
+		`[{"target": "a.example", "rule": "min_grade", "expires": "2025-06"}]`,
+		`[{"target": "a.example", "rule": "min_grade", "approver": "alice"}]`,
+		`[{"target": "a.example", "rule": "min_grade", "expires": "soon", "approver": "alice"}]`,
+	} {
+		if _, err := ReadWaivers(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadWaivers accepted %s", bad)
+		}
+	}
+}
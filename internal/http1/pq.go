@@ -0,0 +1,49 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+const pqTimeout = 3 * time.Second
+
+// Key exchange labels reported in CheckResult.KeyExchange.
+const (
+	keyExchangePQ        = "X25519MLKEM768"
+	keyExchangeClassical = "classical"
+)
+
+// probePQKeyExchange reports whether the server completes a TLS 1.3 handshake
+// when the only key share offered is the hybrid X25519MLKEM768 group. Servers
+// without post-quantum support cannot pick any group we offered, so the
+// handshake fails rather than silently falling back.
+func probePQKeyExchange(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), host, serverName, port string) bool {
+	conn, err := dialTLS(ctx, dial, net.JoinHostPort(host, port), &tls.Config{
+		ServerName:         serverName,
+		MinVersion:         tls.VersionTLS13,
+		CurvePreferences:   []tls.CurveID{tls.X25519MLKEM768},
+		NextProtos:         []string{"h2", "http/1.1"},
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// dialTLS connects with dial and completes a TLS handshake bounded by ctx.
+func dialTLS(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), addr string, cfg *tls.Config) (*tls.Conn, error) {
+	raw, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := tls.Client(raw, cfg)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
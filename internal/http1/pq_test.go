@@ -0,0 +1,77 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestProbePQKeyExchange(t *testing.T) {
+	tests := []struct {
+		name   string
+		curves []tls.CurveID
+		want   string
+	}{
+		{"hybrid", []tls.CurveID{tls.X25519MLKEM768, tls.X25519}, keyExchangePQ},
+		{"classical", []tls.CurveID{tls.X25519, tls.CurveP256}, keyExchangeClassical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The probe's handshake can only succeed on the hybrid group
+			// when that is the only one it offers.
+			var mu sync.Mutex
+			var offered [][]tls.CurveID
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			ts.TLS = &tls.Config{
+				MinVersion:       tls.VersionTLS13,
+				CurvePreferences: tt.curves,
+				GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					offered = append(offered, hello.SupportedCurves)
+					return nil, nil
+				},
+			}
+			ts.EnableHTTP2 = true
+			ts.StartTLS()
+			defer ts.Close()
+
+			u, _ := url.Parse(ts.URL)
+			host, port, _ := net.SplitHostPort(u.Host)
+			if got, want := probePQKeyExchange(context.Background(), (&net.Dialer{}).DialContext, host, "example.com", port), tt.want == keyExchangePQ; got != want {
+				t.Errorf("probePQKeyExchange = %v, want %v", got, want)
+			}
+			mu.Lock()
+			if len(offered) != 1 || !slices.Equal(offered[0], []tls.CurveID{tls.X25519MLKEM768}) {
+				t.Errorf("probe offered groups %v, want only X25519MLKEM768", offered)
+			}
+			mu.Unlock()
+
+			res := runChecks(ts.URL, Options{Versions: []string{"HTTP/1.1", "HTTP/2.0"}, CheckKeyExchange: true})
+			if res.KeyExchange != tt.want {
+				t.Errorf("KeyExchange = %q, want %q", res.KeyExchange, tt.want)
+			}
+
+			// Without the option no extra handshake is made.
+			mu.Lock()
+			offered = nil
+			mu.Unlock()
+			if res := runChecks(ts.URL, Options{Versions: []string{"HTTP/1.1", "HTTP/2.0"}}); res.KeyExchange != "" {
+				t.Errorf("KeyExchange = %q without CheckKeyExchange", res.KeyExchange)
+			}
+			mu.Lock()
+			for _, groups := range offered {
+				if slices.Equal(groups, []tls.CurveID{tls.X25519MLKEM768}) {
+					t.Errorf("post-quantum probe ran without CheckKeyExchange")
+				}
+			}
+			mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package http1
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// h3ProxyNote is appended to the HTTP/3 detail of proxied targets: an HTTP
+// proxy only carries TCP, so QUIC always goes direct.
+const h3ProxyNote = " (HTTP/3 cannot go through the proxy; probed directly)"
+
+// proxyFor returns the proxy Options.Proxy selects for rawURL, or nil.
+func (o Options) proxyFor(rawURL string) *url.URL {
+	if o.Proxy == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	pu, err := o.Proxy(&http.Request{URL: u, Header: http.Header{}})
+	if err != nil {
+		return nil
+	}
+	return pu
+}
+
+// proxiedDial wraps dial for the probes that speak TLS on raw connections
+// (HTTP/2 SETTINGS, resumption, post-quantum, ECH): when proxy selects a
+// proxy for https://addr, the connection is an HTTP CONNECT or SOCKS5
+// tunnel through it.
+func proxiedDial(proxy func(*http.Request) (*url.URL, error), dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		req := &http.Request{URL: &url.URL{Scheme: "https", Host: addr}, Header: http.Header{}}
+		pu, err := proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case pu == nil:
+			return dial(ctx, network, addr)
+		case pu.Scheme == "socks5" || pu.Scheme == "socks5h":
+			return socksTunnel(ctx, dial, pu, addr)
+		}
+		return connectTunnel(ctx, dial, pu, addr)
+	}
+}
+
+// connectTunnel opens a CONNECT tunnel to addr through the proxy at pu.
+func connectTunnel(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), pu *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := pu.Host
+	if pu.Port() == "" {
+		port := "80"
+		if pu.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(pu.Hostname(), port)
+	}
+	conn, err := dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if pu.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: pu.Hostname()})
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if u := pu.User; u != nil {
+		pass, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+pass)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %v", err)
+	}
+	// The server stays silent until we start TLS, so nothing past the
+	// response can be buffered here.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %v", err)
+	}
+	// The response body is never read: after a 200 the stream belongs to
+	// the tunnel, and on error the connection is dropped.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %s", resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// socksTunnel connects to addr through the SOCKS5 proxy at pu. The proxy
+// resolves host names itself, as http.Transport has it do for socks5 URLs.
+func socksTunnel(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), pu *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := pu.Host
+	if pu.Port() == "" {
+		proxyAddr = net.JoinHostPort(pu.Hostname(), "1080")
+	}
+	var auth *proxy.Auth
+	if u := pu.User; u != nil {
+		pass, _ := u.Password()
+		auth = &proxy.Auth{User: u.Username(), Password: pass}
+	}
+	d, err := proxy.SOCKS5("tcp", proxyAddr, auth, dialFunc(dial))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("SOCKS5 proxy: %v", err)
+	}
+	return conn, nil
+}
+
+// dialFunc adapts a dial function to proxy.Dialer and proxy.ContextDialer.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
@@ -0,0 +1,152 @@
+package http1
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// connectProxy is a minimal HTTP CONNECT proxy counting the tunnels it opens.
+func connectProxy(t *testing.T, tunnels *atomic.Int32) *url.URL {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		client, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		tunnels.Add(1)
+		go func() {
+			_, _ = io.Copy(upstream, client)
+			upstream.Close()
+		}()
+		_, _ = io.Copy(client, upstream)
+		client.Close()
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u
+}
+
+func TestProxiedChecks(t *testing.T) {
+	port := startLocalServers(t)
+	var tunnels atomic.Int32
+	proxy := connectProxy(t, &tunnels)
+
+	res := runChecks("https://127.0.0.1:"+port, Options{Port: port, Proxy: http.ProxyURL(proxy), CheckKeyExchange: true, CheckH2Settings: true})
+	if !res.Proxied {
+		t.Error("result not marked as proxied")
+	}
+	if !res.Results[2].Supported {
+		t.Errorf("HTTP/2 through proxy: %+v", res.Results[2])
+	}
+	if res.H2Settings == nil || res.KeyExchange != keyExchangePQ {
+		t.Errorf("raw TLS probes did not tunnel: settings %v, key exchange %q", res.H2Settings, res.KeyExchange)
+	}
+	if n := tunnels.Load(); n < 3 {
+		t.Errorf("proxy saw %d tunnels, want the h1, h2 and raw TLS probes", n)
+	}
+	h3 := res.Results[3]
+	switch {
+	case !h3Available:
+		if !h3.NotTested {
+			t.Errorf("HTTP/3 = %+v, want not tested in a noh3 build", h3)
+		}
+	case !h3.Supported || h3.Detail != "supported"+h3ProxyNote:
+		t.Errorf("HTTP/3 = %+v, want direct support with the proxy note", h3)
+	}
+}
+
+// socksProxy is a minimal SOCKS5 proxy (no authentication, CONNECT only)
+// counting the tunnels it opens.
+func socksProxy(t *testing.T, tunnels *atomic.Int32) *url.URL {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			client, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer client.Close()
+				buf := make([]byte, 262)
+				// Greeting: version, methods; reply "no authentication".
+				if _, err := io.ReadFull(client, buf[:2]); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(client, buf[:buf[1]]); err != nil {
+					return
+				}
+				_, _ = client.Write([]byte{5, 0})
+				// Request: version, CONNECT, reserved, address type.
+				if _, err := io.ReadFull(client, buf[:4]); err != nil {
+					return
+				}
+				var host string
+				switch buf[3] {
+				case 1:
+					_, _ = io.ReadFull(client, buf[:4])
+					host = net.IP(buf[:4]).String()
+				case 3:
+					_, _ = io.ReadFull(client, buf[:1])
+					n := int(buf[0])
+					_, _ = io.ReadFull(client, buf[:n])
+					host = string(buf[:n])
+				default:
+					return
+				}
+				_, _ = io.ReadFull(client, buf[:2])
+				addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+				upstream, err := net.Dial("tcp", addr)
+				if err != nil {
+					_, _ = client.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer upstream.Close()
+				_, _ = client.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				tunnels.Add(1)
+				go func() { _, _ = io.Copy(upstream, client) }()
+				_, _ = io.Copy(client, upstream)
+			}()
+		}
+	}()
+	return &url.URL{Scheme: "socks5", Host: ln.Addr().String()}
+}
+
+func TestSOCKSProxiedChecks(t *testing.T) {
+	port := startLocalServers(t)
+	var tunnels atomic.Int32
+	proxy := socksProxy(t, &tunnels)
+
+	res := runChecks("https://127.0.0.1:"+port, Options{Port: port, Proxy: http.ProxyURL(proxy), CheckKeyExchange: true, CheckH2Settings: true})
+	if !res.Results[1].Supported || !res.Results[2].Supported {
+		t.Errorf("HTTP/1.1 and HTTP/2 through SOCKS5: %+v", res.Results[1:3])
+	}
+	if res.H2Settings == nil || res.KeyExchange != keyExchangePQ {
+		t.Errorf("raw TLS probes did not tunnel: settings %v, key exchange %q", res.H2Settings, res.KeyExchange)
+	}
+	if n := tunnels.Load(); n < 3 {
+		t.Errorf("proxy saw %d tunnels, want the h1, h2 and raw TLS probes", n)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !noh3 && !wasm
+
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// probeQUICVersions enumerates the QUIC versions a server accepts, assuming
+// the regular HTTP/3 probe already established QUIC v1. It offers only QUIC
+// v2: a server that speaks it completes the handshake, while one that does
+// not answers with a Version Negotiation packet listing everything it does
+// support (including draft versions quic-go itself can no longer dial).
+func probeQUICVersions(ctx context.Context, qd *quicDialer, host, serverName, port string) []string {
+	tlsConf := &tls.Config{
+		ServerName:         serverName,
+		NextProtos:         []string{http3.NextProtoH3},
+		InsecureSkipVerify: true,
+	}
+	conf := &quic.Config{Versions: []quic.Version{quic.Version2}}
+
+	conn, err := qd.dial(ctx, net.JoinHostPort(host, port), tlsConf, conf)
+	if err == nil {
+		_ = conn.CloseWithError(0, "")
+		return []string{quic.Version1.String(), quic.Version2.String()}
+	}
+
+	var vnErr *quic.VersionNegotiationError
+	if errors.As(err, &vnErr) {
+		versions := make([]string, 0, len(vnErr.Theirs))
+		for _, v := range vnErr.Theirs {
+			if isGreaseQUICVersion(v) {
+				continue
+			}
+			versions = append(versions, v.String())
+		}
+		if len(versions) > 0 {
+			return versions
+		}
+	}
+
+	// Timeouts or other errors tell us nothing beyond what h3 already proved.
+	return []string{quic.Version1.String()}
+}
+
+// isGreaseQUICVersion reports whether v is a reserved version (RFC 9000
+// §15) that servers mix into Version Negotiation to exercise negotiation.
+func isGreaseQUICVersion(v quic.Version) bool {
+	return uint32(v)&0x0f0f0f0f == 0x0a0a0a0a
+}
@@ -0,0 +1,141 @@
+//go:build !noh3 && !wasm
+
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// serveVersionNegotiation answers every QUIC Initial on a local UDP socket
+// with the Version Negotiation packet reply builds from the client's
+// connection IDs, and returns the socket's port.
+func serveVersionNegotiation(t *testing.T, reply func(dcid, scid []byte) []byte) string {
+	t.Helper()
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("no UDP: %v", err)
+	}
+	t.Cleanup(func() { _ = udp.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := udp.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			// A long header: flags, version, then the length-prefixed
+			// destination and source connection IDs (RFC 9000 §17.2).
+			p := buf[:n]
+			if len(p) < 7 || p[0]&0x80 == 0 {
+				continue
+			}
+			dcid := p[6 : 6+int(p[5])]
+			rest := p[6+len(dcid):]
+			scid := rest[1 : 1+int(rest[0])]
+			_, _ = udp.WriteToUDP(reply(dcid, scid), addr)
+		}
+	}()
+	return fmt.Sprint(udp.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// versionNegotiation builds a Version Negotiation packet (RFC 9000 §17.2.1)
+// answering a client with connection IDs dcid and scid.
+func versionNegotiation(dcid, scid []byte, versions ...uint32) []byte {
+	b := []byte{0x80, 0, 0, 0, 0}
+	b = append(b, byte(len(scid)))
+	b = append(b, scid...)
+	b = append(b, byte(len(dcid)))
+	b = append(b, dcid...)
+	for _, v := range versions {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+func TestProbeQUICVersions(t *testing.T) {
+	const draft29 = 0xff00001d
+	v1 := quic.Version1.String()
+
+	tests := []struct {
+		name  string
+		reply func(dcid, scid []byte) []byte
+		want  []string
+	}{
+		{"version negotiation", func(dcid, scid []byte) []byte {
+			return versionNegotiation(dcid, scid, uint32(quic.Version1), 0x1a2a3a4a, draft29)
+		}, []string{v1, quic.Version(draft29).String()}},
+		{"only grease", func(dcid, scid []byte) []byte {
+			return versionNegotiation(dcid, scid, 0x0a0a0a0a)
+		}, []string{v1}},
+		// quic-go drops a Version Negotiation packet that does not echo
+		// its connection IDs, so the probe times out on what h3 proved.
+		{"wrong connection IDs", func(dcid, scid []byte) []byte {
+			return versionNegotiation([]byte{1, 2, 3, 4}, []byte{5, 6, 7, 8}, draft29)
+		}, []string{v1}},
+		{"truncated", func(dcid, scid []byte) []byte {
+			vn := versionNegotiation(dcid, scid, draft29)
+			return vn[:len(vn)-2]
+		}, []string{v1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := serveVersionNegotiation(t, tt.reply)
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			if got := probeQUICVersions(ctx, &quicDialer{}, "127.0.0.1", "example.com", port); !slices.Equal(got, tt.want) {
+				t.Errorf("versions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("v2", func(t *testing.T) {
+		udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Skipf("no UDP: %v", err)
+		}
+		ts := httptest.NewTLSServer(nil)
+		defer ts.Close()
+		ln, err := quic.Listen(udp, &tls.Config{Certificates: ts.TLS.Certificates, NextProtos: []string{http3.NextProtoH3}}, &quic.Config{Versions: []quic.Version{quic.Version1, quic.Version2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			for {
+				if _, err := ln.Accept(context.Background()); err != nil {
+					return
+				}
+			}
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		port := fmt.Sprint(udp.LocalAddr().(*net.UDPAddr).Port)
+		if got, want := probeQUICVersions(ctx, &quicDialer{}, "127.0.0.1", "example.com", port), []string{v1, quic.Version2.String()}; !slices.Equal(got, want) {
+			t.Errorf("versions = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestCheckQUICVersionsOption(t *testing.T) {
+	port := startLocalServers(t)
+	target := "https://127.0.0.1:" + port
+	versions := []string{"HTTP/3.0"}
+	if res := runChecks(target, Options{Port: port, Versions: versions}); res.QUICVersions != nil {
+		t.Errorf("QUICVersions = %v without CheckQUICVersions", res.QUICVersions)
+	}
+	res := runChecks(target, Options{Port: port, Versions: versions, CheckQUICVersions: true})
+	if !slices.Contains(res.QUICVersions, quic.Version1.String()) {
+		t.Errorf("QUICVersions = %v, want v1 listed", res.QUICVersions)
+	}
+}
@@ -0,0 +1,74 @@
+package http1
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by every probe of a run, so
+// Options.Rate holds across all workers. Each probe takes a token before
+// its timeout starts, so time spent waiting for one never turns into a
+// false "not supported". The bucket holds one second's worth of tokens.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	burst := math.Max(1, math.Ceil(perSecond))
+	return &rateLimiter{rate: perSecond, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until a token is available or ctx is done. Tokens are
+// reserved in call order, so waiting probes are served first come, first
+// served. A nil limiter does not limit.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Hand the reservation back for the probes still waiting.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// rateLimiterKey is the context key under which withRateLimiter stores the
+// run's rateLimiter.
+type rateLimiterKey struct{}
+
+func withRateLimiter(ctx context.Context, l *rateLimiter) context.Context {
+	if l == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimiterKey{}, l)
+}
+
+func rateLimiterFrom(ctx context.Context) *rateLimiter {
+	l, _ := ctx.Value(rateLimiterKey{}).(*rateLimiter)
+	return l
+}
@@ -0,0 +1,53 @@
+package http1
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(20)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = l.wait(context.Background())
+		}()
+	}
+	wg.Wait()
+	// The first 20 go at once from the full bucket, the other 10 at 50ms
+	// intervals.
+	if d := time.Since(start); d < 450*time.Millisecond || d > 2*time.Second {
+		t.Errorf("30 probes at 20/s took %v, want about 500ms", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); err == nil {
+		t.Error("wait on an empty bucket returned before its context expired")
+	}
+	if (*rateLimiter)(nil).wait(context.Background()) != nil {
+		t.Error("nil limiter should not limit")
+	}
+}
+
+func TestProbeContextWaitsForRate(t *testing.T) {
+	opts := Options{Rate: 10}.forRun()
+	base := opts.baseContext()
+	rtt := newRTTTracker()
+	for range 12 {
+		ctx, cancel := rtt.probeContext(base, 20*time.Millisecond)
+		// The probe's own timeout starts after the wait.
+		if ctx.Err() != nil {
+			t.Fatal("probe context expired while waiting for the rate limiter")
+		}
+		cancel()
+	}
+	if throttled := time.Duration(rtt.throttled.Load()); throttled < 150*time.Millisecond {
+		t.Errorf("throttled %v, want the 2 probes over the burst to wait about 100ms each", throttled)
+	}
+}
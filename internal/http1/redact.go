@@ -0,0 +1,283 @@
+package http1
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactPrefix marks pseudonymous tokens in redacted output.
+const redactPrefix = "anon-"
+
+// ipLiteral matches IPv4 addresses and bracketed or bare IPv6 addresses as
+// they appear in Go network error messages.
+var ipLiteral = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\[?[0-9a-fA-F]*:[0-9a-fA-F:]*:[0-9a-fA-F.]*\]?`)
+
+// Redactor replaces hostnames and IP addresses in results with keyed
+// pseudonyms, so results can be shared without revealing the inventory they
+// came from. The same key always maps a host to the same token, which keeps
+// redacted scans comparable over time; without the key the tokens cannot be
+// reversed by hashing candidate hostnames.
+type Redactor struct {
+	key []byte
+}
+
+// NewRedactor returns a Redactor using key as the HMAC secret.
+func NewRedactor(key []byte) *Redactor {
+	return &Redactor{key: key}
+}
+
+// Token returns the pseudonym for a hostname or IP address. Hostnames are
+// case-insensitive and a trailing dot is ignored.
+func (r *Redactor) Token(s string) string {
+	s = strings.TrimSuffix(strings.ToLower(s), ".")
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(s))
+	return redactPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Result returns a copy of res with the target, URL and any host names or IP
+// addresses in detail strings replaced by tokens. Grades, scores and
+// protocol data are left untouched.
+func (r *Redactor) Result(res CheckResult) CheckResult {
+	// Redirect chains and SNI or Host overrides can name other hosts; replace longer names first so
+	// www.a.com is not half-replaced by the pattern for a.com.
+	hosts := []string{targetHost(res.Target), targetHost(res.FinalTarget), res.SNI, targetHost(res.HostHeader), res.HostUnicode}
+	for _, hop := range res.RedirectChain {
+		hosts = append(hosts, targetHost(hop.URL), targetHost(hop.Location))
+	}
+	if res.SNIMismatch != nil {
+		hosts = append(hosts, res.SNIMismatch.UnknownSNI.CertSubject, res.SNIMismatch.UnknownHost.CertSubject)
+	}
+	if res.Coalescing != nil {
+		hosts = append(hosts, res.Coalescing.Authority)
+	}
+	if res.H2Origin != nil {
+		for _, o := range res.H2Origin.Origins {
+			hosts = append(hosts, targetHost(o))
+		}
+	}
+	hosts = append(hosts, res.ReverseDNS...)
+	// Aliases name CDN and hosting accounts, which can identify the target.
+	for _, c := range res.CNAMEChain {
+		hosts = append(hosts, strings.TrimSuffix(c, "."))
+	}
+	if res.AltSvc != nil {
+		for _, e := range res.AltSvc.Endpoints {
+			hosts = append(hosts, e.Host)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return len(hosts[i]) > len(hosts[j]) })
+	var hostPatterns []*regexp.Regexp
+	var hostTokens []string
+	for _, host := range hosts {
+		if host == "" || net.ParseIP(host) != nil {
+			continue
+		}
+		hostPatterns = append(hostPatterns, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(host)))
+		hostTokens = append(hostTokens, r.Token(host))
+	}
+	scrub := func(s string) string {
+		for i, p := range hostPatterns {
+			s = p.ReplaceAllLiteralString(s, hostTokens[i])
+		}
+		return ipLiteral.ReplaceAllStringFunc(s, func(m string) string {
+			ip := strings.Trim(m, "[]")
+			if net.ParseIP(ip) == nil {
+				return m
+			}
+			return strings.Replace(m, ip, r.Token(ip), 1)
+		})
+	}
+
+	out := res
+	out.Target = r.Target(res.Target)
+	out.URL = scrub(res.URL)
+	if res.Labels != nil {
+		// Expanded ranges are labelled with their prefix, which would give
+		// away the internal networks scanned; tokens keep the grouping.
+		out.Labels = make([]string, len(res.Labels))
+		for i, l := range res.Labels {
+			if p, err := netip.ParsePrefix(l); err == nil {
+				out.Labels[i] = r.Token(p.String())
+				continue
+			}
+			out.Labels[i] = scrub(l)
+		}
+	}
+
+	out.Results = make([]VersionResult, len(res.Results))
+	for i, vr := range res.Results {
+		vr.Detail = scrub(vr.Detail)
+		vr.Evidence = scrub(vr.Evidence)
+		out.Results[i] = vr
+	}
+	if res.CNAMEChain != nil {
+		out.CNAMEChain = make([]string, len(res.CNAMEChain))
+		for i, c := range res.CNAMEChain {
+			out.CNAMEChain[i] = r.Token(c)
+		}
+	}
+	if res.ReverseDNS != nil {
+		out.ReverseDNS = make([]string, len(res.ReverseDNS))
+		for i, n := range res.ReverseDNS {
+			out.ReverseDNS[i] = scrub(n)
+		}
+	}
+	out.HostUnicode = scrub(res.HostUnicode)
+	out.HostASCII = scrub(res.HostASCII)
+	out.SNI = scrub(res.SNI)
+	// Certificate fingerprints can be looked up in CT logs.
+	if res.CertSHA256 != "" {
+		out.CertSHA256 = r.Token(res.CertSHA256)
+	}
+	if res.CertChange != nil {
+		cc := *res.CertChange
+		cc.PreviousSHA256 = r.Token(cc.PreviousSHA256)
+		out.CertChange = &cc
+	}
+	if res.TLS != nil && res.TLS.Certificate != nil {
+		ti, cs := *res.TLS, *res.TLS.Certificate
+		cs.Subject = scrub(cs.Subject)
+		// Other names on the certificate would point at the target too.
+		cs.DNSNames = make([]string, len(res.TLS.Certificate.DNSNames))
+		for i, n := range res.TLS.Certificate.DNSNames {
+			cs.DNSNames[i] = r.Token(n)
+		}
+		cs.SHA256 = r.Token(cs.SHA256)
+		ti.Certificate = &cs
+		out.TLS = &ti
+	}
+	if res.Warnings != nil {
+		out.Warnings = make([]Warning, len(res.Warnings))
+		for i, w := range res.Warnings {
+			w.Message = scrub(w.Message)
+			out.Warnings[i] = w
+		}
+	}
+	if res.Notes != nil {
+		out.Notes = make([]Note, len(res.Notes))
+		for i, n := range res.Notes {
+			n.Target = r.Target(n.Target)
+			n.Text = scrub(n.Text)
+			out.Notes[i] = n
+		}
+	}
+	out.HostHeader = scrub(res.HostHeader)
+	if res.FinalTarget != "" {
+		out.FinalTarget = scrub(res.FinalTarget)
+	}
+	if res.RedirectChain != nil {
+		out.RedirectChain = make([]RedirectHop, len(res.RedirectChain))
+		for i, hop := range res.RedirectChain {
+			hop.URL = scrub(hop.URL)
+			hop.Location = scrub(hop.Location)
+			out.RedirectChain[i] = hop
+		}
+	}
+	out.RedirectError = scrub(res.RedirectError)
+	if res.ProbeErrors != nil {
+		out.ProbeErrors = make([]ProbeError, len(res.ProbeErrors))
+		for i, pe := range res.ProbeErrors {
+			pe.Detail = scrub(pe.Detail)
+			out.ProbeErrors[i] = pe
+		}
+	}
+	if res.HTTPSRedirect != nil {
+		hr := *res.HTTPSRedirect
+		hr.Location = scrub(hr.Location)
+		out.HTTPSRedirect = &hr
+	}
+	if res.SNIMismatch != nil {
+		sm := *res.SNIMismatch
+		for _, o := range []*MismatchOutcome{&sm.UnknownSNI, &sm.UnknownHost} {
+			o.CertSubject = scrub(o.CertSubject)
+			o.Detail = scrub(o.Detail)
+		}
+		sm.Detail = scrub(sm.Detail)
+		out.SNIMismatch = &sm
+	}
+	if res.DNS != nil {
+		dt := *res.DNS
+		dt.Addresses = make([]string, len(res.DNS.Addresses))
+		for i, a := range res.DNS.Addresses {
+			dt.Addresses[i] = scrub(a)
+		}
+		dt.Error = scrub(dt.Error)
+		out.DNS = &dt
+	}
+	if res.H2Origin != nil {
+		of := *res.H2Origin
+		of.Origins = make([]string, len(res.H2Origin.Origins))
+		for i, o := range res.H2Origin.Origins {
+			of.Origins[i] = scrub(o)
+		}
+		out.H2Origin = &of
+	}
+	if res.Coalescing != nil {
+		co := *res.Coalescing
+		co.Authority = scrub(co.Authority)
+		co.Detail = scrub(co.Detail)
+		out.Coalescing = &co
+	}
+	if res.AltSvc != nil {
+		as := *res.AltSvc
+		as.Header = scrub(as.Header)
+		as.Endpoints = make([]AltSvcEndpoint, len(res.AltSvc.Endpoints))
+		for i, e := range res.AltSvc.Endpoints {
+			e.Host = scrub(e.Host)
+			e.Detail = scrub(e.Detail)
+			as.Endpoints[i] = e
+		}
+		out.AltSvc = &as
+	}
+	if res.ECH != nil {
+		ech := *res.ECH
+		ech.Detail = scrub(ech.Detail)
+		out.ECH = &ech
+	}
+	if res.EarlyData != nil {
+		ed := *res.EarlyData
+		ed.Detail = scrub(ed.Detail)
+		out.EarlyData = &ed
+	}
+	if res.ExtendedConnect != nil {
+		ec := ExtendedConnectSupport{}
+		for _, p := range []struct{ src, dst **ExtendedConnectResult }{
+			{&res.ExtendedConnect.H2, &ec.H2},
+			{&res.ExtendedConnect.H3, &ec.H3},
+		} {
+			if *p.src != nil {
+				c := **p.src
+				c.Detail = scrub(c.Detail)
+				*p.dst = &c
+			}
+		}
+		out.ExtendedConnect = &ec
+	}
+	return out
+}
+
+// Target returns the pseudonym used for a target as given on the command
+// line: the token of its hostname, so "a.com" and "https://a.com/" agree.
+func (r *Redactor) Target(target string) string {
+	if host := targetHost(target); host != "" {
+		return r.Token(host)
+	}
+	return r.Token(target)
+}
+
+// targetHost returns the bare hostname of a target, or "" if it does not
+// parse.
+func targetHost(target string) string {
+	t, err := ParseTarget(target)
+	if err != nil {
+		return ""
+	}
+	return t.Host
+}
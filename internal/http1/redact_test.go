@@ -0,0 +1,93 @@
+package http1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactorResult(t *testing.T) {
+	r := NewRedactor([]byte("k"))
+	res := CheckResult{
+		Target: "https://Example.com/",
+		URL:    "https://example.com:443/",
+		Grade:  "A",
+		Score:  95,
+		Results: []VersionResult{
+			{Version: "HTTP/1.0", Detail: "dial tcp 192.0.2.7:80: connect: connection refused", Error: true},
+			{Version: "HTTP/3.0", Detail: "dial udp [2001:db8::1]:443: example.com timeout"},
+		},
+		ECH: &ECHResult{Detail: "lookup via 10.0.0.53 failed"},
+	}
+	out := r.Result(res)
+
+	if out.Target != r.Token("example.com") || out.Target != r.Target("example.com") {
+		t.Errorf("Target = %q, want token of example.com", out.Target)
+	}
+	if out.Grade != "A" || out.Score != 95 || out.Results[0].Version != "HTTP/1.0" {
+		t.Errorf("grade or version data changed: %+v", out)
+	}
+	for _, s := range []string{out.URL, out.Results[0].Detail, out.Results[1].Detail, out.ECH.Detail} {
+		for _, leak := range []string{"example.com", "192.0.2.7", "2001:db8::1", "10.0.0.53"} {
+			if strings.Contains(s, leak) {
+				t.Errorf("%q still contains %q", s, leak)
+			}
+		}
+	}
+	if res.Results[0].Detail == out.Results[0].Detail || res.ECH.Detail == out.ECH.Detail {
+		t.Error("Result modified its input")
+	}
+	if NewRedactor([]byte("other")).Token("example.com") == r.Token("example.com") {
+		t.Error("tokens do not depend on the key")
+	}
+}
+
+func TestRedactorCNAMEChain(t *testing.T) {
+	r := NewRedactor([]byte("k"))
+	res := CheckResult{
+		Target:     "a.com",
+		CNAMEChain: []string{"a.com.cdn.example.", "edge.secretcdn.net"},
+		Results:    []VersionResult{{Version: "HTTP/3.0", Detail: "dial udp edge.secretcdn.net:443: timeout"}},
+	}
+	out := r.Result(res)
+	if out.CNAMEChain[0] != r.Token("a.com.cdn.example") || out.CNAMEChain[1] != r.Token("edge.secretcdn.net") {
+		t.Errorf("CNAMEChain = %v, want tokens", out.CNAMEChain)
+	}
+	if strings.Contains(out.Results[0].Detail, "secretcdn") {
+		t.Errorf("detail still names the alias: %q", out.Results[0].Detail)
+	}
+	if res.CNAMEChain[1] != "edge.secretcdn.net" {
+		t.Error("Result modified its input")
+	}
+}
+
+func TestRedactorLabels(t *testing.T) {
+	r := NewRedactor([]byte("k"))
+	res := CheckResult{Target: "10.20.30.7", Labels: []string{"10.20.30.0/24", "prod", "via-10.1.1.1"}}
+	out := r.Result(res)
+	if out.Labels[0] != r.Token("10.20.30.0/24") || out.Labels[1] != "prod" || strings.Contains(out.Labels[2], "10.1.1.1") {
+		t.Errorf("Labels = %v, want the prefix and address tokenized", out.Labels)
+	}
+	if res.Labels[0] != "10.20.30.0/24" {
+		t.Error("Result modified its input")
+	}
+}
+
+func TestRedactorRedirectChain(t *testing.T) {
+	r := NewRedactor([]byte("k"))
+	res := CheckResult{
+		Target:        "a.com",
+		SNI:           "staging.a.com",
+		FinalTarget:   "https://www.a.com/home",
+		RedirectChain: []RedirectHop{{URL: "https://a.com", Status: 301, Location: "https://www.a.com/home"}},
+	}
+	out := r.Result(res)
+	if want := "https://" + r.Token("www.a.com") + "/home"; out.FinalTarget != want {
+		t.Errorf("FinalTarget = %q, want %q", out.FinalTarget, want)
+	}
+	if strings.Contains(out.RedirectChain[0].URL, "a.com") || strings.Contains(out.RedirectChain[0].Location, "a.com") {
+		t.Errorf("chain still names a host: %+v", out.RedirectChain[0])
+	}
+	if out.SNI != r.Token("staging.a.com") {
+		t.Errorf("SNI = %q, want token of staging.a.com", out.SNI)
+	}
+}
@@ -0,0 +1,39 @@
+package http1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFollowRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusMovedPermanently))
+	mux.Handle("/b", http.RedirectHandler("/c", http.StatusFound))
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	chain, final, err := followRedirects(srv.URL+"/a", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final != srv.URL+"/c" || len(chain) != 2 {
+		t.Fatalf("final = %q, chain = %+v", final, chain)
+	}
+	if chain[0].Status != http.StatusMovedPermanently || chain[1].URL != srv.URL+"/b" {
+		t.Errorf("chain = %+v", chain)
+	}
+
+	chain, final, err = followRedirects(srv.URL+"/c", Options{})
+	if err != nil || len(chain) != 0 || final != srv.URL+"/c" {
+		t.Errorf("no redirect: chain = %+v, final = %q, err = %v", chain, final, err)
+	}
+
+	_, _, err = followRedirects(srv.URL+"/loop", Options{})
+	if err == nil || !strings.Contains(err.Error(), "loop") {
+		t.Errorf("loop err = %v", err)
+	}
+}
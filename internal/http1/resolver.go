@@ -0,0 +1,205 @@
+package http1
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dohResponseLimit bounds a DoH response body; DNS messages cannot exceed
+// 64 KiB.
+const dohResponseLimit = 64 << 10
+
+// Resolver sends the scan's DNS queries over DNS-over-HTTPS (RFC 8484) or
+// DNS-over-TLS (RFC 7858) instead of the system resolver, for networks that
+// block plain DNS and for users who would rather not leak their target list
+// to it. The resolver's own host name, if not an IP address, is still looked
+// up with the system resolver.
+type Resolver struct {
+	endpoint string
+	// doh is the DoH endpoint URL; dot the DoT server host:port.
+	doh     string
+	dot     string
+	client  *http.Client
+	tlsConf *tls.Config
+	net     *net.Resolver
+}
+
+// NewResolver parses a DoH endpoint ("https://1.1.1.1/dns-query") or a
+// DoT server ("tls://9.9.9.9", "tls://dns.quad9.net:853").
+func NewResolver(endpoint string) (*Resolver, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid resolver %q (want https://host/path or tls://host[:port])", endpoint)
+	}
+	r := &Resolver{endpoint: endpoint}
+	switch u.Scheme {
+	case "https":
+		r.doh = u.String()
+		r.client = &http.Client{Timeout: dnsTimeout}
+	case "tls":
+		r.dot = u.Host
+		if u.Port() == "" {
+			r.dot = net.JoinHostPort(u.Hostname(), "853")
+		}
+		r.tlsConf = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("invalid resolver %q: scheme must be https (DoH) or tls (DoT)", endpoint)
+	}
+	r.net = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return r.dial(ctx)
+		},
+	}
+	return r, nil
+}
+
+// String returns the endpoint r was created from.
+func (r *Resolver) String() string {
+	return r.endpoint
+}
+
+// dial opens a stream connection speaking TCP-framed DNS to the resolver.
+// For DoH each framed query written is sent as its own POST.
+func (r *Resolver) dial(ctx context.Context) (net.Conn, error) {
+	if r.doh != "" {
+		return &exchangeConn{ctx: ctx, exchange: r.exchangeDoH}, nil
+	}
+	d := &tls.Dialer{Config: r.tlsConf}
+	return d.DialContext(ctx, "tcp", r.dot)
+}
+
+// exchange sends one packed DNS query and returns the packed response.
+func (r *Resolver) exchange(ctx context.Context, packed []byte) ([]byte, error) {
+	if r.doh != "" {
+		return r.exchangeDoH(ctx, packed)
+	}
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return dnsStreamExchange(conn, packed)
+}
+
+func (r *Resolver) exchangeDoH(ctx context.Context, packed []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.doh, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH query: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH query: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, dohResponseLimit))
+}
+
+// exchangeConn lets the standard library resolver talk DoH, or through the
+// dnsCache: it collects the TCP-framed queries written to it and answers
+// each with the framed response from exchange. It is not a net.PacketConn,
+// so the resolver always uses TCP framing with it.
+type exchangeConn struct {
+	ctx      context.Context
+	exchange func(ctx context.Context, packed []byte) ([]byte, error)
+	out      bytes.Buffer
+	in       bytes.Buffer
+}
+
+func (c *exchangeConn) Write(b []byte) (int, error) {
+	return c.out.Write(b)
+}
+
+func (c *exchangeConn) Read(b []byte) (int, error) {
+	if c.in.Len() == 0 {
+		if err := c.roundTrip(); err != nil {
+			return 0, err
+		}
+	}
+	return c.in.Read(b)
+}
+
+func (c *exchangeConn) roundTrip() error {
+	framed := c.out.Bytes()
+	if len(framed) < 2 || len(framed) < 2+int(binary.BigEndian.Uint16(framed)) {
+		return io.ErrUnexpectedEOF
+	}
+	n := int(binary.BigEndian.Uint16(framed))
+	resp, err := c.exchange(c.ctx, framed[2:2+n])
+	c.out.Next(2 + n)
+	if err != nil {
+		return err
+	}
+	var lenBuf [2]byte
+	binary.BigEndian.PutUint16(lenBuf[:], uint16(len(resp)))
+	c.in.Write(lenBuf[:])
+	c.in.Write(resp)
+	return nil
+}
+
+func (c *exchangeConn) Close() error                     { return nil }
+func (c *exchangeConn) LocalAddr() net.Addr              { return exchangeAddr{} }
+func (c *exchangeConn) RemoteAddr() net.Addr             { return exchangeAddr{} }
+func (c *exchangeConn) SetDeadline(time.Time) error      { return nil }
+func (c *exchangeConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *exchangeConn) SetWriteDeadline(time.Time) error { return nil }
+
+type exchangeAddr struct{}
+
+func (exchangeAddr) Network() string { return "dns" }
+func (exchangeAddr) String() string  { return "dns" }
+
+// resolverKey is the context key under which withResolver stores the scan's
+// Resolver, so every probe that resolves names picks it up.
+type resolverKey struct{}
+
+func withResolver(ctx context.Context, r *Resolver) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, resolverKey{}, r)
+}
+
+func resolverFrom(ctx context.Context) *Resolver {
+	r, _ := ctx.Value(resolverKey{}).(*Resolver)
+	return r
+}
+
+// netResolver returns the standard library resolver to use under ctx.
+func netResolver(ctx context.Context) *net.Resolver {
+	if c := dnsCacheFrom(ctx); c != nil {
+		return c.net
+	}
+	if r := resolverFrom(ctx); r != nil {
+		return r.net
+	}
+	return net.DefaultResolver
+}
+
+// dialerFor returns d, or a copy of it resolving through the dnsCache or
+// Resolver carried by ctx.
+func dialerFor(ctx context.Context, d *net.Dialer) *net.Dialer {
+	r := netResolver(ctx)
+	if r == net.DefaultResolver {
+		return d
+	}
+	dd := *d
+	dd.Resolver = r
+	return &dd
+}
@@ -0,0 +1,100 @@
+package http1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohServer is a DoH endpoint answering each query with answer(query).
+func dohServer(t *testing.T, answer func(q dnsmessage.Message) dnsmessage.Message) *Resolver {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var q dnsmessage.Message
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" || q.Unpack(body) != nil {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		resp := answer(q)
+		resp.ID = q.ID
+		resp.Response = true
+		resp.Questions = q.Questions
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	t.Cleanup(srv.Close)
+
+	r, err := NewResolver(srv.URL + "/dns-query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.client = srv.Client()
+	return r
+}
+
+// answerA answers A queries with 192.0.2.7 and anything else with no data.
+func answerA(q dnsmessage.Message) dnsmessage.Message {
+	var resp dnsmessage.Message
+	if len(q.Questions) == 1 && q.Questions[0].Type == dnsmessage.TypeA {
+		resp.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 7}},
+		}}
+	}
+	return resp
+}
+
+func TestResolverDoH(t *testing.T) {
+	ctx := withResolver(context.Background(), dohServer(t, answerA))
+
+	addrs, err := netResolver(ctx).LookupHost(ctx, "www.example.test")
+	if err != nil {
+		t.Fatalf("LookupHost: %v", err)
+	}
+	if !slices.Equal(addrs, []string{"192.0.2.7"}) {
+		t.Errorf("LookupHost = %v, want [192.0.2.7]", addrs)
+	}
+
+	msg, err := dnsQuery(ctx, "www.example.test", dnsmessage.TypeA)
+	if err != nil {
+		t.Fatalf("dnsQuery: %v", err)
+	}
+	if len(msg.Answers) != 1 {
+		t.Errorf("dnsQuery returned %d answers, want 1", len(msg.Answers))
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		doh, dot string
+		wantErr  bool
+	}{
+		{endpoint: "https://1.1.1.1/dns-query", doh: "https://1.1.1.1/dns-query"},
+		{endpoint: "tls://9.9.9.9", dot: "9.9.9.9:853"},
+		{endpoint: "tls://dns.quad9.net:8853", dot: "dns.quad9.net:8853"},
+		{endpoint: "udp://1.1.1.1", wantErr: true},
+		{endpoint: "1.1.1.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			r, err := NewResolver(tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if r.doh != tt.doh || r.dot != tt.dot {
+				t.Errorf("doh %q dot %q, want %q %q", r.doh, r.dot, tt.doh, tt.dot)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package http1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// retryBaseDelay is the backoff before the first retry; each further retry
+// doubles it. The actual wait is drawn between half and all of it, so
+// retries of many probes against one network do not line up.
+const retryBaseDelay = 250 * time.Millisecond
+
+// doWithRetries sends req with client on a fresh probe context per attempt,
+// retrying transport errors up to retries times with jittered exponential
+// backoff. Any response, whatever its status, ends the loop. The last
+// attempt's duration and timings are recorded in vr, and with retries
+// enabled the attempts too. Closing the returned response's body releases
+// its context.
+func (t *rttTracker) doWithRetries(parent context.Context, fallback time.Duration, retries int, client *http.Client, req *http.Request, vr *VersionResult) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	attempt := 0
+	for attempt <= retries {
+		if attempt > 0 {
+			time.Sleep(retryBackoff(attempt))
+		}
+		attempt++
+		ctx, cancel := t.probeContext(parent, fallback)
+		ctx, timer := withProbeTimer(ctx)
+		resp, err = client.Do(req.WithContext(ctx))
+		err = probeError(ctx, err)
+		vr.DurationMS = time.Since(timer.start).Milliseconds()
+		vr.Timings = timer.timings(err == nil)
+		if err == nil {
+			resp.Body = cancelOnClose{resp.Body, cancel}
+			break
+		}
+		cancel()
+	}
+	if retries > 0 {
+		vr.Attempts = attempt
+		if err == nil {
+			vr.Evidence = fmt.Sprintf("succeeded on attempt %d of %d", attempt, retries+1)
+		} else {
+			vr.Evidence = fmt.Sprintf("failed all %d attempts", attempt)
+		}
+	}
+	return resp, err
+}
+
+// retryBackoff is the wait before retry n (1-based).
+func retryBackoff(n int) time.Duration {
+	d := retryBaseDelay << (n - 1)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// cancelOnClose cancels a response's context once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,62 @@
+package http1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails the first failures requests, then answers 200.
+type flakyTransport struct{ failures int }
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("timeout: no recent network activity")
+	}
+	return &http.Response{StatusCode: http.StatusOK, ProtoMajor: 3, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+}
+
+func TestDoWithRetries(t *testing.T) {
+	tests := []struct {
+		failures, retries int
+		wantErr           bool
+		wantAttempts      int
+		wantEvidence      string
+	}{
+		{failures: 0, retries: 0, wantAttempts: 0},
+		{failures: 1, retries: 0, wantErr: true, wantAttempts: 0},
+		{failures: 1, retries: 2, wantAttempts: 2, wantEvidence: "succeeded on attempt 2 of 3"},
+		{failures: 3, retries: 2, wantErr: true, wantAttempts: 3, wantEvidence: "failed all 3 attempts"},
+	}
+	for _, tt := range tests {
+		client := &http.Client{Transport: &flakyTransport{failures: tt.failures}}
+		req, _ := http.NewRequest(http.MethodGet, "https://example.test/", nil)
+		var vr VersionResult
+		resp, err := newRTTTracker().doWithRetries(context.Background(), time.Second, tt.retries, client, req, &vr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%d failures, %d retries: err = %v, wantErr %v", tt.failures, tt.retries, err, tt.wantErr)
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+		if vr.Attempts != tt.wantAttempts || vr.Evidence != tt.wantEvidence {
+			t.Errorf("%d failures, %d retries: attempts %d %q, want %d %q", tt.failures, tt.retries, vr.Attempts, vr.Evidence, tt.wantAttempts, tt.wantEvidence)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for n := 1; n <= 4; n++ {
+		full := retryBaseDelay << (n - 1)
+		for range 20 {
+			if d := retryBackoff(n); d < full/2 || d > full {
+				t.Fatalf("retryBackoff(%d) = %v, want within [%v, %v]", n, d, full/2, full)
+			}
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package http1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaID is the $id of the results schema, where web mode serves it.
+const SchemaID = "https://http1.dev/schema.json"
+
+// schemaEnums lists the values of the string types that are enumerations.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeFor[ErrorKind](): {
+		string(ErrorKindDNSNXDomain), string(ErrorKindTimeout), string(ErrorKindCanceled), string(ErrorKindConnRefused),
+		string(ErrorKindTLSHandshake), string(ErrorKindALPNMismatch), string(ErrorKindReset),
+		string(ErrorKindQUICUnreachable), string(ErrorKindOther),
+	},
+}
+
+// ResultsSchema returns the JSON Schema (draft 2020-12) of the JSON output:
+// a single CheckResult (an --ndjson line), an array of them (--json), or
+// the object --json writes with --group-by-label or --summary. It is
+// generated from the Go types, so it always matches this build; output
+// projected with --fields is not covered.
+func ResultsSchema() []byte {
+	return resultsSchema()
+}
+
+var resultsSchema = sync.OnceValue(func() []byte {
+	g := schemaGen{defs: make(map[string]any)}
+	result := g.schema(reflect.TypeFor[CheckResult]())
+	schema := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     SchemaID,
+		"title":   "http1.dev scan results",
+		"oneOf": []any{
+			result,
+			map[string]any{"type": "array", "items": result},
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"results": map[string]any{"type": "array", "items": result},
+					"groups":  g.schema(reflect.TypeFor[[]LabelGroup]()),
+					"summary": g.schema(reflect.TypeFor[BatchStats]()),
+				},
+				"required": []string{"results"},
+			},
+		},
+		"$defs": g.defs,
+	}
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(out, '\n')
+})
+
+// schemaGen builds JSON Schemas from Go types the way encoding/json
+// marshals them, putting each named struct in defs.
+type schemaGen struct {
+	defs map[string]any
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if values, ok := schemaEnums[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64.
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // placeholder for recursive types
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	// Interfaces hold anything.
+	return map[string]any{}
+}
+
+// object is the schema of a struct's JSON object. Properties without
+// omitempty are always written, so they are required, and those that
+// marshal nil as null may be null.
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" || !f.IsExported() && !f.Anonymous {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s := g.schema(f.Type)
+			// omitempty leaves struct values in; omitzero drops them too.
+			omit := slices.ContainsFunc(strings.Split(opts, ","), func(o string) bool {
+				return o == "omitzero" || o == "omitempty" && f.Type.Kind() != reflect.Struct
+			})
+			if !omit {
+				required = append(required, name)
+				switch f.Type.Kind() {
+				case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+					s = map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+				}
+			}
+			props[name] = s
+		}
+	}
+	walk(t)
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		obj["required"] = required
+	}
+	return obj
+}
+
+// ValidateResults checks JSON output against schema, e.g. ResultsSchema or
+// the schema of another version of the tool, and returns every violation
+// found, each prefixed with its JSON path. NDJSON is checked line by line.
+// Only the keywords the results schema uses are understood: $ref into
+// $defs, type, enum, properties, required, additionalProperties, items,
+// anyOf and oneOf.
+func ValidateResults(schema, data []byte) ([]string, error) {
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	v := schemaValidator{root: root}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err == nil {
+		return v.validate(root, doc, "$"), nil
+	}
+	// Not a single JSON value: try NDJSON.
+	var problems []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &doc); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		problems = append(problems, v.validate(root, doc, fmt.Sprintf("line %d: $", n))...)
+	}
+	return problems, sc.Err()
+}
+
+// schemaValidator checks decoded JSON against a schema subset.
+type schemaValidator struct {
+	root map[string]any
+}
+
+func (v schemaValidator) validate(s map[string]any, doc any, path string) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		def, ok := v.resolve(ref)
+		if !ok {
+			return []string{fmt.Sprintf("%s: unresolvable $ref %q", path, ref)}
+		}
+		return v.validate(def, doc, path)
+	}
+	if branches, ok := s["anyOf"].([]any); ok {
+		return v.validateBranches(branches, doc, path, false)
+	}
+	if branches, ok := s["oneOf"].([]any); ok {
+		return v.validateBranches(branches, doc, path, true)
+	}
+	if types := schemaTypes(s["type"]); len(types) > 0 && !slices.Contains(types, jsonType(doc)) &&
+		!(jsonType(doc) == "integer" && slices.Contains(types, "number")) {
+		return []string{fmt.Sprintf("%s: want %s, got %s", path, strings.Join(types, " or "), jsonType(doc))}
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.Contains(enum, doc) {
+		return []string{fmt.Sprintf("%s: %v is not one of %v", path, doc, enum)}
+	}
+
+	var problems []string
+	switch doc := doc.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		for _, name := range schemaTypes(s["required"]) {
+			if _, ok := doc[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k].(map[string]any); ok {
+				problems = append(problems, v.validate(ps, doc[k], path+"."+k)...)
+				continue
+			}
+			switch extra := s["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					problems = append(problems, fmt.Sprintf("%s: unexpected property %q", path, k))
+				}
+			case map[string]any:
+				problems = append(problems, v.validate(extra, doc[k], fmt.Sprintf("%s[%q]", path, k))...)
+			}
+		}
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range doc {
+				problems = append(problems, v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+// validateBranches checks anyOf (exactly is false) or oneOf. When no branch
+// matches it reports the problems of the first one that at least accepts
+// the value's type, which is the one the output meant.
+func (v schemaValidator) validateBranches(branches []any, doc any, path string, exactly bool) []string {
+	var matched int
+	var first []string
+	var firstWrongType bool
+	for _, b := range branches {
+		bs, _ := b.(map[string]any)
+		problems := v.validate(bs, doc, path)
+		if len(problems) == 0 {
+			matched++
+			continue
+		}
+		if wrongType := strings.HasPrefix(problems[0], path+": want "); first == nil || firstWrongType && !wrongType {
+			first, firstWrongType = problems, wrongType
+		}
+	}
+	switch {
+	case matched == 0:
+		return first
+	case exactly && matched > 1:
+		return []string{fmt.Sprintf("%s: matches %d alternatives, want exactly one", path, matched)}
+	}
+	return nil
+}
+
+// resolve looks up a "#/$defs/Name" reference.
+func (v schemaValidator) resolve(ref string) (map[string]any, bool) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, false
+	}
+	defs, _ := v.root["$defs"].(map[string]any)
+	def, ok := defs[name].(map[string]any)
+	return def, ok
+}
+
+// schemaTypes reads a keyword that is a string or an array of strings.
+func schemaTypes(kw any) []string {
+	switch kw := kw.(type) {
+	case string:
+		return []string{kw}
+	case []any:
+		out := make([]string, 0, len(kw))
+		for _, k := range kw {
+			if s, ok := k.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// jsonType names the JSON Schema type of a decoded value.
+func jsonType(doc any) string {
+	switch doc := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if doc == math.Trunc(doc) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
@@ -0,0 +1,64 @@
+package http1
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultsSchema(t *testing.T) {
+	res := CheckResult{
+		Target:    "example.com",
+		URL:       "https://example.com:443",
+		Port:      "443",
+		Grade:     "B",
+		Score:     90,
+		ScannedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Results: []VersionResult{
+			{Version: "HTTP/2.0", Supported: true, Timings: &ProbeTimings{TLSMS: 3, TTFBMS: 9}},
+			{Version: "HTTP/3.0", Error: true, ErrorKind: ErrorKindQUICUnreachable},
+		},
+		TLS:          &TLSInfo{Version: "TLS 1.3", ALPN: map[string]string{"HTTP/2.0": "h2"}},
+		GradeReasons: gradeReasons(false, true, "TLS 1.3", nil, nil),
+	}
+	one, _ := json.Marshal(res)
+	many, _ := json.Marshal([]CheckResult{res, {Target: "bad"}})
+	wrapped, _ := json.Marshal(map[string]any{"results": []CheckResult{res}, "summary": Summarize([]CheckResult{res})})
+	ndjson := append(append(append([]byte{}, one...), '\n'), one...)
+
+	for name, data := range map[string][]byte{"result": one, "array": many, "wrapped": wrapped, "ndjson": ndjson} {
+		problems, err := ValidateResults(ResultsSchema(), data)
+		if err != nil || len(problems) > 0 {
+			t.Errorf("%s: %v %v", name, err, problems)
+		}
+	}
+
+	bad := bytes.Replace(one, []byte(`"quic_unreachable"`), []byte(`"lost"`), 1)
+	bad = bytes.Replace(bad, []byte(`"score":90`), []byte(`"score":"90"`), 1)
+	problems, err := ValidateResults(ResultsSchema(), bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(problems, "\n")
+	for _, want := range []string{"$.results[1].error_kind: lost is not one of", "$.score: want integer, got string"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("problems %q lack %q", got, want)
+		}
+	}
+	if problems, _ := ValidateResults(ResultsSchema(), []byte(`{"target":"x"}`)); len(problems) == 0 {
+		t.Error("result without required properties passed")
+	}
+}
+
+func TestSchemaFileUpToDate(t *testing.T) {
+	shipped, err := os.ReadFile("../../schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shipped, ResultsSchema()) {
+		t.Error("schema.json is out of date; regenerate it with: go run ./cmd/http1 validate --print-schema > schema.json")
+	}
+}
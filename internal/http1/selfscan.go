@@ -0,0 +1,162 @@
+package http1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSelfScanPath is where SelfScan.Middleware serves the latest result;
+// the Prometheus metrics are under it at "/metrics".
+const DefaultSelfScanPath = "/.well-known/http1"
+
+// DefaultSelfScanInterval is how often a SelfScan rescans by default.
+const DefaultSelfScanInterval = time.Hour
+
+// SelfScan periodically checks a service's own public hostname so the
+// service can report its protocol posture: the latest result and whether it
+// clears a Policy, as JSON and as Prometheus gauges.
+type SelfScan struct {
+	// Target is the service's public hostname or URL.
+	Target  string
+	Options Options
+	Policy  Policy
+	// Interval is the time between scans; zero means
+	// DefaultSelfScanInterval.
+	Interval time.Duration
+	// Path is where Middleware serves the report; empty means
+	// DefaultSelfScanPath.
+	Path string
+
+	mu      sync.RWMutex
+	last    *CheckResult
+	checked time.Time
+}
+
+// SelfScanReport is the JSON document SelfScan serves.
+type SelfScanReport struct {
+	Target     string       `json:"target"`
+	CheckedAt  time.Time    `json:"checked_at"`
+	Passes     bool         `json:"passes"`
+	Violations []string     `json:"violations,omitempty"`
+	Result     *CheckResult `json:"result"`
+}
+
+// Run scans the target right away and then every Interval until ctx is
+// done. It is meant to run in its own goroutine.
+func (s *SelfScan) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSelfScanInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.scan(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan checks the target once and keeps the result, unless ctx was
+// cancelled meanwhile.
+func (s *SelfScan) scan(ctx context.Context) {
+	res := runChecks(s.Target, s.Options)
+	if ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	s.last, s.checked = &res, time.Now()
+	s.mu.Unlock()
+}
+
+// Report returns the latest scan as a report, or false before the first
+// scan finished.
+func (s *SelfScan) Report() (SelfScanReport, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.last == nil {
+		return SelfScanReport{Target: s.Target}, false
+	}
+	v := s.Policy.Violations(*s.last)
+	return SelfScanReport{Target: s.Target, CheckedAt: s.checked, Passes: len(v) == 0, Violations: v, Result: s.last}, true
+}
+
+// Middleware serves the report at Path and the Prometheus metrics at
+// Path+"/metrics", passing every other request to next.
+func (s *SelfScan) Middleware(next http.Handler) http.Handler {
+	path := s.Path
+	if path == "" {
+		path = DefaultSelfScanPath
+	}
+	path = strings.TrimSuffix(path, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case path:
+			report, ok := s.Report()
+			w.Header().Set("Content-Type", "application/json")
+			if !ok {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			_ = json.NewEncoder(w).Encode(report)
+		case path + "/metrics":
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			_ = s.WriteMetrics(w)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// WriteMetrics writes the latest scan as Prometheus gauges in the text
+// exposition format, for services that already serve a /metrics page.
+// Before the first scan only http1_self_scan_up is written, as 0.
+func (s *SelfScan) WriteMetrics(w io.Writer) error {
+	report, ok := s.Report()
+	var b strings.Builder
+	target := fmt.Sprintf("target=%q", report.Target)
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	gauge("http1_self_scan_up", "Whether a self-scan has completed.")
+	if !ok {
+		fmt.Fprintf(&b, "http1_self_scan_up{%s} 0\n", target)
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	res := report.Result
+	fmt.Fprintf(&b, "http1_self_scan_up{%s} 1\n", target)
+	gauge("http1_self_scan_score", "Score of the latest self-scan.")
+	fmt.Fprintf(&b, "http1_self_scan_score{%s} %d\n", target, res.Score)
+	gauge("http1_self_scan_grade", "Grade of the latest self-scan, 1 for the grade obtained.")
+	for _, g := range gradeOrder {
+		fmt.Fprintf(&b, "http1_self_scan_grade{%s,grade=%q} %d\n", target, g, boolGauge(res.Grade == g))
+	}
+	gauge("http1_self_scan_supported", "Whether the latest self-scan found the HTTP version supported.")
+	for _, vr := range res.Results {
+		if !vr.NotTested {
+			fmt.Fprintf(&b, "http1_self_scan_supported{%s,version=%q} %d\n", target, vr.Version, boolGauge(vr.Supported))
+		}
+	}
+	gauge("http1_self_scan_policy_pass", "Whether the latest self-scan clears the policy.")
+	fmt.Fprintf(&b, "http1_self_scan_policy_pass{%s} %d\n", target, boolGauge(report.Passes))
+	gauge("http1_self_scan_timestamp_seconds", "Unix time the latest self-scan finished.")
+	fmt.Fprintf(&b, "http1_self_scan_timestamp_seconds{%s} %d\n", target, report.CheckedAt.Unix())
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func boolGauge(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
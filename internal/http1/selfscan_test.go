@@ -0,0 +1,56 @@
+package http1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelfScan(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer target.Close()
+
+	s := &SelfScan{Target: target.URL, Policy: Policy{MinGrade: "B"}}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("app")) })
+	h := s.Middleware(next)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get(DefaultSelfScanPath); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("before the first scan: status %d", rec.Code)
+	}
+	if body := get(DefaultSelfScanPath + "/metrics").Body.String(); !strings.Contains(body, "http1_self_scan_up{target=") || !strings.HasSuffix(body, "} 0\n") {
+		t.Errorf("metrics before the first scan:\n%s", body)
+	}
+
+	s.scan(context.Background())
+	rec := get(DefaultSelfScanPath)
+	var report SelfScanReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("report: %d %v", rec.Code, err)
+	}
+	// A plain-HTTP test server only speaks HTTP/1.x, so it grades F.
+	if report.Passes || report.Result.Grade != "F" || len(report.Violations) == 0 {
+		t.Errorf("report = %+v", report)
+	}
+	metrics := get(DefaultSelfScanPath + "/metrics").Body.String()
+	for _, want := range []string{
+		"# TYPE http1_self_scan_score gauge\n",
+		`http1_self_scan_grade{target="` + target.URL + `",grade="F"} 1`,
+		`http1_self_scan_supported{target="` + target.URL + `",version="HTTP/1.1"} 1`,
+		`http1_self_scan_policy_pass{target="` + target.URL + `"} 0`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics lack %q:\n%s", want, metrics)
+		}
+	}
+	if body := get("/other").Body.String(); body != "app" {
+		t.Errorf("other paths reach %q", body)
+	}
+}
@@ -0,0 +1,51 @@
+package http1
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortKeys are the orders ResultOrder accepts.
+var SortKeys = []string{"grade", "score", "target"}
+
+// ResultOrder returns the comparison that puts results in the named order,
+// for a stable sort: "grade" lists the worst grades first, lower scores
+// first within a grade; "score" sorts by score alone, lowest first; and
+// "target" sorts by target name. Under grade and score, results without a
+// grade, e.g. invalid targets, come before all others.
+func ResultOrder(by string) (func(a, b CheckResult) int, error) {
+	switch strings.ToLower(strings.TrimSpace(by)) {
+	case "grade":
+		return func(a, b CheckResult) int {
+			if c := cmp.Compare(gradeRank(b), gradeRank(a)); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Score, b.Score)
+		}, nil
+	case "score":
+		return func(a, b CheckResult) int {
+			switch ua, ub := gradeRank(a) == len(gradeOrder), gradeRank(b) == len(gradeOrder); {
+			case ua && !ub:
+				return -1
+			case ub && !ua:
+				return 1
+			}
+			return cmp.Compare(a.Score, b.Score)
+		}, nil
+	case "target":
+		return func(a, b CheckResult) int {
+			return cmp.Compare(a.Target, b.Target)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown sort order %q (want %s)", by, strings.Join(SortKeys, ", "))
+}
+
+// gradeRank is res's position in gradeOrder, or one past F without a grade.
+func gradeRank(res CheckResult) int {
+	if i := slices.Index(gradeOrder, res.Grade); i >= 0 {
+		return i
+	}
+	return len(gradeOrder)
+}
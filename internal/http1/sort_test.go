@@ -0,0 +1,43 @@
+package http1
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResultOrder(t *testing.T) {
+	results := []CheckResult{
+		{Target: "b.example", Grade: "A", Score: 95},
+		{Target: "d.example", Grade: "C", Score: 82},
+		{Target: "a.example", Grade: "B", Score: 74},
+		{Target: "bad target"},
+		{Target: "c.example", Grade: "C", Score: 75},
+		{Target: "e.example", Grade: "F", Score: 45},
+	}
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{"grade", []string{"bad target", "e.example", "c.example", "d.example", "a.example", "b.example"}},
+		{"score", []string{"bad target", "e.example", "a.example", "c.example", "d.example", "b.example"}},
+		{"Target", []string{"a.example", "b.example", "bad target", "c.example", "d.example", "e.example"}},
+	}
+	for _, tt := range tests {
+		compare, err := ResultOrder(tt.by)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted := slices.Clone(results)
+		slices.SortStableFunc(sorted, compare)
+		var got []string
+		for _, res := range sorted {
+			got = append(got, res.Target)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.by, got, tt.want)
+		}
+	}
+	if _, err := ResultOrder("speed"); err == nil {
+		t.Error("unknown order accepted")
+	}
+}
@@ -0,0 +1,82 @@
+package http1
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Server stacks Stack can name.
+const (
+	StackNginx      = "nginx"
+	StackApache     = "Apache"
+	StackHAProxy    = "HAProxy"
+	StackIIS        = "IIS"
+	StackCaddy      = "Caddy"
+	StackCloudFront = "CloudFront"
+	StackFastly     = "Fastly"
+)
+
+// serverPrefixes map lower-case Server header prefixes to stacks.
+var serverPrefixes = []struct {
+	prefix, stack string
+}{
+	{"nginx", StackNginx},
+	{"openresty", StackNginx},
+	{"apache", StackApache},
+	{"haproxy", StackHAProxy},
+	{"microsoft-iis", StackIIS},
+	{"caddy", StackCaddy},
+	{"cloudfront", StackCloudFront},
+}
+
+// cdnCNAMESuffixes map CNAME targets to the CDN that terminates them.
+var cdnCNAMESuffixes = []struct {
+	suffix, stack string
+}{
+	{".cloudfront.net", StackCloudFront},
+	{".fastly.net", StackFastly},
+	{".fastlylb.net", StackFastly},
+}
+
+// fingerprintStack names the stack that answered with h, or "". A CDN's
+// own headers win over the Server header, which CDNs pass through from the
+// origin while terminating the connection themselves.
+func fingerprintStack(h http.Header) string {
+	switch {
+	case h.Get("X-Amz-Cf-Id") != "" || strings.Contains(h.Get("Via"), "CloudFront"):
+		return StackCloudFront
+	case h.Get("X-Fastly-Request-Id") != "" || strings.HasPrefix(h.Get("X-Served-By"), "cache-"):
+		return StackFastly
+	}
+	server := strings.ToLower(h.Get("Server"))
+	for _, p := range serverPrefixes {
+		if strings.HasPrefix(server, p.prefix) {
+			return p.stack
+		}
+	}
+	return ""
+}
+
+// serverStack picks the Server header and stack for a result from its probe
+// responses, preferring HTTP/2's, and from its CNAME chain.
+func serverStack(results []VersionResult, cnames []string) (server, stack string) {
+	for _, i := range []int{2, 1, 3, 0} {
+		if i >= len(results) {
+			continue
+		}
+		if server == "" {
+			server = results[i].server
+		}
+		if stack == "" {
+			stack = results[i].stack
+		}
+	}
+	for _, name := range cnames {
+		for _, c := range cdnCNAMESuffixes {
+			if strings.HasSuffix(strings.TrimSuffix(strings.ToLower(name), "."), c.suffix) {
+				return server, c.stack
+			}
+		}
+	}
+	return server, stack
+}
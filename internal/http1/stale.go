@@ -0,0 +1,41 @@
+package http1
+
+import "time"
+
+// DefaultMaxAge is how old a result may get before NeedsRescan wants it
+// scanned again.
+const DefaultMaxAge = 24 * time.Hour
+
+// NeedsRescan reports whether res, from an earlier scan, should be scanned
+// again rather than reused at time now: when it is older than maxAge or of
+// unknown age, or when it was inconclusive, i.e. Errored or with a negative
+// finding marked unreliable.
+func NeedsRescan(res CheckResult, maxAge time.Duration, now time.Time) bool {
+	if res.ScannedAt.IsZero() || now.Sub(res.ScannedAt) > maxAge {
+		return true
+	}
+	if Errored(res) {
+		return true
+	}
+	for _, vr := range res.Results {
+		if vr.Unreliable {
+			return true
+		}
+	}
+	return false
+}
+
+// Errored reports whether res has a probe that failed (🟧), panicked or
+// hung, or could not be graded at all, e.g. when the scan deadline cut it
+// off.
+func Errored(res CheckResult) bool {
+	if res.Grade == "" || len(res.ProbeErrors) > 0 {
+		return true
+	}
+	for _, vr := range res.Results {
+		if vr.Error {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package http1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNeedsRescan(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	fresh := CheckResult{
+		Grade:     "A",
+		ScannedAt: now.Add(-time.Hour),
+		Results:   []VersionResult{{Version: "HTTP/2.0", Supported: true}, {Version: "HTTP/3.0", NotTested: true}},
+	}
+	tests := []struct {
+		name    string
+		change  func(*CheckResult)
+		want    bool
+		errored bool
+	}{
+		{"fresh", func(*CheckResult) {}, false, false},
+		{"old", func(r *CheckResult) { r.ScannedAt = now.Add(-25 * time.Hour) }, true, false},
+		{"unknown age", func(r *CheckResult) { r.ScannedAt = time.Time{} }, true, false},
+		{"ungraded", func(r *CheckResult) { r.Grade = "" }, true, true},
+		{"probe error", func(r *CheckResult) { r.Results = []VersionResult{{Version: "HTTP/2.0", Error: true}} }, true, true},
+		{"unreliable", func(r *CheckResult) { r.Results = []VersionResult{{Version: "HTTP/3.0", Unreliable: true}} }, true, false},
+		{"watchdog", func(r *CheckResult) { r.ProbeErrors = []ProbeError{{Probe: "HTTP/3.0"}} }, true, true},
+	}
+	for _, tt := range tests {
+		res := fresh
+		tt.change(&res)
+		if got := NeedsRescan(res, DefaultMaxAge, now); got != tt.want {
+			t.Errorf("%s: NeedsRescan = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := Errored(res); got != tt.errored {
+			t.Errorf("%s: Errored = %v, want %v", tt.name, got, tt.errored)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package http1
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// slowestTargets is how many targets BatchStats lists as the slowest.
+const slowestTargets = 5
+
+// BatchStats is the executive summary of a batch of results: how they
+// grade, how many targets support HTTP/2 and HTTP/3 or still serve
+// HTTP/1.0, and which were slowest to answer.
+type BatchStats struct {
+	Targets int `json:"targets"`
+	// Grades counts the results per grade, and Errors those that could not
+	// be graded.
+	Grades map[string]int `json:"grades"`
+	Errors int            `json:"errors,omitempty"`
+	// H2, H3 and H10 count the targets supporting HTTP/2, HTTP/3 and
+	// HTTP/1.0; the percentages are of all Targets.
+	H2         int     `json:"h2"`
+	H2Percent  float64 `json:"h2_percent"`
+	H3         int     `json:"h3"`
+	H3Percent  float64 `json:"h3_percent"`
+	H10        int     `json:"h1_0"`
+	H10Percent float64 `json:"h1_0_percent"`
+	// Slowest lists the targets whose slowest probe took longest, slowest
+	// first.
+	Slowest []SlowTarget `json:"slowest,omitempty"`
+}
+
+// SlowTarget is an entry of BatchStats.Slowest.
+type SlowTarget struct {
+	Target string `json:"target"`
+	// DurationMS is the target's slowest probe, in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// GradeCounts lists s's grade counts best grade first, as grade/count pairs
+// for display.
+func (s BatchStats) GradeCounts() []GradeCount {
+	return LabelGroup{Grades: s.Grades}.GradeCounts()
+}
+
+// StatsTally builds BatchStats as results arrive. The zero value is ready
+// to use.
+type StatsTally struct {
+	s BatchStats
+}
+
+// Add counts res.
+func (t *StatsTally) Add(res CheckResult) {
+	s := &t.s
+	if s.Grades == nil {
+		s.Grades = make(map[string]int)
+	}
+	s.Targets++
+	if res.Grade == "" {
+		s.Errors++
+	} else {
+		s.Grades[res.Grade]++
+	}
+	slow := SlowTarget{Target: res.Target}
+	for _, vr := range res.Results {
+		slow.DurationMS = max(slow.DurationMS, vr.DurationMS)
+		if !vr.Supported || vr.NotTested {
+			continue
+		}
+		switch vr.Version {
+		case "HTTP/1.0":
+			s.H10++
+		case "HTTP/2.0":
+			s.H2++
+		case "HTTP/3.0":
+			s.H3++
+		}
+	}
+	if slow.DurationMS == 0 {
+		return
+	}
+	i, _ := slices.BinarySearchFunc(s.Slowest, slow, func(a, b SlowTarget) int {
+		return cmp.Compare(b.DurationMS, a.DurationMS)
+	})
+	if i < slowestTargets {
+		s.Slowest = slices.Insert(s.Slowest, i, slow)
+		s.Slowest = s.Slowest[:min(len(s.Slowest), slowestTargets)]
+	}
+}
+
+// Stats returns the summary of the results added so far.
+func (t *StatsTally) Stats() BatchStats {
+	s := t.s
+	s.Grades = make(map[string]int, len(t.s.Grades))
+	for g, n := range t.s.Grades {
+		s.Grades[g] = n
+	}
+	s.Slowest = slices.Clone(t.s.Slowest)
+	if s.Targets > 0 {
+		s.H2Percent = percent(s.H2, s.Targets)
+		s.H3Percent = percent(s.H3, s.Targets)
+		s.H10Percent = percent(s.H10, s.Targets)
+	}
+	return s
+}
+
+// Summarize is StatsTally over a finished set of results.
+func Summarize(results []CheckResult) BatchStats {
+	var t StatsTally
+	for _, res := range results {
+		t.Add(res)
+	}
+	return t.Stats()
+}
+
+// percent is n as a percentage of total, to one decimal place.
+func percent(n, total int) float64 {
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
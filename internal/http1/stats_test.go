@@ -0,0 +1,45 @@
+package http1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	if s := Summarize(nil); s.Targets != 0 || s.H2Percent != 0 {
+		t.Errorf("no results: got %+v", s)
+	}
+
+	probe := func(version string, supported bool, ms int64) VersionResult {
+		return VersionResult{Version: version, Supported: supported, DurationMS: ms}
+	}
+	var results []CheckResult
+	for i, ms := range []int64{300, 900, 100, 700, 500, 800} {
+		results = append(results, CheckResult{
+			Target: string(rune('a'+i)) + ".com",
+			Grade:  "A",
+			Results: []VersionResult{
+				probe("HTTP/1.0", i == 0, 10),
+				probe("HTTP/2.0", true, ms),
+				probe("HTTP/3.0", i < 2, 20),
+			},
+		})
+	}
+	results[1].Grade = "F"
+	results = append(results, CheckResult{Target: "down.com"})
+
+	s := Summarize(results)
+	if s.Targets != 7 || s.Errors != 1 || !reflect.DeepEqual(s.Grades, map[string]int{"A": 5, "F": 1}) {
+		t.Errorf("counts: got %+v", s)
+	}
+	if s.H2 != 6 || s.H2Percent != 85.7 || s.H3 != 2 || s.H3Percent != 28.6 || s.H10 != 1 || s.H10Percent != 14.3 {
+		t.Errorf("versions: got %+v", s)
+	}
+	want := []SlowTarget{{"b.com", 900}, {"f.com", 800}, {"d.com", 700}, {"e.com", 500}, {"a.com", 300}}
+	if !reflect.DeepEqual(s.Slowest, want) {
+		t.Errorf("Slowest = %+v, want %+v", s.Slowest, want)
+	}
+	if c := s.GradeCounts(); !reflect.DeepEqual(c, []GradeCount{{"A", 5}, {"F", 1}}) {
+		t.Errorf("GradeCounts = %+v", c)
+	}
+}
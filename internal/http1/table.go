@@ -0,0 +1,117 @@
+package http1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tableVersions are the versions of the table format's status columns, and
+// their headings.
+var tableVersions = [...]struct{ version, heading string }{
+	{"HTTP/1.0", "h1.0"},
+	{"HTTP/1.1", "h1.1"},
+	{"HTTP/2.0", "h2"},
+	{"HTTP/3.0", "h3"},
+}
+
+// Widths of the table format's fixed columns.
+const (
+	tableTLSWidth   = len("TLS 1.3")
+	tableGradeWidth = len("A (100)")
+)
+
+// TableHeader is the header row of the table format, whose target column is
+// targetWidth wide.
+func TableHeader(targetWidth int) string {
+	return tableHeader(nil, targetWidth)
+}
+
+// TableHeader is TableHeader with its headings translated.
+func (t *Translator) TableHeader(targetWidth int) string {
+	return tableHeader(t, targetWidth)
+}
+
+// TableRow formats a result as a row of the table format: the target,
+// padded to targetWidth, each version's status, the TLS version, the grade
+// and the slowest probe's time, in fixed-width columns. With color the
+// statuses and grade get ANSI colors.
+func TableRow(res CheckResult, targetWidth int, color bool) string {
+	return tableRow(res, nil, targetWidth, color)
+}
+
+// TableRow is TableRow with its status words translated.
+func (t *Translator) TableRow(res CheckResult, targetWidth int, color bool) string {
+	return tableRow(res, t, targetWidth, color)
+}
+
+func tableHeader(t *Translator, targetWidth int) string {
+	status := tableStatusWidth(t)
+	var b strings.Builder
+	b.WriteString(pad(t.Message("Target"), max(targetWidth, utf8.RuneCountInString(t.Message("Target")))))
+	for _, v := range tableVersions {
+		b.WriteString("  " + pad(v.heading, status))
+	}
+	fmt.Fprintf(&b, "  %s  %s  %s", pad("TLS", tableTLSWidth), pad(t.Message("Grade"), tableGradeWidth), t.Message("Time"))
+	return strings.TrimRight(b.String(), " ")
+}
+
+func tableRow(res CheckResult, t *Translator, targetWidth int, color bool) string {
+	paint := func(s, code string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+	status := tableStatusWidth(t)
+	var b strings.Builder
+	b.WriteString(pad(res.Target, max(targetWidth, utf8.RuneCountInString(t.Message("Target")))))
+	var slowest int64
+	for _, v := range tableVersions {
+		word, code := "-", ""
+		for _, vr := range res.Results {
+			if vr.Version == v.version {
+				word, code = statusWord(vr)
+				word = t.Message(word)
+				slowest = max(slowest, vr.DurationMS)
+			}
+		}
+		b.WriteString("  " + paint(word, code) + strings.Repeat(" ", max(0, status-utf8.RuneCountInString(word))))
+	}
+	tlsVersion := res.TLSVersion
+	if tlsVersion == "" {
+		tlsVersion = "-"
+	}
+	fmt.Fprintf(&b, "  %s  ", pad(tlsVersion, tableTLSWidth))
+	if res.Grade != "" {
+		grade := fmt.Sprintf("%s (%d)", res.Grade, res.Score)
+		b.WriteString(paint(grade, gradeColor(res.Grade)) + strings.Repeat(" ", max(0, tableGradeWidth-len(grade))))
+	} else {
+		b.WriteString(pad("-", tableGradeWidth))
+	}
+	if slowest > 0 {
+		fmt.Fprintf(&b, "  %s", time.Duration(slowest)*time.Millisecond)
+	} else {
+		b.WriteString("  -")
+	}
+	for _, note := range summaryNotes(res, t) {
+		b.WriteString("  " + paint(note, ansiYellow))
+	}
+	return b.String()
+}
+
+// tableStatusWidth is the width of the table format's status columns: the
+// longest status word, or heading.
+func tableStatusWidth(t *Translator) int {
+	width := len("h1.0")
+	for _, w := range statusWords {
+		width = max(width, utf8.RuneCountInString(t.Message(w)))
+	}
+	return width
+}
+
+// pad left-aligns s in a column width runes wide.
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s)))
+}
@@ -0,0 +1,47 @@
+package http1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableRow(t *testing.T) {
+	res := CheckResult{
+		Target:     "example.com",
+		Grade:      "B",
+		Score:      90,
+		TLSVersion: "TLS 1.3",
+		Results: []VersionResult{
+			{Version: "HTTP/1.0", Supported: false, DurationMS: 40},
+			{Version: "HTTP/1.1", Supported: true, DurationMS: 120},
+			{Version: "HTTP/2.0", Supported: true, DurationMS: 80},
+			{Version: "HTTP/3.0", Error: true},
+		},
+	}
+	header := TableHeader(12)
+	row := TableRow(res, 12, false)
+	if want := "Target        h1.0     h1.1     h2       h3       TLS      Grade    Time"; header != want {
+		t.Errorf("TableHeader = %q\nwant          %q", header, want)
+	}
+	if want := "example.com   no       yes      yes      error    TLS 1.3  B (90)   120ms"; row != want {
+		t.Errorf("TableRow = %q\nwant       %q", row, want)
+	}
+	if i := strings.Index(header, "Grade"); row[i:i+1] != "B" {
+		t.Errorf("grade column misaligned:\n%s\n%s", header, row)
+	}
+
+	// An unreachable target leaves the versions it was not probed for, the
+	// TLS version and the grade empty.
+	row = TableRow(CheckResult{Target: "down.example"}, 4, false)
+	if want := "down.example  -        -        -        -        -        -        -"; row != want {
+		t.Errorf("TableRow = %q\nwant       %q", row, want)
+	}
+
+	de, err := NewTranslator("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := de.TableHeader(0); !strings.HasPrefix(got, "Ziel  h1.0          h1.1") {
+		t.Errorf("German TableHeader = %q", got)
+	}
+}
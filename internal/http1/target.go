@@ -0,0 +1,188 @@
+package http1
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Errors a TargetError can wrap, besides the *url.Error of a target that
+// is not a URL at all.
+var (
+	ErrEmptyTarget = errors.New("empty target")
+	ErrMissingHost = errors.New("missing host in URL")
+	ErrInvalidHost = errors.New("invalid host name")
+)
+
+// TargetError reports a target that ParseTarget or ParseTargetList could not
+// turn into a URL to probe.
+type TargetError struct {
+	// Target is the target as given, without labels.
+	Target string
+	// Line is the 1-based line of Target in a ParseTargetList input.
+	Line int
+	Err  error
+}
+
+func (e *TargetError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: invalid target %q: %v", e.Line, e.Target, e.Err)
+	}
+	return fmt.Sprintf("invalid target %q: %v", e.Target, e.Err)
+}
+
+func (e *TargetError) Unwrap() error { return e.Err }
+
+// Target is one scan target as parsed from user input. Every way of
+// naming targets (arguments, --targets, targets files, the web form and the
+// job API) goes through ParseTarget or ParseTargetList, so they all agree on
+// what a target is and when two targets are the same.
+type Target struct {
+	// Raw is the target as given, e.g. "Example.com:8443/health".
+	Raw string
+	// URL is the normalized URL the probes request.
+	URL string
+	// Scheme is "http" or "https".
+	Scheme string
+	// Host is the host name (ASCII) or IP address, without brackets.
+	Host string
+	// Port is the port from the target, or the scheme's default.
+	Port string
+	// Labels are the words following the target in a list entry, as in
+	// "example.com prod eu"; they are copied to CheckResult.Labels.
+	Labels []string
+	// Source names where the target came from, e.g. a targets file path or
+	// "--targets".
+	Source string
+	// Range is set for an address range such as "203.0.113.0/28", which
+	// ExpandRanges turns into one target per address; Host is then the
+	// range's first address.
+	Range netip.Prefix
+}
+
+// String returns the target as an entry ParseTarget reads back, labels
+// included. The string-based scan APIs accept this form.
+func (t Target) String() string {
+	return strings.Join(append([]string{t.Raw}, t.Labels...), " ")
+}
+
+// Key identifies the server and resource a target probes: scheme and host
+// are case-insensitive and a default port equals an omitted one, while the
+// path and query are compared as given.
+func (t Target) Key() string {
+	if t.Range.IsValid() {
+		return t.Scheme + "://" + t.Range.String()
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return t.URL
+	}
+	rest := u.EscapedPath()
+	if rest == "" {
+		rest = "/"
+	}
+	if u.RawQuery != "" {
+		rest += "?" + u.RawQuery
+	}
+	return t.Scheme + "://" + net.JoinHostPort(strings.ToLower(t.Host), t.Port) + rest
+}
+
+// ParseTarget parses a target as typed by a user ("example.com",
+// "bücher.example:8443/path"), optionally followed by labels. The scheme
+// defaults to https and internationalized names are converted to ASCII.
+// Errors are *TargetError.
+func ParseTarget(raw string) (Target, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return Target{}, &TargetError{Err: ErrEmptyTarget}
+	}
+	t := Target{Raw: fields[0]}
+	if len(fields) > 1 {
+		t.Labels = fields[1:]
+	}
+	if r, ok := parseRange(t.Raw); ok {
+		t.Scheme, t.Range = r.scheme, r.prefix
+		t.Host = r.prefix.Addr().String()
+		t.URL = t.Scheme + "://" + hostURL(r.prefix.Addr())
+		t.Port = defaultPort(t.Scheme)
+		return t, nil
+	}
+	norm, err := normalizeURL(t.Raw)
+	if err != nil {
+		return Target{}, &TargetError{Target: t.Raw, Err: err}
+	}
+	u, err := url.Parse(norm)
+	if err != nil {
+		return Target{}, &TargetError{Target: t.Raw, Err: err}
+	}
+	t.URL, t.Scheme, t.Host, t.Port = norm, u.Scheme, u.Hostname(), u.Port()
+	if t.Port == "" {
+		t.Port = defaultPort(t.Scheme)
+	}
+	return t, nil
+}
+
+// defaultPort is the port of scheme, "http" or "https".
+func defaultPort(scheme string) string {
+	if scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+// ParseTargetList parses targets separated by newlines or commas, as in a
+// targets file or the --targets flag, recording source as each target's
+// Source. Blank entries and lines starting with "#" are skipped. It returns
+// the valid targets in order, duplicates included (see DedupeTargets), and
+// an error joining a *TargetError for each invalid one.
+func ParseTargetList(list, source string) ([]Target, error) {
+	var targets []Target
+	var errs []error
+	for n, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, entry := range strings.Split(line, ",") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			t, err := ParseTarget(entry)
+			if err != nil {
+				te := err.(*TargetError)
+				te.Line = n + 1
+				errs = append(errs, te)
+				continue
+			}
+			t.Source = source
+			targets = append(targets, t)
+		}
+	}
+	return targets, errors.Join(errs...)
+}
+
+// DedupeTargets drops targets whose Key was already seen, keeping the first
+// and adding the labels of its duplicates to it.
+func DedupeTargets(targets []Target) []Target {
+	index := make(map[string]int, len(targets))
+	out := make([]Target, 0, len(targets))
+	for _, t := range targets {
+		key := t.Key()
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, t)
+			continue
+		}
+		for _, l := range t.Labels {
+			if !slices.Contains(out[i].Labels, l) {
+				out[i].Labels = append(out[i].Labels, l)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package http1
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Target
+		wantErr error
+	}{
+		{in: " example.com ", want: Target{Raw: "example.com", URL: "https://example.com", Scheme: "https", Host: "example.com", Port: "443"}},
+		{in: "http://Example.com:8080/a?b prod eu", want: Target{Raw: "http://Example.com:8080/a?b", URL: "http://Example.com:8080/a?b", Scheme: "http", Host: "Example.com", Port: "8080", Labels: []string{"prod", "eu"}}},
+		{in: "[::1]", want: Target{Raw: "[::1]", URL: "https://[::1]", Scheme: "https", Host: "::1", Port: "443"}},
+		{in: "", wantErr: ErrEmptyTarget},
+		{in: "https://", wantErr: ErrMissingHost},
+		{in: "a‍b.example", wantErr: ErrInvalidHost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTarget(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := ParseTarget("example.com:port")
+	var te *TargetError
+	var ue *url.Error
+	if !errors.As(err, &te) || te.Target != "example.com:port" || !errors.As(err, &ue) {
+		t.Errorf("bad port: err = %#v, want a *TargetError wrapping a *url.Error", err)
+	}
+}
+
+func TestTargetKey(t *testing.T) {
+	same := [][2]string{
+		{"Example.COM", "https://example.com"},
+		{"example.com", "example.com:443/"},
+		{"HTTP://Example.com", "http://example.com:80"},
+	}
+	for _, p := range same {
+		a, _ := ParseTarget(p[0])
+		b, _ := ParseTarget(p[1])
+		if a.Key() != b.Key() {
+			t.Errorf("Key(%q) = %q, Key(%q) = %q; want equal", p[0], a.Key(), p[1], b.Key())
+		}
+	}
+	different := [][2]string{
+		{"example.com/Health", "example.com/health"},
+		{"http://example.com", "https://example.com"},
+		{"example.com", "example.com:8443"},
+	}
+	for _, p := range different {
+		a, _ := ParseTarget(p[0])
+		b, _ := ParseTarget(p[1])
+		if a.Key() == b.Key() {
+			t.Errorf("Key(%q) == Key(%q) = %q; want different", p[0], p[1], a.Key())
+		}
+	}
+}
+
+func TestParseTargetList(t *testing.T) {
+	list := "# staging\na.com prod, b.com\n\n  c.com  \nhttps://\nA.com eu,,example.com:port\n"
+	got, err := ParseTargetList(list, "targets.txt")
+	var raw []string
+	for _, tg := range got {
+		if tg.Source != "targets.txt" {
+			t.Errorf("%s: Source = %q", tg.Raw, tg.Source)
+		}
+		raw = append(raw, tg.String())
+	}
+	if want := []string{"a.com prod", "b.com", "c.com", "A.com eu"}; strings.Join(raw, ",") != strings.Join(want, ",") {
+		t.Errorf("targets = %q, want %q", raw, want)
+	}
+	want := "line 5: invalid target \"https://\": missing host in URL\nline 6: invalid target \"example.com:port\": "
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("err = %v, want prefix %q", err, want)
+	}
+	if !errors.Is(err, ErrMissingHost) {
+		t.Errorf("err does not wrap ErrMissingHost")
+	}
+
+	deduped := DedupeTargets(got)
+	if len(deduped) != 3 || !reflect.DeepEqual(deduped[0].Labels, []string{"prod", "eu"}) {
+		t.Errorf("DedupeTargets = %+v, want a.com first with labels prod and eu", deduped)
+	}
+
+	if got, err := ParseTargetList(" \n# only a comment\n", ""); len(got) != 0 || err != nil {
+		t.Errorf("empty list = %q, %v", got, err)
+	}
+}
+
+func FuzzParseTarget(f *testing.F) {
+	for _, seed := range []string{"example.com", "https://Bücher.example:8443/päth prod", "http://[::1]:80/", "a‍b.example", "https://", ":", "%zz"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		tg, err := ParseTarget(raw)
+		if err != nil {
+			var te *TargetError
+			if !errors.As(err, &te) {
+				t.Fatalf("ParseTarget(%q) error %v is not a *TargetError", raw, err)
+			}
+			return
+		}
+		// A target reads back as itself, and its normalized URL is a valid
+		// target that normalizes to itself.
+		back, err := ParseTarget(tg.String())
+		if err != nil || !reflect.DeepEqual(back, tg) {
+			t.Fatalf("ParseTarget(%q) = %+v, but its String reads back as %+v, %v", raw, tg, back, err)
+		}
+		again, err := ParseTarget(tg.URL)
+		if err != nil {
+			t.Fatalf("ParseTarget(%q).URL = %q, which does not parse: %v", raw, tg.URL, err)
+		}
+		if again.URL != tg.URL || again.Key() != tg.Key() {
+			t.Fatalf("ParseTarget(%q) = %+v, but its URL parses as %+v", raw, tg, again)
+		}
+	})
+}
+
+func FuzzParseTargetList(f *testing.F) {
+	f.Add("a.com prod,b.com\n# comment\nhttps://\n")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, list string) {
+		targets, err := ParseTargetList(list, "fuzz")
+		for _, tg := range targets {
+			if tg.Raw == "" || strings.ContainsAny(tg.String(), ",\n") {
+				t.Fatalf("ParseTargetList(%q) returned entry %q", list, tg.String())
+			}
+			if _, err := ParseTarget(tg.String()); err != nil {
+				t.Fatalf("ParseTargetList(%q) returned invalid entry %q: %v", list, tg.String(), err)
+			}
+		}
+		if err != nil && !errors.As(err, new(*TargetError)) {
+			t.Fatalf("ParseTargetList(%q) error %v holds no *TargetError", list, err)
+		}
+		if deduped := DedupeTargets(targets); len(deduped) > len(targets) {
+			t.Fatalf("DedupeTargets grew %d targets to %d", len(targets), len(deduped))
+		}
+	})
+}
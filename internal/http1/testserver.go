@@ -0,0 +1,60 @@
+package http1
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+)
+
+// TestServers is a loopback HTTPS server (HTTP/1.1 and HTTP/2) plus an
+// HTTP/3 server sharing the same port number, so a single target exercises
+// every probe. It backs the benchmarks and the selftest command.
+type TestServers struct {
+	Port string
+
+	https *httptest.Server
+	h3    io.Closer
+	udp   *net.UDPConn
+}
+
+// StartTestServers starts the servers on a free 127.0.0.1 port.
+func StartTestServers() (*TestServers, error) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	for attempt := 0; attempt < 10; attempt++ {
+		ts := httptest.NewUnstartedServer(handler)
+		ts.EnableHTTP2 = true
+		// The HTTP/1.0 probe speaks plain HTTP to this TLS port; keep the
+		// resulting handshake errors out of the output.
+		ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+		ts.StartTLS()
+
+		_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+		p, _ := strconv.Atoi(port)
+		udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: p})
+		if err != nil {
+			// UDP port already taken; try another TCP port.
+			ts.Close()
+			continue
+		}
+
+		h3 := serveH3(udp, handler, ts.TLS.Certificates)
+		return &TestServers{Port: port, https: ts, h3: h3, udp: udp}, nil
+	}
+	return nil, fmt.Errorf("could not find a free TCP/UDP port pair")
+}
+
+// Close shuts all servers down.
+func (s *TestServers) Close() {
+	if s.h3 != nil {
+		_ = s.h3.Close()
+	}
+	_ = s.udp.Close()
+	s.https.Close()
+}
@@ -0,0 +1,157 @@
+package http1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bounds for RTT-derived probe timeouts. Nearby hosts get close to the
+// minimum so dead protocols fail fast; distant hosts get up to the maximum so
+// a slow handshake is not mistaken for missing support.
+const (
+	adaptiveMinTimeout = 1 * time.Second
+	adaptiveMaxTimeout = 8 * time.Second
+
+	// A probe needs roughly one RTT each for TCP/QUIC, TLS and the request
+	// itself; the factor leaves headroom for server think time and jitter.
+	rttTimeoutFactor = 8
+	rttTimeoutSlack  = 500 * time.Millisecond
+)
+
+// errProbeTimeout is the cause probeContext cancels a probe with when its
+// timeout passes, so the probe's error can be told apart from the run being
+// canceled.
+var errProbeTimeout = errors.New("probe timed out")
+
+// rttTracker records the first TCP connect time observed for a target and
+// lets probes that are already in flight adapt their deadlines to it.
+type rttTracker struct {
+	once  sync.Once
+	ready chan struct{}
+	rtt   time.Duration
+
+	// throttled adds up the time the target's probes spent waiting for
+	// the Options.Rate limiter, which the watchdog does not count.
+	throttled atomic.Int64
+}
+
+func newRTTTracker() *rttTracker {
+	return &rttTracker{ready: make(chan struct{})}
+}
+
+func (t *rttTracker) observe(d time.Duration) {
+	t.once.Do(func() {
+		t.rtt = d
+		close(t.ready)
+	})
+}
+
+// RTT returns the observed round-trip time, or 0 if none was measured.
+func (t *rttTracker) RTT() time.Duration {
+	select {
+	case <-t.ready:
+		return t.rtt
+	default:
+		return 0
+	}
+}
+
+// rttKey is the context key under which probeContext stores its tracker.
+type rttKey struct{}
+
+// rttDial returns a DialContext that times successful TCP connects and
+// reports them to the tracker carried by the dial context, if any. Because
+// the tracker travels with the context, one dialer can serve many targets.
+// Names are resolved with the context's Resolver, and connections use its
+// IP version, if any.
+func rttDial(d *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := dialerFor(ctx, d).DialContext(ctx, ipNetwork(ctx, network), addr)
+		if t, ok := ctx.Value(rttKey{}).(*rttTracker); ok && err == nil {
+			t.observe(time.Since(start))
+		}
+		return conn, err
+	}
+}
+
+// probeContext returns a context for a probe starting now, or once parent's
+// rate limiter, if any, lets it. Until an RTT is known the probe is bounded
+// by fallback (the fixed per-protocol timeout); once one is, the deadline
+// moves to now+adaptiveTimeout(rtt). Connections dialed with the returned
+// context report their connect time to t.
+func (t *rttTracker) probeContext(parent context.Context, fallback time.Duration) (context.Context, context.CancelFunc) {
+	if l := rateLimiterFrom(parent); l != nil {
+		waitStart := time.Now()
+		_ = l.wait(parent)
+		t.throttled.Add(int64(time.Since(waitStart)))
+	}
+	start := time.Now()
+	bounded, cancelBounded := context.WithTimeout(context.WithValue(parent, rttKey{}, t), adaptiveMaxTimeout)
+	ctx, cancelCause := context.WithCancelCause(bounded)
+	cancel := func() {
+		cancelCause(context.Canceled)
+		cancelBounded()
+	}
+
+	go func() {
+		fallbackTimer := time.NewTimer(fallback)
+		defer fallbackTimer.Stop()
+		select {
+		case <-t.ready:
+		case <-fallbackTimer.C:
+			cancelCause(errProbeTimeout)
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		remaining := time.Until(start.Add(adaptiveTimeout(t.rtt)))
+		if remaining <= 0 {
+			cancelCause(errProbeTimeout)
+			return
+		}
+		deadline := time.NewTimer(remaining)
+		defer deadline.Stop()
+		select {
+		case <-deadline.C:
+			cancelCause(errProbeTimeout)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// probeError returns the error of a probe sent with ctx, from probeContext,
+// marked as a timeout when probeContext's timeout ended the probe: the
+// probe itself only sees its context canceled.
+func probeError(ctx context.Context, err error) error {
+	if err != nil && context.Cause(ctx) == errProbeTimeout {
+		return probeTimeoutError{err}
+	}
+	return err
+}
+
+// probeTimeoutError is a probe error caused by its timeout. It reads as the
+// error the probe saw.
+type probeTimeoutError struct{ err error }
+
+func (e probeTimeoutError) Error() string   { return e.err.Error() }
+func (e probeTimeoutError) Unwrap() []error { return []error{e.err, errProbeTimeout} }
+
+// adaptiveTimeout derives a probe timeout from a measured RTT.
+func adaptiveTimeout(rtt time.Duration) time.Duration {
+	d := rtt*rttTimeoutFactor + rttTimeoutSlack
+	if d < adaptiveMinTimeout {
+		return adaptiveMinTimeout
+	}
+	if d > adaptiveMaxTimeout {
+		return adaptiveMaxTimeout
+	}
+	return d
+}
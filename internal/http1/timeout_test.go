@@ -0,0 +1,120 @@
+package http1
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	tests := []struct {
+		rtt, want time.Duration
+	}{
+		{0, adaptiveMinTimeout},
+		{time.Millisecond, adaptiveMinTimeout},
+		{100 * time.Millisecond, 100*time.Millisecond*rttTimeoutFactor + rttTimeoutSlack},
+		{time.Second, adaptiveMaxTimeout},
+	}
+	for _, tt := range tests {
+		if got := adaptiveTimeout(tt.rtt); got != tt.want {
+			t.Errorf("adaptiveTimeout(%v) = %v, want %v", tt.rtt, got, tt.want)
+		}
+	}
+}
+
+func TestProbeContextFallback(t *testing.T) {
+	ctx, cancel := newRTTTracker().probeContext(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(adaptiveMinTimeout):
+		t.Fatal("probe without an RTT outlived its fallback timeout")
+	}
+}
+
+func TestProbeError(t *testing.T) {
+	// A probe that outlives its timeout only sees its context canceled.
+	ctx, cancel := newRTTTracker().probeContext(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if err := probeError(ctx, ctx.Err()); errorKind(err) != ErrorKindTimeout || err.Error() != context.Canceled.Error() {
+		t.Errorf("timed out probe: error %q, kind %q; want %q, %q", err, errorKind(err), context.Canceled, ErrorKindTimeout)
+	}
+
+	// A probe whose run is canceled was not timed out.
+	parent, cancelRun := context.WithCancel(context.Background())
+	ctx, cancel = newRTTTracker().probeContext(parent, time.Minute)
+	defer cancel()
+	cancelRun()
+	<-ctx.Done()
+	if err := probeError(ctx, ctx.Err()); errorKind(err) != ErrorKindCanceled {
+		t.Errorf("canceled probe: kind %q, want %q", errorKind(err), ErrorKindCanceled)
+	}
+
+	if err := probeError(ctx, nil); err != nil {
+		t.Errorf("probeError(nil) = %v", err)
+	}
+}
+
+// TestProbeTimeouts checks that probes against servers that stall the TLS
+// handshake or the first response byte give up as timeouts.
+func TestProbeTimeouts(t *testing.T) {
+	stalledHandshake, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalledHandshake.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+		}()
+		for {
+			c, err := stalledHandshake.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+
+	release := make(chan struct{})
+	stalledResponse := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	stalledResponse.EnableHTTP2 = true
+	stalledResponse.StartTLS()
+	defer stalledResponse.Close()
+	defer close(release)
+
+	for _, tt := range []struct {
+		name, url string
+	}{
+		{"handshake", "https://" + stalledHandshake.Addr().String()},
+		{"first byte", stalledResponse.URL},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			res := runChecks(tt.url, Options{Versions: []string{"HTTP/1.1", "HTTP/2.0"}})
+			if elapsed := time.Since(start); elapsed > 2*adaptiveMaxTimeout {
+				t.Errorf("probes took %v", elapsed)
+			}
+			for _, vr := range res.Results {
+				if vr.NotTested {
+					continue
+				}
+				if vr.Supported || vr.ErrorKind != ErrorKindTimeout {
+					t.Errorf("%s = supported %v, kind %q (%s), want a timeout", vr.Version, vr.Supported, vr.ErrorKind, vr.Detail)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ProbeTimings breaks a probe's last attempt down into its phases, in
+// milliseconds. A phase that ran counts at least 1, so 0 means the probe
+// skipped it, e.g. DNS for an IP address target or everything but TTFB on a
+// reused connection.
+type ProbeTimings struct {
+	DNSMS     int64 `json:"dns_ms,omitempty"`
+	ConnectMS int64 `json:"connect_ms,omitempty"`
+	// TLSMS is the TLS handshake; for HTTP/3 it is the QUIC handshake,
+	// which includes connecting.
+	TLSMS int64 `json:"tls_ms,omitempty"`
+	// TTFBMS is the time from sending the request to its first response
+	// byte, connection setup included.
+	TTFBMS int64 `json:"ttfb_ms,omitempty"`
+}
+
+// probeTimer records the phases of one probe attempt, from httptrace hooks
+// for HTTP/1.x and HTTP/2 and from the QUIC dialer for HTTP/3. Hooks may
+// fire concurrently, e.g. for the addresses of a dual-stack dial.
+type probeTimer struct {
+	start time.Time
+
+	mu                            sync.Mutex
+	dnsStart, connStart, tlsStart time.Time
+	t                             ProbeTimings
+}
+
+// probeTimerKey is the context key under which withProbeTimer stores its
+// timer, for the QUIC dialer.
+type probeTimerKey struct{}
+
+// withProbeTimer starts timing a probe attempt sent with the returned
+// context.
+func withProbeTimer(ctx context.Context) (context.Context, *probeTimer) {
+	pt := &probeTimer{start: time.Now()}
+	ctx = context.WithValue(ctx, probeTimerKey{}, pt)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { pt.mark(&pt.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { pt.since(pt.dnsStart, &pt.t.DNSMS) },
+		ConnectStart: func(string, string) {
+			pt.mark(&pt.connStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				pt.since(pt.connStart, &pt.t.ConnectMS)
+			}
+		},
+		TLSHandshakeStart: func() { pt.mark(&pt.tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				pt.since(pt.tlsStart, &pt.t.TLSMS)
+			}
+		},
+		GotFirstResponseByte: func() { pt.since(pt.start, &pt.t.TTFBMS) },
+	}), pt
+}
+
+func probeTimerFrom(ctx context.Context) *probeTimer {
+	pt, _ := ctx.Value(probeTimerKey{}).(*probeTimer)
+	return pt
+}
+
+// mark sets *at to now unless an earlier hook already set it.
+func (pt *probeTimer) mark(at *time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+// since records the time from start in *ms, once.
+func (pt *probeTimer) since(start time.Time, ms *int64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if !start.IsZero() && *ms == 0 {
+		*ms = max(time.Since(start).Milliseconds(), 1)
+	}
+}
+
+// handshake records a QUIC handshake that started at start.
+func (pt *probeTimer) handshake(start time.Time) {
+	if pt != nil {
+		pt.since(start, &pt.t.TLSMS)
+	}
+}
+
+// timings returns what was recorded, or nil if nothing was. gotResponse
+// stands in for the first-byte hook, which the HTTP/3 client does not call:
+// response headers have arrived by the time the request returns.
+func (pt *probeTimer) timings(gotResponse bool) *ProbeTimings {
+	if gotResponse {
+		pt.since(pt.start, &pt.t.TTFBMS)
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if pt.t == (ProbeTimings{}) {
+		return nil
+	}
+	t := pt.t
+	return &t
+}
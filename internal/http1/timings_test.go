@@ -0,0 +1,25 @@
+package http1
+
+import "testing"
+
+func TestProbeTimings(t *testing.T) {
+	port := startLocalServers(t)
+	res := runChecks("https://localhost:"+port, Options{Versions: []string{"HTTP/1.1", "HTTP/2.0", "HTTP/3.0"}})
+	for _, vr := range res.Results {
+		if vr.NotTested {
+			continue
+		}
+		if !vr.Supported {
+			t.Errorf("%s not supported: %s", vr.Version, vr.Detail)
+			continue
+		}
+		tm := vr.Timings
+		if tm == nil || tm.TLSMS == 0 || tm.TTFBMS < tm.TLSMS {
+			t.Errorf("%s timings = %+v, want a TLS handshake before the first byte", vr.Version, tm)
+			continue
+		}
+		if vr.Version != "HTTP/3.0" && (tm.DNSMS == 0 || tm.ConnectMS == 0) {
+			t.Errorf("%s timings = %+v, want DNS and connect", vr.Version, tm)
+		}
+	}
+}
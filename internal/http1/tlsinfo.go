@@ -0,0 +1,88 @@
+package http1
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+)
+
+// TLSInfo details a target's TLS handshakes, beyond CheckResult.TLSVersion.
+// The version, cipher suite and certificate are those of the HTTP/2 probe's
+// handshake, or, when it failed, the HTTP/1.1 or HTTP/3 probe's.
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	// Group is the key exchange group, when known: X25519MLKEM768 when the
+	// server accepts it, which the probes then prefer. A classical group is
+	// not reported by the TLS stack, so Group is empty then.
+	Group string `json:"group,omitempty"`
+	// ALPN maps each probe that completed a handshake to the protocol it
+	// negotiated, e.g. {"HTTP/2.0": "h2", "HTTP/3.0": "h3"}; "" means the
+	// server chose none.
+	ALPN map[string]string `json:"alpn"`
+	// Resumed reports whether a fresh connection after the HTTP/2 probe
+	// resumed its TLS session; it is only tried with
+	// Options.CheckEarlyData.
+	Resumed     bool         `json:"resumed,omitempty"`
+	Certificate *CertSummary `json:"certificate,omitempty"`
+}
+
+// CertSummary summarizes a server's leaf certificate.
+type CertSummary struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	KeyAlgorithm       string    `json:"key_algorithm"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	SHA256             string    `json:"sha256"`
+}
+
+// tlsProbes are the probes whose handshakes TLSInfo reports, in order of
+// preference for its version, cipher suite and certificate.
+var tlsProbes = [...]string{"HTTP/2.0", "HTTP/1.1", "HTTP/3.0"}
+
+// newTLSInfo builds a TLSInfo from the probes' connection states, keyed by
+// version; it is nil when no probe completed a handshake.
+func newTLSInfo(states map[string]*tls.ConnectionState, resumed, pq bool) *TLSInfo {
+	var info *TLSInfo
+	for _, probe := range tlsProbes {
+		cs := states[probe]
+		if cs == nil {
+			continue
+		}
+		if info == nil {
+			info = &TLSInfo{
+				Version:     tls.VersionName(cs.Version),
+				CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+				ALPN:        make(map[string]string),
+				Resumed:     resumed,
+			}
+			if len(cs.PeerCertificates) > 0 {
+				info.Certificate = newCertSummary(cs.PeerCertificates[0])
+			}
+			if pq {
+				info.Group = keyExchangePQ
+			}
+		}
+		info.ALPN[probe] = cs.NegotiatedProtocol
+	}
+	return info
+}
+
+func newCertSummary(cert *x509.Certificate) *CertSummary {
+	sum := sha256.Sum256(cert.Raw)
+	return &CertSummary{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		DNSNames:           cert.DNSNames,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyAlgorithm:       cert.PublicKeyAlgorithm.String(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SHA256:             hex.EncodeToString(sum[:]),
+	}
+}
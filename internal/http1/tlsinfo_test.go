@@ -0,0 +1,56 @@
+package http1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTLSInfo(t *testing.T) {
+	port := startLocalServers(t)
+	res := runChecks("https://localhost:"+port, Options{Versions: []string{"HTTP/1.1", "HTTP/2.0", "HTTP/3.0"}})
+	info := res.TLS
+	if info == nil {
+		t.Fatal("no TLS info")
+	}
+	if info.Version != res.TLSVersion || !strings.HasPrefix(info.CipherSuite, "TLS_") {
+		t.Errorf("version %q, cipher suite %q", info.Version, info.CipherSuite)
+	}
+	wantH3 := ""
+	if h3Available {
+		wantH3 = "h3"
+	}
+	if info.ALPN["HTTP/2.0"] != "h2" || info.ALPN["HTTP/3.0"] != wantH3 {
+		t.Errorf("ALPN = %v, want HTTP/3.0 %q", info.ALPN, wantH3)
+	}
+	if _, ok := info.ALPN["HTTP/1.0"]; ok {
+		t.Errorf("ALPN = %v, want no entry for the plain-HTTP probe", info.ALPN)
+	}
+	cert := info.Certificate
+	if cert == nil || cert.SHA256 != res.CertSHA256 || cert.Issuer != res.CertIssuer || cert.NotAfter.IsZero() {
+		t.Errorf("certificate = %+v", cert)
+	}
+
+	if newTLSInfo(nil, false, false) != nil {
+		t.Error("TLS info without any handshake")
+	}
+}
+
+func TestRedactorTLSInfo(t *testing.T) {
+	r := NewRedactor([]byte("k"))
+	res := CheckResult{
+		Target: "example.com",
+		TLS: &TLSInfo{Certificate: &CertSummary{
+			Subject:  "CN=example.com",
+			DNSNames: []string{"example.com", "internal.example.net"},
+			SHA256:   "abcd",
+		}},
+	}
+	out := r.Result(res)
+	cert := out.TLS.Certificate
+	if strings.Contains(cert.Subject, "example.com") || cert.DNSNames[0] != r.Token("example.com") || cert.DNSNames[1] == "internal.example.net" || cert.SHA256 == "abcd" {
+		t.Errorf("certificate not redacted: %+v", cert)
+	}
+	if res.TLS.Certificate.DNSNames[1] != "internal.example.net" {
+		t.Error("Result modified its input")
+	}
+}
@@ -0,0 +1,177 @@
+package http1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Low-resource mode limits. The worker cap keeps a Raspberry Pi-class box
+// responsive; the QUIC windows bound per-connection receive buffers well
+// below quic-go's defaults, which are sized for bulk transfers.
+const (
+	lowResourceMaxWorkers       = 4
+	lowResourceSessionCacheSize = 64
+	lowResourceIOBufferSize     = 1 << 10
+)
+
+// idleConnTimeout closes the idle connections of transports a Checker
+// keeps for its lifetime, which would otherwise pile up one per target.
+const idleConnTimeout = 30 * time.Second
+
+// probeTransports are the clients the version probes use. Normally each
+// target gets its own set so connection state never leaks between targets;
+// in low-resource mode one set, including a single UDP socket for all QUIC
+// traffic, is shared by the whole scan, and a Checker shares one for its
+// lifetime.
+type probeTransports struct {
+	dial  func(ctx context.Context, network, addr string) (net.Conn, error)
+	quic  *quicDialer
+	h1    *http.Client
+	h2    *http.Client
+	h3    *http.Client
+	h2TLS *tls.Config
+	h3TLS *tls.Config
+
+	// h3Idle drops the HTTP/3 client's idle connections.
+	h3Idle  func()
+	closers []func() error
+}
+
+// newProbeTransports builds the probe clients. We use separate TLS configs
+// for HTTP/1.x and HTTP/2 so that HTTP/1.x probes never accidentally
+// negotiate HTTP/2 via ALPN (which would cause "malformed HTTP response"
+// errors when parsed as HTTP/1.x).
+func newProbeTransports(opts Options) (*probeTransports, error) {
+	lowResource := opts.LowResource
+	// Probe timeouts adapt to the first TCP connect time we observe; the
+	// clients themselves carry no timeout and rely on per-probe contexts.
+	dial := rttDial(opts.dialer())
+	pt := &probeTransports{
+		dial: dial,
+		quic: &quicDialer{},
+	}
+	if opts.Proxy != nil {
+		// The HTTP clients reach the proxy with dial themselves; raw TLS
+		// probes tunnel through it.
+		pt.dial = proxiedDial(opts.Proxy, dial)
+	}
+	cacheSize := sessionCacheSize
+	if lowResource {
+		cacheSize = lowResourceSessionCacheSize
+	}
+
+	baseTLS := opts.tlsConfig()
+
+	h1TLS := baseTLS.Clone()
+	h1TLS.NextProtos = []string{"http/1.1"}
+	h1Transport := &http.Transport{
+		ForceAttemptHTTP2: false,
+		TLSClientConfig:   h1TLS,
+		DialContext:       dial,
+		Proxy:             opts.Proxy,
+		IdleConnTimeout:   idleConnTimeout,
+	}
+	// Probes report on the server they were pointed at, so redirects are
+	// returned rather than followed; the plain-HTTP probe inspects them.
+	pt.h1 = &http.Client{
+		Transport:     h1Transport,
+		CheckRedirect: noRedirects,
+	}
+
+	// The HTTP/2 and HTTP/3 probes keep their session tickets so the early
+	// data follow-ups can attempt resumption against the same server.
+	pt.h2TLS = baseTLS.Clone()
+	pt.h2TLS.NextProtos = []string{"h2", "http/1.1"}
+	pt.h2TLS.ClientSessionCache = tls.NewLRUClientSessionCache(cacheSize)
+	h2Transport := &http.Transport{
+		TLSClientConfig: pt.h2TLS,
+		DialContext:     dial,
+		Proxy:           opts.Proxy,
+		IdleConnTimeout: idleConnTimeout,
+	}
+	// Enable HTTP/2 on this transport so that when servers speak h2 via ALPN
+	// we parse the response correctly as HTTP/2 instead of HTTP/1.x.
+	_ = http2.ConfigureTransport(h2Transport)
+	// Following a redirect would credit the destination's HSTS, TLS
+	// version and certificate to the target.
+	pt.h2 = &http.Client{
+		Transport:     h2Transport,
+		CheckRedirect: noRedirects,
+	}
+
+	if err := pt.setupH3(opts, cacheSize); err != nil {
+		return nil, err
+	}
+
+	if lowResource {
+		// Shared transports would otherwise keep an idle connection to
+		// every target scanned so far.
+		for _, tr := range []*http.Transport{h1Transport, h2Transport} {
+			tr.DisableKeepAlives = true
+			tr.ReadBufferSize = lowResourceIOBufferSize
+			tr.WriteBufferSize = lowResourceIOBufferSize
+		}
+	}
+	return pt, nil
+}
+
+// noRedirects makes a client return redirects instead of following them.
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// sharedTransports returns the transports a multi-target scan shares in
+// low-resource mode, and a function that releases them. It returns nil
+// (per-target transports) otherwise, or if the shared socket cannot be
+// opened.
+func sharedTransports(opts Options) (*probeTransports, func()) {
+	if !opts.LowResource {
+		return nil, func() {}
+	}
+	pt, err := newProbeTransports(opts)
+	if err != nil {
+		return nil, func() {}
+	}
+	return pt, pt.close
+}
+
+// done releases per-target resources once a target's probes finish. Shared
+// transports only drop their idle QUIC connections.
+func (pt *probeTransports) done(shared bool) {
+	if shared {
+		pt.h3Idle()
+		return
+	}
+	pt.close()
+}
+
+func (pt *probeTransports) close() {
+	for _, c := range pt.closers {
+		_ = c()
+	}
+}
+
+// resolveUDPAddr resolves addr to its first address on network ("ip",
+// "ip4" or "ip6").
+func resolveUDPAddr(ctx context.Context, network, addr string) (*net.UDPAddr, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := netResolver(ctx).LookupNetIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	return net.UDPAddrFromAddrPort(netip.AddrPortFrom(ips[0].Unmap(), uint16(port))), nil
+}
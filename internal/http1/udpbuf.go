@@ -0,0 +1,83 @@
+package http1
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+// wantUDPBufferSize is the socket buffer size quic-go asks for. Smaller
+// buffers drop packets under load, which can make a working HTTP/3 endpoint
+// time out and be reported as unsupported.
+const wantUDPBufferSize = 7 << 20
+
+// UDPBufferReport describes the UDP socket buffers this host grants QUIC.
+type UDPBufferReport struct {
+	// Receive and Send are the sizes obtained after trying to raise them to
+	// Wanted; 0 means they could not be read on this platform.
+	Receive    int    `json:"receive_bytes"`
+	Send       int    `json:"send_bytes"`
+	Wanted     int    `json:"wanted_bytes"`
+	Sufficient bool   `json:"sufficient"`
+	Guidance   string `json:"guidance,omitempty"`
+}
+
+// udpBufferReport runs the check on first use only, since every QUIC
+// socket of the process hits the same limits.
+var udpBufferReport = sync.OnceValue(func() UDPBufferReport {
+	return checkUDPBuffers(raiseSocketBuffers)
+})
+
+// CheckUDPBuffers tries to raise a UDP socket's buffers to what quic-go
+// wants (forcing past the system limit where the process is privileged to)
+// and reports what it got. The result is computed once per process.
+func CheckUDPBuffers() UDPBufferReport {
+	return udpBufferReport()
+}
+
+// checkUDPBuffers is CheckUDPBuffers, reading the buffer sizes a socket got
+// with raise.
+func checkUDPBuffers(raise func(raw syscall.RawConn, size int) (recv, send int, err error)) UDPBufferReport {
+	r := UDPBufferReport{Wanted: wantUDPBufferSize}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		r.Guidance = fmt.Sprintf("could not open a UDP socket: %v", err)
+		return r
+	}
+	defer conn.Close()
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		r.Guidance = fmt.Sprintf("could not inspect UDP socket: %v", err)
+		return r
+	}
+	_ = conn.SetReadBuffer(wantUDPBufferSize)
+	_ = conn.SetWriteBuffer(wantUDPBufferSize)
+	r.Receive, r.Send, err = raise(raw, wantUDPBufferSize)
+	if err != nil {
+		// Nothing to compare against; assume the platform default is fine.
+		r.Sufficient = true
+		return r
+	}
+
+	r.Sufficient = r.Receive >= wantUDPBufferSize && r.Send >= wantUDPBufferSize
+	if !r.Sufficient {
+		r.Guidance = fmt.Sprintf("UDP buffers are %d KiB receive / %d KiB send (want %d KiB); "+
+			"HTTP/3 probes may lose packets and report false negatives under load. %s",
+			r.Receive/1024, r.Send/1024, wantUDPBufferSize/1024, udpBufferFix())
+	}
+	return r
+}
+
+// udpBufferFix suggests how to raise the system limit.
+func udpBufferFix() string {
+	switch runtime.GOOS {
+	case "linux":
+		return "Raise the limit with: sysctl -w net.core.rmem_max=7500000 net.core.wmem_max=7500000"
+	case "darwin", "freebsd", "openbsd", "netbsd":
+		return "Raise the limit with: sysctl -w kern.ipc.maxsockbuf=8441037"
+	}
+	return "See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes"
+}
@@ -0,0 +1,26 @@
+package http1
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// raiseSocketBuffers forces both buffers to size, which succeeds past
+// net.core.[rw]mem_max only with CAP_NET_ADMIN, and returns the resulting
+// sizes. Linux reports double the requested size to account for
+// bookkeeping overhead, so the values are halved.
+func raiseSocketBuffers(raw syscall.RawConn, size int) (recv, send int, err error) {
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		_ = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUFFORCE, size)
+		_ = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUFFORCE, size)
+		if recv, serr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF); serr != nil {
+			return
+		}
+		send, serr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF)
+	}); err != nil {
+		return 0, 0, err
+	}
+	return recv / 2, send / 2, serr
+}
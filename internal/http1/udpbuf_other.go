@@ -0,0 +1,13 @@
+//go:build !linux
+
+package http1
+
+import (
+	"errors"
+	"syscall"
+)
+
+// raiseSocketBuffers cannot read buffer sizes back portably outside Linux.
+func raiseSocketBuffers(syscall.RawConn, int) (recv, send int, err error) {
+	return 0, 0, errors.New("not supported on this platform")
+}
@@ -0,0 +1,44 @@
+package http1
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCheckUDPBuffers(t *testing.T) {
+	granted := func(recv, send int, err error) func(syscall.RawConn, int) (int, int, error) {
+		return func(_ syscall.RawConn, size int) (int, int, error) {
+			if size != wantUDPBufferSize {
+				t.Errorf("raised to %d, want %d", size, wantUDPBufferSize)
+			}
+			return recv, send, err
+		}
+	}
+	tests := []struct {
+		name       string
+		raise      func(syscall.RawConn, int) (int, int, error)
+		sufficient bool
+		guidance   string
+	}{
+		{"raised", granted(wantUDPBufferSize, 8<<20, nil), true, ""},
+		{"receive capped", granted(208<<10, wantUDPBufferSize, nil), false, "UDP buffers are 208 KiB receive / 7168 KiB send (want 7168 KiB)"},
+		{"send capped", granted(wantUDPBufferSize, 208<<10, nil), false, "7168 KiB receive / 208 KiB send"},
+		{"unreadable", granted(0, 0, errors.New("not supported on this platform")), true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := checkUDPBuffers(tt.raise)
+			if r.Wanted != wantUDPBufferSize || r.Sufficient != tt.sufficient {
+				t.Errorf("report = %+v, want sufficient %v", r, tt.sufficient)
+			}
+			if tt.guidance == "" && r.Guidance != "" {
+				t.Errorf("guidance = %q, want none", r.Guidance)
+			}
+			if tt.guidance != "" && (!strings.Contains(r.Guidance, tt.guidance) || !strings.HasSuffix(r.Guidance, udpBufferFix())) {
+				t.Errorf("guidance = %q, want %q and how to fix it", r.Guidance, tt.guidance)
+			}
+		})
+	}
+}
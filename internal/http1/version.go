@@ -0,0 +1,31 @@
+package http1
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Version is the tool version, set at build time with
+// -ldflags "-X http1.dev/internal/http1.Version=v1.2.3". Binaries built with
+// "go install module@version" report the module version instead.
+var Version = "dev"
+
+var (
+	userAgentOnce sync.Once
+	userAgent     string
+)
+
+// DefaultUserAgent identifies the scanner and its version to the servers it
+// probes, e.g. "http1/v1.2.3 (+https://http1.dev)".
+func DefaultUserAgent() string {
+	userAgentOnce.Do(func() {
+		v := Version
+		if v == "dev" {
+			if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+				v = info.Main.Version
+			}
+		}
+		userAgent = "http1/" + v + " (+https://http1.dev)"
+	})
+	return userAgent
+}
@@ -0,0 +1,161 @@
+package http1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Rules a Waiver can name besides the anomaly types, which waive monitor
+// alerts: RuleMinGrade is Policy.MinGrade, and each required version has
+// its own, e.g. "require_h3" for HTTP/3. RuleAll waives everything.
+const (
+	RuleMinGrade = "min_grade"
+	RuleAll      = "*"
+)
+
+// requireRules names the Policy.Require rule of each version.
+var requireRules = map[string]string{
+	"HTTP/1.0": "require_h1.0",
+	"HTTP/1.1": "require_h1.1",
+	"HTTP/2.0": "require_h2",
+	"HTTP/3.0": "require_h3",
+}
+
+// WaiverRules lists the rules a Waiver can name.
+func WaiverRules() []string {
+	rules := []string{RuleMinGrade}
+	for _, v := range []string{"HTTP/1.0", "HTTP/1.1", "HTTP/2.0", "HTTP/3.0"} {
+		rules = append(rules, requireRules[v])
+	}
+	return append(append(rules, AnomalyTypes...), RuleAll)
+}
+
+// Waiver exempts a target from one policy rule or monitor alert until it
+// expires. Waivers are meant to be reviewed, so each names who approved it
+// and none lasts forever.
+type Waiver struct {
+	// Target is the waived target; like notes, waivers match any spelling
+	// of it.
+	Target   string    `json:"target"`
+	Rule     string    `json:"rule"`
+	Expires  time.Time `json:"expires"`
+	Approver string    `json:"approver"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// UnmarshalJSON decodes a waiver, taking any expiry ParseExpiry does, e.g.
+// "2025-06" for the end of June 2025.
+func (w *Waiver) UnmarshalJSON(data []byte) error {
+	type plain Waiver
+	var v struct {
+		plain
+		Expires string `json:"expires"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*w = Waiver(v.plain)
+	if v.Expires == "" {
+		return nil
+	}
+	t, err := ParseExpiry(v.Expires)
+	if err != nil {
+		return err
+	}
+	w.Expires = t
+	return nil
+}
+
+// Active reports whether w still applies at time now.
+func (w Waiver) Active(now time.Time) bool {
+	return now.Before(w.Expires)
+}
+
+// covers reports whether w is about target and rule, active or not.
+func (w Waiver) covers(target, rule string) bool {
+	return (w.Rule == rule || w.Rule == RuleAll) && noteKey(w.Target) == noteKey(target)
+}
+
+func (w Waiver) validate() error {
+	if _, err := ParseTarget(w.Target); err != nil {
+		return err
+	}
+	if rules := WaiverRules(); !slices.Contains(rules, w.Rule) {
+		return fmt.Errorf("%s: unknown rule %q (want %s)", w.Target, w.Rule, strings.Join(rules, ", "))
+	}
+	if strings.TrimSpace(w.Approver) == "" {
+		return fmt.Errorf("%s: waiver of %s needs an approver", w.Target, w.Rule)
+	}
+	if w.Expires.IsZero() {
+		return fmt.Errorf("%s: waiver of %s needs an expiry", w.Target, w.Rule)
+	}
+	return nil
+}
+
+// Waivers is a set of waivers, as read from a waivers file.
+type Waivers []Waiver
+
+// ReadWaivers reads a JSON array of waivers, checking that each names a
+// valid target, a known rule, an approver and an expiry.
+func ReadWaivers(r io.Reader) (Waivers, error) {
+	var ws Waivers
+	if err := json.NewDecoder(r).Decode(&ws); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i, w := range ws {
+		if err := w.validate(); err != nil {
+			return nil, fmt.Errorf("waiver %d: %w", i+1, err)
+		}
+	}
+	return ws, nil
+}
+
+// LoadWaivers reads the waivers file at path.
+func LoadWaivers(path string) (Waivers, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ws, err := ReadWaivers(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid waivers file %s: %w", path, err)
+	}
+	return ws, nil
+}
+
+// Find returns the waiver of rule for target that applies at time now, or
+// failing that the one that expired last, and whether it applies. It
+// returns nil when no waiver names them.
+func (ws Waivers) Find(target, rule string, now time.Time) (*Waiver, bool) {
+	var expired *Waiver
+	for i, w := range ws {
+		if !w.covers(target, rule) {
+			continue
+		}
+		if w.Active(now) {
+			return &ws[i], true
+		}
+		if expired == nil || w.Expires.After(expired.Expires) {
+			expired = &ws[i]
+		}
+	}
+	return expired, false
+}
+
+// Expired returns the waivers that have expired by time now, for review.
+func (ws Waivers) Expired(now time.Time) Waivers {
+	var out Waivers
+	for _, w := range ws {
+		if !w.Active(now) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package http1
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Warning codes, as found in CheckResult.Warnings.
+const (
+	WarningUDPBuffer       = "udp_buffer_small"
+	WarningQUICCalibration = "quic_calibration_failed"
+	WarningH3Unproxied     = "h3_unproxied"
+	WarningRateLimited     = "rate_limited"
+	WarningCertExpiring    = "cert_expiring"
+	WarningCertExpired     = "cert_expired"
+	WarningCertChanged     = "cert_changed"
+	WarningCrossCheck      = "cross_check_mismatch"
+	WarningAltSvc          = "alt_svc_unverified"
+)
+
+// certExpiryHorizon is how close to expiry a certificate draws a warning.
+const certExpiryHorizon = 30 * 24 * time.Hour
+
+// Warning is a heads-up about a check rather than a probe outcome: a
+// condition of the scanner or the target worth knowing when reading the
+// results, such as undersized UDP buffers or a certificate about to expire.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// checkWarnings collects the warnings for a finished check. probedH3 tells
+// whether HTTP/3 was probed, and notAfter is the expiry of the certificate
+// the HTTP/2 probe saw, if any.
+func checkWarnings(res *CheckResult, probedH3 bool, notAfter, now time.Time) []Warning {
+	var out []Warning
+	if res.UDPBuffer != nil {
+		out = append(out, Warning{WarningUDPBuffer, res.UDPBuffer.Guidance})
+	}
+	if cal := res.QUICCalibration; cal != nil {
+		out = append(out, Warning{WarningQUICCalibration, "QUIC calibration against reference hosts failed, so an HTTP/3 failure may be this network's: " + cal.Detail})
+	}
+	if res.Proxied && probedH3 {
+		out = append(out, Warning{WarningH3Unproxied, "HTTP/3 cannot go through the proxy and was probed directly"})
+	}
+	for _, vr := range res.Results {
+		if vr.status == http.StatusTooManyRequests {
+			out = append(out, Warning{WarningRateLimited, fmt.Sprintf("the %s probe was answered with 429 Too Many Requests; later results may be throttled, so consider a lower scan rate", vr.Version)})
+			break
+		}
+	}
+	if a := res.AltSvc; a != nil && a.Advertised && !a.Verified {
+		var at []string
+		for _, e := range a.Endpoints {
+			at = append(at, e.Authority())
+		}
+		out = append(out, Warning{WarningAltSvc, fmt.Sprintf("Alt-Svc advertises HTTP/3 at %s, but no advertised endpoint answered over HTTP/3", strings.Join(at, ", "))})
+	}
+	if !notAfter.IsZero() {
+		left := notAfter.Sub(now)
+		switch {
+		case left < 0:
+			out = append(out, Warning{WarningCertExpired, fmt.Sprintf("certificate expired on %s", notAfter.UTC().Format(time.DateOnly))})
+		case left < certExpiryHorizon:
+			out = append(out, Warning{WarningCertExpiring, fmt.Sprintf("certificate expires on %s, in %d days", notAfter.UTC().Format(time.DateOnly), int(left.Hours()/24))})
+		}
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package http1
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckWarnings(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	codes := func(ws []Warning) []string {
+		var out []string
+		for _, w := range ws {
+			out = append(out, w.Code)
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		res      CheckResult
+		notAfter time.Time
+		want     []string
+	}{
+		{"quiet", CheckResult{}, now.AddDate(1, 0, 0), nil},
+		{"expiring", CheckResult{}, now.AddDate(0, 0, 10), []string{WarningCertExpiring}},
+		{"expired", CheckResult{}, now.AddDate(0, 0, -1), []string{WarningCertExpired}},
+		{"udp and calibration", CheckResult{UDPBuffer: &UDPBufferReport{}, QUICCalibration: &QUICCalibration{}}, time.Time{}, []string{WarningUDPBuffer, WarningQUICCalibration}},
+		{"rate limited", CheckResult{Results: []VersionResult{
+			{Version: "HTTP/1.1", status: http.StatusTooManyRequests},
+			{Version: "HTTP/2.0", status: http.StatusTooManyRequests},
+		}}, time.Time{}, []string{WarningRateLimited}},
+	}
+	for _, tt := range tests {
+		if got := codes(checkWarnings(&tt.res, true, tt.notAfter, now)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: warnings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package http1
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// watchdogSlack is added to the longest a target's probes can legitimately
+// run before the watchdog gives up on them.
+const watchdogSlack = 30 * time.Second
+
+// internalErrorPrefix starts the detail of every probe lost to a panic or
+// the watchdog, to tell scanner bugs from the target's behavior.
+const internalErrorPrefix = "internal probe error: "
+
+// ProbeError reports a probe that panicked or never returned. The rest of
+// the result is still valid; only that probe's findings are missing.
+type ProbeError struct {
+	// Probe is the row's version (e.g. "HTTP/3.0"), the result field of an
+	// auxiliary probe (e.g. "ech"), or "target" when the whole target was
+	// lost.
+	Probe  string `json:"probe"`
+	Detail string `json:"detail"`
+}
+
+// probeGuard collects the panics of one target's probe goroutines.
+type probeGuard struct {
+	mu     sync.Mutex
+	errors []ProbeError
+}
+
+// catch is deferred by each probe goroutine. It turns a panic into a
+// ProbeError and, for a protocol probe, into an error row in vr, so one
+// crashing probe costs its own finding rather than the whole scan. Panics
+// on goroutines started by the probe itself (e.g. inside quic-go) are
+// beyond its reach.
+func (g *probeGuard) catch(probe string, vr *VersionResult) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	detail := fmt.Sprintf("%spanic: %v", internalErrorPrefix, r)
+	if vr != nil {
+		*vr = VersionResult{Version: probe, Error: true, Detail: detail}
+	}
+	g.mu.Lock()
+	g.errors = append(g.errors, ProbeError{Probe: probe, Detail: detail})
+	g.mu.Unlock()
+}
+
+// watchdogTimeout bounds one target's probes. The HTTP/3 probe has the
+// longest chain: every attempt and its backoff, then three follow-up
+// handshakes, each capped by adaptiveMaxTimeout.
+func watchdogTimeout(retries int) time.Duration {
+	d := time.Duration(retries+4)*adaptiveMaxTimeout + watchdogSlack
+	for n := 1; n <= retries && n <= 16; n++ {
+		d += retryBaseDelay << (n - 1)
+	}
+	return d
+}
+
+// guardCheck runs check on its own goroutine and gives up on it after
+// limit, plus any time rtt's probes spent waiting for the rate limiter, so
+// a probe stuck in a library that ignores its context, or a panic outside
+// the probe goroutines, fails this target with an internal probe error
+// instead of hanging or crashing a bulk scan or the web server. An
+// abandoned check keeps running until it returns; its result is dropped.
+func guardCheck(target string, opts Options, limit time.Duration, rtt *rttTracker, check func() CheckResult) CheckResult {
+	done := make(chan CheckResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- internalErrorResult(target, opts, fmt.Sprintf("%spanic: %v", internalErrorPrefix, r))
+			}
+		}()
+		done <- check()
+	}()
+
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+	var extended time.Duration
+	for {
+		select {
+		case res := <-done:
+			return res
+		case <-timer.C:
+			if throttled := time.Duration(rtt.throttled.Load()); throttled > extended {
+				timer.Reset(throttled - extended)
+				extended = throttled
+				continue
+			}
+			return internalErrorResult(target, opts, fmt.Sprintf("%sno result after %s; probes abandoned", internalErrorPrefix, (limit+extended).Round(time.Second)))
+		case <-opts.context().Done():
+			return unscannedResult(target, opts, opts.stopReason()+"; probes abandoned")
+		}
+	}
+}
+
+// unscannedResult is the result of a target a cancelled scan or
+// Options.Deadline cut off: an error row saying so, and no findings.
+func unscannedResult(target string, opts Options, detail string) CheckResult {
+	res := CheckResult{
+		Target:    target,
+		Vantage:   opts.Vantage,
+		Results:   []VersionResult{{Version: "error", Error: true, Detail: detail}},
+		Unscanned: true,
+	}
+	if t, err := ParseTarget(target); err == nil {
+		res.Target, res.Labels = t.Raw, t.Labels
+	}
+	return res
+}
+
+func internalErrorResult(target string, opts Options, detail string) CheckResult {
+	res := CheckResult{
+		Target:  target,
+		Vantage: opts.Vantage,
+		Results: []VersionResult{{
+			Version: "error",
+			Error:   true,
+			Detail:  detail,
+		}},
+		ProbeErrors: []ProbeError{{Probe: "target", Detail: detail}},
+	}
+	if t, err := ParseTarget(target); err == nil {
+		res.Target, res.Labels = t.Raw, t.Labels
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package http1
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProbeGuardCatch(t *testing.T) {
+	var guard probeGuard
+	results := make([]VersionResult, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer guard.catch("HTTP/3.0", &results[0])
+		var m map[string]int
+		m["boom"]++
+	}()
+	go func() {
+		defer wg.Done()
+		defer guard.catch("ech", nil)
+		results[1] = VersionResult{Version: "HTTP/1.1", Supported: true}
+	}()
+	wg.Wait()
+
+	if vr := results[0]; vr.Version != "HTTP/3.0" || !vr.Error || !strings.HasPrefix(vr.Detail, internalErrorPrefix+"panic:") {
+		t.Errorf("panicking probe row = %+v, want an internal probe error", vr)
+	}
+	if !results[1].Supported {
+		t.Errorf("healthy probe row = %+v, want it untouched", results[1])
+	}
+	if len(guard.errors) != 1 || guard.errors[0].Probe != "HTTP/3.0" {
+		t.Errorf("probe errors = %+v, want one for HTTP/3.0", guard.errors)
+	}
+}
+
+func TestGuardCheck(t *testing.T) {
+	opts := Options{Vantage: "lab"}
+	tests := []struct {
+		name   string
+		check  func() CheckResult
+		detail string
+	}{
+		{"ok", func() CheckResult { return CheckResult{Target: "a.test", Grade: "A"} }, ""},
+		{"panic", func() CheckResult { panic("nil dereference") }, internalErrorPrefix + "panic: nil dereference"},
+		{"hang", func() CheckResult { select {} }, internalErrorPrefix + "no result after"},
+	}
+	for _, tt := range tests {
+		res := guardCheck("a.test", opts, 50*time.Millisecond, newRTTTracker(), tt.check)
+		if tt.detail == "" {
+			if res.Grade != "A" || res.ProbeErrors != nil {
+				t.Errorf("%s: got %+v, want the check's result", tt.name, res)
+			}
+			continue
+		}
+		if res.Target != "a.test" || res.Vantage != "lab" || len(res.Results) != 1 || !res.Results[0].Error {
+			t.Errorf("%s: got %+v, want an error row for a.test", tt.name, res)
+			continue
+		}
+		if !strings.HasPrefix(res.Results[0].Detail, tt.detail) {
+			t.Errorf("%s: detail %q, want prefix %q", tt.name, res.Results[0].Detail, tt.detail)
+		}
+		if len(res.ProbeErrors) != 1 || res.ProbeErrors[0].Probe != "target" {
+			t.Errorf("%s: probe errors %+v, want one for the target", tt.name, res.ProbeErrors)
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	// A check still running at the deadline is abandoned.
+	opts := Options{Deadline: time.Now().Add(50 * time.Millisecond)}.forRun()
+	defer opts.endRun()
+	res := guardCheck("a.test prod", opts, time.Minute, newRTTTracker(), func() CheckResult { select {} })
+	if !res.Unscanned || res.Target != "a.test" || len(res.Labels) != 1 || res.Grade != "" || res.ProbeErrors != nil {
+		t.Errorf("abandoned check = %+v, want an unscanned a.test", res)
+	}
+
+	// Targets reached after it are not probed at all.
+	var got []CheckResult
+	CheckHTTPVersionsEach([]string{"a.test", "b.test"}, Options{Deadline: time.Now().Add(-time.Second)}, func(res CheckResult) {
+		got = append(got, res)
+	})
+	if len(got) != 2 {
+		t.Fatalf("got %d results, want 2", len(got))
+	}
+	for _, res := range got {
+		if !res.Unscanned || !strings.HasPrefix(res.Results[0].Detail, "not scanned") {
+			t.Errorf("late target = %+v, want it unscanned", res)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package http1
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Projection is the grade a scanned host would get after one change, as
+// computed by GradeConfiguration.
+type Projection struct {
+	Change    string `json:"change"`
+	FromGrade string `json:"from_grade"`
+	ToGrade   string `json:"to_grade"`
+	FromScore int    `json:"from_score"`
+	ToScore   int    `json:"to_score"`
+}
+
+// String phrases p for the result card, e.g. "Enabling HTTP/3 would raise
+// your grade from C to A".
+func (p Projection) String() string {
+	if p.FromGrade != p.ToGrade {
+		return fmt.Sprintf("%s would raise your grade from %s to %s", p.Change, p.FromGrade, p.ToGrade)
+	}
+	return fmt.Sprintf("%s would raise your score from %d to %d", p.Change, p.FromScore, p.ToScore)
+}
+
+// whatIfChange is a single improvement to try on a Configuration. apply
+// reports false when the change is already in place.
+type whatIfChange struct {
+	name  string
+	apply func(c *Configuration) bool
+}
+
+var whatIfChanges = []whatIfChange{
+	{"Enabling HTTP/3", func(c *Configuration) bool {
+		if c.HTTP3 {
+			return false
+		}
+		c.HTTP3 = true
+		addTLSVersion(c, "TLS 1.3")
+		return true
+	}},
+	{"Enabling HTTP/2", func(c *Configuration) bool {
+		if c.HTTP2 {
+			return false
+		}
+		c.HTTP2 = true
+		if len(c.TLSVersions) == 0 {
+			addTLSVersion(c, "TLS 1.3")
+		}
+		return true
+	}},
+	{"Enabling TLS 1.3", func(c *Configuration) bool {
+		return len(c.TLSVersions) > 0 && addTLSVersion(c, "TLS 1.3")
+	}},
+	{"Sending HSTS with a max-age of at least 180 days", func(c *Configuration) bool {
+		if c.HSTSMaxAge >= hstsMinMaxAge {
+			return false
+		}
+		c.HSTSMaxAge = hstsMinMaxAge
+		return true
+	}},
+	{"Redirecting plain HTTP to HTTPS", func(c *Configuration) bool {
+		if c.HTTPSRedirect != nil && *c.HTTPSRedirect {
+			return false
+		}
+		redirect := true
+		c.HTTPSRedirect = &redirect
+		return true
+	}},
+}
+
+func addTLSVersion(c *Configuration, v string) bool {
+	if slices.Contains(c.TLSVersions, v) {
+		return false
+	}
+	c.TLSVersions = append(slices.Clone(c.TLSVersions), v)
+	return true
+}
+
+// ConfigurationOf describes what a scan observed as a Configuration.
+func ConfigurationOf(res CheckResult) Configuration {
+	var c Configuration
+	for _, vr := range res.Results {
+		switch vr.Version {
+		case "HTTP/2.0":
+			c.HTTP2 = vr.Supported
+		case "HTTP/3.0":
+			c.HTTP3 = vr.Supported
+		}
+	}
+	if res.TLSVersion != "" {
+		c.TLSVersions = []string{res.TLSVersion}
+	}
+	if c.HTTP3 {
+		// QUIC always runs TLS 1.3, whatever the h2 probe negotiated.
+		addTLSVersion(&c, "TLS 1.3")
+	}
+	if res.HSTS != nil {
+		c.HSTSMaxAge = res.HSTS.MaxAge
+	}
+	if r := res.HTTPSRedirect; r != nil {
+		switch {
+		case r.Redirects:
+			redirect := true
+			c.HTTPSRedirect = &redirect
+		case r.Status >= 200 && r.Status < 300:
+			c.HTTPSRedirect = new(bool)
+		}
+	}
+	return c
+}
+
+// Projections lists the single changes that would improve res's score,
+// best first, by re-grading its configuration with each change applied.
+func Projections(res CheckResult) []Projection {
+	if res.Grade == "" {
+		// Nothing was graded, e.g. the target did not parse.
+		return nil
+	}
+	base := ConfigurationOf(res)
+	from, err := GradeConfiguration(base)
+	if err != nil {
+		return nil
+	}
+	var out []Projection
+	for _, ch := range whatIfChanges {
+		c := base
+		if !ch.apply(&c) {
+			continue
+		}
+		to, err := GradeConfiguration(c)
+		if err != nil || to.Score <= from.Score {
+			continue
+		}
+		out = append(out, Projection{
+			Change:    ch.name,
+			FromGrade: from.Grade,
+			ToGrade:   to.Grade,
+			FromScore: from.Score,
+			ToScore:   to.Score,
+		})
+	}
+	slices.SortStableFunc(out, func(a, b Projection) int { return b.ToScore - a.ToScore })
+	return out
+}
@@ -0,0 +1,44 @@
+package http1
+
+import "testing"
+
+func TestProjections(t *testing.T) {
+	h2Only := CheckResult{
+		Grade:      "C",
+		TLSVersion: "TLS 1.2",
+		Results: []VersionResult{
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2.0", Supported: true},
+			{Version: "HTTP/3.0"},
+		},
+		HTTPSRedirect: &HTTPSRedirect{Redirects: true, Status: 301},
+	}
+	got := Projections(h2Only)
+	want := []string{
+		"Enabling HTTP/3 would raise your grade from C to A",
+		"Enabling TLS 1.3 would raise your grade from C to B",
+		"Sending HSTS with a max-age of at least 180 days would raise your score from 82 to 85",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("projection %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	best := CheckResult{
+		Grade:         "A",
+		TLSVersion:    "TLS 1.3",
+		Results:       []VersionResult{{Version: "HTTP/2.0", Supported: true}, {Version: "HTTP/3.0", Supported: true}},
+		HSTS:          &HSTSResult{Present: true, MaxAge: 63072000},
+		HTTPSRedirect: &HTTPSRedirect{Redirects: true, Status: 301},
+	}
+	if got := Projections(best); len(got) != 0 {
+		t.Errorf("fully upgraded host got projections %v", got)
+	}
+	if got := Projections(CheckResult{}); got != nil {
+		t.Errorf("ungraded result got projections %v", got)
+	}
+}
@@ -0,0 +1,423 @@
+package http1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Where is a compiled --where filter expression. Expressions are evaluated
+// against the JSON form of a CheckResult, so field names match the JSON
+// output, and the results list is addressable by version:
+//
+//	grade == "F" && results["HTTP/1.0"].supported
+//	score < 90 || !(tls_version == "TLS 1.3")
+//
+// Supported operators are ==, !=, <, <=, >, >=, &&, || and !, with
+// parentheses for grouping. Unknown fields evaluate to null.
+type Where struct {
+	src  string
+	root whereNode
+}
+
+// ParseWhere compiles a filter expression.
+func ParseWhere(expr string) (*Where, error) {
+	toks, err := lexWhere(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.peek().text, p.peek().pos)
+	}
+	return &Where{src: expr, root: root}, nil
+}
+
+// String returns the source expression.
+func (w *Where) String() string { return w.src }
+
+// Match reports whether res satisfies the expression.
+func (w *Where) Match(res CheckResult) bool {
+	return truthy(w.root.eval(resultFields(res)))
+}
+
+// resultFields converts a CheckResult into a generic JSON-shaped map and
+// re-keys the results slice by version so it can be indexed by name.
+func resultFields(res CheckResult) map[string]any {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	if list, ok := m["results"].([]any); ok {
+		byVersion := make(map[string]any, len(list))
+		for _, item := range list {
+			if vr, ok := item.(map[string]any); ok {
+				if v, ok := vr["version"].(string); ok {
+					byVersion[v] = vr
+				}
+			}
+		}
+		m["results"] = byVersion
+	}
+	return m
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type whereTok struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func lexWhere(s string) ([]whereTok, error) {
+	var toks []whereTok
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(s) && s[i] != c {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			toks = append(toks, whereTok{kind: tokString, text: b.String(), pos: start})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			toks = append(toks, whereTok{kind: tokNumber, text: s[start:i], pos: start})
+		case isIdentStart(s[i:]):
+			start := i
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			toks = append(toks, whereTok{kind: tokIdent, text: s[start:i], pos: start})
+		default:
+			start := i
+			two := ""
+			if i+1 < len(s) {
+				two = s[i : i+2]
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				toks = append(toks, whereTok{kind: tokOp, text: two, pos: start})
+				i += 2
+				continue
+			}
+			switch c {
+			case '<', '>', '!', '(', ')', '[', ']', '.':
+				toks = append(toks, whereTok{kind: tokOp, text: string(c), pos: start})
+				i++
+			default:
+				r, _ := utf8.DecodeRuneInString(s[i:])
+				return nil, fmt.Errorf("unexpected character %q at offset %d", r, start)
+			}
+		}
+	}
+	toks = append(toks, whereTok{kind: tokEOF, pos: len(s)})
+	return toks, nil
+}
+
+// isIdentStart reports whether s starts with a letter or underscore,
+// decoding a whole rune so non-ASCII names lex as one identifier.
+func isIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+type whereParser struct {
+	toks []whereTok
+	pos  int
+}
+
+func (p *whereParser) peek() whereTok { return p.toks[p.pos] }
+
+func (p *whereParser) next() whereTok {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *whereParser) acceptOp(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *whereParser) parseOr() (whereNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseAnd() (whereNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseUnary() (whereNode, error) {
+	if p.acceptOp("!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *whereParser) parseCompare() (whereNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokOp {
+		switch t.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return cmpNode{op: t.text, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parsePrimary() (whereNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return litNode{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return litNode{f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return litNode{true}, nil
+		case "false":
+			return litNode{false}, nil
+		case "null":
+			return litNode{nil}, nil
+		}
+		path := []string{t.text}
+		for {
+			if p.acceptOp(".") {
+				id := p.next()
+				if id.kind != tokIdent {
+					return nil, fmt.Errorf("expected field name at offset %d", id.pos)
+				}
+				path = append(path, id.text)
+				continue
+			}
+			if p.acceptOp("[") {
+				key := p.next()
+				if key.kind != tokString && key.kind != tokNumber {
+					return nil, fmt.Errorf("expected string index at offset %d", key.pos)
+				}
+				if !p.acceptOp("]") {
+					return nil, fmt.Errorf("expected ] at offset %d", p.peek().pos)
+				}
+				path = append(path, key.text)
+				continue
+			}
+			break
+		}
+		return pathNode(path), nil
+	case tokOp:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.acceptOp(")") {
+				return nil, fmt.Errorf("expected ) at offset %d", p.peek().pos)
+			}
+			return inner, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+type whereNode interface {
+	eval(env map[string]any) any
+}
+
+type litNode struct{ v any }
+
+func (n litNode) eval(map[string]any) any { return n.v }
+
+type pathNode []string
+
+func (n pathNode) eval(env map[string]any) any {
+	var cur any = env
+	for _, key := range n {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+	return cur
+}
+
+type notNode struct{ inner whereNode }
+
+func (n notNode) eval(env map[string]any) any { return !truthy(n.inner.eval(env)) }
+
+type andNode struct{ left, right whereNode }
+
+func (n andNode) eval(env map[string]any) any {
+	return truthy(n.left.eval(env)) && truthy(n.right.eval(env))
+}
+
+type orNode struct{ left, right whereNode }
+
+func (n orNode) eval(env map[string]any) any {
+	return truthy(n.left.eval(env)) || truthy(n.right.eval(env))
+}
+
+type cmpNode struct {
+	op          string
+	left, right whereNode
+}
+
+func (n cmpNode) eval(env map[string]any) any {
+	l, r := n.left.eval(env), n.right.eval(env)
+	switch n.op {
+	case "==", "!=":
+		// Objects and lists have no equality; comparing them is false
+		// either way rather than a runtime panic.
+		if !scalar(l) || !scalar(r) {
+			return false
+		}
+		return (l == r) == (n.op == "==")
+	}
+
+	// Ordering is only defined between two numbers or two strings.
+	if lf, ok := l.(float64); ok {
+		if rf, ok := r.(float64); ok {
+			return orderHolds(n.op, compareFloat(lf, rf))
+		}
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return orderHolds(n.op, strings.Compare(ls, rs))
+		}
+	}
+	return false
+}
+
+// scalar reports whether v is a JSON null, boolean, number or string.
+func scalar(v any) bool {
+	switch v.(type) {
+	case nil, bool, float64, string:
+		return true
+	}
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func orderHolds(op string, c int) bool {
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case float64:
+		return x != 0
+	}
+	return true
+}
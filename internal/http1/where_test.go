@@ -0,0 +1,65 @@
+package http1
+
+import "testing"
+
+func TestWhereMatch(t *testing.T) {
+	res := CheckResult{
+		Target: "example.com",
+		Port:   "443",
+		Score:  40,
+		Grade:  "F",
+		Results: []VersionResult{
+			{Version: "HTTP/1.0", Supported: true},
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2.0", Supported: false, Error: true},
+			{Version: "HTTP/3.0", Supported: false},
+		},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{name: "grade equality", expr: `grade == "F"`, want: true},
+		{name: "grade inequality", expr: `grade != "F"`, want: false},
+		{name: "indexed result", expr: `grade=="F" && results["HTTP/1.0"].supported`, want: true},
+		{name: "negation", expr: `!results["HTTP/3.0"].supported`, want: true},
+		{name: "numeric ordering", expr: `score < 80`, want: true},
+		{name: "or with parens", expr: `(score >= 90) || results["HTTP/2.0"].error`, want: true},
+		{name: "unknown field is null", expr: `nope == null`, want: true},
+		{name: "single quoted string", expr: `target == 'example.com'`, want: true},
+		{name: "mixed types never order", expr: `grade > 1`, want: false},
+		{name: "objects never equal", expr: `results == results`, want: false},
+		{name: "objects never unequal", expr: `results != results`, want: false},
+		{name: "object against scalar", expr: `results["HTTP/1.0"] == "x"`, want: false},
+		{name: "non-ASCII field is null", expr: `grädé == null`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ParseWhere(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseWhere(%q): %v", tt.expr, err)
+			}
+			if got := w.Match(res); got != tt.want {
+				t.Fatalf("Match(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	for _, expr := range []string{
+		`grade ==`,
+		`grade == "F`,
+		`(grade == "F"`,
+		`results[grade]`,
+		`grade = "F"`,
+		`grade == "F" €`,
+	} {
+		if _, err := ParseWhere(expr); err == nil {
+			t.Errorf("ParseWhere(%q) succeeded, want error", expr)
+		}
+	}
+}
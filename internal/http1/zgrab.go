@@ -0,0 +1,129 @@
+package http1
+
+import (
+	"net"
+	"strings"
+)
+
+// ZgrabRecord maps a result onto the zgrab2 http module schema, so it can be
+// fed to pipelines built around zgrab2 or Censys-style data:
+//
+//	domain / ip                                   target host
+//	data.http.status                              "success" if HTTP/2 or HTTP/1.1 worked, else an error status
+//	data.http.protocol                            "http"
+//	data.http.result.response.protocol            highest TCP version: {name, major, minor}
+//	data.http.result.response.request.tls_log
+//	  .handshake_log.server_hello.version         negotiated TLS version {name, value}
+//	  .handshake_log.server_hello.alpn_protocol   negotiated ALPN
+//	data.http1                                    the full http1 result
+//
+// zgrab2 has no HTTP/3 module, so HTTP/3 and the other http1-specific
+// findings are only available under data.http1. The output reads back with
+// grade-import.
+func ZgrabRecord(res CheckResult) any {
+	type version struct {
+		Name  string `json:"name"`
+		Value int    `json:"value,omitempty"`
+	}
+	type serverHello struct {
+		Version version `json:"version"`
+		ALPN    string  `json:"alpn_protocol,omitempty"`
+	}
+	type protocol struct {
+		Name  string `json:"name"`
+		Major int    `json:"major"`
+		Minor int    `json:"minor"`
+	}
+	type handshakeLog struct {
+		ServerHello serverHello `json:"server_hello"`
+	}
+	type tlsLog struct {
+		HandshakeLog handshakeLog `json:"handshake_log"`
+	}
+	type request struct {
+		TLSLog tlsLog `json:"tls_log"`
+	}
+	type response struct {
+		Protocol *protocol `json:"protocol,omitempty"`
+		Request  *request  `json:"request,omitempty"`
+	}
+	type httpModule struct {
+		Status   string `json:"status"`
+		Protocol string `json:"protocol"`
+		Result   struct {
+			Response response `json:"response"`
+		} `json:"result"`
+		Error string `json:"error,omitempty"`
+	}
+	type record struct {
+		IP     string `json:"ip,omitempty"`
+		Domain string `json:"domain,omitempty"`
+		Data   struct {
+			HTTP  httpModule  `json:"http"`
+			HTTP1 CheckResult `json:"http1"`
+		} `json:"data"`
+	}
+
+	var rec record
+	host := targetHost(res.Target)
+	if net.ParseIP(host) != nil {
+		rec.IP = host
+	} else {
+		rec.Domain = host
+	}
+	rec.Data.HTTP1 = res
+
+	m := &rec.Data.HTTP
+	m.Protocol = "http"
+	m.Status = "success"
+	switch {
+	case versionResult(res, "HTTP/2.0").Supported:
+		m.Result.Response.Protocol = &protocol{Name: "HTTP/2.0", Major: 2}
+	case versionResult(res, "HTTP/1.1").Supported:
+		m.Result.Response.Protocol = &protocol{Name: "HTTP/1.1", Major: 1, Minor: 1}
+	default:
+		vr := versionResult(res, "HTTP/1.1")
+		m.Error = vr.Detail
+		m.Status = "unknown-error"
+		if strings.Contains(vr.Detail, "timeout") || strings.Contains(vr.Detail, "deadline exceeded") {
+			m.Status = "connection-timeout"
+		}
+	}
+
+	if res.TLSVersion != "" {
+		hello := serverHello{
+			Version: version{
+				Name:  strings.Replace(res.TLSVersion, "TLS ", "TLSv", 1),
+				Value: tlsVersionValue(res.TLSVersion),
+			},
+			ALPN: res.ALPN,
+		}
+		m.Result.Response.Request = &request{TLSLog: tlsLog{HandshakeLog: handshakeLog{ServerHello: hello}}}
+	}
+	return rec
+}
+
+// versionResult returns the result for version, or a zero VersionResult.
+func versionResult(res CheckResult, version string) VersionResult {
+	for _, vr := range res.Results {
+		if vr.Version == version {
+			return vr
+		}
+	}
+	return VersionResult{}
+}
+
+// tlsVersionValue is the inverse of tlsVersionName for CheckResult labels.
+func tlsVersionValue(name string) int {
+	switch name {
+	case "TLS 1.3":
+		return 0x0304
+	case "TLS 1.2":
+		return 0x0303
+	case "TLS 1.1":
+		return 0x0302
+	case "TLS 1.0":
+		return 0x0301
+	}
+	return 0
+}
@@ -0,0 +1,32 @@
+package http1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestZgrabRecordRoundTrip(t *testing.T) {
+	res := CheckResult{
+		Target:     "https://example.com/",
+		Port:       "443",
+		ALPN:       "h2",
+		TLSVersion: "TLS 1.3",
+		Grade:      "A",
+		Results: []VersionResult{
+			{Version: "HTTP/1.1", Supported: true},
+			{Version: "HTTP/2.0", Supported: true},
+		},
+	}
+	data, err := json.Marshal(ZgrabRecord(res))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obs, err := ParseScanRecord(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Observation{Target: "example.com", Port: "443", ALPN: "h2", TLSVersion: "TLS 1.3", HTTPProto: "HTTP/2.0"}
+	if obs != want {
+		t.Fatalf("round trip = %+v, want %+v", obs, want)
+	}
+}